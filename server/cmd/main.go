@@ -3,9 +3,15 @@ package main
 import (
 	"fmt"
 	"gin-quickstart/config"
+	"gin-quickstart/internal/blobstore"
 	"gin-quickstart/internal/handler"
+	"gin-quickstart/internal/jwtkeys"
+	"gin-quickstart/internal/lockout"
+	"gin-quickstart/internal/mailer"
 	"gin-quickstart/internal/middleware"
 	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/oidc"
+	"gin-quickstart/internal/ratelimit"
 	"gin-quickstart/internal/repository"
 	"gin-quickstart/internal/routes"
 	"gin-quickstart/internal/service"
@@ -13,6 +19,7 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"gorm.io/gorm"
 )
 
 func main() {
@@ -23,6 +30,9 @@ func main() {
 
 	// Load configuration
 	config.LoadConfig()
+	if err := config.AppConfig.Validate(); err != nil {
+		log.Fatalf("❌ Invalid configuration: %v", err)
+	}
 
 	// Initialize database connection
 	db, err := config.InitDB()
@@ -31,19 +41,98 @@ func main() {
 	}
 	defer config.CloseDB()
 
+	// One-off data migrations are recorded, some of them run before the schema migration
+	if err := db.AutoMigrate(&model.DataMigration{}); err != nil {
+		log.Fatalf("❌ Failed to migrate database: %v", err)
+	}
+	dataMigrationRepo := repository.NewDataMigrationRepository(db)
+
+	// Record owners were added to existing contacts and deals: the column is added nullable,
+	// backfilled with the creator and only then made NOT NULL, which AutoMigrate cannot do
+	if _, err := dataMigrationRepo.RunOnce("backfill_record_owners", func(tx *gorm.DB) error {
+		for _, table := range []string{"contacts", "deals"} {
+			if !tx.Migrator().HasTable(table) || tx.Migrator().HasColumn(table, "owner_id") {
+				continue
+			}
+			for _, statement := range []string{
+				"ALTER TABLE " + table + " ADD COLUMN owner_id bigint",
+				"UPDATE " + table + " SET owner_id = created_by",
+				"ALTER TABLE " + table + " ALTER COLUMN owner_id SET NOT NULL",
+			} {
+				if err := tx.Exec(statement).Error; err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}); err != nil {
+		log.Fatalf("❌ Failed to add owners to contacts and deals: %v", err)
+	}
+
+	// User emails became unique ignoring case (idx_users_email_lower): existing addresses
+	// are lowercased first, which fails while two accounts differ only in case
+	if _, err := dataMigrationRepo.RunOnce("lowercase_user_emails", func(tx *gorm.DB) error {
+		if !tx.Migrator().HasTable(&model.User{}) {
+			return nil
+		}
+		return tx.Exec("UPDATE users SET email = LOWER(TRIM(email)) WHERE email <> LOWER(TRIM(email))").Error
+	}); err != nil {
+		log.Fatalf("❌ Failed to lowercase user emails, merge accounts whose emails differ only in case: %v", err)
+	}
+
 	// Auto migrate database schema
 	if err := db.AutoMigrate(
 		&model.Tenant{},
 		&model.User{},
 		&model.TenantUser{},
+		&model.Permission{},
+		&model.Role{},
+		&model.Session{},
+		&model.RefreshToken{},
+		&model.PasswordResetToken{},
+		&model.EmailChangeToken{},
+		&model.MFARecoveryCode{},
+		&model.MFAChallenge{},
 		&model.TenantSetting{},
+		&model.Team{},
+		&model.Invitation{},
+		&model.APIKey{},
+		&model.TenantSSOConfig{},
+		&model.UserIdentity{},
+		&model.SSOLogin{},
+		&model.TenantTombstone{},
+		&model.TenantExport{},
+		&model.TenantImport{},
+		&model.ContactImport{},
+		&model.ContactMerge{},
+		&model.CustomFieldDefinition{},
+		&model.Impersonation{},
 		&model.AuditLog{},
+		&model.SecurityEvent{},
+		&model.DataMigration{},
+		&model.Company{},
 		&model.Contact{},
 		&model.PipelineStage{},
 		&model.Deal{},
 	); err != nil {
 		log.Fatalf("❌ Failed to migrate database: %v", err)
 	}
+
+	// Audit entries of API key actions have no user
+	if db.Migrator().HasConstraint(&model.AuditLog{}, "fk_audit_logs_user") {
+		db.Migrator().DropConstraint(&model.AuditLog{}, "fk_audit_logs_user")
+	}
+
+	// Replaced by the case-insensitive idx_users_email_lower
+	if db.Migrator().HasIndex(&model.User{}, "idx_users_email") {
+		db.Migrator().DropIndex(&model.User{}, "idx_users_email")
+	}
+
+	// Role names became unique per tenant (idx_tenant_role_name)
+	if db.Migrator().HasIndex(&model.Role{}, "idx_tenant_role") {
+		db.Migrator().DropIndex(&model.Role{}, "idx_tenant_role")
+	}
+
 	log.Println("✅ Database migration completed")
 
 	// Initialize repositories
@@ -54,23 +143,175 @@ func main() {
 	contactRepo := repository.NewContactRepository(db)
 	pipelineStageRepo := repository.NewPipelineStageRepository(db)
 	dealRepo := repository.NewDealRepository(db)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	sessionRepo := repository.NewSessionRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	settingRepo := repository.NewTenantSettingRepository(db)
+	teamRepo := repository.NewTeamRepository(db)
+	invitationRepo := repository.NewInvitationRepository(db)
+	passwordResetRepo := repository.NewPasswordResetRepository(db)
+	emailChangeRepo := repository.NewEmailChangeRepository(db)
+	mfaRepo := repository.NewMFARepository(db)
+	securityEventRepo := repository.NewSecurityEventRepository(db)
+	apiKeyRepo := repository.NewAPIKeyRepository(db)
+	ssoRepo := repository.NewSSORepository(db)
+	tenantPurgeRepo := repository.NewTenantPurgeRepository(db)
+	tenantExportRepo := repository.NewTenantExportRepository(db)
+	tenantImportRepo := repository.NewTenantImportRepository(db)
+	contactImportRepo := repository.NewContactImportRepository(db)
+	contactMergeRepo := repository.NewContactMergeRepository(db)
+	customFieldRepo := repository.NewCustomFieldRepository(db)
+	companyRepo := repository.NewCompanyRepository(db)
+	impersonationRepo := repository.NewImpersonationRepository(db)
+
+	// Owner foreign keys were created with ON DELETE SET NULL on NOT NULL columns. Recreate
+	// them as RESTRICT, once: users are soft deleted, and records must keep their owner.
+	if _, err := dataMigrationRepo.RunOnce("restrict_record_owner_delete", func(tx *gorm.DB) error {
+		for _, record := range []interface{}{&model.Company{}, &model.Contact{}, &model.Deal{}} {
+			if tx.Migrator().HasConstraint(record, "Owner") {
+				if err := tx.Migrator().DropConstraint(record, "Owner"); err != nil {
+					return err
+				}
+			}
+			if err := tx.Migrator().CreateConstraint(record, "Owner"); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		log.Fatalf("❌ Failed to update record owner constraints: %v", err)
+	}
+
+	// Contacts saved before companies existed are linked to a company of their company name, once
+	var createdCompanies int64
+	if ran, err := dataMigrationRepo.RunOnce("link_contact_companies", func(tx *gorm.DB) error {
+		var err error
+		createdCompanies, err = repository.NewCompanyRepository(tx).LinkContacts(0)
+		return err
+	}); err != nil {
+		log.Fatalf("❌ Failed to link contacts to companies: %v", err)
+	} else if ran {
+		log.Printf("✅ Created %d companies from contact company names", createdCompanies)
+	}
+
+	// The "owner" record visibility was replaced by "manager", under which members still
+	// see only their own records, once
+	if _, err := dataMigrationRepo.RunOnce("replace_owner_record_visibility", func(tx *gorm.DB) error {
+		return tx.Model(&model.TenantSetting{}).
+			Where("key = ? AND value = ?", model.SettingRecordVisibility, "owner").
+			Update("value", model.VisibilityManager).Error
+	}); err != nil {
+		log.Fatalf("❌ Failed to update record visibility settings: %v", err)
+	}
+
+	// Keep the permission catalog in sync with the code
+	if err := roleRepo.SyncPermissionCatalog(model.PermissionCatalog); err != nil {
+		log.Fatalf("❌ Failed to sync permission catalog: %v", err)
+	}
+	if err := roleRepo.GrantAllPermissionsToAdmins(); err != nil {
+		log.Fatalf("❌ Failed to sync admin permissions: %v", err)
+	}
+
+	// Tenants created before owners existed are owned by their first admin
+	if assigned, err := tenantRepo.AssignMissingOwners(); err != nil {
+		log.Fatalf("❌ Failed to assign tenant owners: %v", err)
+	} else if assigned > 0 {
+		log.Printf("✅ Assigned owners to %d tenants", assigned)
+	}
+
+	// Platform operators are managed through PLATFORM_ADMIN_USER_IDS only. IDs rather than
+	// emails, since anyone can register an address that is listed but not yet taken.
+	if err := userRepo.SyncPlatformAdmins(config.AppConfig.Platform.AdminUserIDs); err != nil {
+		log.Fatalf("❌ Failed to sync platform admins: %v", err)
+	}
+
+	// Live tenant membership lookups (shared by middleware and services for invalidation)
+	membershipCache := middleware.NewMembershipCache(tenantUserRepo, roleRepo, settingRepo, teamRepo, config.AppConfig.Cache.MembershipTTL)
+
+	// Outgoing email (MAIL_DRIVER=smtp in production)
+	mail := mailer.New(config.AppConfig.Mail)
+
+	// Generated files such as exports (local disk; swap the store for object storage)
+	blobs := blobstore.New(config.AppConfig.Storage)
+
+	// Failed login tracking (in-process; swap the store to share limits across instances)
+	loginLimiter := lockout.New(lockout.NewMemoryStore(), config.AppConfig.Lockout)
+
+	// Access token signing keys (rotated in the background for RS256/EdDSA)
+	tokenKeys, err := jwtkeys.New(config.AppConfig.JWT)
+	if err != nil {
+		log.Fatalf("❌ Failed to load JWT keys: %v", err)
+	}
+	tokenKeys.Start()
+
+	// Per-tenant and per-user API rate limits
+	rateLimiter := ratelimit.New()
 
 	// Initialize services
-	authService := service.NewAuthService(userRepo, tenantRepo, tenantUserRepo)
-	tenantService := service.NewTenantService(tenantRepo, userRepo, tenantUserRepo, auditLogRepo)
-	auditService := service.NewAuditService(auditLogRepo)
-	contactService := service.NewContactService(contactRepo, auditLogRepo)
-	dashboardService := service.NewDashboardService(contactRepo, auditLogRepo)
+	authService := service.NewAuthService(userRepo, tenantRepo, tenantUserRepo, roleRepo)
+	roleService := service.NewRoleService(roleRepo, membershipCache)
+	tokenService := service.NewTokenService(refreshTokenRepo, sessionRepo, userRepo, tenantUserRepo, tokenKeys)
+	tenantService := service.NewTenantService(tenantRepo, userRepo, tenantUserRepo, auditLogRepo, settingRepo, teamRepo, roleService, membershipCache)
+	tenantLifecycleService := service.NewTenantLifecycleService(tenantRepo, tenantUserRepo, userRepo, tenantPurgeRepo, blobs, membershipCache, config.AppConfig.Tenant)
+	auditService := service.NewAuditService(auditLogRepo, tenantUserRepo, securityEventRepo)
+	contactService := service.NewContactService(contactRepo, tenantUserRepo, teamRepo, auditLogRepo, settingRepo, customFieldRepo, companyRepo)
+	dashboardService := service.NewDashboardService(contactRepo, auditLogRepo, teamRepo)
 	pipelineStageService := service.NewPipelineStageService(pipelineStageRepo)
-	dealService := service.NewDealService(dealRepo, pipelineStageRepo, contactRepo)
+	dealService := service.NewDealService(dealRepo, pipelineStageRepo, contactRepo, tenantUserRepo, teamRepo, customFieldRepo, companyRepo)
+	teamService := service.NewTeamService(teamRepo, userRepo, tenantUserRepo, membershipCache)
+	passwordService := service.NewPasswordService(userRepo, passwordResetRepo, tokenService, mail)
+	profileService := service.NewProfileService(userRepo, emailChangeRepo, tenantRepo, tenantUserRepo, teamRepo, tokenService, mail, membershipCache)
+	apiKeyService := service.NewAPIKeyService(apiKeyRepo, roleRepo)
+	mfaService := service.NewMFAService(mfaRepo, userRepo, membershipCache)
+	invitationService := service.NewInvitationService(invitationRepo, userRepo, tenantRepo, tenantUserRepo, roleService, mail, membershipCache)
+	exportService := service.NewExportService(tenantExportRepo, auditLogRepo, blobs, config.AppConfig.Export, config.AppConfig.Server.PublicURL)
+	importService := service.NewImportService(tenantImportRepo, auditLogRepo, blobs)
+	contactImportService := service.NewContactImportService(contactImportRepo, contactRepo, auditLogRepo, settingRepo, customFieldRepo, companyRepo, blobs)
+	contactMergeService := service.NewContactMergeService(contactMergeRepo, contactRepo, tenantUserRepo, customFieldRepo, config.AppConfig.Contacts.MergeUndoWindow)
+	customFieldService := service.NewCustomFieldService(customFieldRepo)
+	companyService := service.NewCompanyService(companyRepo, contactRepo, tenantUserRepo)
+	platformService := service.NewPlatformService(tenantRepo, tenantUserRepo, userRepo, sessionRepo, impersonationRepo, tenantLifecycleService, tokenService, config.AppConfig.Platform.ImpersonationExpiry)
+	ssoService := service.NewSSOService(ssoRepo, userRepo, tenantRepo, tenantUserRepo, roleService, oidc.NewClient(config.AppConfig.Server.GinMode != "release"), membershipCache)
+
+	// Contacts saved before duplicate detection get their E.164 phone keys
+	if updated, err := contactService.BackfillPhoneKeys(); err != nil {
+		log.Fatalf("❌ Failed to normalize contact phone numbers: %v", err)
+	} else if updated > 0 {
+		log.Printf("✅ Normalized phone numbers of %d contacts", updated)
+	}
+
+	// Permanently delete tenants whose deletion grace period has ended
+	tenantLifecycleService.StartPurgeJob()
+
+	// Resume queued exports and imports, delete export archives past their retention period
+	exportService.StartExportJob()
+	importService.StartImportJob()
+	contactImportService.StartContactImportJob()
 
 	// Initialize handlers
-	authHandler := handler.NewAuthHandler(authService, tenantUserRepo)
-	tenantHandler := handler.NewTenantHandler(tenantService, auditService)
+	authHandler := handler.NewAuthHandler(authService, tokenService, passwordService, mfaService, ssoService, auditService, tenantUserRepo, loginLimiter)
+	tenantHandler := handler.NewTenantHandler(tenantService, tenantLifecycleService, auditService)
 	contactHandler := handler.NewContactHandler(contactService, auditService)
 	dashboardHandler := handler.NewDashboardHandler(dashboardService)
 	pipelineStageHandler := handler.NewPipelineStageHandler(pipelineStageService, auditService)
 	dealHandler := handler.NewDealHandler(dealService, auditService)
+	roleHandler := handler.NewRoleHandler(roleService, auditService)
+	teamHandler := handler.NewTeamHandler(teamService, auditService)
+	invitationHandler := handler.NewInvitationHandler(invitationService, tokenService, mfaService, auditService)
+	mfaHandler := handler.NewMFAHandler(mfaService, auditService, loginLimiter)
+	apiKeyHandler := handler.NewAPIKeyHandler(apiKeyService, auditService)
+	ssoHandler := handler.NewSSOHandler(ssoService, auditService)
+	sessionHandler := handler.NewSessionHandler(tokenService, auditService)
+	profileHandler := handler.NewProfileHandler(profileService, auditService)
+	exportHandler := handler.NewExportHandler(exportService, auditService)
+	importHandler := handler.NewImportHandler(importService, auditService)
+	contactImportHandler := handler.NewContactImportHandler(contactImportService, auditService)
+	contactMergeHandler := handler.NewContactMergeHandler(contactMergeService, auditService)
+	customFieldHandler := handler.NewCustomFieldHandler(customFieldService, auditService)
+	companyHandler := handler.NewCompanyHandler(companyService, auditService)
+	platformHandler := handler.NewPlatformHandler(platformService, auditService)
+	metricsHandler := handler.NewMetricsHandler(rateLimiter, config.AppConfig.RateLimit.MetricsToken)
+	jwksHandler := handler.NewJWKSHandler(tokenKeys)
 
 	// Setup Gin router
 	gin.SetMode(config.AppConfig.Server.GinMode)
@@ -82,7 +323,7 @@ func main() {
 	router.Use(middleware.CORS())
 
 	// Setup routes
-	routes.SetupRoutes(router, authHandler, tenantHandler, contactHandler, dashboardHandler, pipelineStageHandler, dealHandler)
+	routes.SetupRoutes(router, middleware.AuthMiddleware(tokenKeys, userRepo, apiKeyRepo, sessionRepo), middleware.TenantMiddleware(membershipCache), middleware.RateLimit(rateLimiter, tokenKeys, membershipCache, config.AppConfig.RateLimit), middleware.RequirePlatformAdmin(userRepo, config.AppConfig.Platform.RequireMFA), authHandler, tenantHandler, contactHandler, dashboardHandler, pipelineStageHandler, dealHandler, roleHandler, teamHandler, invitationHandler, mfaHandler, apiKeyHandler, ssoHandler, sessionHandler, profileHandler, exportHandler, importHandler, contactImportHandler, contactMergeHandler, customFieldHandler, companyHandler, platformHandler, metricsHandler, jwksHandler)
 
 	// Start server
 	port := config.AppConfig.Server.Port
@@ -1,16 +1,28 @@
 package config
 
 import (
+	"errors"
 	"log"
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 type Config struct {
-	Database DatabaseConfig
-	JWT      JWTConfig
-	Server   ServerConfig
+	Database  DatabaseConfig
+	JWT       JWTConfig
+	Server    ServerConfig
+	Cache     CacheConfig
+	Auth      AuthConfig
+	Lockout   LockoutConfig
+	RateLimit RateLimitConfig
+	Mail      MailConfig
+	Tenant    TenantConfig
+	Storage   StorageConfig
+	Export    ExportConfig
+	Platform  PlatformConfig
+	Contacts  ContactsConfig
 }
 
 type DatabaseConfig struct {
@@ -26,13 +38,94 @@ type DatabaseConfig struct {
 }
 
 type JWTConfig struct {
-	Secret string
-	Expiry time.Duration
+	Algorithm       string        // HS256 (shared secret), RS256 or EdDSA (key files)
+	Secret          string        // HS256 only
+	KeysDir         string        // RS256/EdDSA: directory of PEM key files, one per kid
+	RotationPeriod  time.Duration // Generate a new signing key this often (0 = manual rotation)
+	ActivationDelay time.Duration // New keys are published this long before they sign tokens
+	Expiry          time.Duration // Access token lifetime
+	RefreshExpiry   time.Duration // Refresh token lifetime
+}
+
+// defaultJWTSecrets are the placeholder secrets shipped in code and .env.example
+var defaultJWTSecrets = map[string]bool{
+	"":                            true,
+	"your-secret-key-change-this": true,
+	"your-secret-key-change-this-in-production": true,
 }
 
 type ServerConfig struct {
-	Port    string
-	GinMode string
+	Port        string
+	GinMode     string
+	FrontendURL string // Base URL used in links sent by email
+	PublicURL   string // Base URL of this API as reached by browsers (SSO callbacks)
+}
+
+type CacheConfig struct {
+	MembershipTTL time.Duration // How long a resolved tenant membership is trusted
+}
+
+type AuthConfig struct {
+	InvitationExpiry    time.Duration // How long an invitation link stays valid
+	PasswordResetExpiry time.Duration // How long a password reset link stays valid
+	EmailChangeExpiry   time.Duration // How long an email change confirmation link stays valid
+	MFAIssuer           string        // Issuer name shown in authenticator apps
+	MFAChallengeExpiry  time.Duration // How long the second login step may take
+	SSOLoginExpiry      time.Duration // How long a user may take at the identity provider
+}
+
+type LockoutConfig struct {
+	MaxFailures      int           // Failed logins per email before it is locked
+	MaxFailuresPerIP int           // Failed logins per client IP before it is locked
+	LockoutBase      time.Duration // First lockout; doubles with every further failure
+	LockoutMax       time.Duration // Upper bound for a single lockout
+	FailureWindow    time.Duration // Failures are forgotten after this long without a new one
+}
+
+type RateLimitConfig struct {
+	Enabled      bool
+	TenantRPM    int    // Default requests per minute for a whole tenant
+	UserRPM      int    // Default requests per minute per user within a tenant
+	MaxTenantRPM int    // Upper bound for the tenant's rate_limit_tenant_rpm setting
+	MaxUserRPM   int    // Upper bound for the tenant's rate_limit_user_rpm setting
+	MetricsToken string // Bearer token required by /metrics (open when empty, except in release mode)
+}
+
+type MailConfig struct {
+	Driver       string // "smtp" or "log"
+	From         string
+	LogFile      string // Log driver: append emails to this file instead of the log
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+}
+
+type TenantConfig struct {
+	DeletionGracePeriod time.Duration // How long a deletion request can be cancelled
+	PurgeInterval       time.Duration // How often the purge job looks for tenants past their grace period
+	PurgeBatchSize      int           // Rows deleted per statement while purging
+}
+
+type StorageConfig struct {
+	Driver string // Blob store for generated files: "local"
+	Dir    string // Local driver: root directory
+}
+
+type ExportConfig struct {
+	URLSigningKey string        // Signs download links (random per process when empty)
+	URLExpiry     time.Duration // How long a download link stays valid
+	Retention     time.Duration // Export archives are deleted this long after completion
+}
+
+type PlatformConfig struct {
+	AdminUserIDs        []uint        // Users with these IDs are platform operators
+	RequireMFA          bool          // Platform operators must have two-factor authentication enabled
+	ImpersonationExpiry time.Duration // Lifetime of an impersonation session
+}
+
+type ContactsConfig struct {
+	MergeUndoWindow time.Duration // How long a contact merge can be undone
 }
 
 var AppConfig *Config
@@ -51,19 +144,104 @@ func LoadConfig() *Config {
 			ConnMaxLifetime: getEnvAsDuration("DB_CONN_MAX_LIFETIME", 5*time.Minute),
 		},
 		JWT: JWTConfig{
-			Secret: getEnv("JWT_SECRET", "your-secret-key-change-this"),
-			Expiry: getEnvAsDuration("JWT_EXPIRY", 24*time.Hour),
+			Algorithm:       getEnv("JWT_ALGORITHM", "HS256"),
+			Secret:          getEnv("JWT_SECRET", "your-secret-key-change-this"),
+			KeysDir:         getEnv("JWT_KEYS_DIR", "./keys"),
+			RotationPeriod:  getEnvAsDuration("JWT_KEY_ROTATION", 0),
+			ActivationDelay: getEnvAsDuration("JWT_KEY_ACTIVATION_DELAY", 10*time.Minute),
+			Expiry:          getEnvAsDuration("JWT_EXPIRY", 15*time.Minute),
+			RefreshExpiry:   getEnvAsDuration("JWT_REFRESH_EXPIRY", 30*24*time.Hour),
 		},
 		Server: ServerConfig{
-			Port:    getEnv("PORT", "8080"),
-			GinMode: getEnv("GIN_MODE", "debug"),
+			Port:        getEnv("PORT", "8080"),
+			GinMode:     getEnv("GIN_MODE", "debug"),
+			FrontendURL: getEnv("FRONTEND_URL", "http://localhost:5173"),
+			PublicURL:   getEnv("PUBLIC_URL", "http://localhost:8080"),
+		},
+		Cache: CacheConfig{
+			MembershipTTL: getEnvAsDuration("MEMBERSHIP_CACHE_TTL", 30*time.Second),
+		},
+		Auth: AuthConfig{
+			InvitationExpiry:    getEnvAsDuration("INVITATION_EXPIRY", 7*24*time.Hour),
+			PasswordResetExpiry: getEnvAsDuration("PASSWORD_RESET_EXPIRY", time.Hour),
+			EmailChangeExpiry:   getEnvAsDuration("EMAIL_CHANGE_EXPIRY", 24*time.Hour),
+			MFAIssuer:           getEnv("MFA_ISSUER", "CRM"),
+			MFAChallengeExpiry:  getEnvAsDuration("MFA_CHALLENGE_EXPIRY", 5*time.Minute),
+			SSOLoginExpiry:      getEnvAsDuration("SSO_LOGIN_EXPIRY", 10*time.Minute),
 		},
+		Lockout: LockoutConfig{
+			MaxFailures:      getEnvAsInt("LOGIN_MAX_FAILURES", 5),
+			MaxFailuresPerIP: getEnvAsInt("LOGIN_MAX_FAILURES_PER_IP", 20),
+			LockoutBase:      getEnvAsDuration("LOGIN_LOCKOUT_BASE", time.Minute),
+			LockoutMax:       getEnvAsDuration("LOGIN_LOCKOUT_MAX", time.Hour),
+			FailureWindow:    getEnvAsDuration("LOGIN_FAILURE_WINDOW", time.Hour),
+		},
+		RateLimit: RateLimitConfig{
+			Enabled:      getEnv("RATE_LIMIT_ENABLED", "true") == "true",
+			TenantRPM:    getEnvAsInt("RATE_LIMIT_TENANT_RPM", 600),
+			UserRPM:      getEnvAsInt("RATE_LIMIT_USER_RPM", 120),
+			MaxTenantRPM: getEnvAsInt("RATE_LIMIT_MAX_TENANT_RPM", 6000),
+			MaxUserRPM:   getEnvAsInt("RATE_LIMIT_MAX_USER_RPM", 1200),
+			MetricsToken: getEnv("METRICS_TOKEN", ""),
+		},
+		Mail: MailConfig{
+			Driver:       getEnv("MAIL_DRIVER", "log"),
+			From:         getEnv("MAIL_FROM", "no-reply@localhost"),
+			LogFile:      getEnv("MAIL_LOG_FILE", ""),
+			SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+			SMTPPort:     getEnv("SMTP_PORT", "587"),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		},
+		Tenant: TenantConfig{
+			DeletionGracePeriod: getEnvAsDuration("TENANT_DELETION_GRACE_PERIOD", 30*24*time.Hour),
+			PurgeInterval:       getEnvAsDuration("TENANT_PURGE_INTERVAL", time.Hour),
+			PurgeBatchSize:      getEnvAsInt("TENANT_PURGE_BATCH_SIZE", 1000),
+		},
+		Storage: StorageConfig{
+			Driver: getEnv("STORAGE_DRIVER", "local"),
+			Dir:    getEnv("STORAGE_DIR", "./data"),
+		},
+		Export: ExportConfig{
+			URLSigningKey: getEnv("EXPORT_URL_SIGNING_KEY", ""),
+			URLExpiry:     getEnvAsDuration("EXPORT_URL_EXPIRY", 15*time.Minute),
+			Retention:     getEnvAsDuration("EXPORT_RETENTION", 7*24*time.Hour),
+		},
+		Platform: PlatformConfig{
+			AdminUserIDs:        getEnvAsIDList("PLATFORM_ADMIN_USER_IDS"),
+			RequireMFA:          getEnv("PLATFORM_REQUIRE_MFA", "true") == "true",
+			ImpersonationExpiry: getEnvAsDuration("IMPERSONATION_EXPIRY", 30*time.Minute),
+		},
+		Contacts: ContactsConfig{
+			MergeUndoWindow: getEnvAsDuration("CONTACT_MERGE_UNDO_WINDOW", 7*24*time.Hour),
+		},
+	}
+
+	if len(getEnvAsList("PLATFORM_ADMIN_EMAILS")) > 0 {
+		log.Println("⚠️  PLATFORM_ADMIN_EMAILS is no longer supported, list platform operators in PLATFORM_ADMIN_USER_IDS")
 	}
 
 	log.Println("✅ Configuration loaded successfully")
 	return AppConfig
 }
 
+// Validate rejects configurations that are unsafe to run with
+func (c *Config) Validate() error {
+	switch c.JWT.Algorithm {
+	case "HS256":
+		if c.Server.GinMode == "release" && defaultJWTSecrets[c.JWT.Secret] {
+			return errors.New("JWT_SECRET is not set or still the default value, refusing to start in release mode")
+		}
+	case "RS256", "EdDSA":
+		if c.JWT.KeysDir == "" {
+			return errors.New("JWT_KEYS_DIR is required for " + c.JWT.Algorithm)
+		}
+	default:
+		return errors.New("JWT_ALGORITHM must be HS256, RS256 or EdDSA")
+	}
+	return nil
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -80,6 +258,31 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvAsList splits a comma-separated value, dropping empty entries
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// getEnvAsIDList parses a comma-separated list of IDs, dropping invalid entries
+func getEnvAsIDList(key string) []uint {
+	var ids []uint
+	for _, value := range getEnvAsList(key) {
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil || id == 0 {
+			log.Printf("⚠️  Ignoring invalid ID %q in %s", value, key)
+			continue
+		}
+		ids = append(ids, uint(id))
+	}
+	return ids
+}
+
 func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
@@ -0,0 +1,37 @@
+package blobstore
+
+import (
+	"errors"
+	"gin-quickstart/config"
+	"io"
+	"log"
+)
+
+// ErrNotFound is returned when no blob is stored under a key
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps generated files (e.g. tenant exports) outside the database. Keys are
+// slash-separated paths such as "tenants/1/exports/7.zip". LocalStore works for a single
+// instance; an object storage implementation (S3, GCS, ...) shares files across instances.
+type Store interface {
+	// Put stores everything read from r under key and returns the number of bytes
+	// written. A failed Put leaves nothing behind.
+	Put(key string, r io.Reader) (int64, error)
+	// Open returns the blob stored under key
+	Open(key string) (io.ReadCloser, error)
+	// Delete removes the blob; deleting a missing blob is not an error
+	Delete(key string) error
+	// DeletePrefix removes every blob whose key starts with prefix (ending in "/")
+	DeletePrefix(prefix string) error
+}
+
+// New returns the store selected by STORAGE_DRIVER ("local")
+func New(cfg config.StorageConfig) Store {
+	switch cfg.Driver {
+	case "local":
+		return NewLocalStore(cfg.Dir)
+	default:
+		log.Printf("⚠️  Unknown STORAGE_DRIVER %q, falling back to local storage", cfg.Driver)
+		return NewLocalStore(cfg.Dir)
+	}
+}
@@ -0,0 +1,76 @@
+package blobstore
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+)
+
+// LocalStore keeps blobs as files below a root directory
+type LocalStore struct {
+	dir string
+}
+
+func NewLocalStore(dir string) *LocalStore {
+	return &LocalStore{dir: dir}
+}
+
+// Put writes to a temporary file first so that readers never see a partial blob
+func (s *LocalStore) Put(key string, r io.Reader) (int64, error) {
+	target := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
+		return 0, err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name()) // No-op once renamed
+
+	written, err := io.Copy(tmp, r)
+	if err != nil {
+		tmp.Close()
+		return 0, err
+	}
+	if err := tmp.Close(); err != nil {
+		return 0, err
+	}
+
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return 0, err
+	}
+	return written, nil
+}
+
+func (s *LocalStore) Open(key string) (io.ReadCloser, error) {
+	f, err := os.Open(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	return f, err
+}
+
+func (s *LocalStore) Delete(key string) error {
+	err := os.Remove(s.path(key))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// DeletePrefix treats the prefix as a directory and removes it with its contents
+func (s *LocalStore) DeletePrefix(prefix string) error {
+	if path.Clean("/"+prefix) == "/" {
+		return errors.New("refusing to delete the storage root")
+	}
+	return os.RemoveAll(s.path(prefix))
+}
+
+// path maps a key to a file below the root; cleaning it as an absolute path keeps ".."
+// segments from escaping the root
+func (s *LocalStore) path(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(path.Clean("/"+key)))
+}
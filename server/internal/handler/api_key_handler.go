@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"gin-quickstart/internal/middleware"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/service"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type APIKeyHandler struct {
+	apiKeyService service.APIKeyService
+	auditService  service.AuditService
+}
+
+func NewAPIKeyHandler(apiKeyService service.APIKeyService, auditService service.AuditService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+		auditService:  auditService,
+	}
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" binding:"required"`
+	Scopes    []string   `json:"scopes" binding:"required"`
+	ExpiresAt *time.Time `json:"expires_at"` // Optional, RFC 3339
+}
+
+// GetAPIKeys returns the tenant's API keys (without the secret keys)
+func (h *APIKeyHandler) GetAPIKeys(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+
+	keys, err := h.apiKeyService.GetAPIKeys(tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch API keys"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"api_keys": keys,
+		"total":    len(keys),
+	})
+}
+
+// CreateAPIKey creates a key. The key itself is only returned in this response.
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+
+	// A key would outlive the impersonation session and act as the impersonated user
+	if middleware.GetOperatorID(c) != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "API keys cannot be created while impersonating"})
+		return
+	}
+
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	apiKey, rawKey, err := h.apiKeyService.CreateAPIKey(tenantID, userID, req.Name, req.Scopes, req.ExpiresAt, middleware.GetPermissions(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "create",
+		Resource:   "api_key",
+		ResourceID: apiKey.ID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "API key created. Store the key now, it cannot be shown again",
+		"api_key": apiKey,
+		"key":     rawKey,
+	})
+}
+
+// RevokeAPIKey permanently disables a key
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		return
+	}
+
+	if err := h.apiKeyService.RevokeAPIKey(tenantID, uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "revoke",
+		Resource:   "api_key",
+		ResourceID: uint(id),
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "API key revoked successfully"})
+}
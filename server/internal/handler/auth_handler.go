@@ -1,9 +1,16 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
+	"gin-quickstart/config"
+	"gin-quickstart/internal/lockout"
 	"gin-quickstart/internal/middleware"
+	"gin-quickstart/internal/model"
 	"gin-quickstart/internal/repository"
 	"gin-quickstart/internal/service"
+	"log"
+	"math"
 	"net/http"
 	"strconv"
 
@@ -11,14 +18,35 @@ import (
 )
 
 type AuthHandler struct {
-	authService    service.AuthService
-	tenantUserRepo repository.TenantUserRepository
+	authService     service.AuthService
+	tokenService    service.TokenService
+	passwordService service.PasswordService
+	mfaService      service.MFAService
+	ssoService      service.SSOService
+	auditService    service.AuditService
+	tenantUserRepo  repository.TenantUserRepository
+	loginLimiter    *lockout.Limiter
 }
 
-func NewAuthHandler(authService service.AuthService, tenantUserRepo repository.TenantUserRepository) *AuthHandler {
+func NewAuthHandler(
+	authService service.AuthService,
+	tokenService service.TokenService,
+	passwordService service.PasswordService,
+	mfaService service.MFAService,
+	ssoService service.SSOService,
+	auditService service.AuditService,
+	tenantUserRepo repository.TenantUserRepository,
+	loginLimiter *lockout.Limiter,
+) *AuthHandler {
 	return &AuthHandler{
-		authService:    authService,
-		tenantUserRepo: tenantUserRepo,
+		authService:     authService,
+		tokenService:    tokenService,
+		passwordService: passwordService,
+		mfaService:      mfaService,
+		ssoService:      ssoService,
+		auditService:    auditService,
+		tenantUserRepo:  tenantUserRepo,
+		loginLimiter:    loginLimiter,
 	}
 }
 
@@ -35,6 +63,38 @@ type LoginRequest struct {
 	TenantID uint   `json:"tenant_id"` // Optional: if user belongs to multiple tenants
 }
 
+type VerifyMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"` // TOTP code or recovery code
+}
+
+type SSOExchangeRequest struct {
+	Code string `json:"code" binding:"required"` // One-time code from the SSO callback redirect
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+	AllSessions  bool   `json:"all_sessions"` // Also revoke every other session of the user
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
 // Register creates a new user and their tenant
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req RegisterRequest
@@ -57,22 +117,26 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	// Generate JWT token
-	token, err := middleware.GenerateToken(user.ID, tenant.ID, user.Email, "admin")
+	// Generate access and refresh tokens
+	tokens, err := h.tokenService.IssueTokens(user, tenant.ID, "admin", c.ClientIP(), c.GetHeader("User-Agent"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "Registration successful",
-		"user":    user,
-		"tenant":  tenant,
-		"token":   token,
+		"message":            "Registration successful",
+		"user":               user,
+		"tenant":             tenant,
+		"token":              tokens.AccessToken,
+		"refresh_token":      tokens.RefreshToken,
+		"expires_in":         tokens.ExpiresIn,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
 	})
 }
 
-// Login authenticates a user
+// Login authenticates a user. Users with two-factor authentication enabled receive an
+// MFA challenge token instead of tokens, to be exchanged at VerifyMFA.
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -80,57 +144,248 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
+	// Reserve the attempt before checking the password, refusing locked accounts and IPs,
+	// so parallel attempts cannot all pass before any of them is counted
+	attempt, lock, err := h.loginLimiter.Begin(req.Email, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login attempts"})
+		return
+	}
+	if lock != nil {
+		respondLocked(c, lock)
+		return
+	}
+	defer h.loginLimiter.Release(attempt)
+
 	// Authenticate user
 	user, err := h.authService.Login(req.Email, req.Password)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			if lock := h.recordLoginFailure(c, req.Email, nil, attempt); lock != nil {
+				respondLocked(c, lock)
+				return
+			}
+		}
 		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Get user's tenants
-	tenantUsers, err := h.tenantUserRepo.FindTenantsByUser(user.ID)
-	if err != nil || len(tenantUsers) == 0 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User not associated with any tenant"})
+	tenantUsers, current, ok := h.resolveLoginTenant(c, user.ID, req.TenantID)
+	if !ok {
+		return
+	}
+
+	// Failures are only cleared once the second factor has been passed too
+	if user.MFAEnabled {
+		h.respondWithMFAChallenge(c, user.ID, current.TenantID)
+		return
+	}
+
+	h.loginLimiter.RecordSuccess(user.Email)
+	h.respondWithLogin(c, user, current, tenantUsers)
+}
+
+// VerifyMFA completes a two-step login with a TOTP or recovery code
+func (h *AuthHandler) VerifyMFA(c *gin.Context) {
+	var req VerifyMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// If tenant_id specified, use that; otherwise use first tenant
-	var tenantID uint
-	var role string
+	challengeUser, err := h.mfaService.ChallengeUser(req.MFAToken)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidMFAChallenge) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+
+	// Wrong codes count towards the account lockout like wrong passwords, reserved up front
+	// like password attempts
+	attempt, lock, err := h.loginLimiter.Begin(challengeUser.Email, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login attempts"})
+		return
+	}
+	if lock != nil {
+		respondLocked(c, lock)
+		return
+	}
+	defer h.loginLimiter.Release(attempt)
 
-	if req.TenantID > 0 {
-		// Verify user has access to specified tenant
-		for _, tu := range tenantUsers {
-			if tu.TenantID == req.TenantID {
-				tenantID = tu.TenantID
-				role = tu.Role
-				break
+	verification, err := h.mfaService.VerifyChallenge(req.MFAToken, req.Code)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidMFACode) && verification != nil {
+			if lock := h.recordLoginFailure(c, verification.User.Email, &verification.User.ID, attempt); lock != nil {
+				respondLocked(c, lock)
+				return
 			}
 		}
-		if tenantID == 0 {
-			c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to specified tenant"})
+		if errors.Is(err, service.ErrInvalidMFAChallenge) || errors.Is(err, service.ErrInvalidMFACode) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			return
 		}
-	} else {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify code"})
+		return
+	}
+
+	user := verification.User
+	h.loginLimiter.RecordSuccess(user.Email)
+
+	if verification.UsedRecoveryCode {
+		h.auditService.LogForUser(c, user.ID, &model.AuditLog{
+			UserID:     user.ID,
+			Action:     "mfa_recovery_code_used",
+			Resource:   "user",
+			ResourceID: user.ID,
+			IPAddress:  c.ClientIP(),
+			UserAgent:  c.GetHeader("User-Agent"),
+		})
+	}
+
+	tenantUsers, current, ok := h.resolveLoginTenant(c, user.ID, verification.TenantID)
+	if !ok {
+		return
+	}
+
+	h.respondWithLogin(c, user, current, tenantUsers)
+}
+
+// SSOExchange completes a single sign-on login with the one-time code the callback
+// handed to the frontend. Users with two-factor authentication still get a challenge.
+func (h *AuthHandler) SSOExchange(c *gin.Context) {
+	var req SSOExchangeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, tenantID, err := h.ssoService.ExchangeCode(req.Code)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidSSOLogin) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to complete single sign-on"})
+		return
+	}
+
+	tenantUsers, current, ok := h.resolveLoginTenant(c, user.ID, tenantID)
+	if !ok {
+		return
+	}
+
+	if user.MFAEnabled {
+		h.respondWithMFAChallenge(c, user.ID, current.TenantID)
+		return
+	}
+
+	h.respondWithLogin(c, user, current, tenantUsers)
+}
+
+// recordLoginFailure counts the reserved attempt of a failed login and records a security
+// event when it triggers a lockout. Returns the applied lock, if any.
+func (h *AuthHandler) recordLoginFailure(c *gin.Context, email string, userID *uint, attempt *lockout.Attempt) *lockout.Lock {
+	return recordAttemptFailure(c, h.loginLimiter, h.auditService, email, userID, attempt)
+}
+
+// recordAttemptFailure counts a failed attempt reserved with the limiter, logging a
+// security event when it triggers a lockout. Returns the applied lock, if any.
+func recordAttemptFailure(c *gin.Context, limiter *lockout.Limiter, auditService service.AuditService, email string, userID *uint, attempt *lockout.Attempt) *lockout.Lock {
+	lock, err := limiter.Fail(attempt)
+	if err != nil {
+		log.Printf("⚠️  Failed to record login failure: %v", err)
+		return nil
+	}
+	if lock == nil {
+		return nil
+	}
+
+	auditService.LogSecurityEvent(&model.SecurityEvent{
+		Event:     model.SecurityEventLoginLockout,
+		Email:     email,
+		UserID:    userID,
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+		Details:   fmt.Sprintf("%s locked for %s after %d failed attempts", lock.Scope, lock.RetryAfter, lock.Failures),
+	})
+
+	return lock
+}
+
+// respondLocked rejects a login attempt during a lockout
+func respondLocked(c *gin.Context, lock *lockout.Lock) {
+	retryAfter := int64(math.Ceil(lock.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+	c.JSON(http.StatusTooManyRequests, gin.H{
+		"error":       "Too many failed login attempts, please try again later",
+		"retry_after": retryAfter,
+	})
+}
+
+// resolveLoginTenant picks the tenant to sign in to: the requested one if the user
+// belongs to it, otherwise their first tenant. Writes the error response on failure.
+func (h *AuthHandler) resolveLoginTenant(c *gin.Context, userID, requestedTenantID uint) ([]model.TenantUser, *model.TenantUser, bool) {
+	// Get user's tenants
+	tenantUsers, err := h.tenantUserRepo.FindTenantsByUser(userID)
+	if err != nil || len(tenantUsers) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User not associated with any tenant"})
+		return nil, nil, false
+	}
+
+	if requestedTenantID == 0 {
 		// Use first tenant
-		tenantID = tenantUsers[0].TenantID
-		role = tenantUsers[0].Role
+		return tenantUsers, &tenantUsers[0], true
+	}
+
+	// Verify user has access to specified tenant
+	for i := range tenantUsers {
+		if tenantUsers[i].TenantID == requestedTenantID {
+			return tenantUsers, &tenantUsers[i], true
+		}
+	}
+
+	c.JSON(http.StatusForbidden, gin.H{"error": "Access denied to specified tenant"})
+	return nil, nil, false
+}
+
+// respondWithMFAChallenge starts the second login step
+func (h *AuthHandler) respondWithMFAChallenge(c *gin.Context, userID, tenantID uint) {
+	mfaToken, err := h.mfaService.CreateChallenge(userID, tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start two-factor authentication"})
+		return
 	}
 
-	// Generate JWT token with tenant context
-	token, err := middleware.GenerateToken(user.ID, tenantID, user.Email, role)
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Two-factor authentication required",
+		"mfa_required": true,
+		"mfa_token":    mfaToken,
+		"expires_in":   int64(config.AppConfig.Auth.MFAChallengeExpiry.Seconds()),
+	})
+}
+
+// respondWithLogin issues tokens with tenant context and writes the login response
+func (h *AuthHandler) respondWithLogin(c *gin.Context, user *model.User, current *model.TenantUser, tenantUsers []model.TenantUser) {
+	tokens, err := h.tokenService.IssueTokens(user, current.TenantID, current.Role, c.ClientIP(), c.GetHeader("User-Agent"))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":           "Login successful",
-		"user":              user,
-		"token":             token,
-		"tenant_id":         tenantID,
-		"role":              role,
-		"available_tenants": tenantUsers,
+		"message":            "Login successful",
+		"user":               user,
+		"token":              tokens.AccessToken,
+		"refresh_token":      tokens.RefreshToken,
+		"expires_in":         tokens.ExpiresIn,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
+		"tenant_id":          current.TenantID,
+		"role":               current.Role,
+		"available_tenants":  tenantUsers,
 	})
 }
 
@@ -149,7 +404,7 @@ func (h *AuthHandler) GetMyTenants(c *gin.Context) {
 	})
 }
 
-// SwitchTenant generates a new token for a different tenant
+// SwitchTenant moves the current session to a different tenant and issues new tokens
 func (h *AuthHandler) SwitchTenant(c *gin.Context) {
 	userID := middleware.GetUserID(c)
 	tenantIDStr := c.Param("tenant_id")
@@ -172,16 +427,175 @@ func (h *AuthHandler) SwitchTenant(c *gin.Context) {
 	}
 
 	email, _ := c.Get("email")
-	token, err := middleware.GenerateToken(userID, uint(tenantID), email.(string), tenantUser.Role)
+	user := &model.User{ID: userID, Email: email.(string)}
+	tokens, err := h.tokenService.SwitchTenant(middleware.GetSessionID(c), user, uint(tenantID), tenantUser.Role, c.ClientIP(), c.GetHeader("User-Agent"))
 	if err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":   "Tenant switched successfully",
-		"token":     token,
-		"tenant_id": tenantID,
-		"role":      tenantUser.Role,
+		"message":            "Tenant switched successfully",
+		"token":              tokens.AccessToken,
+		"refresh_token":      tokens.RefreshToken,
+		"expires_in":         tokens.ExpiresIn,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
+		"tenant_id":          tenantID,
+		"role":               tenantUser.Role,
+	})
+}
+
+// Refresh exchanges a refresh token for a new token pair (rotation)
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tokens, err := h.tokenService.Refresh(req.RefreshToken, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) || errors.Is(err, service.ErrRefreshTokenReused) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "Token refreshed successfully",
+		"token":              tokens.AccessToken,
+		"refresh_token":      tokens.RefreshToken,
+		"expires_in":         tokens.ExpiresIn,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
+	})
+}
+
+// Logout revokes the refresh token family (and optionally every session of the user)
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req LogoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.tokenService.Logout(req.RefreshToken, req.AllSessions); err != nil {
+		if errors.Is(err, service.ErrInvalidRefreshToken) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to logout"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logout successful"})
+}
+
+// ForgotPassword emails a password reset link. The response is the same whether or not
+// the account exists.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.passwordService.ForgotPassword(req.Email, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password reset request"})
+		return
+	}
+
+	if user != nil {
+		h.auditService.LogForUser(c, user.ID, &model.AuditLog{
+			UserID:     user.ID,
+			Action:     "password_reset_requested",
+			Resource:   "user",
+			ResourceID: user.ID,
+			IPAddress:  c.ClientIP(),
+			UserAgent:  c.GetHeader("User-Agent"),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "If an account exists for this email, a password reset link has been sent"})
+}
+
+// ResetPassword sets a new password using an emailed reset token and signs out all sessions
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.passwordService.ResetPassword(req.Token, req.Password)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.auditService.LogForUser(c, user.ID, &model.AuditLog{
+		UserID:     user.ID,
+		Action:     "password_reset",
+		Resource:   "user",
+		ResourceID: user.ID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset, please login again"})
+}
+
+// ChangePassword changes the authenticated user's password. Every session is revoked and
+// a fresh token pair is returned for the current one.
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	tenantID := middleware.GetTenantID(c)
+
+	var req ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.passwordService.ChangePassword(userID, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.auditService.LogForUser(c, user.ID, &model.AuditLog{
+		UserID:     user.ID,
+		Action:     "password_changed",
+		Resource:   "user",
+		ResourceID: user.ID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	// Re-read the role: the JWT claim may be stale
+	tenantUser, err := h.tenantUserRepo.FindByTenantAndUser(tenantID, userID)
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully, please login again"})
+		return
+	}
+
+	tokens, err := h.tokenService.IssueTokens(user, tenantID, tenantUser.Role, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"message": "Password changed successfully, please login again"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "Password changed successfully",
+		"token":              tokens.AccessToken,
+		"refresh_token":      tokens.RefreshToken,
+		"expires_in":         tokens.ExpiresIn,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
 	})
 }
@@ -0,0 +1,258 @@
+package handler
+
+import (
+	"errors"
+	"gin-quickstart/internal/middleware"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CompanyHandler struct {
+	companyService service.CompanyService
+	auditService   service.AuditService
+}
+
+func NewCompanyHandler(companyService service.CompanyService, auditService service.AuditService) *CompanyHandler {
+	return &CompanyHandler{
+		companyService: companyService,
+		auditService:   auditService,
+	}
+}
+
+// CompanyRequest is the body of a company creation
+type CompanyRequest struct {
+	Name       string `json:"name" binding:"required"`
+	Domain     string `json:"domain"`
+	Industry   string `json:"industry"`
+	Size       string `json:"size"`
+	Address    string `json:"address"`
+	City       string `json:"city"`
+	Province   string `json:"province"`
+	PostalCode string `json:"postal_code"`
+	Country    string `json:"country"`
+	Notes      string `json:"notes"`
+	OwnerID    uint   `json:"owner_id"`
+}
+
+// GetCompanies returns a list of companies filtered by search, industry, size and owner_id
+func (h *CompanyHandler) GetCompanies(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	filter := &model.CompanyFilter{
+		Search:   c.Query("search"),
+		Industry: c.Query("industry"),
+		Size:     c.Query("size"),
+	}
+	if ownerIDStr := c.Query("owner_id"); ownerIDStr != "" {
+		if ownerID, err := strconv.ParseUint(ownerIDStr, 10, 32); err == nil {
+			ownerIDUint := uint(ownerID)
+			filter.OwnerID = &ownerIDUint
+		}
+	}
+
+	companies, total, err := h.companyService.GetCompanies(tenantID, filter, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch companies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"companies":   companies,
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+	})
+}
+
+// SearchCompanies searches companies by name and domain
+func (h *CompanyHandler) SearchCompanies(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	query := c.Query("q")
+
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
+		return
+	}
+
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	companies, total, err := h.companyService.SearchCompanies(tenantID, query, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search companies"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"companies": companies,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+		"query":     query,
+	})
+}
+
+// GetCompany returns a company with its contact count, open deal value, won revenue and
+// first contacts, counting only the contacts and deals the user may see
+func (h *CompanyHandler) GetCompany(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
+		return
+	}
+
+	detail, err := h.companyService.GetCompanyDetail(middleware.GetTenantID(c), uint(id), middleware.GetRecordAccess(c))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, detail)
+}
+
+// CreateCompany creates a company, owned by the creator unless owner_id is given
+func (h *CompanyHandler) CreateCompany(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+
+	var req CompanyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	company := &model.Company{
+		TenantID:   tenantID,
+		CreatedBy:  userID,
+		OwnerID:    req.OwnerID,
+		Name:       req.Name,
+		Domain:     req.Domain,
+		Industry:   req.Industry,
+		Size:       req.Size,
+		Address:    req.Address,
+		City:       req.City,
+		Province:   req.Province,
+		PostalCode: req.PostalCode,
+		Country:    req.Country,
+		Notes:      req.Notes,
+	}
+	if err := h.companyService.CreateCompany(company); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "create",
+		Resource:   "company",
+		ResourceID: company.ID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Company created successfully",
+		"company": company,
+	})
+}
+
+// UpdateCompany changes the attributes given in the body; a new name is copied to the
+// company's contacts
+func (h *CompanyHandler) UpdateCompany(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
+		return
+	}
+
+	var req service.CompanyUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	company, err := h.companyService.UpdateCompany(tenantID, uint(id), companyOwnerScope(c), &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "update",
+		Resource:   "company",
+		ResourceID: company.ID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Company updated successfully",
+		"company": company,
+	})
+}
+
+// DeleteCompany deletes a company; its contacts and deals are unlinked and the company
+// name is cleared from its contacts
+func (h *CompanyHandler) DeleteCompany(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid company ID"})
+		return
+	}
+
+	company, err := h.companyService.DeleteCompany(tenantID, uint(id), companyOwnerScope(c))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "delete",
+		Resource:   "company",
+		ResourceID: company.ID,
+		Metadata: map[string]interface{}{
+			"name": company.Name,
+		},
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Company deleted successfully"})
+}
+
+// companyOwnerScope returns the user when their permission is limited to their own
+// records, who may then only change the companies they own, or 0
+func companyOwnerScope(c *gin.Context) uint {
+	if middleware.IsOwnScope(c) {
+		return middleware.GetUserID(c)
+	}
+	return 0
+}
+
+func (h *CompanyHandler) respondError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrCompanyNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
@@ -1,12 +1,18 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"gin-quickstart/internal/middleware"
 	"gin-quickstart/internal/model"
 	"gin-quickstart/internal/service"
+	"gin-quickstart/internal/spreadsheet"
+	"log"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -38,13 +44,18 @@ func (h *ContactHandler) CreateContact(c *gin.Context) {
 	req.TenantID = tenantID
 	req.CreatedBy = userID
 
-	if err := h.contactService.CreateContact(&req); err != nil {
+	duplicates, err := h.contactService.CreateContact(&req)
+	if err != nil {
+		if errors.Is(err, service.ErrDuplicateContact) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "duplicates": duplicates})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Log audit
-	h.auditService.Log(&model.AuditLog{
+	h.auditService.Log(c, &model.AuditLog{
 		TenantID:   tenantID,
 		UserID:     userID,
 		Action:     "create",
@@ -54,10 +65,14 @@ func (h *ContactHandler) CreateContact(c *gin.Context) {
 		UserAgent:  c.GetHeader("User-Agent"),
 	})
 
-	c.JSON(http.StatusCreated, gin.H{
+	response := gin.H{
 		"message": "Contact created successfully",
 		"contact": req,
-	})
+	}
+	if len(duplicates) > 0 {
+		response["duplicates"] = duplicates
+	}
+	c.JSON(http.StatusCreated, response)
 }
 
 // GetContact returns a single contact by ID
@@ -69,7 +84,7 @@ func (h *ContactHandler) GetContact(c *gin.Context) {
 		return
 	}
 
-	contact, err := h.contactService.GetContact(tenantID, uint(id))
+	contact, err := h.contactService.GetContact(tenantID, uint(id), middleware.GetRecordAccess(c))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
@@ -78,6 +93,27 @@ func (h *ContactHandler) GetContact(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"contact": contact})
 }
 
+// GetDuplicates lists the other contacts matching a contact's email, phone numbers or
+// name and company
+func (h *ContactHandler) GetDuplicates(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid contact ID"})
+		return
+	}
+
+	duplicates, err := h.contactService.FindDuplicates(middleware.GetTenantID(c), uint(id), middleware.GetRecordAccess(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if duplicates == nil {
+		duplicates = []service.ContactDuplicate{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"duplicates": duplicates})
+}
+
 // GetContacts returns a list of contacts with filtering and pagination
 func (h *ContactHandler) GetContacts(c *gin.Context) {
 	tenantID := middleware.GetTenantID(c)
@@ -86,21 +122,13 @@ func (h *ContactHandler) GetContacts(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
 
-	// Parse filters
-	filter := &model.ContactFilter{
-		Search:   c.Query("search"),
-		Status:   c.Query("status"),
-		Source:   c.Query("source"),
-		City:     c.Query("city"),
-		Province: c.Query("province"),
-	}
+	filter := contactFilterFromQuery(c)
 
-	// Parse tags (comma-separated)
-	if tagsStr := c.Query("tags"); tagsStr != "" {
-		filter.Tags = strings.Split(tagsStr, ",")
+	contacts, total, err := h.contactService.GetContacts(tenantID, filter, page, pageSize, middleware.GetRecordAccess(c))
+	if errors.Is(err, service.ErrInvalidFilter) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-
-	contacts, total, err := h.contactService.GetContacts(tenantID, filter, page, pageSize)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch contacts"})
 		return
@@ -115,6 +143,140 @@ func (h *ContactHandler) GetContacts(c *gin.Context) {
 	})
 }
 
+// contactFilterFromQuery parses the contact list filters: search, status, source, city,
+// province, tags (comma-separated), team_id, company_id, custom fields, sort_by and
+// sort_order
+func contactFilterFromQuery(c *gin.Context) *model.ContactFilter {
+	filter := &model.ContactFilter{
+		Search:       c.Query("search"),
+		Status:       c.Query("status"),
+		Source:       c.Query("source"),
+		City:         c.Query("city"),
+		Province:     c.Query("province"),
+		CustomFields: customFieldConditionsFromQuery(c),
+		SortBy:       c.Query("sort_by"),
+		SortOrder:    c.Query("sort_order"),
+	}
+
+	// Parse tags (comma-separated)
+	if tagsStr := c.Query("tags"); tagsStr != "" {
+		filter.Tags = strings.Split(tagsStr, ",")
+	}
+
+	// Team filter
+	if teamIDStr := c.Query("team_id"); teamIDStr != "" {
+		if teamID, err := strconv.ParseUint(teamIDStr, 10, 32); err == nil {
+			teamIDUint := uint(teamID)
+			filter.TeamID = &teamIDUint
+		}
+	}
+
+	// Company filter
+	if companyIDStr := c.Query("company_id"); companyIDStr != "" {
+		if companyID, err := strconv.ParseUint(companyIDStr, 10, 32); err == nil {
+			companyIDUint := uint(companyID)
+			filter.CompanyID = &companyIDUint
+		}
+	}
+
+	return filter
+}
+
+// customFieldConditionsFromQuery parses custom field filters: custom_fields.<key>=value
+// for equality, custom_fields.<key>.gte=value and custom_fields.<key>.lte=value for ranges
+func customFieldConditionsFromQuery(c *gin.Context) []model.CustomFieldCondition {
+	query := c.Request.URL.Query()
+	params := make([]string, 0, len(query))
+	for param := range query {
+		if strings.HasPrefix(param, model.CustomFieldPrefix) {
+			params = append(params, param)
+		}
+	}
+	sort.Strings(params)
+
+	var conditions []model.CustomFieldCondition
+	for _, param := range params {
+
+		key, op := strings.TrimPrefix(param, model.CustomFieldPrefix), "eq"
+		if i := strings.LastIndex(key, "."); i >= 0 {
+			key, op = key[:i], key[i+1:]
+		}
+		if op != "eq" && op != "gte" && op != "lte" {
+			continue
+		}
+		conditions = append(conditions, model.CustomFieldCondition{Key: key, Op: op, Value: query.Get(param)})
+	}
+	return conditions
+}
+
+// ExportContacts streams the contacts matching the GetContacts filters as a CSV or XLSX
+// file (?format=csv|xlsx) with the selected ?columns
+func (h *ContactHandler) ExportContacts(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+
+	format := c.DefaultQuery("format", spreadsheet.FormatCSV)
+	if format != spreadsheet.FormatCSV && format != spreadsheet.FormatXLSX {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv or xlsx"})
+		return
+	}
+
+	customColumns, err := h.contactService.ExportColumns(tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export contacts"})
+		return
+	}
+
+	columns, err := model.ParseContactExportColumns(c.Query("columns"), customColumns)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	filter := contactFilterFromQuery(c)
+	if err := h.contactService.CheckFilter(tenantID, filter); err != nil {
+		if errors.Is(err, service.ErrInvalidFilter) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export contacts"})
+		return
+	}
+
+	filename := fmt.Sprintf("contacts-%s.%s", time.Now().UTC().Format("20060102-150405"), format)
+	c.Header("Content-Type", spreadsheet.ContentType(format))
+	c.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+
+	count := 0
+	writer, err := spreadsheet.NewWriter(c.Writer, format)
+	if err == nil {
+		count, err = h.contactService.ExportContacts(tenantID, filter, columns, middleware.GetRecordAccess(c), writer)
+	}
+	if err != nil {
+		// Headers are sent already, the client receives a truncated file
+		log.Printf("⚠️  Contact export of tenant %d failed after %d rows: %v", tenantID, count, err)
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:  tenantID,
+		UserID:    userID,
+		Action:    "contacts_exported",
+		Resource:  "contact",
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+		Metadata: map[string]interface{}{
+			"format":   format,
+			"rows":     count,
+			"columns":  columns,
+			"query":    c.Request.URL.RawQuery,
+			"complete": err == nil,
+		},
+	})
+}
+
 // UpdateContact updates an existing contact
 func (h *ContactHandler) UpdateContact(c *gin.Context) {
 	tenantID := middleware.GetTenantID(c)
@@ -132,13 +294,13 @@ func (h *ContactHandler) UpdateContact(c *gin.Context) {
 	}
 
 	req.ID = uint(id)
-	if err := h.contactService.UpdateContact(tenantID, &req); err != nil {
+	if err := h.contactService.UpdateContact(tenantID, &req, middleware.GetRecordAccess(c)); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Log audit
-	h.auditService.Log(&model.AuditLog{
+	h.auditService.Log(c, &model.AuditLog{
 		TenantID:   tenantID,
 		UserID:     userID,
 		Action:     "update",
@@ -164,13 +326,13 @@ func (h *ContactHandler) DeleteContact(c *gin.Context) {
 		return
 	}
 
-	if err := h.contactService.DeleteContact(tenantID, uint(id)); err != nil {
+	if err := h.contactService.DeleteContact(tenantID, uint(id), middleware.GetRecordAccess(c)); err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Log audit
-	h.auditService.Log(&model.AuditLog{
+	h.auditService.Log(c, &model.AuditLog{
 		TenantID:   tenantID,
 		UserID:     userID,
 		Action:     "delete",
@@ -183,6 +345,82 @@ func (h *ContactHandler) DeleteContact(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Contact deleted successfully"})
 }
 
+// ReassignOwner hands a contact to another tenant member
+func (h *ContactHandler) ReassignOwner(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid contact ID"})
+		return
+	}
+
+	var req struct {
+		OwnerID uint `json:"owner_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.contactService.ReassignOwner(tenantID, uint(id), req.OwnerID, middleware.GetRecordAccess(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "reassign_owner",
+		Resource:   "contact",
+		ResourceID: uint(id),
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Contact owner updated successfully", "owner_id": req.OwnerID})
+}
+
+// AssignTeam shares a contact with a team, or unassigns it when team_id is null
+func (h *ContactHandler) AssignTeam(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid contact ID"})
+		return
+	}
+
+	var req struct {
+		TeamID *uint `json:"team_id"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.contactService.AssignTeam(tenantID, uint(id), req.TeamID, middleware.GetRecordAccess(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "assign_team",
+		Resource:   "contact",
+		ResourceID: uint(id),
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Contact team updated successfully", "team_id": req.TeamID})
+}
+
 // SearchContacts searches contacts by query
 func (h *ContactHandler) SearchContacts(c *gin.Context) {
 	tenantID := middleware.GetTenantID(c)
@@ -196,7 +434,7 @@ func (h *ContactHandler) SearchContacts(c *gin.Context) {
 	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
 	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
 
-	contacts, total, err := h.contactService.SearchContacts(tenantID, query, page, pageSize)
+	contacts, total, err := h.contactService.SearchContacts(tenantID, query, page, pageSize, middleware.GetRecordAccess(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search contacts"})
 		return
@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"gin-quickstart/internal/middleware"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxContactImportFileSize bounds uploaded contact spreadsheets
+const maxContactImportFileSize = 50 << 20
+
+type ContactImportHandler struct {
+	contactImportService service.ContactImportService
+	auditService         service.AuditService
+}
+
+func NewContactImportHandler(contactImportService service.ContactImportService, auditService service.AuditService) *ContactImportHandler {
+	return &ContactImportHandler{
+		contactImportService: contactImportService,
+		auditService:         auditService,
+	}
+}
+
+// UploadImport stores a CSV or XLSX file (multipart field "file", optional form field
+// "delimiter" for CSV) and returns its columns with a suggested mapping
+func (h *ContactImportHandler) UploadImport(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxContactImportFileSize)
+	header, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A .csv or .xlsx file is required in the \"file\" field (max 50 MB)"})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read the uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	fields, err := h.contactImportService.Fields(tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load the contact fields"})
+		return
+	}
+
+	imp, err := h.contactImportService.Upload(tenantID, requester(c), header.Filename, file, c.PostForm("delimiter"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "contact_import_uploaded",
+		Resource:   "contact_import",
+		ResourceID: imp.ID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "File uploaded, confirm the column mapping to start the import",
+		"import":  contactImportResponse(imp),
+		"fields":  fields,
+	})
+}
+
+// GetImports lists the tenant's recent contact imports
+func (h *ContactImportHandler) GetImports(c *gin.Context) {
+	imports, err := h.contactImportService.ListImports(middleware.GetTenantID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load imports"})
+		return
+	}
+
+	responses := make([]contactImportView, 0, len(imports))
+	for i := range imports {
+		responses = append(responses, contactImportResponse(&imports[i]))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"imports": responses})
+}
+
+// GetImport returns an import's mapping and progress
+func (h *ContactImportHandler) GetImport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import ID"})
+		return
+	}
+
+	imp, err := h.contactImportService.GetImport(middleware.GetTenantID(c), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"import": contactImportResponse(imp)})
+}
+
+// PreviewImport validates the first rows with the given mapping without creating contacts
+func (h *ContactImportHandler) PreviewImport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import ID"})
+		return
+	}
+
+	var options service.ContactImportOptions
+	if !bindOptionalJSON(c, &options) {
+		return
+	}
+
+	rows, err := h.contactImportService.Preview(middleware.GetTenantID(c), uint(id), options)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"rows": rows})
+}
+
+// StartImport confirms the mapping and creates the contacts in the background
+func (h *ContactImportHandler) StartImport(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import ID"})
+		return
+	}
+
+	var options service.ContactImportOptions
+	if !bindOptionalJSON(c, &options) {
+		return
+	}
+
+	imp, err := h.contactImportService.Start(tenantID, uint(id), options)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "contact_import_started",
+		Resource:   "contact_import",
+		ResourceID: imp.ID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Import started",
+		"import":  contactImportResponse(imp),
+	})
+}
+
+// DownloadErrorReport returns the CSV of the rows that were not imported
+func (h *ContactImportHandler) DownloadErrorReport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import ID"})
+		return
+	}
+
+	imp, report, err := h.contactImportService.OpenErrorReport(middleware.GetTenantID(c), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	defer report.Close()
+
+	filename := fmt.Sprintf("contact-import-%d-errors.csv", imp.ID)
+	c.DataFromReader(http.StatusOK, -1, "text/csv; charset=utf-8", report, map[string]string{
+		"Content-Disposition": `attachment; filename="` + filename + `"`,
+		"Cache-Control":       "no-store",
+	})
+}
+
+func (h *ContactImportHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrContactImportStarted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrContactImportNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
+
+// bindOptionalJSON binds a JSON body if there is one; an empty body keeps the defaults
+func bindOptionalJSON(c *gin.Context, obj interface{}) bool {
+	if c.Request.ContentLength == 0 {
+		return true
+	}
+	if err := c.ShouldBindJSON(obj); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+	return true
+}
+
+// contactImportView adds the error report link to an import
+type contactImportView struct {
+	*model.ContactImport
+	ErrorReportURL string `json:"error_report_url,omitempty"`
+}
+
+func contactImportResponse(imp *model.ContactImport) contactImportView {
+	view := contactImportView{ContactImport: imp}
+	if imp.HasErrorReport() {
+		view.ErrorReportURL = fmt.Sprintf("/api/contacts/imports/%d/errors", imp.ID)
+	}
+	return view
+}
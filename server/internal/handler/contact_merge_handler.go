@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"errors"
+	"gin-quickstart/internal/middleware"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ContactMergeHandler struct {
+	contactMergeService service.ContactMergeService
+	auditService        service.AuditService
+}
+
+func NewContactMergeHandler(contactMergeService service.ContactMergeService, auditService service.AuditService) *ContactMergeHandler {
+	return &ContactMergeHandler{
+		contactMergeService: contactMergeService,
+		auditService:        auditService,
+	}
+}
+
+// MergeContacts merges a duplicate contact into a primary contact
+func (h *ContactMergeHandler) MergeContacts(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+
+	var req service.ContactMergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	merge, contact, err := h.contactMergeService.Merge(tenantID, userID, &req, middleware.GetRecordAccess(c))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "contacts_merged",
+		Resource:   "contact",
+		ResourceID: merge.PrimaryID,
+		Metadata: map[string]interface{}{
+			"merge_id":       merge.ID,
+			"duplicate_id":   merge.DuplicateID,
+			"moved_deal_ids": merge.MovedDealIDs,
+			"fields":         req.Fields,
+		},
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Contacts merged successfully",
+		"merge":   merge,
+		"contact": contact,
+	})
+}
+
+// GetMerges lists the tenant's contact merges the user may see with pagination, most recent first
+func (h *ContactMergeHandler) GetMerges(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "20"))
+
+	merges, total, err := h.contactMergeService.ListMerges(middleware.GetTenantID(c), page, pageSize, middleware.GetRecordAccess(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch merges"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"merges":      merges,
+		"total":       total,
+		"page":        page,
+		"page_size":   pageSize,
+		"total_pages": (total + int64(pageSize) - 1) / int64(pageSize),
+	})
+}
+
+// UndoMerge restores both contacts of a merge and moves the deals back
+func (h *ContactMergeHandler) UndoMerge(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid merge ID"})
+		return
+	}
+
+	merge, err := h.contactMergeService.Undo(tenantID, userID, uint(id), middleware.GetRecordAccess(c))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "contact_merge_undone",
+		Resource:   "contact",
+		ResourceID: merge.PrimaryID,
+		Metadata: map[string]interface{}{
+			"merge_id":     merge.ID,
+			"duplicate_id": merge.DuplicateID,
+		},
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Merge undone successfully",
+		"merge":   merge,
+	})
+}
+
+func (h *ContactMergeHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, service.ErrContactMergeNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, service.ErrContactMergeConflict),
+		errors.Is(err, service.ErrMergeNotUndoable),
+		errors.Is(err, service.ErrMergeUndoConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	}
+}
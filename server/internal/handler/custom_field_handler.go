@@ -0,0 +1,180 @@
+package handler
+
+import (
+	"errors"
+	"gin-quickstart/internal/middleware"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type CustomFieldHandler struct {
+	customFieldService service.CustomFieldService
+	auditService       service.AuditService
+}
+
+func NewCustomFieldHandler(customFieldService service.CustomFieldService, auditService service.AuditService) *CustomFieldHandler {
+	return &CustomFieldHandler{
+		customFieldService: customFieldService,
+		auditService:       auditService,
+	}
+}
+
+// CustomFieldRequest is the body of a custom field creation
+type CustomFieldRequest struct {
+	Entity   string      `json:"entity" binding:"required"`
+	Key      string      `json:"key" binding:"required"`
+	Label    string      `json:"label" binding:"required"`
+	Type     string      `json:"type" binding:"required"`
+	Options  []string    `json:"options"`
+	Required bool        `json:"required"`
+	Default  interface{} `json:"default"`
+	Position int         `json:"position"`
+}
+
+// GetFields lists the tenant's custom fields (?entity=contact|deal for one entity)
+func (h *CustomFieldHandler) GetFields(c *gin.Context) {
+	fields, err := h.customFieldService.ListFields(middleware.GetTenantID(c), c.Query("entity"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"custom_fields": fields,
+		"total":         len(fields),
+	})
+}
+
+// CreateField defines a custom contact or deal field
+func (h *CustomFieldHandler) CreateField(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+
+	var req CustomFieldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	field := &model.CustomFieldDefinition{
+		TenantID: tenantID,
+		Entity:   req.Entity,
+		Key:      req.Key,
+		Label:    req.Label,
+		Type:     req.Type,
+		Options:  req.Options,
+		Required: req.Required,
+		Default:  req.Default,
+		Position: req.Position,
+	}
+	if err := h.customFieldService.CreateField(field); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "create",
+		Resource:   "custom_field",
+		ResourceID: field.ID,
+		Metadata: map[string]interface{}{
+			"entity": field.Entity,
+			"key":    field.Key,
+			"type":   field.Type,
+		},
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "Custom field created successfully",
+		"custom_field": field,
+	})
+}
+
+// UpdateField changes the label, options, required flag, default or position of a field
+func (h *CustomFieldHandler) UpdateField(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid custom field ID"})
+		return
+	}
+
+	var req service.CustomFieldUpdate
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	field, err := h.customFieldService.UpdateField(tenantID, uint(id), &req)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "update",
+		Resource:   "custom_field",
+		ResourceID: field.ID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":      "Custom field updated successfully",
+		"custom_field": field,
+	})
+}
+
+// DeleteField deletes a field and its values on every contact or deal
+func (h *CustomFieldHandler) DeleteField(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid custom field ID"})
+		return
+	}
+
+	field, err := h.customFieldService.DeleteField(tenantID, uint(id))
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "delete",
+		Resource:   "custom_field",
+		ResourceID: field.ID,
+		Metadata: map[string]interface{}{
+			"entity": field.Entity,
+			"key":    field.Key,
+		},
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Custom field deleted successfully"})
+}
+
+func (h *CustomFieldHandler) respondError(c *gin.Context, err error) {
+	if errors.Is(err, service.ErrCustomFieldNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
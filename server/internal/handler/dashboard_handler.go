@@ -1,9 +1,11 @@
 package handler
 
 import (
+	"errors"
 	"gin-quickstart/internal/middleware"
 	"gin-quickstart/internal/service"
 	"net/http"
+	"strconv"
 
 	"github.com/gin-gonic/gin"
 )
@@ -32,14 +34,31 @@ func (h *DashboardHandler) GetStats(c *gin.Context) {
 		return
 	}
 
-	stats, err := h.dashboardService.GetDashboardStats(tenantID, period)
+	// Optional team filter
+	var teamID *uint
+	if teamIDStr := c.Query("team_id"); teamIDStr != "" {
+		parsed, err := strconv.ParseUint(teamIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid team ID"})
+			return
+		}
+		teamIDUint := uint(parsed)
+		teamID = &teamIDUint
+	}
+
+	stats, err := h.dashboardService.GetDashboardStats(tenantID, period, teamID, middleware.GetRecordAccess(c))
 	if err != nil {
+		if errors.Is(err, service.ErrTeamNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch dashboard stats"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"stats":  stats,
-		"period": period,
+		"stats":   stats,
+		"period":  period,
+		"team_id": teamID,
 	})
 }
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"gin-quickstart/internal/middleware"
 	"gin-quickstart/internal/model"
 	"gin-quickstart/internal/repository"
@@ -51,6 +52,22 @@ func (h *DealHandler) GetDeals(c *gin.Context) {
 		}
 	}
 
+	// Team filter
+	if teamIDStr := c.Query("team_id"); teamIDStr != "" {
+		if teamID, err := strconv.ParseUint(teamIDStr, 10, 32); err == nil {
+			teamIDUint := uint(teamID)
+			filter.TeamID = &teamIDUint
+		}
+	}
+
+	// Company filter
+	if companyIDStr := c.Query("company_id"); companyIDStr != "" {
+		if companyID, err := strconv.ParseUint(companyIDStr, 10, 32); err == nil {
+			companyIDUint := uint(companyID)
+			filter.CompanyID = &companyIDUint
+		}
+	}
+
 	// Value range filters
 	if minValueStr := c.Query("min_value"); minValueStr != "" {
 		if minValue, err := strconv.ParseFloat(minValueStr, 64); err == nil {
@@ -74,6 +91,9 @@ func (h *DealHandler) GetDeals(c *gin.Context) {
 	// Search
 	filter.Search = c.Query("search")
 
+	// Custom field filters
+	filter.CustomFields = customFieldConditionsFromQuery(c)
+
 	// Sorting
 	filter.SortBy = c.DefaultQuery("sort_by", "created_at")
 	filter.SortOrder = c.DefaultQuery("sort_order", "desc")
@@ -94,7 +114,11 @@ func (h *DealHandler) GetDeals(c *gin.Context) {
 	}
 
 	// Get deals
-	deals, total, err := h.dealService.GetDeals(tenantID, filter)
+	deals, total, err := h.dealService.GetDeals(tenantID, filter, middleware.GetRecordAccess(c))
+	if errors.Is(err, service.ErrInvalidFilter) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch deals"})
 		return
@@ -117,7 +141,7 @@ func (h *DealHandler) GetDeal(c *gin.Context) {
 		return
 	}
 
-	deal, err := h.dealService.GetDealByID(tenantID, uint(id))
+	deal, err := h.dealService.GetDealByID(tenantID, uint(id), middleware.GetRecordAccess(c))
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
@@ -141,13 +165,13 @@ func (h *DealHandler) CreateDeal(c *gin.Context) {
 	req.TenantID = tenantID
 	req.CreatedBy = userID
 
-	if err := h.dealService.CreateDeal(&req); err != nil {
+	if err := h.dealService.CreateDeal(&req, middleware.GetRecordAccess(c)); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Log audit
-	h.auditService.Log(&model.AuditLog{
+	h.auditService.Log(c, &model.AuditLog{
 		TenantID:   tenantID,
 		UserID:     userID,
 		Action:     "create",
@@ -180,13 +204,13 @@ func (h *DealHandler) UpdateDeal(c *gin.Context) {
 	}
 
 	req.ID = uint(id)
-	if err := h.dealService.UpdateDeal(tenantID, &req); err != nil {
+	if err := h.dealService.UpdateDeal(tenantID, &req, middleware.GetRecordAccess(c)); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Log audit
-	h.auditService.Log(&model.AuditLog{
+	h.auditService.Log(c, &model.AuditLog{
 		TenantID:   tenantID,
 		UserID:     userID,
 		Action:     "update",
@@ -212,13 +236,13 @@ func (h *DealHandler) DeleteDeal(c *gin.Context) {
 		return
 	}
 
-	if err := h.dealService.DeleteDeal(tenantID, uint(id)); err != nil {
+	if err := h.dealService.DeleteDeal(tenantID, uint(id), middleware.GetRecordAccess(c)); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Log audit
-	h.auditService.Log(&model.AuditLog{
+	h.auditService.Log(c, &model.AuditLog{
 		TenantID:   tenantID,
 		UserID:     userID,
 		Action:     "delete",
@@ -250,14 +274,14 @@ func (h *DealHandler) MoveToStage(c *gin.Context) {
 		return
 	}
 
-	deal, err := h.dealService.MoveToStage(tenantID, uint(id), req.StageID)
+	deal, err := h.dealService.MoveToStage(tenantID, uint(id), req.StageID, middleware.GetRecordAccess(c))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Log audit
-	h.auditService.Log(&model.AuditLog{
+	h.auditService.Log(c, &model.AuditLog{
 		TenantID:   tenantID,
 		UserID:     userID,
 		Action:     "move_stage",
@@ -292,13 +316,13 @@ func (h *DealHandler) UpdateStatus(c *gin.Context) {
 		return
 	}
 
-	if err := h.dealService.UpdateStatus(tenantID, uint(id), req.Status); err != nil {
+	if err := h.dealService.UpdateStatus(tenantID, uint(id), req.Status, middleware.GetRecordAccess(c)); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Log audit
-	h.auditService.Log(&model.AuditLog{
+	h.auditService.Log(c, &model.AuditLog{
 		TenantID:   tenantID,
 		UserID:     userID,
 		Action:     "update_status",
@@ -311,11 +335,99 @@ func (h *DealHandler) UpdateStatus(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Deal status updated successfully"})
 }
 
+// ReassignOwner hands a deal to another tenant member
+func (h *DealHandler) ReassignOwner(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deal ID"})
+		return
+	}
+
+	var req struct {
+		OwnerID uint `json:"owner_id" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.dealService.ReassignOwner(tenantID, uint(id), req.OwnerID, middleware.GetRecordAccess(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "reassign_owner",
+		Resource:   "deal",
+		ResourceID: uint(id),
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Deal owner updated successfully", "owner_id": req.OwnerID})
+}
+
+// AssignTeam shares a deal with a team, or unassigns it when team_id is null
+func (h *DealHandler) AssignTeam(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid deal ID"})
+		return
+	}
+
+	var req struct {
+		TeamID *uint `json:"team_id"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.dealService.AssignTeam(tenantID, uint(id), req.TeamID, middleware.GetRecordAccess(c)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "assign_team",
+		Resource:   "deal",
+		ResourceID: uint(id),
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Deal team updated successfully", "team_id": req.TeamID})
+}
+
 // GetPipelineValue returns total value by stage
 func (h *DealHandler) GetPipelineValue(c *gin.Context) {
 	tenantID := middleware.GetTenantID(c)
 
-	values, err := h.dealService.GetPipelineValue(tenantID)
+	// Optional team filter
+	var teamID *uint
+	if teamIDStr := c.Query("team_id"); teamIDStr != "" {
+		parsed, err := strconv.ParseUint(teamIDStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid team ID"})
+			return
+		}
+		teamIDUint := uint(parsed)
+		teamID = &teamIDUint
+	}
+
+	values, err := h.dealService.GetPipelineValue(tenantID, teamID, middleware.GetRecordAccess(c))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pipeline values"})
 		return
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"gin-quickstart/internal/middleware"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/service"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExportHandler struct {
+	exportService service.ExportService
+	auditService  service.AuditService
+}
+
+func NewExportHandler(exportService service.ExportService, auditService service.AuditService) *ExportHandler {
+	return &ExportHandler{
+		exportService: exportService,
+		auditService:  auditService,
+	}
+}
+
+// ExportResponse is an export with a signed download link once it has completed
+type ExportResponse struct {
+	model.TenantExport
+	DownloadURL       string     `json:"download_url,omitempty"`
+	DownloadExpiresAt *time.Time `json:"download_url_expires_at,omitempty"`
+}
+
+func (h *ExportHandler) toResponse(export *model.TenantExport) ExportResponse {
+	response := ExportResponse{TenantExport: *export}
+	if export.Status == model.ExportCompleted {
+		url, expiresAt := h.exportService.DownloadURL(export)
+		response.DownloadURL = url
+		response.DownloadExpiresAt = &expiresAt
+	}
+	return response
+}
+
+// CreateExport starts an asynchronous export of all tenant data
+func (h *ExportHandler) CreateExport(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+
+	export, err := h.exportService.RequestExport(tenantID, requester(c))
+	if err != nil {
+		if errors.Is(err, service.ErrExportInProgress) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "export_requested",
+		Resource:   "tenant_export",
+		ResourceID: export.ID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Export started",
+		"export":  export,
+	})
+}
+
+// GetExports lists the tenant's recent exports
+func (h *ExportHandler) GetExports(c *gin.Context) {
+	exports, err := h.exportService.ListExports(middleware.GetTenantID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load exports"})
+		return
+	}
+
+	responses := make([]ExportResponse, 0, len(exports))
+	for i := range exports {
+		responses = append(responses, h.toResponse(&exports[i]))
+	}
+
+	c.JSON(http.StatusOK, gin.H{"exports": responses})
+}
+
+// GetExport returns an export's progress, with a download link once completed
+func (h *ExportHandler) GetExport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid export ID"})
+		return
+	}
+
+	export, err := h.exportService.GetExport(middleware.GetTenantID(c), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"export": h.toResponse(export)})
+}
+
+// DownloadExport streams an export archive. Authorized by the link's signature alone so
+// that browsers and download tools can fetch it without a token.
+func (h *ExportHandler) DownloadExport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid export ID"})
+		return
+	}
+
+	export, archive, err := h.exportService.OpenDownload(uint(id), c.Query("expires"), c.Query("signature"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidDownloadLink) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	defer archive.Close()
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   export.TenantID,
+		Action:     "export_downloaded",
+		Resource:   "tenant_export",
+		ResourceID: export.ID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	filename := fmt.Sprintf("tenant-%d-export-%d.zip", export.TenantID, export.ID)
+	c.DataFromReader(http.StatusOK, export.SizeBytes, "application/zip", archive, map[string]string{
+		"Content-Disposition": `attachment; filename="` + filename + `"`,
+		"Cache-Control":       "no-store",
+	})
+}
+
+// requester describes who requested a background job in this request
+func requester(c *gin.Context) service.Requester {
+	return service.Requester{
+		UserID:     middleware.GetUserID(c),
+		APIKeyID:   middleware.GetAPIKeyID(c),
+		OperatorID: middleware.GetOperatorID(c),
+	}
+}
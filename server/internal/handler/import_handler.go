@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"errors"
+	"gin-quickstart/internal/middleware"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImportArchiveSize bounds uploaded export archives
+const maxImportArchiveSize = 512 << 20
+
+type ImportHandler struct {
+	importService service.ImportService
+	auditService  service.AuditService
+}
+
+func NewImportHandler(importService service.ImportService, auditService service.AuditService) *ImportHandler {
+	return &ImportHandler{
+		importService: importService,
+		auditService:  auditService,
+	}
+}
+
+// CreateImport uploads an export archive (multipart field "archive") and imports it in
+// the background; ?dry_run=true only previews the result
+func (h *ImportHandler) CreateImport(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+	dryRun := c.Query("dry_run") == "true"
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportArchiveSize)
+	header, err := c.FormFile("archive")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An export archive is required in the \"archive\" field (max 512 MB)"})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read the uploaded archive"})
+		return
+	}
+	defer file.Close()
+
+	imp, err := h.importService.RequestImport(tenantID, requester(c), header.Filename, file, dryRun)
+	if err != nil {
+		if errors.Is(err, service.ErrImportInProgress) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "import_requested",
+		Resource:   "tenant_import",
+		ResourceID: imp.ID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Import started",
+		"import":  imp,
+	})
+}
+
+// GetImports lists the tenant's recent imports
+func (h *ImportHandler) GetImports(c *gin.Context) {
+	imports, err := h.importService.ListImports(middleware.GetTenantID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load imports"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"imports": imports})
+}
+
+// GetImport returns an import's progress and, once finished, its result
+func (h *ImportHandler) GetImport(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid import ID"})
+		return
+	}
+
+	imp, err := h.importService.GetImport(middleware.GetTenantID(c), uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"import": imp})
+}
@@ -0,0 +1,244 @@
+package handler
+
+import (
+	"errors"
+	"gin-quickstart/config"
+	"gin-quickstart/internal/middleware"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type InvitationHandler struct {
+	invitationService service.InvitationService
+	tokenService      service.TokenService
+	mfaService        service.MFAService
+	auditService      service.AuditService
+}
+
+func NewInvitationHandler(
+	invitationService service.InvitationService,
+	tokenService service.TokenService,
+	mfaService service.MFAService,
+	auditService service.AuditService,
+) *InvitationHandler {
+	return &InvitationHandler{
+		invitationService: invitationService,
+		tokenService:      tokenService,
+		mfaService:        mfaService,
+		auditService:      auditService,
+	}
+}
+
+type AcceptInvitationRequest struct {
+	Password string `json:"password" binding:"required"` // New password, or the existing account's password
+	FullName string `json:"full_name"`                   // Used when a new account is created
+}
+
+// GetInvitations returns the tenant's invitations (optionally filtered by status)
+func (h *InvitationHandler) GetInvitations(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+
+	invitations, err := h.invitationService.GetInvitations(tenantID, c.Query("status"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch invitations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"invitations": invitations,
+		"total":       len(invitations),
+	})
+}
+
+// CreateInvitation invites someone to the tenant by email
+func (h *InvitationHandler) CreateInvitation(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+
+	var req struct {
+		Email string `json:"email" binding:"required,email"`
+		Role  string `json:"role" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	invitation, err := h.invitationService.CreateInvitation(tenantID, userID, req.Email, req.Role, grantor(c))
+	if errors.Is(err, service.ErrRoleNotGrantable) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil && !errors.Is(err, service.ErrInvitationNotSent) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "create",
+		Resource:   "invitation",
+		ResourceID: invitation.ID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	message := "Invitation sent successfully"
+	if err != nil {
+		message = err.Error()
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":    message,
+		"invitation": invitation,
+		"email_sent": err == nil,
+	})
+}
+
+// ResendInvitation sends a new link for a pending invitation
+func (h *InvitationHandler) ResendInvitation(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	invitation, err := h.invitationService.ResendInvitation(tenantID, uint(id))
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrInvitationNotSent) {
+			status = http.StatusBadGateway
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "resend",
+		Resource:   "invitation",
+		ResourceID: invitation.ID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "Invitation resent successfully",
+		"invitation": invitation,
+	})
+}
+
+// RevokeInvitation cancels a pending invitation
+func (h *InvitationHandler) RevokeInvitation(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid invitation ID"})
+		return
+	}
+
+	if err := h.invitationService.RevokeInvitation(tenantID, uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "revoke",
+		Resource:   "invitation",
+		ResourceID: uint(id),
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Invitation revoked successfully"})
+}
+
+// PreviewInvitation describes an invitation to the invitee (public)
+func (h *InvitationHandler) PreviewInvitation(c *gin.Context) {
+	preview, err := h.invitationService.PreviewInvitation(c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"invitation": preview})
+}
+
+// AcceptInvitation joins the tenant and logs the invitee in (public)
+func (h *InvitationHandler) AcceptInvitation(c *gin.Context) {
+	var req AcceptInvitationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, invitation, err := h.invitationService.AcceptInvitation(c.Param("token"), req.Password, req.FullName)
+	if err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, service.ErrInvalidInvitation) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   invitation.TenantID,
+		UserID:     user.ID,
+		Action:     "accept",
+		Resource:   "invitation",
+		ResourceID: invitation.ID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	// Existing accounts with two-factor authentication finish signing in at /auth/mfa/verify
+	if user.MFAEnabled {
+		mfaToken, err := h.mfaService.CreateChallenge(user.ID, invitation.TenantID)
+		if err != nil {
+			c.JSON(http.StatusOK, gin.H{"message": "Invitation accepted, please login"})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"message":      "Invitation accepted, two-factor authentication required",
+			"mfa_required": true,
+			"mfa_token":    mfaToken,
+			"expires_in":   int64(config.AppConfig.Auth.MFAChallengeExpiry.Seconds()),
+		})
+		return
+	}
+
+	tokens, err := h.tokenService.IssueTokens(user, invitation.TenantID, invitation.Role, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":            "Invitation accepted",
+		"user":               user,
+		"token":              tokens.AccessToken,
+		"refresh_token":      tokens.RefreshToken,
+		"expires_in":         tokens.ExpiresIn,
+		"refresh_expires_at": tokens.RefreshExpiresAt,
+		"tenant_id":          invitation.TenantID,
+		"role":               invitation.Role,
+	})
+}
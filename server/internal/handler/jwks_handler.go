@@ -0,0 +1,23 @@
+package handler
+
+import (
+	"gin-quickstart/internal/jwtkeys"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type JWKSHandler struct {
+	keys *jwtkeys.Manager
+}
+
+func NewJWKSHandler(keys *jwtkeys.Manager) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// GetJWKS publishes the public keys that verify access tokens, for other services
+func (h *JWKSHandler) GetJWKS(c *gin.Context) {
+	// Keys are published before they sign, so a short cache is safe
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keys.JWKS())
+}
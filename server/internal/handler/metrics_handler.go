@@ -0,0 +1,45 @@
+package handler
+
+import (
+	"crypto/subtle"
+	"gin-quickstart/internal/ratelimit"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MetricsHandler struct {
+	rateLimiter *ratelimit.Limiter
+	token       string
+}
+
+func NewMetricsHandler(rateLimiter *ratelimit.Limiter, token string) *MetricsHandler {
+	return &MetricsHandler{
+		rateLimiter: rateLimiter,
+		token:       token,
+	}
+}
+
+// GetMetrics serves counters in the Prometheus text format. Requires
+// "Authorization: Bearer <METRICS_TOKEN>" when a token is configured; in release mode
+// the counters are not served without one.
+func (h *MetricsHandler) GetMetrics(c *gin.Context) {
+	if h.token == "" && gin.Mode() == gin.ReleaseMode {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Metrics are disabled, set METRICS_TOKEN to enable them"})
+		return
+	}
+
+	if h.token != "" {
+		expected := "Bearer " + h.token
+		if subtle.ConstantTimeCompare([]byte(c.GetHeader("Authorization")), []byte(expected)) != 1 {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid metrics token"})
+			return
+		}
+	}
+
+	c.Header("Content-Type", "text/plain; version=0.0.4")
+	c.Status(http.StatusOK)
+	if err := h.rateLimiter.WritePrometheus(c.Writer); err != nil {
+		c.Error(err)
+	}
+}
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"errors"
+	"gin-quickstart/internal/lockout"
+	"gin-quickstart/internal/middleware"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MFAHandler struct {
+	mfaService   service.MFAService
+	auditService service.AuditService
+	loginLimiter *lockout.Limiter
+}
+
+func NewMFAHandler(mfaService service.MFAService, auditService service.AuditService, loginLimiter *lockout.Limiter) *MFAHandler {
+	return &MFAHandler{
+		mfaService:   mfaService,
+		auditService: auditService,
+		loginLimiter: loginLimiter,
+	}
+}
+
+type MFAPasswordRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type DisableMFARequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"` // TOTP code or recovery code
+}
+
+// GetStatus returns whether two-factor authentication is enabled for the current user
+func (h *MFAHandler) GetStatus(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	status, err := h.mfaService.GetStatus(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch two-factor status"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"mfa": status})
+}
+
+// BeginEnrollment returns a new TOTP secret and provisioning URI for the authenticator app
+func (h *MFAHandler) BeginEnrollment(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	var req MFAPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	enrollment, err := h.mfaService.BeginEnrollment(userID, req.Password)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "Scan the QR code with your authenticator app, then confirm with a code",
+		"secret":           enrollment.Secret,
+		"provisioning_uri": enrollment.ProvisioningURI,
+	})
+}
+
+// ConfirmEnrollment enables two-factor authentication and returns the recovery codes
+func (h *MFAHandler) ConfirmEnrollment(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	codes, err := h.mfaService.ConfirmEnrollment(userID, req.Code)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	h.logEvent(c, userID, "mfa_enabled")
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Two-factor authentication enabled. Store the recovery codes somewhere safe, they are shown only once",
+		"recovery_codes": codes,
+	})
+}
+
+// Disable turns two-factor authentication off
+func (h *MFAHandler) Disable(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	var req DisableMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, attempt, ok := h.beginCodeAttempt(c, userID)
+	if !ok {
+		return
+	}
+	defer h.loginLimiter.Release(attempt)
+
+	if err := h.mfaService.Disable(userID, req.Password, req.Code); err != nil {
+		h.respondCodeError(c, user, attempt, err)
+		return
+	}
+
+	h.logEvent(c, userID, "mfa_disabled")
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes replaces the current user's recovery codes
+func (h *MFAHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, attempt, ok := h.beginCodeAttempt(c, userID)
+	if !ok {
+		return
+	}
+	defer h.loginLimiter.Release(attempt)
+
+	codes, err := h.mfaService.RegenerateRecoveryCodes(userID, req.Code)
+	if err != nil {
+		h.respondCodeError(c, user, attempt, err)
+		return
+	}
+
+	h.logEvent(c, userID, "mfa_recovery_codes_regenerated")
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Recovery codes regenerated, the previous codes no longer work",
+		"recovery_codes": codes,
+	})
+}
+
+// beginCodeAttempt reserves an attempt against the account lockout before a password or
+// code is checked, so that a stolen access token cannot guess codes without limit. Writes
+// the response when the user has no two-factor authentication or is locked out.
+func (h *MFAHandler) beginCodeAttempt(c *gin.Context, userID uint) (*model.User, *lockout.Attempt, bool) {
+	user, err := h.mfaService.CodeUser(userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, nil, false
+	}
+
+	attempt, lock, err := h.loginLimiter.Begin(user.Email, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check login attempts"})
+		return nil, nil, false
+	}
+	if lock != nil {
+		respondLocked(c, lock)
+		return nil, nil, false
+	}
+	return user, attempt, true
+}
+
+// respondCodeError counts wrong passwords and codes like failed logins
+func (h *MFAHandler) respondCodeError(c *gin.Context, user *model.User, attempt *lockout.Attempt, err error) {
+	if errors.Is(err, service.ErrIncorrectPassword) || errors.Is(err, service.ErrInvalidMFACode) {
+		if lock := recordAttemptFailure(c, h.loginLimiter, h.auditService, user.Email, &user.ID, attempt); lock != nil {
+			respondLocked(c, lock)
+			return
+		}
+	}
+	c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+}
+
+// logEvent records an account-level two-factor event in every tenant of the user
+func (h *MFAHandler) logEvent(c *gin.Context, userID uint, action string) {
+	// Log audit
+	h.auditService.LogForUser(c, userID, &model.AuditLog{
+		UserID:     userID,
+		Action:     action,
+		Resource:   "user",
+		ResourceID: userID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+}
@@ -77,7 +77,7 @@ func (h *PipelineStageHandler) CreateStage(c *gin.Context) {
 	}
 
 	// Log audit
-	h.auditService.Log(&model.AuditLog{
+	h.auditService.Log(c, &model.AuditLog{
 		TenantID:   tenantID,
 		UserID:     userID,
 		Action:     "create",
@@ -116,7 +116,7 @@ func (h *PipelineStageHandler) UpdateStage(c *gin.Context) {
 	}
 
 	// Log audit
-	h.auditService.Log(&model.AuditLog{
+	h.auditService.Log(c, &model.AuditLog{
 		TenantID:   tenantID,
 		UserID:     userID,
 		Action:     "update",
@@ -148,7 +148,7 @@ func (h *PipelineStageHandler) DeleteStage(c *gin.Context) {
 	}
 
 	// Log audit
-	h.auditService.Log(&model.AuditLog{
+	h.auditService.Log(c, &model.AuditLog{
 		TenantID:   tenantID,
 		UserID:     userID,
 		Action:     "delete",
@@ -186,7 +186,7 @@ func (h *PipelineStageHandler) ReorderStages(c *gin.Context) {
 	}
 
 	// Log audit
-	h.auditService.Log(&model.AuditLog{
+	h.auditService.Log(c, &model.AuditLog{
 		TenantID:  tenantID,
 		UserID:    userID,
 		Action:    "reorder",
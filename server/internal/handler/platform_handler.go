@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"errors"
+	"gin-quickstart/internal/middleware"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PlatformHandler serves the /api/platform routes for platform operators. Actions on a
+// tenant are recorded in that tenant's audit log with the operator set.
+type PlatformHandler struct {
+	platformService service.PlatformService
+	auditService    service.AuditService
+}
+
+func NewPlatformHandler(platformService service.PlatformService, auditService service.AuditService) *PlatformHandler {
+	return &PlatformHandler{
+		platformService: platformService,
+		auditService:    auditService,
+	}
+}
+
+// ImpersonateRequest names the reason and, optionally, the admin to impersonate
+type ImpersonateRequest struct {
+	Reason string `json:"reason" binding:"required,max=500"`
+	UserID uint   `json:"user_id"` // Defaults to the tenant owner
+}
+
+// GetTenants lists all tenants with their usage (?search=name or owner email, ?status=)
+func (h *PlatformHandler) GetTenants(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	tenants, total, err := h.platformService.ListTenants(c.Query("search"), c.Query("status"), page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tenants"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"tenants":   tenants,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// GetTenant returns a tenant with its usage
+func (h *PlatformHandler) GetTenant(c *gin.Context) {
+	tenantID, ok := parseTenantID(c)
+	if !ok {
+		return
+	}
+
+	tenant, err := h.platformService.GetTenant(tenantID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tenant": tenant})
+}
+
+// GetTenantUsers lists the members of any tenant
+func (h *PlatformHandler) GetTenantUsers(c *gin.Context) {
+	tenantID, ok := parseTenantID(c)
+	if !ok {
+		return
+	}
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	users, total, err := h.platformService.GetTenantUsers(tenantID, page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users":     users,
+		"total":     total,
+		"page":      page,
+		"page_size": pageSize,
+	})
+}
+
+// SuspendTenant makes a tenant read-only for its members
+func (h *PlatformHandler) SuspendTenant(c *gin.Context) {
+	h.setTenantStatus(c, model.TenantStatusSuspended, "tenant_suspended", "Tenant suspended")
+}
+
+// ReactivateTenant lifts a suspension
+func (h *PlatformHandler) ReactivateTenant(c *gin.Context) {
+	h.setTenantStatus(c, model.TenantStatusActive, "tenant_reactivated", "Tenant reactivated")
+}
+
+func (h *PlatformHandler) setTenantStatus(c *gin.Context, status, action, message string) {
+	tenantID, ok := parseTenantID(c)
+	if !ok {
+		return
+	}
+	operatorID := middleware.GetUserID(c)
+
+	if err := h.platformService.SetTenantStatus(tenantID, status); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     operatorID,
+		OperatorID: &operatorID,
+		Action:     action,
+		Resource:   "tenant",
+		ResourceID: tenantID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// Impersonate starts an impersonation of a tenant admin and returns its access token
+func (h *PlatformHandler) Impersonate(c *gin.Context) {
+	tenantID, ok := parseTenantID(c)
+	if !ok {
+		return
+	}
+	operatorID := middleware.GetUserID(c)
+
+	var req ImpersonateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	grant, err := h.platformService.Impersonate(operatorID, tenantID, req.UserID, req.Reason, c.ClientIP(), c.GetHeader("User-Agent"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     grant.Impersonation.UserID,
+		OperatorID: &operatorID,
+		Action:     "impersonation_started",
+		Resource:   "impersonation",
+		ResourceID: grant.Impersonation.ID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusCreated, grant)
+}
+
+// GetImpersonations lists impersonations across all tenants (?active=true for running ones)
+func (h *PlatformHandler) GetImpersonations(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
+
+	impersonations, total, err := h.platformService.ListImpersonations(c.Query("active") == "true", page, pageSize)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch impersonations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"impersonations": impersonations,
+		"total":          total,
+		"page":           page,
+		"page_size":      pageSize,
+	})
+}
+
+// EndImpersonation ends an impersonation before it expires
+func (h *PlatformHandler) EndImpersonation(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid impersonation ID"})
+		return
+	}
+	operatorID := middleware.GetUserID(c)
+
+	impersonation, err := h.platformService.EndImpersonation(uint(id), operatorID)
+	if err != nil {
+		if errors.Is(err, service.ErrImpersonationEnded) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   impersonation.TenantID,
+		UserID:     impersonation.UserID,
+		OperatorID: &operatorID,
+		Action:     "impersonation_ended",
+		Resource:   "impersonation",
+		ResourceID: impersonation.ID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "Impersonation ended",
+		"impersonation": impersonation,
+	})
+}
+
+// parseTenantID reads the :id route parameter, responding with 400 when it is invalid
+func parseTenantID(c *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tenant ID"})
+		return 0, false
+	}
+	return uint(id), true
+}
@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"errors"
+	"gin-quickstart/internal/middleware"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/service"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ProfileHandler struct {
+	profileService service.ProfileService
+	auditService   service.AuditService
+}
+
+func NewProfileHandler(profileService service.ProfileService, auditService service.AuditService) *ProfileHandler {
+	return &ProfileHandler{
+		profileService: profileService,
+		auditService:   auditService,
+	}
+}
+
+// UpdateProfileRequest changes only the fields present; "" clears optional fields
+type UpdateProfileRequest struct {
+	FullName  *string `json:"full_name"`
+	AvatarURL *string `json:"avatar_url"`
+	Locale    *string `json:"locale"`   // e.g. en-US
+	Timezone  *string `json:"timezone"` // e.g. Europe/Berlin
+	Phone     *string `json:"phone"`
+}
+
+type ChangeEmailRequest struct {
+	NewEmail string `json:"new_email" binding:"required,email"`
+	Password string `json:"password" binding:"required"`
+}
+
+type ConfirmEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// GetProfile returns the current user's account
+func (h *ProfileHandler) GetProfile(c *gin.Context) {
+	user, err := h.profileService.GetProfile(middleware.GetUserID(c))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"user": user})
+}
+
+// UpdateProfile changes the current user's profile fields
+func (h *ProfileHandler) UpdateProfile(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	var req UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.profileService.UpdateProfile(userID, service.ProfileUpdate{
+		FullName:  req.FullName,
+		AvatarURL: req.AvatarURL,
+		Locale:    req.Locale,
+		Timezone:  req.Timezone,
+		Phone:     req.Phone,
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.LogForUser(c, userID, &model.AuditLog{
+		UserID:     userID,
+		Action:     "profile_updated",
+		Resource:   "user",
+		ResourceID: userID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Profile updated successfully",
+		"user":    user,
+	})
+}
+
+// ChangeEmail sends a confirmation link to the new address
+func (h *ProfileHandler) ChangeEmail(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	var req ChangeEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	changeToken, err := h.profileService.RequestEmailChange(userID, req.Password, req.NewEmail, c.ClientIP())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.LogForUser(c, userID, &model.AuditLog{
+		UserID:     userID,
+		Action:     "email_change_requested",
+		Resource:   "user",
+		ResourceID: userID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":    "A confirmation link has been sent to the new email address",
+		"new_email":  changeToken.NewEmail,
+		"expires_at": changeToken.ExpiresAt,
+	})
+}
+
+// ConfirmEmail applies an email change using the emailed token and signs out all sessions
+func (h *ProfileHandler) ConfirmEmail(c *gin.Context) {
+	var req ConfirmEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := h.profileService.ConfirmEmailChange(req.Token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.LogForUser(c, user.ID, &model.AuditLog{
+		UserID:     user.ID,
+		Action:     "email_changed",
+		Resource:   "user",
+		ResourceID: user.ID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Email address changed, please login again",
+		"email":   user.Email,
+	})
+}
+
+// DeleteAccount deletes the current user's account. Refused while the user owns a tenant
+// or is its last admin.
+func (h *ProfileHandler) DeleteAccount(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	var req DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenantIDs, err := h.profileService.DeleteAccount(userID, req.Password)
+	if err != nil {
+		if errors.Is(err, service.ErrLastAdmin) || errors.Is(err, service.ErrTenantOwner) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit in every tenant the user left
+	for _, tenantID := range tenantIDs {
+		h.auditService.Log(c, &model.AuditLog{
+			TenantID:   tenantID,
+			UserID:     userID,
+			Action:     "account_deleted",
+			Resource:   "user",
+			ResourceID: userID,
+			IPAddress:  c.ClientIP(),
+			UserAgent:  c.GetHeader("User-Agent"),
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account deleted"})
+}
@@ -0,0 +1,177 @@
+package handler
+
+import (
+	"errors"
+	"gin-quickstart/internal/middleware"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/service"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SessionHandler struct {
+	tokenService service.TokenService
+	auditService service.AuditService
+}
+
+func NewSessionHandler(tokenService service.TokenService, auditService service.AuditService) *SessionHandler {
+	return &SessionHandler{
+		tokenService: tokenService,
+		auditService: auditService,
+	}
+}
+
+// SessionResponse is a session with a readable device name
+type SessionResponse struct {
+	model.Session
+	Device  string `json:"device"`
+	Current bool   `json:"current"` // The session making this request
+}
+
+// GetSessions lists the devices the current user is signed in on
+func (h *SessionHandler) GetSessions(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	currentID := middleware.GetSessionID(c)
+
+	sessions, err := h.tokenService.GetSessions(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch sessions"})
+		return
+	}
+
+	response := make([]SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, SessionResponse{
+			Session: session,
+			Device:  describeDevice(session.UserAgent),
+			Current: session.ID == currentID,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sessions": response,
+		"total":    len(response),
+	})
+}
+
+// RevokeSession logs one of the current user's sessions out
+func (h *SessionHandler) RevokeSession(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+
+	if err := h.tokenService.RevokeSession(userID, uint(id)); err != nil {
+		if errors.Is(err, service.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+
+	// Log audit
+	h.auditService.LogForUser(c, userID, &model.AuditLog{
+		UserID:     userID,
+		Action:     "session_revoked",
+		Resource:   "session",
+		ResourceID: uint(id),
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
+
+// ForceLogout ends every session a tenant member has signed in to the current tenant
+func (h *SessionHandler) ForceLogout(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	adminID := middleware.GetUserID(c)
+
+	userID, err := strconv.ParseUint(c.Param("user_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	revoked, err := h.tokenService.RevokeTenantSessions(tenantID, uint(userID), adminID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     adminID,
+		Action:     "force_logout",
+		Resource:   "user",
+		ResourceID: uint(userID),
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":          "User logged out of this tenant",
+		"sessions_revoked": revoked,
+	})
+}
+
+// describeDevice turns a User-Agent header into a short "Browser on OS" label
+func describeDevice(userAgent string) string {
+	if userAgent == "" {
+		return "Unknown device"
+	}
+
+	browsers := []struct{ token, name string }{
+		{"Edg/", "Edge"},
+		{"OPR/", "Opera"},
+		{"Firefox/", "Firefox"},
+		{"Chrome/", "Chrome"},
+		{"Safari/", "Safari"},
+		{"curl/", "curl"},
+		{"PostmanRuntime/", "Postman"},
+	}
+	systems := []struct{ token, name string }{
+		{"Windows", "Windows"},
+		{"iPhone", "iOS"},
+		{"iPad", "iPadOS"},
+		{"Android", "Android"},
+		{"Mac OS X", "macOS"},
+		{"Linux", "Linux"},
+	}
+
+	browser, system := "", ""
+	for _, b := range browsers {
+		if strings.Contains(userAgent, b.token) {
+			browser = b.name
+			break
+		}
+	}
+	for _, s := range systems {
+		if strings.Contains(userAgent, s.token) {
+			system = s.name
+			break
+		}
+	}
+
+	switch {
+	case browser != "" && system != "":
+		return browser + " on " + system
+	case browser != "":
+		return browser
+	case system != "":
+		return system
+	}
+
+	if len(userAgent) > 60 {
+		return userAgent[:60] + "..."
+	}
+	return userAgent
+}
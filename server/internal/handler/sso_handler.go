@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"errors"
+	"gin-quickstart/config"
+	"gin-quickstart/internal/middleware"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/service"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+type SSOHandler struct {
+	ssoService   service.SSOService
+	auditService service.AuditService
+}
+
+func NewSSOHandler(ssoService service.SSOService, auditService service.AuditService) *SSOHandler {
+	return &SSOHandler{
+		ssoService:   ssoService,
+		auditService: auditService,
+	}
+}
+
+type UpdateSSOConfigRequest struct {
+	Enabled        *bool    `json:"enabled"` // Default true
+	Issuer         string   `json:"issuer" binding:"required,url"`
+	ClientID       string   `json:"client_id" binding:"required"`
+	ClientSecret   string   `json:"client_secret"` // Omit to keep the stored secret
+	AllowedDomains []string `json:"allowed_domains"`
+	AutoProvision  *bool    `json:"auto_provision"` // Default true
+	DefaultRole    string   `json:"default_role"`   // Default member
+}
+
+// GetConfig returns the tenant's single sign-on configuration (without the client secret)
+func (h *SSOHandler) GetConfig(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+
+	ssoConfig, err := h.ssoService.GetConfig(tenantID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"sso":          ssoConfig,
+		"login_url":    ssoLoginURL(tenantID),
+		"redirect_uri": service.SSORedirectURI(),
+	})
+}
+
+// UpdateConfig creates or replaces the tenant's identity provider configuration
+func (h *SSOHandler) UpdateConfig(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+
+	var req UpdateSSOConfigRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	input := &model.TenantSSOConfig{
+		Enabled:        req.Enabled == nil || *req.Enabled,
+		Issuer:         req.Issuer,
+		ClientID:       req.ClientID,
+		ClientSecret:   req.ClientSecret,
+		AllowedDomains: strings.Join(req.AllowedDomains, ","),
+		AutoProvision:  req.AutoProvision == nil || *req.AutoProvision,
+		DefaultRole:    req.DefaultRole,
+	}
+
+	ssoConfig, err := h.ssoService.SaveConfig(tenantID, input)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "update",
+		Resource:   "sso_config",
+		ResourceID: ssoConfig.ID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Single sign-on configuration saved",
+		"sso":       ssoConfig,
+		"login_url": ssoLoginURL(tenantID),
+	})
+}
+
+// DeleteConfig removes the tenant's single sign-on configuration
+func (h *SSOHandler) DeleteConfig(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+
+	if err := h.ssoService.DeleteConfig(tenantID); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:  tenantID,
+		UserID:    userID,
+		Action:    "delete",
+		Resource:  "sso_config",
+		IPAddress: c.ClientIP(),
+		UserAgent: c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Single sign-on configuration deleted"})
+}
+
+// Login redirects the browser to the tenant's identity provider
+func (h *SSOHandler) Login(c *gin.Context) {
+	tenantID, err := strconv.ParseUint(c.Param("tenant_id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tenant ID"})
+		return
+	}
+
+	authURL, err := h.ssoService.StartLogin(uint(tenantID))
+	if err != nil {
+		if errors.Is(err, service.ErrSSONotConfigured) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to reach the identity provider"})
+		return
+	}
+
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback receives the identity provider's redirect and sends the browser back to the
+// frontend with a one-time code (or an error), to be redeemed at AuthHandler.SSOExchange
+func (h *SSOHandler) Callback(c *gin.Context) {
+	if providerError := c.Query("error"); providerError != "" {
+		message := c.Query("error_description")
+		if message == "" {
+			message = providerError
+		}
+		redirectToFrontend(c, url.Values{"error": {message}})
+		return
+	}
+
+	state, code := c.Query("state"), c.Query("code")
+	if state == "" || code == "" {
+		redirectToFrontend(c, url.Values{"error": {service.ErrInvalidSSOLogin.Error()}})
+		return
+	}
+
+	result, err := h.ssoService.HandleCallback(state, code)
+	if err != nil {
+		redirectToFrontend(c, url.Values{"error": {err.Error()}})
+		return
+	}
+
+	switch {
+	case result.Provisioned || result.Joined:
+		h.logEvent(c, result, "sso_provisioned")
+	case result.Linked:
+		h.logEvent(c, result, "sso_linked")
+	}
+
+	redirectToFrontend(c, url.Values{"code": {result.ExchangeCode}})
+}
+
+func (h *SSOHandler) logEvent(c *gin.Context, result *service.SSOCallbackResult, action string) {
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   result.TenantID,
+		UserID:     result.User.ID,
+		Action:     action,
+		Resource:   "user",
+		ResourceID: result.User.ID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+}
+
+// redirectToFrontend sends the browser to the frontend's SSO completion page
+func redirectToFrontend(c *gin.Context, query url.Values) {
+	target := strings.TrimRight(config.AppConfig.Server.FrontendURL, "/") + "/sso/complete?" + query.Encode()
+	c.Redirect(http.StatusFound, target)
+}
+
+// ssoLoginURL is the address users open to sign in to the tenant through its provider
+func ssoLoginURL(tenantID uint) string {
+	return strings.TrimRight(config.AppConfig.Server.PublicURL, "/") + "/api/auth/sso/" + strconv.FormatUint(uint64(tenantID), 10) + "/login"
+}
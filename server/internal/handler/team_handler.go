@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"gin-quickstart/internal/middleware"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/service"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type TeamHandler struct {
+	teamService  service.TeamService
+	auditService service.AuditService
+}
+
+func NewTeamHandler(teamService service.TeamService, auditService service.AuditService) *TeamHandler {
+	return &TeamHandler{
+		teamService:  teamService,
+		auditService: auditService,
+	}
+}
+
+type TeamRequest struct {
+	Name        string `json:"name" binding:"required"`
+	Description string `json:"description"`
+	ManagerID   *uint  `json:"manager_id"`
+	MemberIDs   []uint `json:"member_ids"`
+}
+
+// GetTeams returns all teams of the tenant
+func (h *TeamHandler) GetTeams(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+
+	teams, err := h.teamService.GetTeams(tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch teams"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"teams": teams,
+		"total": len(teams),
+	})
+}
+
+// GetTeam returns a single team by ID
+func (h *TeamHandler) GetTeam(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid team ID"})
+		return
+	}
+
+	team, err := h.teamService.GetTeam(tenantID, uint(id))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"team": team})
+}
+
+// CreateTeam creates a team
+func (h *TeamHandler) CreateTeam(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+
+	var req TeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	team, err := h.teamService.CreateTeam(tenantID, req.Name, req.Description, req.ManagerID, req.MemberIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "create",
+		Resource:   "team",
+		ResourceID: team.ID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Team created successfully",
+		"team":    team,
+	})
+}
+
+// UpdateTeam replaces a team's name, description, manager and members
+func (h *TeamHandler) UpdateTeam(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid team ID"})
+		return
+	}
+
+	var req TeamRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	team, err := h.teamService.UpdateTeam(tenantID, uint(id), req.Name, req.Description, req.ManagerID, req.MemberIDs)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "update",
+		Resource:   "team",
+		ResourceID: team.ID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Team updated successfully",
+		"team":    team,
+	})
+}
+
+// DeleteTeam deletes a team and unassigns its contacts and deals
+func (h *TeamHandler) DeleteTeam(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid team ID"})
+		return
+	}
+
+	if err := h.teamService.DeleteTeam(tenantID, uint(id)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "delete",
+		Resource:   "team",
+		ResourceID: uint(id),
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "Team deleted successfully"})
+}
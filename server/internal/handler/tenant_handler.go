@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"gin-quickstart/internal/middleware"
 	"gin-quickstart/internal/model"
 	"gin-quickstart/internal/service"
@@ -11,14 +12,20 @@ import (
 )
 
 type TenantHandler struct {
-	tenantService service.TenantService
-	auditService  service.AuditService
+	tenantService    service.TenantService
+	lifecycleService service.TenantLifecycleService
+	auditService     service.AuditService
 }
 
-func NewTenantHandler(tenantService service.TenantService, auditService service.AuditService) *TenantHandler {
+func NewTenantHandler(
+	tenantService service.TenantService,
+	lifecycleService service.TenantLifecycleService,
+	auditService service.AuditService,
+) *TenantHandler {
 	return &TenantHandler{
-		tenantService: tenantService,
-		auditService:  auditService,
+		tenantService:    tenantService,
+		lifecycleService: lifecycleService,
+		auditService:     auditService,
 	}
 }
 
@@ -35,32 +42,13 @@ func (h *TenantHandler) GetTenant(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"tenant": tenant})
 }
 
-// GetAllTenants returns all tenants (admin only)
-func (h *TenantHandler) GetAllTenants(c *gin.Context) {
-	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
-	pageSize, _ := strconv.Atoi(c.DefaultQuery("page_size", "10"))
-
-	tenants, total, err := h.tenantService.GetAllTenants(page, pageSize)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tenants"})
-		return
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"tenants":   tenants,
-		"total":     total,
-		"page":      page,
-		"page_size": pageSize,
-	})
-}
-
 // UpdateTenant updates tenant information
 func (h *TenantHandler) UpdateTenant(c *gin.Context) {
 	tenantID := middleware.GetTenantID(c)
 
 	var updateReq struct {
 		Name   string `json:"name"`
-		Status string `json:"status"`
+		Status string `json:"status"` // Read-only here, see DeleteTenant and TenantLifecycleService
 	}
 
 	if err := c.ShouldBindJSON(&updateReq); err != nil {
@@ -74,12 +62,14 @@ func (h *TenantHandler) UpdateTenant(c *gin.Context) {
 		return
 	}
 
+	if updateReq.Status != "" && updateReq.Status != tenant.Status {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tenant status cannot be changed here, use DELETE /api/tenant to delete the tenant"})
+		return
+	}
+
 	if updateReq.Name != "" {
 		tenant.Name = updateReq.Name
 	}
-	if updateReq.Status != "" {
-		tenant.Status = updateReq.Status
-	}
 
 	if err := h.tenantService.UpdateTenant(tenant); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tenant"})
@@ -87,7 +77,7 @@ func (h *TenantHandler) UpdateTenant(c *gin.Context) {
 	}
 
 	// Log audit
-	h.auditService.Log(&model.AuditLog{
+	h.auditService.Log(c, &model.AuditLog{
 		TenantID:   tenantID,
 		UserID:     middleware.GetUserID(c),
 		Action:     "update",
@@ -120,15 +110,18 @@ func (h *TenantHandler) GetTenantUsers(c *gin.Context) {
 	})
 }
 
-// AddUser creates a new user or adds existing user to tenant
-func (h *TenantHandler) AddUser(c *gin.Context) {
+// UpdateUserRole updates a user's role in the tenant
+func (h *TenantHandler) UpdateUserRole(c *gin.Context) {
 	tenantID := middleware.GetTenantID(c)
+	userIDStr := c.Param("user_id")
+	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
 
 	var req struct {
-		Email    string `json:"email" binding:"required,email"`
-		Password string `json:"password"` // Optional if user exists
-		FullName string `json:"full_name"`
-		Role     string `json:"role" binding:"required"`
+		Role string `json:"role" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -136,42 +129,35 @@ func (h *TenantHandler) AddUser(c *gin.Context) {
 		return
 	}
 
-	user, isNewUser, err := h.tenantService.AddUser(tenantID, req.Email, req.Password, req.FullName, req.Role)
-	if err != nil {
+	if err := h.tenantService.UpdateUserRole(tenantID, uint(userID), req.Role, grantor(c)); err != nil {
+		if errors.Is(err, service.ErrLastAdmin) || errors.Is(err, service.ErrTenantOwner) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrRoleNotGrantable) {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Log audit
-	action := "add_user"
-	if isNewUser {
-		action = "create_user"
-	}
-
-	h.auditService.Log(&model.AuditLog{
+	h.auditService.Log(c, &model.AuditLog{
 		TenantID:   tenantID,
 		UserID:     middleware.GetUserID(c),
-		Action:     action,
+		Action:     "update_role",
 		Resource:   "user",
-		ResourceID: user.ID,
+		ResourceID: uint(userID),
 		IPAddress:  c.ClientIP(),
 		UserAgent:  c.GetHeader("User-Agent"),
 	})
 
-	message := "User added to tenant successfully"
-	if isNewUser {
-		message = "User created and added to tenant successfully"
-	}
-
-	c.JSON(http.StatusCreated, gin.H{
-		"message":     message,
-		"user":        user,
-		"is_new_user": isNewUser,
-	})
+	c.JSON(http.StatusOK, gin.H{"message": "User role updated successfully"})
 }
 
-// UpdateUserRole updates a user's role in the tenant
-func (h *TenantHandler) UpdateUserRole(c *gin.Context) {
+// RemoveUser removes a user from the tenant
+func (h *TenantHandler) RemoveUser(c *gin.Context) {
 	tenantID := middleware.GetTenantID(c)
 	userIDStr := c.Param("user_id")
 	userID, err := strconv.ParseUint(userIDStr, 10, 32)
@@ -180,8 +166,37 @@ func (h *TenantHandler) UpdateUserRole(c *gin.Context) {
 		return
 	}
 
+	if err := h.tenantService.RemoveUserFromTenant(tenantID, uint(userID)); err != nil {
+		if errors.Is(err, service.ErrLastAdmin) || errors.Is(err, service.ErrTenantOwner) {
+			c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove user"})
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     middleware.GetUserID(c),
+		Action:     "remove",
+		Resource:   "user",
+		ResourceID: uint(userID),
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{"message": "User removed successfully"})
+}
+
+// TransferOwnership makes another member the tenant owner (confirmed with the owner's password)
+func (h *TenantHandler) TransferOwnership(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+
 	var req struct {
-		Role string `json:"role" binding:"required"`
+		UserID   uint   `json:"user_id" binding:"required"`
+		Password string `json:"password" binding:"required"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -189,52 +204,138 @@ func (h *TenantHandler) UpdateUserRole(c *gin.Context) {
 		return
 	}
 
-	if err := h.tenantService.UpdateUserRole(tenantID, uint(userID), req.Role); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update role"})
+	if err := h.tenantService.TransferOwnership(tenantID, userID, req.UserID, req.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Log audit
-	h.auditService.Log(&model.AuditLog{
+	h.auditService.Log(c, &model.AuditLog{
 		TenantID:   tenantID,
-		UserID:     middleware.GetUserID(c),
-		Action:     "update_role",
+		UserID:     userID,
+		Action:     "ownership_transferred",
 		Resource:   "user",
-		ResourceID: uint(userID),
+		ResourceID: req.UserID,
 		IPAddress:  c.ClientIP(),
 		UserAgent:  c.GetHeader("User-Agent"),
 	})
 
-	c.JSON(http.StatusOK, gin.H{"message": "User role updated successfully"})
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Ownership transferred successfully",
+		"owner_id": req.UserID,
+	})
 }
 
-// RemoveUser removes a user from the tenant
-func (h *TenantHandler) RemoveUser(c *gin.Context) {
+// DeleteTenant schedules the tenant for deletion after the grace period (owner only,
+// confirmed with the password). The tenant is read-only until then.
+func (h *TenantHandler) DeleteTenant(c *gin.Context) {
 	tenantID := middleware.GetTenantID(c)
-	userIDStr := c.Param("user_id")
-	userID, err := strconv.ParseUint(userIDStr, 10, 32)
+	userID := middleware.GetUserID(c)
+
+	var req struct {
+		Password string `json:"password" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tenant, err := h.lifecycleService.RequestDeletion(tenantID, userID, req.Password)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	if err := h.tenantService.RemoveUserFromTenant(tenantID, uint(userID)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove user"})
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "deletion_requested",
+		Resource:   "tenant",
+		ResourceID: tenantID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Tenant scheduled for deletion",
+		"tenant":  tenant,
+	})
+}
+
+// CancelDeletion reactivates a tenant during its deletion grace period
+func (h *TenantHandler) CancelDeletion(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+	userID := middleware.GetUserID(c)
+
+	tenant, err := h.lifecycleService.CancelDeletion(tenantID, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
 	// Log audit
-	h.auditService.Log(&model.AuditLog{
+	h.auditService.Log(c, &model.AuditLog{
+		TenantID:   tenantID,
+		UserID:     userID,
+		Action:     "deletion_cancelled",
+		Resource:   "tenant",
+		ResourceID: tenantID,
+		IPAddress:  c.ClientIP(),
+		UserAgent:  c.GetHeader("User-Agent"),
+	})
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Tenant deletion cancelled",
+		"tenant":  tenant,
+	})
+}
+
+// GetSettings returns the tenant's settings
+func (h *TenantHandler) GetSettings(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+
+	settings, err := h.tenantService.GetSettings(tenantID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"settings": settings})
+}
+
+// UpdateSettings updates one or more tenant settings
+func (h *TenantHandler) UpdateSettings(c *gin.Context) {
+	tenantID := middleware.GetTenantID(c)
+
+	var req struct {
+		Settings map[string]string `json:"settings" binding:"required"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := h.tenantService.UpdateSettings(tenantID, req.Settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Log audit
+	h.auditService.Log(c, &model.AuditLog{
 		TenantID:   tenantID,
 		UserID:     middleware.GetUserID(c),
-		Action:     "remove",
-		Resource:   "user",
-		ResourceID: uint(userID),
+		Action:     "update_settings",
+		Resource:   "tenant",
+		ResourceID: tenantID,
 		IPAddress:  c.ClientIP(),
 		UserAgent:  c.GetHeader("User-Agent"),
 	})
 
-	c.JSON(http.StatusOK, gin.H{"message": "User removed successfully"})
+	settings, _ := h.tenantService.GetSettings(tenantID)
+	c.JSON(http.StatusOK, gin.H{"message": "Settings updated successfully", "settings": settings})
 }
 
 // GetAuditLogs returns audit logs for the tenant
@@ -256,3 +357,8 @@ func (h *TenantHandler) GetAuditLogs(c *gin.Context) {
 		"page_size": pageSize,
 	})
 }
+
+// grantor describes the member assigning or inviting a role in this request
+func grantor(c *gin.Context) service.Grantor {
+	return service.Grantor{Role: middleware.GetRole(c), Permissions: middleware.GetPermissions(c)}
+}
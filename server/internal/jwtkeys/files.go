@@ -0,0 +1,167 @@
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Key files are named "<kid>.pem". Private keys (PKCS#8 or PKCS#1) can sign; public key
+// files ("PUBLIC KEY") are only used for verification.
+const keyFileExt = ".pem"
+
+// loadKeyDir reads every key file of the directory, creating the directory if needed
+func loadKeyDir(dir string) ([]*Key, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]*Key, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), keyFileExt) {
+			continue
+		}
+
+		key, err := loadKeyFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+func loadKeyFile(path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM data found")
+	}
+
+	key := &Key{
+		ID:        strings.TrimSuffix(filepath.Base(path), keyFileExt),
+		CreatedAt: info.ModTime(),
+	}
+
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		signer, ok := parsed.(crypto.Signer)
+		if !ok {
+			return nil, errors.New("unsupported private key type")
+		}
+		key.private = signer
+		key.public = signer.Public()
+	case "RSA PRIVATE KEY":
+		parsed, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		key.private = parsed
+		key.public = parsed.Public()
+	case "PUBLIC KEY":
+		if key.public, err = x509.ParsePKIXPublicKey(block.Bytes); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+
+	if key.Method, err = methodFor(key.public); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// generateKey writes a new PKCS#8 private key. The file is written under a temporary
+// name and renamed so other instances sharing the directory never read a partial key.
+func generateKey(dir string, method jwt.SigningMethod) (*Key, error) {
+	var signer crypto.Signer
+	var err error
+
+	switch method {
+	case jwt.SigningMethodRS256:
+		signer, err = rsa.GenerateKey(rand.Reader, 2048)
+	case jwt.SigningMethodEdDSA:
+		_, signer, err = ed25519.GenerateKey(rand.Reader)
+	default:
+		return nil, errors.New("cannot generate keys for " + method.Alg())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(signer)
+	if err != nil {
+		return nil, err
+	}
+
+	suffix := make([]byte, 4)
+	if _, err := rand.Read(suffix); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	id := now.UTC().Format("20060102T150405Z") + "-" + hex.EncodeToString(suffix)
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, id+keyFileExt)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		return nil, err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return nil, err
+	}
+
+	info, err := os.Stat(path)
+	if err == nil {
+		now = info.ModTime()
+	}
+
+	return &Key{
+		ID:        id,
+		Method:    method,
+		CreatedAt: now,
+		public:    signer.Public(),
+		private:   signer,
+	}, nil
+}
+
+func removeKeyFile(dir, id string) error {
+	err := os.Remove(filepath.Join(dir, id+keyFileExt))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
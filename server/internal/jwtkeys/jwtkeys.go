@@ -0,0 +1,337 @@
+// Package jwtkeys manages the keys that sign and verify access tokens. With HS256 it
+// wraps the shared secret; with RS256 or EdDSA it loads PEM key files from a directory,
+// signs with the newest active key (identified by the kid header), keeps older keys for
+// verification until their tokens have expired, and publishes the public keys as a JWKS.
+package jwtkeys
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"gin-quickstart/config"
+	"log"
+	"math/big"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// reloadInterval is how often the key directory is re-read, so that keys added by
+// operators or other instances are picked up
+const reloadInterval = time.Minute
+
+// Key is one verification key and, unless loaded from a public key file, its private key
+type Key struct {
+	ID        string
+	Method    jwt.SigningMethod
+	CreatedAt time.Time // File modification time; orders keys for rotation
+
+	public  crypto.PublicKey
+	private crypto.Signer // nil for verification-only keys
+}
+
+// JSONWebKey is a public key in JWK format (RFC 7517)
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+}
+
+// JSONWebKeySet is served at /.well-known/jwks.json
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// Manager signs and verifies access tokens
+type Manager struct {
+	cfg    config.JWTConfig
+	method jwt.SigningMethod
+
+	mu   sync.RWMutex
+	keys []*Key // Oldest first
+}
+
+// New loads the configured keys. For RS256 and EdDSA a first key is generated when the
+// directory holds no private key for the algorithm.
+func New(cfg config.JWTConfig) (*Manager, error) {
+	m := &Manager{cfg: cfg}
+
+	switch cfg.Algorithm {
+	case "HS256":
+		m.method = jwt.SigningMethodHS256
+		return m, nil
+	case "RS256":
+		m.method = jwt.SigningMethodRS256
+	case "EdDSA":
+		m.method = jwt.SigningMethodEdDSA
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", cfg.Algorithm)
+	}
+
+	if err := m.reload(); err != nil {
+		return nil, err
+	}
+
+	if m.newestSigningKey() == nil {
+		if _, err := m.generate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+// Start re-reads the key directory and performs scheduled rotations in the background
+func (m *Manager) Start() {
+	if m.method == jwt.SigningMethodHS256 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(reloadInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if err := m.reload(); err != nil {
+				log.Printf("⚠️  Failed to reload JWT keys: %v", err)
+				continue
+			}
+			if err := m.rotate(); err != nil {
+				log.Printf("⚠️  Failed to rotate JWT keys: %v", err)
+			}
+		}
+	}()
+}
+
+// Sign returns the signed token. Asymmetric tokens carry the signing key's kid.
+func (m *Manager) Sign(claims jwt.Claims) (string, error) {
+	if m.method == jwt.SigningMethodHS256 {
+		return jwt.NewWithClaims(m.method, claims).SignedString([]byte(m.cfg.Secret))
+	}
+
+	key := m.signingKey()
+	if key == nil {
+		return "", errors.New("no JWT signing key available")
+	}
+
+	token := jwt.NewWithClaims(key.Method, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString(key.private)
+}
+
+// Keyfunc resolves the verification key of a token for jwt.Parse. The token's alg must
+// match the key it names, so an RSA public key can never be used as an HMAC secret.
+func (m *Manager) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if m.method == jwt.SigningMethodHS256 {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+		return []byte(m.cfg.Secret), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	if kid == "" {
+		return nil, errors.New("token has no key ID")
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, key := range m.keys {
+		if key.ID == kid {
+			if token.Method.Alg() != key.Method.Alg() {
+				return nil, errors.New("unexpected signing method")
+			}
+			return key.public, nil
+		}
+	}
+	return nil, errors.New("unknown signing key")
+}
+
+// ValidMethods lists the algorithms accepted when parsing tokens
+func (m *Manager) ValidMethods() []string {
+	if m.method == jwt.SigningMethodHS256 {
+		return []string{jwt.SigningMethodHS256.Alg()}
+	}
+	return []string{jwt.SigningMethodRS256.Alg(), jwt.SigningMethodEdDSA.Alg()}
+}
+
+// JWKS returns the public keys currently accepted for verification. It is empty with
+// HS256, whose secret cannot be published.
+func (m *Manager) JWKS() JSONWebKeySet {
+	set := JSONWebKeySet{Keys: make([]JSONWebKey, 0)}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, key := range m.keys {
+		jwk := JSONWebKey{Kid: key.ID, Use: "sig", Alg: key.Method.Alg()}
+
+		switch pub := key.public.(type) {
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = base64.RawURLEncoding.EncodeToString(pub.N.Bytes())
+			jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes())
+		case ed25519.PublicKey:
+			jwk.Kty = "OKP"
+			jwk.Crv = "Ed25519"
+			jwk.X = base64.RawURLEncoding.EncodeToString(pub)
+		default:
+			continue
+		}
+
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
+
+// signingKey picks the newest private key of the configured algorithm whose activation
+// delay has passed, so verifiers had time to fetch it. Falls back to the newest key when
+// none is active yet (e.g. on first start).
+func (m *Manager) signingKey() *Key {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	var newest *Key
+	for i := len(m.keys) - 1; i >= 0; i-- {
+		key := m.keys[i]
+		if key.private == nil || key.Method != m.method {
+			continue
+		}
+		if !key.CreatedAt.Add(m.cfg.ActivationDelay).After(now) {
+			return key
+		}
+		if newest == nil {
+			newest = key
+		}
+	}
+	return newest
+}
+
+// newestSigningKey returns the newest private key of the configured algorithm
+func (m *Manager) newestSigningKey() *Key {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for i := len(m.keys) - 1; i >= 0; i-- {
+		if m.keys[i].private != nil && m.keys[i].Method == m.method {
+			return m.keys[i]
+		}
+	}
+	return nil
+}
+
+// rotate generates a new key once the newest one is older than the rotation period and
+// deletes keys whose tokens can no longer be valid
+func (m *Manager) rotate() error {
+	if m.cfg.RotationPeriod <= 0 {
+		return nil
+	}
+
+	if newest := m.newestSigningKey(); newest == nil || time.Since(newest.CreatedAt) >= m.cfg.RotationPeriod {
+		key, err := m.generate()
+		if err != nil {
+			return err
+		}
+		log.Printf("🔑 Generated JWT signing key %s, active from %s", key.ID, key.CreatedAt.Add(m.cfg.ActivationDelay).Format(time.RFC3339))
+	}
+
+	return m.prune()
+}
+
+// prune removes keys superseded by an active key for longer than the token lifetime
+func (m *Manager) prune() error {
+	active := m.signingKey()
+	if active == nil {
+		return nil
+	}
+
+	// Tokens signed by older keys were issued before the active key took over
+	retiredAt := active.CreatedAt.Add(m.cfg.ActivationDelay)
+	if time.Since(retiredAt) < m.cfg.Expiry+time.Minute {
+		return nil
+	}
+
+	m.mu.RLock()
+	var expired []*Key
+	for _, key := range m.keys {
+		if key.CreatedAt.Before(active.CreatedAt) {
+			expired = append(expired, key)
+		}
+	}
+	m.mu.RUnlock()
+
+	for _, key := range expired {
+		if err := removeKeyFile(m.cfg.KeysDir, key.ID); err != nil {
+			return err
+		}
+		log.Printf("🔑 Removed retired JWT key %s", key.ID)
+	}
+
+	if len(expired) > 0 {
+		return m.reload()
+	}
+	return nil
+}
+
+// reload replaces the key list with the contents of the key directory
+func (m *Manager) reload() error {
+	keys, err := loadKeyDir(m.cfg.KeysDir)
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].CreatedAt.Equal(keys[j].CreatedAt) {
+			return keys[i].ID < keys[j].ID
+		}
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+
+	m.mu.Lock()
+	m.keys = keys
+	m.mu.Unlock()
+	return nil
+}
+
+// generate creates, stores and loads a new private key of the configured algorithm
+func (m *Manager) generate() (*Key, error) {
+	key, err := generateKey(m.cfg.KeysDir, m.method)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	m.keys = append(m.keys, key)
+	m.mu.Unlock()
+	return key, nil
+}
+
+// methodFor returns the signing method used with a public key
+func methodFor(pub crypto.PublicKey) (jwt.SigningMethod, error) {
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		if pub.N.BitLen() < 2048 {
+			return nil, errors.New("RSA keys must be at least 2048 bits")
+		}
+		return jwt.SigningMethodRS256, nil
+	case ed25519.PublicKey:
+		return jwt.SigningMethodEdDSA, nil
+	case *ecdsa.PublicKey:
+		return nil, errors.New("ECDSA keys are not supported, use RSA or Ed25519")
+	default:
+		return nil, errors.New("unsupported key type")
+	}
+}
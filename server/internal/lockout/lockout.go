@@ -0,0 +1,175 @@
+// Package lockout throttles repeated failed logins per account and per client IP with
+// exponentially growing lockouts.
+package lockout
+
+import (
+	"gin-quickstart/config"
+	"strings"
+	"time"
+)
+
+// Scopes a failure counter applies to
+const (
+	ScopeEmail = "email"
+	ScopeIP    = "ip"
+)
+
+// Lock describes an active lockout
+type Lock struct {
+	Scope      string        // ScopeEmail or ScopeIP
+	Failures   int           // Failures counted when the lock was applied
+	RetryAfter time.Duration // Time left until the lock expires
+}
+
+// Limiter applies the lockout policy on top of a Store
+type Limiter struct {
+	store Store
+	cfg   config.LockoutConfig
+}
+
+func New(store Store, cfg config.LockoutConfig) *Limiter {
+	return &Limiter{store: store, cfg: cfg}
+}
+
+// Check returns the active lock for the email or IP, if any
+func (l *Limiter) Check(email, ip string) (*Lock, error) {
+	for _, k := range l.keys(email, ip) {
+		until, err := l.store.LockedUntil(k.key)
+		if err != nil {
+			return nil, err
+		}
+		if !until.IsZero() {
+			return &Lock{Scope: k.scope, RetryAfter: time.Until(until)}, nil
+		}
+	}
+	return nil, nil
+}
+
+// Attempt is a login attempt reserved by Begin, which Fail or Release ends
+type Attempt struct {
+	keys  []limiterKey
+	ended bool
+}
+
+// Begin reserves a login attempt before the password is checked, so that a burst of
+// parallel attempts cannot all pass a check made before any of them failed. Only as many
+// attempts as the email and IP have left before their threshold may be in progress at
+// once, and past the threshold one at a time. Returns the lock when the email or IP is
+// locked or out of attempts.
+func (l *Limiter) Begin(email, ip string) (*Attempt, *Lock, error) {
+	if lock, err := l.Check(email, ip); err != nil || lock != nil {
+		return nil, lock, err
+	}
+
+	attempt := &Attempt{}
+	for _, k := range l.keys(email, ip) {
+		failures, pending, err := l.store.Begin(k.key, l.cfg.FailureWindow)
+		if err != nil {
+			l.Release(attempt)
+			return nil, nil, err
+		}
+		attempt.keys = append(attempt.keys, k)
+
+		if pending > max(k.threshold-failures, 1) {
+			l.Release(attempt)
+			// The attempts in progress decide whether a lock follows
+			return nil, &Lock{Scope: k.scope, Failures: failures, RetryAfter: time.Second}, nil
+		}
+	}
+	return attempt, nil, nil
+}
+
+// Fail counts a reserved attempt as failed. Once a counter reaches its threshold every
+// further failure locks it out for twice as long as the previous one, up to LockoutMax.
+// Returns the lock applied by this failure, if any.
+func (l *Limiter) Fail(attempt *Attempt) (*Lock, error) {
+	if attempt.ended {
+		return nil, nil
+	}
+	attempt.ended = true
+
+	var applied *Lock
+	for _, k := range attempt.keys {
+		failures, err := l.store.End(k.key, true, l.cfg.FailureWindow)
+		if err != nil {
+			return nil, err
+		}
+		lock, err := l.lockIfExceeded(k, failures)
+		if err != nil {
+			return nil, err
+		}
+		if lock != nil && (applied == nil || lock.RetryAfter > applied.RetryAfter) {
+			applied = lock
+		}
+	}
+	return applied, nil
+}
+
+// Release ends a reserved attempt that did not fail, e.g. a successful login. Releasing
+// a nil or ended attempt does nothing.
+func (l *Limiter) Release(attempt *Attempt) error {
+	if attempt == nil || attempt.ended {
+		return nil
+	}
+	attempt.ended = true
+
+	for _, k := range attempt.keys {
+		if _, err := l.store.End(k.key, false, l.cfg.FailureWindow); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// lockIfExceeded locks a counter that reached its threshold, for longer the further past
+// it is. Returns the applied lock, if any.
+func (l *Limiter) lockIfExceeded(k limiterKey, failures int) (*Lock, error) {
+	if failures < k.threshold {
+		return nil, nil
+	}
+
+	duration := l.backoff(failures - k.threshold)
+	if err := l.store.Lock(k.key, time.Now().Add(duration)); err != nil {
+		return nil, err
+	}
+	return &Lock{Scope: k.scope, Failures: failures, RetryAfter: duration}, nil
+}
+
+// RecordSuccess clears the account's failures. The IP counter is kept so that one valid
+// account cannot be used to reset guessing against others.
+func (l *Limiter) RecordSuccess(email string) error {
+	return l.store.Reset(emailKey(email))
+}
+
+type limiterKey struct {
+	scope     string
+	key       string
+	threshold int
+}
+
+func (l *Limiter) keys(email, ip string) []limiterKey {
+	keys := make([]limiterKey, 0, 2)
+	if email != "" {
+		keys = append(keys, limiterKey{scope: ScopeEmail, key: emailKey(email), threshold: l.cfg.MaxFailures})
+	}
+	if ip != "" {
+		keys = append(keys, limiterKey{scope: ScopeIP, key: "login:ip:" + ip, threshold: l.cfg.MaxFailuresPerIP})
+	}
+	return keys
+}
+
+// backoff returns LockoutBase doubled for each failure past the threshold, capped at LockoutMax
+func (l *Limiter) backoff(excess int) time.Duration {
+	duration := l.cfg.LockoutBase
+	for i := 0; i < excess && duration < l.cfg.LockoutMax; i++ {
+		duration *= 2
+	}
+	if duration > l.cfg.LockoutMax {
+		duration = l.cfg.LockoutMax
+	}
+	return duration
+}
+
+func emailKey(email string) string {
+	return "login:email:" + strings.ToLower(strings.TrimSpace(email))
+}
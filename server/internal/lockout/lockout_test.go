@@ -0,0 +1,241 @@
+package lockout
+
+import (
+	"gin-quickstart/config"
+	"testing"
+	"time"
+)
+
+var testConfig = config.LockoutConfig{
+	MaxFailures:      3,
+	MaxFailuresPerIP: 5,
+	LockoutBase:      time.Minute,
+	LockoutMax:       10 * time.Minute,
+	FailureWindow:    time.Hour,
+}
+
+type login struct{ email, ip string }
+
+// fail reserves an attempt and counts it as failed, returning the lock applied by the
+// failure or the lock that refused the attempt
+func fail(t *testing.T, l *Limiter, attempt login) *Lock {
+	t.Helper()
+	reserved, lock, err := l.Begin(attempt.email, attempt.ip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lock != nil {
+		return lock
+	}
+	lock, err = l.Fail(reserved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return lock
+}
+
+func TestLimiterThresholds(t *testing.T) {
+	tests := []struct {
+		name      string
+		failures  []login
+		next      login
+		wantScope string // "" when the next attempt is allowed
+	}{
+		{
+			name:     "email below threshold",
+			failures: []login{{"jane@example.com", "10.0.0.1"}, {"jane@example.com", "10.0.0.2"}},
+			next:     login{"jane@example.com", "10.0.0.3"},
+		},
+		{
+			name:      "email at threshold from any IP",
+			failures:  []login{{"jane@example.com", "10.0.0.1"}, {"jane@example.com", "10.0.0.2"}, {"jane@example.com", "10.0.0.3"}},
+			next:      login{"jane@example.com", "10.0.0.4"},
+			wantScope: ScopeEmail,
+		},
+		{
+			name:      "email ignores case and spaces",
+			failures:  []login{{"Jane@example.com", ""}, {" jane@EXAMPLE.com", ""}, {"jane@example.com ", ""}},
+			next:      login{"JANE@example.com", ""},
+			wantScope: ScopeEmail,
+		},
+		{
+			name:     "other email from a failing IP",
+			failures: []login{{"jane@example.com", "10.0.0.1"}, {"jane@example.com", "10.0.0.1"}, {"jane@example.com", "10.0.0.1"}},
+			next:     login{"budi@example.com", "10.0.0.1"},
+		},
+		{
+			name: "IP at threshold for any email",
+			failures: []login{
+				{"a@example.com", "10.0.0.1"}, {"b@example.com", "10.0.0.1"}, {"c@example.com", "10.0.0.1"},
+				{"d@example.com", "10.0.0.1"}, {"e@example.com", "10.0.0.1"},
+			},
+			next:      login{"f@example.com", "10.0.0.1"},
+			wantScope: ScopeIP,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(NewMemoryStore(), testConfig)
+			for _, attempt := range tt.failures {
+				fail(t, l, attempt)
+			}
+
+			lock, err := l.Check(tt.next.email, tt.next.ip)
+			if err != nil {
+				t.Fatal(err)
+			}
+			switch {
+			case tt.wantScope == "" && lock != nil:
+				t.Errorf("Check() = %+v, want no lock", lock)
+			case tt.wantScope != "" && (lock == nil || lock.Scope != tt.wantScope):
+				t.Errorf("Check() = %+v, want a %s lock", lock, tt.wantScope)
+			}
+		})
+	}
+}
+
+func TestLimiterLocksAtThreshold(t *testing.T) {
+	l := New(NewMemoryStore(), testConfig)
+	attempt := login{"jane@example.com", ""}
+
+	for i := 1; i < testConfig.MaxFailures; i++ {
+		if lock := fail(t, l, attempt); lock != nil {
+			t.Fatalf("failure %d locked: %+v", i, lock)
+		}
+	}
+
+	lock := fail(t, l, attempt)
+	if lock == nil || lock.Scope != ScopeEmail || lock.Failures != testConfig.MaxFailures || lock.RetryAfter != testConfig.LockoutBase {
+		t.Errorf("failure at threshold = %+v, want an email lock of %s after %d failures", lock, testConfig.LockoutBase, testConfig.MaxFailures)
+	}
+}
+
+func TestLimiterCapsPendingAttempts(t *testing.T) {
+	tests := []struct {
+		name        string
+		failures    int
+		wantPending int // Attempts that may be in progress at once
+	}{
+		{"no failures", 0, 3},
+		{"one failure", 1, 2},
+		{"one failure left", 2, 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New(NewMemoryStore(), testConfig)
+			for i := 0; i < tt.failures; i++ {
+				fail(t, l, login{"jane@example.com", ""})
+			}
+
+			var reserved []*Attempt
+			for i := 0; i < tt.wantPending; i++ {
+				attempt, lock, err := l.Begin("jane@example.com", "")
+				if err != nil || lock != nil {
+					t.Fatalf("attempt %d refused: %+v, %v", i+1, lock, err)
+				}
+				reserved = append(reserved, attempt)
+			}
+
+			_, lock, err := l.Begin("jane@example.com", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if lock == nil || lock.RetryAfter != time.Second || lock.Failures != tt.failures {
+				t.Errorf("attempt past the cap = %+v, want a 1s lock after %d failures", lock, tt.failures)
+			}
+
+			// Ending an attempt frees its place
+			if err := l.Release(reserved[0]); err != nil {
+				t.Fatal(err)
+			}
+			if _, lock, err := l.Begin("jane@example.com", ""); err != nil || lock != nil {
+				t.Errorf("attempt after a release refused: %+v, %v", lock, err)
+			}
+		})
+	}
+}
+
+func TestLimiterReleaseIsNotAFailure(t *testing.T) {
+	l := New(NewMemoryStore(), testConfig)
+
+	for i := 0; i < 2*testConfig.MaxFailuresPerIP; i++ {
+		attempt, lock, err := l.Begin("jane@example.com", "10.0.0.1")
+		if err != nil || lock != nil {
+			t.Fatalf("attempt %d refused: %+v, %v", i+1, lock, err)
+		}
+		if err := l.Release(attempt); err != nil {
+			t.Fatal(err)
+		}
+		// An ended attempt cannot be failed afterwards
+		if lock, err := l.Fail(attempt); err != nil || lock != nil {
+			t.Fatalf("Fail after Release = %+v, %v", lock, err)
+		}
+	}
+
+	if lock, err := l.Check("jane@example.com", "10.0.0.1"); err != nil || lock != nil {
+		t.Errorf("Check() after releases = %+v, %v, want no lock", lock, err)
+	}
+	if err := l.Release(nil); err != nil {
+		t.Errorf("Release(nil) = %v", err)
+	}
+
+	// The failures before a release still count
+	for i := 1; i < testConfig.MaxFailures; i++ {
+		fail(t, l, login{"jane@example.com", ""})
+	}
+	if lock := fail(t, l, login{"jane@example.com", ""}); lock == nil {
+		t.Error("failure at threshold after releases did not lock")
+	}
+}
+
+func TestLimiterBackoff(t *testing.T) {
+	l := New(NewMemoryStore(), testConfig)
+
+	tests := []struct {
+		excess int
+		want   time.Duration
+	}{
+		{0, time.Minute},
+		{1, 2 * time.Minute},
+		{2, 4 * time.Minute},
+		{3, 8 * time.Minute},
+		{4, 10 * time.Minute},
+		{50, 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		if got := l.backoff(tt.excess); got != tt.want {
+			t.Errorf("backoff(%d) = %s, want %s", tt.excess, got, tt.want)
+		}
+	}
+}
+
+func TestLimiterLockGrowsPastThreshold(t *testing.T) {
+	store := NewMemoryStore()
+	l := New(store, testConfig)
+	attempt := login{"jane@example.com", ""}
+
+	want := []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 10 * time.Minute, 10 * time.Minute}
+	for i := 1; i < testConfig.MaxFailures; i++ {
+		fail(t, l, attempt)
+	}
+	for i, duration := range want {
+		lock := fail(t, l, attempt)
+		if lock == nil || lock.RetryAfter != duration {
+			t.Fatalf("lock %d = %+v, want %s", i+1, lock, duration)
+		}
+		// Let the lock expire without forgetting the failures
+		if err := store.Lock(emailKey(attempt.email), time.Now().Add(-time.Second)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := l.RecordSuccess(attempt.email); err != nil {
+		t.Fatal(err)
+	}
+	if lock := fail(t, l, attempt); lock != nil {
+		t.Errorf("failure after a success locked: %+v", lock)
+	}
+}
@@ -0,0 +1,125 @@
+package lockout
+
+import (
+	"sync"
+	"time"
+)
+
+// Store keeps failure counters and lock expiries. MemoryStore works for a single
+// instance; a shared implementation (e.g. Redis INCR/EXPIRE) makes limits hold across
+// instances.
+type Store interface {
+	// Begin adds an attempt in progress for key and returns the failures and the attempts
+	// in progress, this one included. The counters expire once no attempt has been made
+	// for window.
+	Begin(key string, window time.Duration) (failures, pending int, err error)
+	// End ends an attempt in progress for key, counting it as a failure if failed, and
+	// returns the failures
+	End(key string, failed bool, window time.Duration) (int, error)
+	// Lock blocks key until the given time
+	Lock(key string, until time.Time) error
+	// LockedUntil returns the lock expiry of key, or the zero time if it is not locked
+	LockedUntil(key string) (time.Time, error)
+	// Reset clears the counter and any lock of key
+	Reset(key string) error
+}
+
+type memoryEntry struct {
+	failures    int
+	pending     int
+	expiresAt   time.Time
+	lockedUntil time.Time
+}
+
+// MemoryStore is an in-process Store
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]*memoryEntry
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*memoryEntry)}
+}
+
+func (s *MemoryStore) Begin(key string, window time.Duration) (int, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.entryLocked(key, window)
+	entry.pending++
+	return entry.failures, entry.pending, nil
+}
+
+func (s *MemoryStore) End(key string, failed bool, window time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.entryLocked(key, window)
+	// The counters may have been reset while the attempt was in progress
+	if entry.pending > 0 {
+		entry.pending--
+	}
+	if failed {
+		entry.failures++
+	}
+	return entry.failures, nil
+}
+
+// entryLocked returns the live entry of key, extended by window; caller must hold mu
+func (s *MemoryStore) entryLocked(key string, window time.Duration) *memoryEntry {
+	now := time.Now()
+	entry := s.entries[key]
+	if entry == nil || now.After(entry.expiresAt) {
+		s.sweepLocked(now)
+		entry = &memoryEntry{}
+		s.entries[key] = entry
+	}
+	entry.expiresAt = now.Add(window)
+	return entry
+}
+
+func (s *MemoryStore) Lock(key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := s.entries[key]
+	if entry == nil {
+		entry = &memoryEntry{}
+		s.entries[key] = entry
+	}
+
+	entry.lockedUntil = until
+	if entry.expiresAt.Before(until) {
+		entry.expiresAt = until
+	}
+	return nil
+}
+
+func (s *MemoryStore) LockedUntil(key string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if entry := s.entries[key]; entry != nil && time.Now().Before(entry.lockedUntil) {
+		return entry.lockedUntil, nil
+	}
+	return time.Time{}, nil
+}
+
+func (s *MemoryStore) Reset(key string) error {
+	s.mu.Lock()
+	delete(s.entries, key)
+	s.mu.Unlock()
+	return nil
+}
+
+// sweepLocked removes expired entries once the map grows large; caller must hold mu
+func (s *MemoryStore) sweepLocked(now time.Time) {
+	if len(s.entries) < 10000 {
+		return
+	}
+	for key, entry := range s.entries {
+		if now.After(entry.expiresAt) && now.After(entry.lockedUntil) {
+			delete(s.entries, key)
+		}
+	}
+}
@@ -0,0 +1,31 @@
+package mailer
+
+import (
+	"gin-quickstart/config"
+	"log"
+)
+
+// Message is a plain-text email
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers transactional email (invitations, password resets, ...)
+type Mailer interface {
+	Send(msg Message) error
+}
+
+// New returns the mailer selected by MAIL_DRIVER ("smtp" or "log")
+func New(cfg config.MailConfig) Mailer {
+	switch cfg.Driver {
+	case "smtp":
+		return NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.From)
+	case "log":
+		return NewLogMailer(cfg.LogFile, cfg.From)
+	default:
+		log.Printf("⚠️  Unknown MAIL_DRIVER %q, falling back to log mailer", cfg.Driver)
+		return NewLogMailer(cfg.LogFile, cfg.From)
+	}
+}
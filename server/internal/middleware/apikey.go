@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/repository"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiKeyTouchInterval limits last-used bookkeeping to one write per key per interval
+const apiKeyTouchInterval = time.Minute
+
+// HashAPIKey returns the stored form of an API key
+func HashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// extractAPIKey returns the key of an "Authorization: ApiKey <key>" header
+func extractAPIKey(authHeader string) (string, bool) {
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || strings.ToLower(parts[0]) != "apikey" {
+		return "", false
+	}
+	return parts[1], true
+}
+
+// authenticateAPIKey resolves a usable API key and records its use
+func authenticateAPIKey(c *gin.Context, apiKeyRepo repository.APIKeyRepository, key string) (*model.APIKey, error) {
+	if !strings.HasPrefix(key, model.APIKeyPrefix) {
+		return nil, errors.New("invalid API key")
+	}
+
+	apiKey, err := apiKeyRepo.FindByHash(HashAPIKey(key))
+	if err != nil || !apiKey.IsUsable() {
+		return nil, errors.New("invalid API key")
+	}
+
+	if apiKey.LastUsedAt == nil || time.Since(*apiKey.LastUsedAt) > apiKeyTouchInterval {
+		apiKeyRepo.TouchLastUsed(apiKey.ID, c.ClientIP())
+	}
+
+	return apiKey, nil
+}
+
+// RequireUserSession rejects API keys and impersonating operators on account endpoints
+// (password, MFA, tenant switching) that only make sense for the interactive user
+func RequireUserSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if GetAPIKeyID(c) != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "API keys cannot access this endpoint"})
+			c.Abort()
+			return
+		}
+		if GetOperatorID(c) != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This endpoint is not available while impersonating"})
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// GetAPIKeyID returns the ID of the API key that authenticated the request, or nil for user sessions
+func GetAPIKeyID(c *gin.Context) *uint {
+	if id, exists := c.Get("api_key_id"); exists {
+		apiKeyID := id.(uint)
+		return &apiKeyID
+	}
+	return nil
+}
@@ -1,16 +1,55 @@
 package middleware
 
 import (
+	"errors"
+	"gin-quickstart/internal/jwtkeys"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/repository"
 	"net/http"
+	"strings"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
-// AuthMiddleware validates JWT and sets user context
-func AuthMiddleware() gin.HandlerFunc {
+// RoleAPIKey is the role reported for requests authenticated with an API key
+const RoleAPIKey = "api_key"
+
+// AuthMiddleware validates a JWT or tenant API key and sets user context. Requests
+// authenticated with an API key act as the user who created the key.
+func AuthMiddleware(
+	keys *jwtkeys.Manager,
+	userRepo repository.UserRepository,
+	apiKeyRepo repository.APIKeyRepository,
+	sessionRepo repository.SessionRepository,
+) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 
+		if key, ok := extractAPIKey(authHeader); ok {
+			apiKey, err := authenticateAPIKey(c, apiKeyRepo, key)
+			if err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid, expired or revoked API key"})
+				c.Abort()
+				return
+			}
+
+			scopes := make(map[string]bool, len(apiKey.Scopes))
+			for _, key := range apiKey.ScopeKeys() {
+				scopes[key] = true
+			}
+
+			c.Set("user_id", apiKey.CreatedBy)
+			c.Set("email", "")
+			c.Set("tenant_id", apiKey.TenantID)
+			c.Set("role", RoleAPIKey)
+			c.Set("api_key_id", apiKey.ID)
+			c.Set("api_key_scopes", scopes)
+
+			c.Next()
+			return
+		}
+
 		tokenString, err := ExtractToken(authHeader)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Missing or invalid authorization header"})
@@ -18,13 +57,33 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		claims, err := ValidateToken(tokenString)
+		claims, err := ValidateToken(tokenString, keys, userRepo)
 		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
 		}
 
+		// Revoked sessions are logged out immediately, not when the access token expires
+		if claims.SessionID != 0 {
+			if err := checkSession(c, sessionRepo, claims); err != nil {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+				c.Abort()
+				return
+			}
+			c.Set("session_id", claims.SessionID)
+		}
+
+		// Impersonation ends as soon as the operator loses platform access
+		if claims.OperatorID != 0 {
+			if claims.SessionID == 0 || !isPlatformAdmin(userRepo, claims.OperatorID) {
+				c.JSON(http.StatusUnauthorized, gin.H{"error": "Impersonation is no longer allowed"})
+				c.Abort()
+				return
+			}
+			c.Set("operator_id", claims.OperatorID)
+		}
+
 		// Set user context for downstream handlers
 		c.Set("user_id", claims.UserID)
 		c.Set("email", claims.Email)
@@ -35,8 +94,9 @@ func AuthMiddleware() gin.HandlerFunc {
 	}
 }
 
-// TenantMiddleware ensures tenant context is set (requires AuthMiddleware)
-func TenantMiddleware() gin.HandlerFunc {
+// TenantMiddleware ensures tenant context is set (requires AuthMiddleware) and
+// re-checks the live membership, overriding the role carried in the JWT
+func TenantMiddleware(memberships *MembershipCache) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		tenantID, exists := c.Get("tenant_id")
 		if !exists || tenantID.(uint) == 0 {
@@ -45,10 +105,89 @@ func TenantMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		membership, err := memberships.Get(tenantID.(uint), GetUserID(c))
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Access to this tenant has been revoked"})
+			} else {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify tenant access"})
+			}
+			c.Abort()
+			return
+		}
+
+		if !membership.UserActive {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User account is inactive"})
+			c.Abort()
+			return
+		}
+
+		if model.IsReadOnlyTenantStatus(membership.TenantStatus) {
+			if !isReadMethod(c.Request.Method) && !readOnlyTenantRoutes[c.Request.Method+" "+c.FullPath()] {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error":         "Tenant is " + membership.TenantStatus + ", only read access is allowed",
+					"tenant_status": membership.TenantStatus,
+				})
+				c.Abort()
+				return
+			}
+		} else if membership.TenantStatus != model.TenantStatusActive {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Tenant is " + membership.TenantStatus})
+			c.Abort()
+			return
+		}
+
+		apiKeyScopes, isAPIKey := c.Get("api_key_scopes")
+
+		// Members must enroll under /api/me/mfa before using a tenant that requires it
+		if membership.RequireMFA && !membership.UserMFAEnabled && !isAPIKey {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":                   "This tenant requires two-factor authentication",
+				"mfa_enrollment_required": true,
+			})
+			c.Abort()
+			return
+		}
+
+		if isAPIKey {
+			// A key never exceeds the current permissions of the user who created it
+			c.Set("permissions", intersectPermissions(membership.Permissions, apiKeyScopes.(map[string]bool)))
+		} else {
+			// Live role wins over the (possibly stale) JWT claim
+			c.Set("role", membership.Role)
+			c.Set("permissions", membership.Permissions)
+		}
+		c.Set("record_visibility", membership.RecordVisibility)
+		c.Set("team_ids", membership.TeamIDs)
+		c.Set("managed_user_ids", membership.ManagedUserIDs)
+
 		c.Next()
 	}
 }
 
+// readOnlyTenantRoutes accept writes while the tenant is read-only. Exporting creates no
+// tenant data and is how a tenant awaiting deletion takes its data out.
+var readOnlyTenantRoutes = map[string]bool{
+	"POST /api/tenant/exports": true,
+}
+
+// isReadMethod reports whether a request method only reads data
+func isReadMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// intersectPermissions keeps the scopes the user holds. An ":own" scope is also kept
+// when the user holds the unrestricted permission.
+func intersectPermissions(userPermissions, scopes map[string]bool) map[string]bool {
+	permissions := make(map[string]bool)
+	for key := range scopes {
+		if userPermissions[key] || userPermissions[strings.TrimSuffix(key, model.PermissionOwnSuffix)] {
+			permissions[key] = true
+		}
+	}
+	return permissions
+}
+
 // RoleMiddleware checks if user has required role
 func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -72,6 +211,33 @@ func RoleMiddleware(allowedRoles ...string) gin.HandlerFunc {
 	}
 }
 
+// RequirePermission checks that the user's role grants the permission. A role holding
+// only the ":own" variant passes too, with the request marked as own-scoped so that
+// handlers restrict the action to records the user owns.
+func RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		permissions := GetPermissions(c)
+
+		if permissions[permission] {
+			// When several permissions are required, the narrowest scope applies
+			if c.GetString("permission_scope") != "own" {
+				c.Set("permission_scope", "all")
+			}
+			c.Next()
+			return
+		}
+
+		if permissions[permission+model.PermissionOwnSuffix] {
+			c.Set("permission_scope", "own")
+			c.Next()
+			return
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions", "required_permission": permission})
+		c.Abort()
+	}
+}
+
 // GetUserID retrieves user ID from context
 func GetUserID(c *gin.Context) uint {
 	if userID, exists := c.Get("user_id"); exists {
@@ -95,3 +261,47 @@ func GetRole(c *gin.Context) string {
 	}
 	return ""
 }
+
+// GetPermissions retrieves the permission set resolved by TenantMiddleware
+func GetPermissions(c *gin.Context) map[string]bool {
+	if permissions, exists := c.Get("permissions"); exists {
+		return permissions.(map[string]bool)
+	}
+	return map[string]bool{}
+}
+
+// IsOwnScope reports whether the current permission only covers the user's own records
+func IsOwnScope(c *gin.Context) bool {
+	return c.GetString("permission_scope") == "own"
+}
+
+// GetRecordAccess returns which contacts and deals the user may act on, combining
+// ":own" permission scopes with the tenant's record visibility setting
+func GetRecordAccess(c *gin.Context) model.RecordAccess {
+	userID := GetUserID(c)
+
+	if IsOwnScope(c) {
+		return model.OwnRecordAccess(userID)
+	}
+
+	if GetPermissions(c)[model.PermRecordsViewAll] {
+		return model.FullRecordAccess
+	}
+
+	switch c.GetString("record_visibility") {
+	case model.VisibilityTeam, model.VisibilityManager:
+		// The membership only resolves the teams and members the mode grants
+		managed, _ := c.Get("managed_user_ids")
+		teamIDs, _ := c.Get("team_ids")
+		access := model.OwnRecordAccess(userID)
+		if ids, ok := managed.([]uint); ok {
+			access.OwnerIDs = append(access.OwnerIDs, ids...)
+		}
+		if ids, ok := teamIDs.([]uint); ok {
+			access.TeamIDs = ids
+		}
+		return access
+	}
+
+	return model.FullRecordAccess
+}
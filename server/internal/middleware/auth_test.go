@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"gin-quickstart/internal/model"
+	"reflect"
+	"testing"
+)
+
+func TestIntersectPermissions(t *testing.T) {
+	own := model.PermDealsUpdate + model.PermissionOwnSuffix
+
+	tests := []struct {
+		name   string
+		user   map[string]bool
+		scopes map[string]bool
+		want   map[string]bool
+	}{
+		{
+			name:   "scopes the user holds",
+			user:   map[string]bool{model.PermDealsRead: true, model.PermDealsUpdate: true, model.PermContactsRead: true},
+			scopes: map[string]bool{model.PermDealsRead: true, model.PermDealsUpdate: true},
+			want:   map[string]bool{model.PermDealsRead: true, model.PermDealsUpdate: true},
+		},
+		{
+			name:   "creator lost a permission",
+			user:   map[string]bool{model.PermDealsRead: true},
+			scopes: map[string]bool{model.PermDealsRead: true, model.PermDealsDelete: true},
+			want:   map[string]bool{model.PermDealsRead: true},
+		},
+		{
+			name:   "creator lost every permission",
+			user:   map[string]bool{},
+			scopes: map[string]bool{model.PermDealsRead: true},
+			want:   map[string]bool{},
+		},
+		{
+			name:   "unscoped permission keeps own scope",
+			user:   map[string]bool{model.PermDealsUpdate: true},
+			scopes: map[string]bool{own: true},
+			want:   map[string]bool{own: true},
+		},
+		{
+			name:   "own permission does not keep unscoped scope",
+			user:   map[string]bool{own: true},
+			scopes: map[string]bool{model.PermDealsUpdate: true},
+			want:   map[string]bool{},
+		},
+		{
+			name:   "creator narrowed to own",
+			user:   map[string]bool{own: true},
+			scopes: map[string]bool{model.PermDealsUpdate: true, own: true},
+			want:   map[string]bool{own: true},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := intersectPermissions(tt.user, tt.scopes); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: intersectPermissions() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -3,6 +3,8 @@ package middleware
 import (
 	"errors"
 	"gin-quickstart/config"
+	"gin-quickstart/internal/jwtkeys"
+	"gin-quickstart/internal/repository"
 	"strings"
 	"time"
 
@@ -10,39 +12,82 @@ import (
 )
 
 type Claims struct {
-	UserID   uint   `json:"user_id"`
-	Email    string `json:"email"`
-	TenantID uint   `json:"tenant_id"`
-	Role     string `json:"role"`
+	UserID     uint   `json:"user_id"`
+	Email      string `json:"email"`
+	TenantID   uint   `json:"tenant_id"`
+	Role       string `json:"role"`
+	SessionID  uint   `json:"sid,omitempty"`
+	OperatorID uint   `json:"op,omitempty"` // Platform operator acting as UserID (impersonation tokens only)
 	jwt.RegisteredClaims
 }
 
-// GenerateToken creates a JWT token with user and tenant info
-func GenerateToken(userID, tenantID uint, email, role string) (string, error) {
-	claims := Claims{
-		UserID:   userID,
-		Email:    email,
-		TenantID: tenantID,
-		Role:     role,
+// GenerateToken creates a JWT token with user and tenant info, signed with the current key
+func GenerateToken(keys *jwtkeys.Manager, userID, tenantID, sessionID uint, email, role string) (string, error) {
+	claims := newClaims(userID, tenantID, sessionID, email, role, time.Now().Add(config.AppConfig.JWT.Expiry))
+	return keys.Sign(claims)
+}
+
+// GenerateImpersonationToken creates a JWT token that lets a platform operator act as
+// userID until expiresAt. It cannot be refreshed.
+func GenerateImpersonationToken(keys *jwtkeys.Manager, userID, tenantID, sessionID, operatorID uint, email, role string, expiresAt time.Time) (string, error) {
+	claims := newClaims(userID, tenantID, sessionID, email, role, expiresAt)
+	claims.OperatorID = operatorID
+	return keys.Sign(claims)
+}
+
+func newClaims(userID, tenantID, sessionID uint, email, role string, expiresAt time.Time) Claims {
+	now := time.Now()
+	return Claims{
+		UserID:    userID,
+		Email:     email,
+		TenantID:  tenantID,
+		Role:      role,
+		SessionID: sessionID,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(config.AppConfig.JWT.Expiry)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
-			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    config.AppConfig.Server.PublicURL,
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
 		},
 	}
+}
+
+// ValidateToken validates and parses JWT token, rejecting tokens issued before
+// the user's TokensValidAfter timestamp (server-side revocation)
+func ValidateToken(tokenString string, keys *jwtkeys.Manager, userRepo repository.UserRepository) (*Claims, error) {
+	claims, err := parseToken(tokenString, keys)
+	if err != nil {
+		return nil, err
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(config.AppConfig.JWT.Secret))
+	user, err := userRepo.FindByID(claims.UserID)
+	if err != nil {
+		return nil, errors.New("invalid token")
+	}
+
+	if user.TokensValidAfter != nil && claims.IssuedAt != nil && issuedBefore(claims, *user.TokensValidAfter) {
+		return nil, errors.New("token has been revoked")
+	}
+
+	return claims, nil
+}
+
+// issuedBefore reports whether a token predates cutoff. JWT timestamps have second
+// precision, so a token issued in the second of the cutoff may predate it: the cutoff
+// moves to the next whole second. Tokens of a session are exempt within that second, as
+// revocations end the sessions too (checkSession) while the fresh token issued alongside
+// one, e.g. on password change, belongs to a new session.
+func issuedBefore(claims *Claims, cutoff time.Time) bool {
+	second := cutoff.Truncate(time.Second)
+	if claims.IssuedAt.Time.Before(second) {
+		return true
+	}
+	return claims.SessionID == 0 && cutoff.After(second) && claims.IssuedAt.Time.Equal(second)
 }
 
-// ValidateToken validates and parses JWT token
-func ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(config.AppConfig.JWT.Secret), nil
-	})
+// parseToken verifies the signature and registered claims of a JWT token
+func parseToken(tokenString string, keys *jwtkeys.Manager) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, keys.Keyfunc, jwt.WithValidMethods(keys.ValidMethods()))
 
 	if err != nil {
 		return nil, err
@@ -0,0 +1,240 @@
+package middleware
+
+import (
+	"errors"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/repository"
+	"strconv"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Membership is the live view of a user's access to a tenant
+type Membership struct {
+	Role         string
+	Permissions  map[string]bool
+	TenantStatus string
+	UserActive   bool
+
+	RequireMFA     bool // Tenant's require_mfa setting
+	UserMFAEnabled bool
+
+	RateLimitTenant int // Tenant's rate_limit_tenant_rpm setting (0 = server default)
+	RateLimitUser   int // Tenant's rate_limit_user_rpm setting (0 = server default)
+
+	RecordVisibility string // Tenant's record_visibility setting
+	TeamIDs          []uint // Teams whose shared records the user sees (team and manager visibility)
+	ManagedUserIDs   []uint // Members of the teams the user manages (manager visibility only)
+}
+
+type membershipKey struct {
+	tenantID uint
+	userID   uint
+}
+
+type membershipEntry struct {
+	membership Membership
+	expiresAt  time.Time
+}
+
+// MembershipCache resolves TenantUser rows with a short TTL so that role changes,
+// removals and suspensions take effect without waiting for the JWT to expire
+type MembershipCache struct {
+	tenantUserRepo repository.TenantUserRepository
+	roleRepo       repository.RoleRepository
+	settingRepo    repository.TenantSettingRepository
+	teamRepo       repository.TeamRepository
+	ttl            time.Duration
+
+	mu      sync.RWMutex
+	entries map[membershipKey]membershipEntry
+}
+
+func NewMembershipCache(
+	tenantUserRepo repository.TenantUserRepository,
+	roleRepo repository.RoleRepository,
+	settingRepo repository.TenantSettingRepository,
+	teamRepo repository.TeamRepository,
+	ttl time.Duration,
+) *MembershipCache {
+	return &MembershipCache{
+		tenantUserRepo: tenantUserRepo,
+		roleRepo:       roleRepo,
+		settingRepo:    settingRepo,
+		teamRepo:       teamRepo,
+		ttl:            ttl,
+		entries:        make(map[membershipKey]membershipEntry),
+	}
+}
+
+// Get returns the cached membership or loads it from the database
+func (c *MembershipCache) Get(tenantID, userID uint) (*Membership, error) {
+	key := membershipKey{tenantID: tenantID, userID: userID}
+
+	c.mu.RLock()
+	entry, ok := c.entries[key]
+	c.mu.RUnlock()
+
+	if ok && time.Now().Before(entry.expiresAt) {
+		membership := entry.membership
+		return &membership, nil
+	}
+
+	tenantUser, err := c.tenantUserRepo.FindMembership(tenantID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if tenantUser.Tenant.ID == 0 || tenantUser.User.ID == 0 {
+		// Tenant or user was soft deleted
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	permissions, err := c.resolvePermissions(tenantID, tenantUser.Role)
+	if err != nil {
+		return nil, err
+	}
+
+	visibility, err := c.resolveSetting(tenantID, model.SettingRecordVisibility)
+	if err != nil {
+		return nil, err
+	}
+
+	requireMFA, err := c.resolveSetting(tenantID, model.SettingRequireMFA)
+	if err != nil {
+		return nil, err
+	}
+
+	rateLimitTenant, err := c.resolveSetting(tenantID, model.SettingRateLimitTenant)
+	if err != nil {
+		return nil, err
+	}
+	rateLimitUser, err := c.resolveSetting(tenantID, model.SettingRateLimitUser)
+	if err != nil {
+		return nil, err
+	}
+
+	membership := Membership{
+		Role:             tenantUser.Role,
+		Permissions:      permissions,
+		TenantStatus:     tenantUser.Tenant.Status,
+		UserActive:       tenantUser.User.IsActive,
+		RequireMFA:       requireMFA == "true",
+		UserMFAEnabled:   tenantUser.User.MFAEnabled,
+		RateLimitTenant:  atoiOrZero(rateLimitTenant),
+		RateLimitUser:    atoiOrZero(rateLimitUser),
+		RecordVisibility: visibility,
+	}
+
+	switch visibility {
+	case model.VisibilityTeam:
+		if membership.TeamIDs, err = c.teamRepo.FindVisibleTeamIDs(tenantID, userID); err != nil {
+			return nil, err
+		}
+	case model.VisibilityManager:
+		if membership.TeamIDs, err = c.teamRepo.FindManagedTeamIDs(tenantID, userID); err != nil {
+			return nil, err
+		}
+		if membership.ManagedUserIDs, err = c.teamRepo.FindManagedMemberIDs(tenantID, userID); err != nil {
+			return nil, err
+		}
+	}
+
+	c.mu.Lock()
+	c.sweepLocked()
+	c.entries[key] = membershipEntry{membership: membership, expiresAt: time.Now().Add(c.ttl)}
+	c.mu.Unlock()
+
+	return &membership, nil
+}
+
+// resolvePermissions loads the permission set of a tenant role. Tenants whose
+// built-in roles have not been seeded yet fall back to the built-in defaults.
+func (c *MembershipCache) resolvePermissions(tenantID uint, roleName string) (map[string]bool, error) {
+	var keys []string
+
+	role, err := c.roleRepo.FindByName(tenantID, roleName)
+	switch {
+	case err == nil:
+		keys = role.PermissionKeys()
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		keys = model.BuiltInRolePermissions[roleName]
+	default:
+		return nil, err
+	}
+
+	permissions := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		permissions[key] = true
+	}
+	return permissions, nil
+}
+
+// resolveSetting reads a tenant setting, falling back to its default
+func (c *MembershipCache) resolveSetting(tenantID uint, key string) (string, error) {
+	setting, err := c.settingRepo.Get(tenantID, key)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.TenantSettingDefaults[key], nil
+		}
+		return "", err
+	}
+	return setting.Value, nil
+}
+
+func atoiOrZero(value string) int {
+	n, _ := strconv.Atoi(value)
+	return n
+}
+
+// RateLimits returns the tenant's rate limit settings from the cached membership, an
+// expired one too, without loading it. Both are 0 (server default) when none is cached.
+func (c *MembershipCache) RateLimits(tenantID, userID uint) (tenantRPM, userRPM int) {
+	c.mu.RLock()
+	entry := c.entries[membershipKey{tenantID: tenantID, userID: userID}]
+	c.mu.RUnlock()
+	return entry.membership.RateLimitTenant, entry.membership.RateLimitUser
+}
+
+// Invalidate drops the cached membership of a single user in a tenant
+func (c *MembershipCache) Invalidate(tenantID, userID uint) {
+	c.mu.Lock()
+	delete(c.entries, membershipKey{tenantID: tenantID, userID: userID})
+	c.mu.Unlock()
+}
+
+// InvalidateTenant drops every cached membership of a tenant (e.g. after suspension)
+func (c *MembershipCache) InvalidateTenant(tenantID uint) {
+	c.mu.Lock()
+	for key := range c.entries {
+		if key.tenantID == tenantID {
+			delete(c.entries, key)
+		}
+	}
+	c.mu.Unlock()
+}
+
+// InvalidateUser drops every cached membership of a user (e.g. after deactivation)
+func (c *MembershipCache) InvalidateUser(userID uint) {
+	c.mu.Lock()
+	for key := range c.entries {
+		if key.userID == userID {
+			delete(c.entries, key)
+		}
+	}
+	c.mu.Unlock()
+}
+
+// sweepLocked removes expired entries once the map grows large; caller must hold mu
+func (c *MembershipCache) sweepLocked() {
+	if len(c.entries) < 10000 {
+		return
+	}
+	now := time.Now()
+	for key, entry := range c.entries {
+		if now.After(entry.expiresAt) {
+			delete(c.entries, key)
+		}
+	}
+}
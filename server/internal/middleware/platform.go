@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"gin-quickstart/internal/repository"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequirePlatformAdmin restricts the /api/platform routes to platform operators. The
+// flag is read live so that removing an operator takes effect immediately.
+func RequirePlatformAdmin(userRepo repository.UserRepository, requireMFA bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if GetAPIKeyID(c) != nil || GetOperatorID(c) != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Platform access requires a user session"})
+			c.Abort()
+			return
+		}
+
+		user, err := userRepo.FindByID(GetUserID(c))
+		if err != nil || !user.IsActive || !user.IsPlatformAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Platform admin access required"})
+			c.Abort()
+			return
+		}
+
+		if requireMFA && !user.MFAEnabled {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":                   "Platform access requires two-factor authentication",
+				"mfa_enrollment_required": true,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// GetOperatorID returns the platform operator impersonating the request's user, or nil
+func GetOperatorID(c *gin.Context) *uint {
+	if id, exists := c.Get("operator_id"); exists {
+		operatorID := id.(uint)
+		return &operatorID
+	}
+	return nil
+}
+
+// isPlatformAdmin reports whether the user is an active platform operator
+func isPlatformAdmin(userRepo repository.UserRepository, userID uint) bool {
+	user, err := userRepo.FindByID(userID)
+	return err == nil && user.IsActive && user.IsPlatformAdmin
+}
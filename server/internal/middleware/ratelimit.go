@@ -0,0 +1,147 @@
+package middleware
+
+import (
+	"fmt"
+	"gin-quickstart/config"
+	"gin-quickstart/internal/jwtkeys"
+	"gin-quickstart/internal/ratelimit"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimit applies token buckets per tenant and per user ahead of AuthMiddleware, so
+// that rejected requests cost no database lookup. Buckets are keyed on the claims of an
+// access token whose signature verifies, or on the hash of an API key that authenticated
+// before; API keys not seen yet share a bucket per client IP, so rotating made-up keys
+// cannot avoid the limit. Other requests are left to AuthMiddleware. Limits come from
+// the tenant's settings in the membership cache, falling back to the configured
+// defaults and capped at the configured maximums.
+func RateLimit(limiter *ratelimit.Limiter, keys *jwtkeys.Manager, memberships *MembershipCache, cfg config.RateLimitConfig) gin.HandlerFunc {
+	apiKeys := &apiKeyOwners{owners: make(map[string]rateLimitOwner)}
+
+	return func(c *gin.Context) {
+		if !cfg.Enabled {
+			c.Next()
+			return
+		}
+
+		var owner rateLimitOwner
+		var userKey string
+
+		authHeader := c.GetHeader("Authorization")
+		if key, ok := extractAPIKey(authHeader); ok {
+			// API keys get their own bucket instead of sharing their creator's. The
+			// tenant of a key is known once it has authenticated.
+			hash := HashAPIKey(key)
+			var known bool
+			owner, known = apiKeys.get(hash)
+			userKey = "apikey:" + hash
+			if !known {
+				userKey = "apikey-ip:" + c.ClientIP()
+			}
+			defer func() {
+				if GetAPIKeyID(c) != nil {
+					apiKeys.set(hash, rateLimitOwner{tenantID: GetTenantID(c), userID: GetUserID(c)})
+				}
+			}()
+		} else {
+			tokenString, err := ExtractToken(authHeader)
+			if err != nil {
+				c.Next()
+				return
+			}
+			claims, err := parseToken(tokenString, keys)
+			if err != nil {
+				c.Next()
+				return
+			}
+			owner = rateLimitOwner{tenantID: claims.TenantID, userID: claims.UserID}
+			userKey = fmt.Sprintf("user:%d:%d", claims.TenantID, claims.UserID)
+		}
+
+		tenantRPM, userRPM := memberships.RateLimits(owner.tenantID, owner.userID)
+		buckets := []ratelimit.Bucket{{
+			Scope: "user",
+			Key:   userKey,
+			Limit: effectiveLimit(userRPM, cfg.UserRPM, cfg.MaxUserRPM),
+		}}
+		if owner.tenantID != 0 {
+			buckets = append(buckets, ratelimit.Bucket{
+				Scope: "tenant",
+				Key:   fmt.Sprintf("tenant:%d", owner.tenantID),
+				Limit: effectiveLimit(tenantRPM, cfg.TenantRPM, cfg.MaxTenantRPM),
+			})
+		}
+
+		result := limiter.Allow(strconv.FormatUint(uint64(owner.tenantID), 10), buckets...)
+
+		if result.Limit > 0 {
+			c.Header("X-RateLimit-Limit", strconv.Itoa(result.Limit))
+			c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			c.Header("X-RateLimit-Reset", strconv.FormatInt(ceilSeconds(result.Reset), 10))
+			c.Header("X-RateLimit-Scope", result.Scope)
+		}
+
+		if !result.Allowed {
+			retryAfter := ceilSeconds(result.RetryAfter)
+			c.Header("X-RateLimit-Reset", strconv.FormatInt(retryAfter, 10))
+			c.Header("Retry-After", strconv.FormatInt(retryAfter, 10))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error":       "Rate limit exceeded, please slow down",
+				"scope":       result.Scope,
+				"retry_after": retryAfter,
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// rateLimitOwner is the tenant and user whose buckets a request draws from
+type rateLimitOwner struct {
+	tenantID uint
+	userID   uint
+}
+
+// apiKeyOwners remembers the tenant and creator of API keys that authenticated, by key
+// hash, so that their requests are limited before the key is looked up
+type apiKeyOwners struct {
+	mu     sync.RWMutex
+	owners map[string]rateLimitOwner
+}
+
+func (a *apiKeyOwners) get(hash string) (rateLimitOwner, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	owner, ok := a.owners[hash]
+	return owner, ok
+}
+
+func (a *apiKeyOwners) set(hash string, owner rateLimitOwner) {
+	a.mu.Lock()
+	a.owners[hash] = owner
+	a.mu.Unlock()
+}
+
+// effectiveLimit picks the tenant's limit, or the default when unset, capped at max
+func effectiveLimit(tenantLimit, defaultLimit, max int) int {
+	limit := defaultLimit
+	if tenantLimit > 0 {
+		limit = tenantLimit
+	}
+	if max > 0 && (limit > max || limit <= 0) {
+		limit = max
+	}
+	return limit
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	return int64(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,26 @@
+package middleware
+
+import "testing"
+
+func TestEffectiveLimit(t *testing.T) {
+	tests := []struct {
+		name                    string
+		tenant, defaultRPM, max int
+		want                    int
+	}{
+		{"default when tenant unset", 0, 600, 6000, 600},
+		{"tenant override", 1200, 600, 6000, 1200},
+		{"tenant override below default", 60, 600, 6000, 60},
+		{"tenant override clamped to max", 100000, 600, 6000, 6000},
+		{"default clamped to max", 0, 10000, 6000, 6000},
+		{"unlimited default clamped to max", 0, 0, 6000, 6000},
+		{"no max", 100000, 600, 0, 100000},
+		{"unlimited without max", 0, 0, 0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := effectiveLimit(tt.tenant, tt.defaultRPM, tt.max); got != tt.want {
+			t.Errorf("%s: effectiveLimit(%d, %d, %d) = %d, want %d", tt.name, tt.tenant, tt.defaultRPM, tt.max, got, tt.want)
+		}
+	}
+}
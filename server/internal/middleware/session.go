@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"errors"
+	"gin-quickstart/internal/repository"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sessionTouchInterval limits last-seen bookkeeping to one write per session per interval
+const sessionTouchInterval = time.Minute
+
+// checkSession verifies that the session of an access token is still active and
+// records activity from it
+func checkSession(c *gin.Context, sessionRepo repository.SessionRepository, claims *Claims) error {
+	session, err := sessionRepo.FindByID(claims.SessionID)
+	if err != nil || session.UserID != claims.UserID || session.RevokedAt != nil {
+		return errors.New("session revoked")
+	}
+
+	if time.Since(session.LastSeenAt) > sessionTouchInterval {
+		sessionRepo.Touch(session.ID, c.ClientIP(), c.GetHeader("User-Agent"))
+	}
+
+	return nil
+}
+
+// GetSessionID returns the session of the request's access token, or 0 for API keys and
+// tokens issued before sessions existed
+func GetSessionID(c *gin.Context) uint {
+	if id, exists := c.Get("session_id"); exists {
+		return id.(uint)
+	}
+	return 0
+}
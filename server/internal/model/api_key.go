@@ -0,0 +1,78 @@
+package model
+
+import (
+	"time"
+)
+
+// APIKeyPrefix starts every API key so leaked keys are easy to recognise
+const APIKeyPrefix = "crm_"
+
+// APIKey authenticates server-to-server integrations for a single tenant. Requests made
+// with a key are limited to its scopes and to the permissions of the user who created it.
+type APIKey struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	TenantID   uint       `gorm:"not null;index" json:"tenant_id"`
+	Name       string     `gorm:"type:varchar(100);not null" json:"name"`
+	Prefix     string     `gorm:"type:varchar(16);not null" json:"prefix"` // First characters of the key, for display
+	KeyHash    string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	CreatedBy  uint       `gorm:"not null;index" json:"created_by"` // Owner of records created with the key
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `gorm:"type:varchar(45)" json:"last_used_ip,omitempty"`
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+
+	// Relationships
+	Tenant  Tenant       `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+	Creator User         `gorm:"foreignKey:CreatedBy;constraint:OnDelete:CASCADE" json:"-"`
+	Scopes  []Permission `gorm:"many2many:api_key_scopes;constraint:OnDelete:CASCADE" json:"scopes"`
+}
+
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// GetTenantID implements TenantScoped interface
+func (k *APIKey) GetTenantID() uint {
+	return k.TenantID
+}
+
+// IsUsable reports whether the key is neither revoked nor expired
+func (k *APIKey) IsUsable() bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || time.Now().Before(*k.ExpiresAt))
+}
+
+// ScopeKeys returns the permission keys granted to the key
+func (k *APIKey) ScopeKeys() []string {
+	keys := make([]string, 0, len(k.Scopes))
+	for _, p := range k.Scopes {
+		keys = append(keys, p.Key)
+	}
+	return keys
+}
+
+// APIKeyScopes lists the permissions an API key may be granted. Account and
+// administration permissions are reserved for interactive users.
+var APIKeyScopes = map[string]bool{
+	PermUsersRead:                            true,
+	PermTeamsRead:                            true,
+	PermAuditLogsRead:                        true,
+	PermReportsRead:                          true,
+	PermRecordsViewAll:                       true,
+	PermContactsRead:                         true,
+	PermContactsCreate:                       true,
+	PermContactsUpdate:                       true,
+	PermContactsUpdate + PermissionOwnSuffix: true,
+	PermContactsDelete:                       true,
+	PermContactsDelete + PermissionOwnSuffix: true,
+	PermPipelineRead:                         true,
+	PermPipelineManage:                       true,
+	PermDealsRead:                            true,
+	PermDealsCreate:                          true,
+	PermDealsUpdate:                          true,
+	PermDealsUpdate + PermissionOwnSuffix:    true,
+	PermDealsDelete:                          true,
+	PermDealsDelete + PermissionOwnSuffix:    true,
+}
@@ -0,0 +1,69 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Company sizes by number of employees
+var CompanySizes = []string{"1-10", "11-50", "51-200", "201-500", "501-1000", "1000+"}
+
+// Company is a customer organization (account) that contacts and deals belong to. Names
+// are unique per tenant, case-insensitively.
+type Company struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	TenantID  uint `gorm:"not null;uniqueIndex:idx_tenant_company_name,priority:1" json:"tenant_id"`
+	CreatedBy uint `gorm:"not null;index" json:"created_by"` // User who created this company
+	OwnerID   uint `gorm:"not null;index" json:"owner_id"`   // Account manager (reassignable)
+
+	Name     string `gorm:"type:varchar(255);not null;uniqueIndex:idx_tenant_company_name,priority:2,expression:LOWER(name),where:deleted_at IS NULL" json:"name"`
+	Domain   string `gorm:"type:varchar(255);index" json:"domain"` // e.g. example.co.id, without scheme or www
+	Industry string `gorm:"type:varchar(100);index" json:"industry"`
+	Size     string `gorm:"type:varchar(20)" json:"size"` // One of CompanySizes
+
+	// Address Information
+	Address    string `gorm:"type:text" json:"address"`
+	City       string `gorm:"type:varchar(100)" json:"city"`
+	Province   string `gorm:"type:varchar(100)" json:"province"`
+	PostalCode string `gorm:"type:varchar(20)" json:"postal_code"`
+	Country    string `gorm:"type:varchar(100);default:'Indonesia'" json:"country"`
+
+	Notes string `gorm:"type:text" json:"notes"`
+
+	// Relationships
+	Tenant Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+	User   User   `gorm:"foreignKey:CreatedBy;constraint:OnDelete:SET NULL" json:"-"`
+	Owner  User   `gorm:"foreignKey:OwnerID;constraint:OnDelete:RESTRICT" json:"-"`
+}
+
+// TableName overrides the table name
+func (Company) TableName() string {
+	return "companies"
+}
+
+// GetTenantID implements TenantScoped interface
+func (c *Company) GetTenantID() uint {
+	return c.TenantID
+}
+
+// CompanyFilter represents filter parameters for company queries
+type CompanyFilter struct {
+	Search   string // Search in name and domain
+	Industry string // Filter by industry
+	Size     string // Filter by size
+	OwnerID  *uint  // Filter by owner
+}
+
+// CompanyStats aggregates the contacts and deals of a company visible to a user
+type CompanyStats struct {
+	Contacts      int64   `json:"contacts"`
+	OpenDeals     int64   `json:"open_deals"`
+	OpenDealValue float64 `json:"open_deal_value"` // Sum of active deal values
+	WonDeals      int64   `json:"won_deals"`
+	WonRevenue    float64 `json:"won_revenue"` // Sum of won deal values
+}
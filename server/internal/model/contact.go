@@ -4,6 +4,8 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -47,8 +49,10 @@ type Contact struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
-	TenantID  uint `gorm:"not null;index:idx_tenant_contact" json:"tenant_id"`
-	CreatedBy uint `gorm:"not null;index" json:"created_by"` // User who created this contact
+	TenantID  uint  `gorm:"not null;index:idx_tenant_contact" json:"tenant_id"`
+	CreatedBy uint  `gorm:"not null;index" json:"created_by"` // User who created this contact
+	OwnerID   uint  `gorm:"not null;index" json:"owner_id"`   // User responsible for this contact (reassignable)
+	TeamID    *uint `gorm:"index" json:"team_id"`             // Team the record is shared with (optional)
 
 	// Personal Information
 	FirstName string `gorm:"type:varchar(100);not null;index" json:"first_name"`
@@ -57,7 +61,13 @@ type Contact struct {
 	Phone     string `gorm:"type:varchar(50);index" json:"phone"`
 	Mobile    string `gorm:"type:varchar(50)" json:"mobile"`
 
-	// Company Information
+	// Phone and Mobile in E.164 format (e.g. +6281234567890) for duplicate detection;
+	// empty when the number could not be normalized
+	PhoneE164  string `gorm:"column:phone_e164;type:varchar(20);default:'';index" json:"-"`
+	MobileE164 string `gorm:"column:mobile_e164;type:varchar(20);default:'';index" json:"-"`
+
+	// Company Information. CompanyName is the name of the linked company, if any.
+	CompanyID   *uint  `gorm:"index" json:"company_id"`
 	CompanyName string `gorm:"type:varchar(255);index" json:"company_name"`
 	Position    string `gorm:"type:varchar(100)" json:"position"`
 	Department  string `gorm:"type:varchar(100)" json:"department"`
@@ -75,9 +85,15 @@ type Contact struct {
 	Tags   StringArray `gorm:"type:text;serializer:json" json:"tags"`                 // JSON array: ["VIP", "potential"]
 	Notes  string      `gorm:"type:text" json:"notes"`
 
+	// Values of the tenant's custom contact fields, by key
+	CustomFields CustomFieldValues `gorm:"type:jsonb;not null;default:'{}';index:idx_contacts_custom_fields,type:gin" json:"custom_fields"`
+
 	// Relationships
-	Tenant Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
-	User   User   `gorm:"foreignKey:CreatedBy;constraint:OnDelete:SET NULL" json:"-"`
+	Tenant  Tenant   `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+	User    User     `gorm:"foreignKey:CreatedBy;constraint:OnDelete:SET NULL" json:"-"`
+	Owner   User     `gorm:"foreignKey:OwnerID;constraint:OnDelete:RESTRICT" json:"-"`
+	Team    *Team    `gorm:"foreignKey:TeamID;constraint:OnDelete:SET NULL" json:"-"`
+	Company *Company `gorm:"foreignKey:CompanyID;constraint:OnDelete:SET NULL" json:"-"`
 }
 
 // TableName overrides the table name
@@ -92,10 +108,51 @@ func (c *Contact) GetTenantID() uint {
 
 // ContactFilter represents filter parameters for contact queries
 type ContactFilter struct {
-	Search   string   // Search in name, email, phone, company
-	Status   string   // Filter by status
-	Source   string   // Filter by source
-	Tags     []string // Filter by tags
-	City     string   // Filter by city
-	Province string   // Filter by province
+	Search    string   // Search in name, email, phone, company
+	Status    string   // Filter by status
+	Source    string   // Filter by source
+	Tags      []string // Filter by tags
+	City      string   // Filter by city
+	Province  string   // Filter by province
+	TeamID    *uint    // Filter by team
+	CompanyID *uint    // Filter by company
+
+	CustomFields []CustomFieldCondition // Filter by custom field values
+
+	SortBy    string // Column, or CustomFieldPrefix + key; first and last name by default
+	SortOrder string // asc (default) or desc
+	SortType  string // Type of the custom field in SortBy, set by the service
+}
+
+// ContactExportColumns lists the columns a contact export can contain, in their default order
+var ContactExportColumns = []string{
+	"id", "first_name", "last_name", "email", "phone", "mobile",
+	"company_name", "position", "department",
+	"address", "city", "province", "postal_code", "country",
+	"status", "source", "tags", "notes",
+	"owner_id", "team_id", "created_at", "updated_at",
+}
+
+// ParseContactExportColumns validates a comma-separated list of export columns, among
+// ContactExportColumns and the tenant's customColumns (CustomFieldPrefix + key). An empty
+// list selects every column.
+func ParseContactExportColumns(value string, customColumns []string) ([]string, error) {
+	if strings.TrimSpace(value) == "" {
+		return append(append([]string{}, ContactExportColumns...), customColumns...), nil
+	}
+
+	known := make(map[string]bool, len(ContactExportColumns)+len(customColumns))
+	for _, column := range append(append([]string{}, ContactExportColumns...), customColumns...) {
+		known[column] = true
+	}
+
+	var columns []string
+	for _, column := range strings.Split(value, ",") {
+		column = strings.TrimSpace(column)
+		if !known[column] {
+			return nil, fmt.Errorf("unknown column %q", column)
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
 }
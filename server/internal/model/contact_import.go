@@ -0,0 +1,80 @@
+package model
+
+import (
+	"time"
+)
+
+// Contact import statuses
+const (
+	ContactImportUploaded  = "uploaded" // Waiting for the column mapping to be confirmed
+	ContactImportPending   = "pending"
+	ContactImportRunning   = "running"
+	ContactImportCompleted = "completed"
+	ContactImportFailed    = "failed"
+)
+
+// ContactImport creates contacts from the rows of an uploaded CSV or XLSX file. The
+// first row holds the column names; Mapping assigns columns to contact fields.
+type ContactImport struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	TenantID    uint   `gorm:"not null;index" json:"tenant_id"`
+	RequestedBy uint   `gorm:"not null" json:"requested_by"`                            // User ID; owns contacts without an owner column
+	APIKeyID    *uint  `json:"api_key_id,omitempty"`                                    // Set when requested with an API key
+	OperatorID  *uint  `json:"operator_id,omitempty"`                                   // Platform operator who requested it, directly or impersonating RequestedBy
+	Status      string `gorm:"type:varchar(20);default:'uploaded';index" json:"status"` // uploaded, pending, running, completed, failed
+
+	// Uploaded file
+	Filename  string   `gorm:"type:varchar(255)" json:"filename"`
+	Format    string   `gorm:"type:varchar(10)" json:"format"`   // csv, xlsx
+	Delimiter string   `gorm:"type:varchar(4)" json:"delimiter"` // CSV field delimiter
+	SizeBytes int64    `json:"size_bytes"`
+	BlobKey   string   `gorm:"type:varchar(255)" json:"-"`               // Deleted once processed
+	Columns   []string `gorm:"type:text;serializer:json" json:"columns"` // Header row
+
+	// Column name -> contact field (see ContactImportFields); suggested from the column
+	// names on upload
+	Mapping      map[string]string `gorm:"type:text;serializer:json" json:"mapping"`
+	TagDelimiter string            `gorm:"type:varchar(4)" json:"tag_delimiter"` // Separates tags within the tags column
+
+	// Progress
+	TotalRows     int `json:"total_rows"` // Data rows, header and empty rows excluded
+	ProcessedRows int `json:"processed_rows"`
+	CreatedRows   int `json:"created_rows"`
+	FailedRows    int `json:"failed_rows"`    // Listed in the error report
+	DuplicateRows int `json:"duplicate_rows"` // Rows matching an existing contact or an earlier row
+
+	ErrorReportKey string     `gorm:"type:varchar(255)" json:"-"`
+	Error          string     `gorm:"type:text" json:"error,omitempty"`
+	StartedAt      *time.Time `json:"started_at,omitempty"`
+	CompletedAt    *time.Time `json:"completed_at,omitempty"`
+
+	// Relationships
+	Tenant Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func (ContactImport) TableName() string {
+	return "contact_imports"
+}
+
+// GetTenantID implements TenantScoped interface
+func (i *ContactImport) GetTenantID() uint {
+	return i.TenantID
+}
+
+// HasErrorReport reports whether rows failed and their report can be downloaded
+func (i *ContactImport) HasErrorReport() bool {
+	return i.ErrorReportKey != ""
+}
+
+// ContactImportFields lists the contact fields an import column can be mapped to.
+// owner_email assigns the contact to the tenant member with that email. Columns can also
+// be mapped to the tenant's custom contact fields (CustomFieldPrefix + key).
+var ContactImportFields = []string{
+	"first_name", "last_name", "email", "phone", "mobile",
+	"company_name", "position", "department",
+	"address", "city", "province", "postal_code", "country",
+	"status", "source", "tags", "notes", "owner_email",
+}
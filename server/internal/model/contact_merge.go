@@ -0,0 +1,57 @@
+package model
+
+import (
+	"time"
+)
+
+// ContactMerge records that a duplicate contact was merged into a primary contact: the
+// duplicate is soft-deleted and its deals are moved to the primary. It keeps what is
+// needed to undo the merge until UndoExpiresAt.
+type ContactMerge struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	TenantID    uint `gorm:"not null;index" json:"tenant_id"`
+	PrimaryID   uint `gorm:"not null;index" json:"primary_id"`   // Contact that was kept
+	DuplicateID uint `gorm:"not null;index" json:"duplicate_id"` // Contact that was merged and deleted
+	MergedBy    uint `gorm:"not null" json:"merged_by"`
+
+	// State before the merge, restored by an undo
+	PrimaryBefore Contact `gorm:"type:text;serializer:json" json:"-"`
+	MovedDealIDs  []uint  `gorm:"type:text;serializer:json" json:"moved_deal_ids"`
+
+	// The primary's updated_at right after the merge; an undo is refused once the primary
+	// has been edited again
+	PrimaryUpdatedAt time.Time `json:"-"`
+
+	UndoExpiresAt time.Time  `json:"undo_expires_at"`
+	UndoneAt      *time.Time `json:"undone_at,omitempty"`
+	UndoneBy      *uint      `json:"undone_by,omitempty"`
+
+	// Relationships
+	Tenant Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func (ContactMerge) TableName() string {
+	return "contact_merges"
+}
+
+// GetTenantID implements TenantScoped interface
+func (m *ContactMerge) GetTenantID() uint {
+	return m.TenantID
+}
+
+// CanUndo reports whether the merge can still be undone
+func (m *ContactMerge) CanUndo() bool {
+	return m.UndoneAt == nil && time.Now().Before(m.UndoExpiresAt)
+}
+
+// ContactMergeFields lists the contact fields a merge picks from either contact. Tags are
+// the union of both contacts' tags.
+var ContactMergeFields = []string{
+	"first_name", "last_name", "email", "phone", "mobile",
+	"company_name", "position", "department",
+	"address", "city", "province", "postal_code", "country",
+	"status", "source", "notes",
+	"owner_id", "team_id",
+}
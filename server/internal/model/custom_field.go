@@ -0,0 +1,109 @@
+package model
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// Records that can have custom fields
+const (
+	CustomFieldEntityContact = "contact"
+	CustomFieldEntityDeal    = "deal"
+)
+
+// Custom field types
+const (
+	CustomFieldText        = "text"
+	CustomFieldNumber      = "number"
+	CustomFieldDate        = "date"         // "2006-01-02"
+	CustomFieldSelect      = "select"       // One of the field's options
+	CustomFieldMultiSelect = "multi_select" // Any of the field's options
+	CustomFieldBoolean     = "boolean"
+)
+
+// CustomFieldPrefix names a custom field in filters, sort orders, export columns and
+// import mappings, e.g. "custom_fields.npwp"
+const CustomFieldPrefix = "custom_fields."
+
+// CustomFieldDefinition is an extra field a tenant adds to its contacts or deals. Values
+// are stored by Key in the records' custom_fields column.
+type CustomFieldDefinition struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	TenantID uint   `gorm:"not null;uniqueIndex:idx_tenant_custom_field" json:"tenant_id"`
+	Entity   string `gorm:"type:varchar(20);not null;uniqueIndex:idx_tenant_custom_field" json:"entity"` // contact, deal
+	Key      string `gorm:"type:varchar(50);not null;uniqueIndex:idx_tenant_custom_field" json:"key"`    // snake_case, cannot be changed
+	Label    string `gorm:"type:varchar(100);not null" json:"label"`
+	Type     string `gorm:"type:varchar(20);not null" json:"type"` // Cannot be changed
+
+	Options  StringArray `gorm:"type:text;serializer:json" json:"options"` // select and multi_select only
+	Required bool        `gorm:"default:false" json:"required"`
+	Default  interface{} `gorm:"type:text;serializer:json" json:"default"` // Value given to new records without one
+	Position int         `gorm:"default:0" json:"position"`                // Display order
+
+	// Relationships
+	Tenant Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func (CustomFieldDefinition) TableName() string {
+	return "custom_field_definitions"
+}
+
+// GetTenantID implements TenantScoped interface
+func (f *CustomFieldDefinition) GetTenantID() uint {
+	return f.TenantID
+}
+
+// CustomFieldValues holds a record's custom field values by key: strings (text, date,
+// select), numbers, booleans and string arrays (multi_select). Stored as JSONB.
+type CustomFieldValues map[string]interface{}
+
+// Scan implements the sql.Scanner interface for reading from database
+func (v *CustomFieldValues) Scan(value interface{}) error {
+	var data []byte
+	switch value := value.(type) {
+	case nil:
+		*v = CustomFieldValues{}
+		return nil
+	case []byte:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		return errors.New("failed to scan CustomFieldValues")
+	}
+
+	values := CustomFieldValues{}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &values); err != nil {
+			return err
+		}
+	}
+	*v = values
+	return nil
+}
+
+// Value implements the driver.Valuer interface for writing to database
+func (v CustomFieldValues) Value() (driver.Value, error) {
+	if len(v) == 0 {
+		return "{}", nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// CustomFieldCondition filters records on a custom field. The service checks Key against
+// the tenant's definitions, normalizes Value and sets Type.
+type CustomFieldCondition struct {
+	Key   string
+	Op    string // eq, gte or lte (gte and lte for number and date fields)
+	Value string
+	Type  string
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"time"
+)
+
+// Export statuses
+const (
+	ExportPending   = "pending"
+	ExportRunning   = "running"
+	ExportCompleted = "completed"
+	ExportFailed    = "failed"
+	ExportExpired   = "expired" // The archive was deleted after the retention period
+)
+
+// TenantExport is an asynchronous export of all tenant data into a ZIP archive of JSON
+// and CSV files, kept in the blob store until ExpiresAt.
+type TenantExport struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	TenantID    uint   `gorm:"not null;index" json:"tenant_id"`
+	RequestedBy uint   `gorm:"not null" json:"requested_by"`                           // User ID
+	APIKeyID    *uint  `json:"api_key_id,omitempty"`                                   // Set when requested with an API key
+	OperatorID  *uint  `json:"operator_id,omitempty"`                                  // Platform operator who requested it, directly or impersonating RequestedBy
+	Status      string `gorm:"type:varchar(20);default:'pending';index" json:"status"` // pending, running, completed, failed, expired
+
+	BlobKey     string     `gorm:"type:varchar(255)" json:"-"`
+	SizeBytes   int64      `json:"size_bytes"`
+	Error       string     `gorm:"type:text" json:"error,omitempty"`
+	StartedAt   *time.Time `json:"started_at,omitempty"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+	ExpiresAt   *time.Time `gorm:"index" json:"expires_at,omitempty"` // Archive deletion time
+
+	// Relationships
+	Tenant Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func (TenantExport) TableName() string {
+	return "tenant_exports"
+}
+
+// GetTenantID implements TenantScoped interface
+func (e *TenantExport) GetTenantID() uint {
+	return e.TenantID
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"time"
+)
+
+// Import statuses
+const (
+	ImportPending   = "pending"
+	ImportRunning   = "running"
+	ImportCompleted = "completed" // Applied, or previewed for a dry run
+	ImportFailed    = "failed"
+)
+
+// TenantImport loads the CRM data of a tenant export archive (pipeline stages, contacts
+// and deals) into the current tenant. A dry run performs the whole import and rolls it back.
+type TenantImport struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	TenantID    uint   `gorm:"not null;index" json:"tenant_id"`
+	RequestedBy uint   `gorm:"not null" json:"requested_by"` // User ID; owns records of unknown users
+	APIKeyID    *uint  `json:"api_key_id,omitempty"`         // Set when requested with an API key
+	OperatorID  *uint  `json:"operator_id,omitempty"`        // Platform operator who requested it, directly or impersonating RequestedBy
+	DryRun      bool   `gorm:"default:false" json:"dry_run"`
+	Status      string `gorm:"type:varchar(20);default:'pending';index" json:"status"` // pending, running, completed, failed
+
+	Filename    string        `gorm:"type:varchar(255)" json:"filename"`
+	SizeBytes   int64         `json:"size_bytes"`
+	BlobKey     string        `gorm:"type:varchar(255)" json:"-"` // Uploaded archive, deleted once processed
+	Error       string        `gorm:"type:text" json:"error,omitempty"`
+	Result      *ImportResult `gorm:"type:text;serializer:json" json:"result,omitempty"`
+	StartedAt   *time.Time    `json:"started_at,omitempty"`
+	CompletedAt *time.Time    `json:"completed_at,omitempty"`
+
+	// Relationships
+	Tenant Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// ImportResult counts the archive's records per dataset
+type ImportResult struct {
+	Created  map[string]int `json:"created"`  // New records
+	Matched  map[string]int `json:"matched"`  // Mapped onto existing records (stages by name, contacts by email)
+	Skipped  map[string]int `json:"skipped"`  // Not imported, see Warnings
+	Warnings []string       `json:"warnings"` // Capped, the counts are always complete
+}
+
+func (TenantImport) TableName() string {
+	return "tenant_imports"
+}
+
+// GetTenantID implements TenantScoped interface
+func (i *TenantImport) GetTenantID() uint {
+	return i.TenantID
+}
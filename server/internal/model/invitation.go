@@ -0,0 +1,49 @@
+package model
+
+import (
+	"time"
+)
+
+// Invitation statuses
+const (
+	InvitationPending  = "pending"
+	InvitationAccepted = "accepted"
+	InvitationRevoked  = "revoked"
+)
+
+// Invitation lets an admin add someone to a tenant by email. The invitee accepts
+// with the emailed token, setting their own password or using an existing account.
+type Invitation struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	TenantID  uint   `gorm:"not null;index:idx_tenant_invitation,priority:1" json:"tenant_id"`
+	Email     string `gorm:"type:varchar(255);not null;index:idx_tenant_invitation,priority:2" json:"email"`
+	Role      string `gorm:"type:varchar(50);not null" json:"role"`
+	InvitedBy uint   `gorm:"not null" json:"invited_by"`
+
+	TokenHash  string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"` // SHA-256 of the raw token
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	Status     string     `gorm:"type:varchar(20);default:'pending';index" json:"status"` // pending, accepted, revoked
+	AcceptedAt *time.Time `json:"accepted_at,omitempty"`
+	UserID     *uint      `json:"user_id,omitempty"` // Set once accepted
+
+	// Relationships
+	Tenant  Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+	Inviter User   `gorm:"foreignKey:InvitedBy;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func (Invitation) TableName() string {
+	return "invitations"
+}
+
+// GetTenantID implements TenantScoped interface
+func (i *Invitation) GetTenantID() uint {
+	return i.TenantID
+}
+
+// IsUsable reports whether the invitation can still be accepted
+func (i *Invitation) IsUsable() bool {
+	return i.Status == InvitationPending && time.Now().Before(i.ExpiresAt)
+}
@@ -0,0 +1,48 @@
+package model
+
+import (
+	"time"
+)
+
+// MFARecoveryCode is a single-use backup code for users who lose their authenticator
+type MFARecoveryCode struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	UserID   uint       `gorm:"not null;index" json:"user_id"`
+	CodeHash string     `gorm:"type:varchar(64);not null;index" json:"-"` // SHA-256 of the normalized code
+	UsedAt   *time.Time `json:"used_at,omitempty"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func (MFARecoveryCode) TableName() string {
+	return "mfa_recovery_codes"
+}
+
+// MFAChallenge is issued after a correct password when the user has MFA enabled and
+// is exchanged, together with a TOTP or recovery code, for the real tokens
+type MFAChallenge struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	TenantID  uint       `json:"tenant_id"` // Tenant requested at login (0 = default)
+	TokenHash string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	Attempts  int        `gorm:"default:0" json:"attempts"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func (MFAChallenge) TableName() string {
+	return "mfa_challenges"
+}
+
+// IsUsable reports whether the challenge can still be answered
+func (c *MFAChallenge) IsUsable(maxAttempts int) bool {
+	return c.UsedAt == nil && c.Attempts < maxAttempts && time.Now().Before(c.ExpiresAt)
+}
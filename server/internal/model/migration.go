@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// DataMigration records a one-off data migration run at startup, so that it runs once
+type DataMigration struct {
+	Name      string    `gorm:"type:varchar(100);primaryKey" json:"name"`
+	AppliedAt time.Time `gorm:"not null" json:"applied_at"`
+}
+
+// TableName overrides the table name
+func (DataMigration) TableName() string {
+	return "data_migrations"
+}
@@ -46,9 +46,12 @@ type Deal struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
-	TenantID  uint `gorm:"not null;index:idx_tenant_deal" json:"tenant_id"`
-	CreatedBy uint `gorm:"not null;index" json:"created_by"` // User who created this deal
-	ContactID uint `gorm:"not null;index" json:"contact_id"` // Link to contact
+	TenantID  uint  `gorm:"not null;index:idx_tenant_deal" json:"tenant_id"`
+	CreatedBy uint  `gorm:"not null;index" json:"created_by"` // User who created this deal
+	OwnerID   uint  `gorm:"not null;index" json:"owner_id"`   // User responsible for this deal (reassignable)
+	TeamID    *uint `gorm:"index" json:"team_id"`             // Team the record is shared with (optional)
+	ContactID uint  `gorm:"not null;index" json:"contact_id"` // Link to contact
+	CompanyID *uint `gorm:"index" json:"company_id"`          // Account the deal is with; the contact's company by default
 
 	// Basic Information
 	Title       string  `gorm:"type:varchar(255);not null" json:"title"`
@@ -74,11 +77,17 @@ type Deal struct {
 	Tags   StringArray `gorm:"type:text;serializer:json" json:"tags"`
 	Notes  string      `gorm:"type:text" json:"notes"`
 
+	// Values of the tenant's custom deal fields, by key
+	CustomFields CustomFieldValues `gorm:"type:jsonb;not null;default:'{}';index:idx_deals_custom_fields,type:gin" json:"custom_fields"`
+
 	// Relationships
 	Tenant  Tenant        `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
-	Contact Contact       `gorm:"foreignKey:ContactID;constraint:OnDelete:CASCADE" json:"contact,omitempty"`
+	Contact *Contact      `gorm:"foreignKey:ContactID;constraint:OnDelete:CASCADE" json:"contact,omitempty"` // Only loaded when visible
+	Company *Company      `gorm:"foreignKey:CompanyID;constraint:OnDelete:SET NULL" json:"-"`
 	Stage   PipelineStage `gorm:"foreignKey:StageID;constraint:OnDelete:RESTRICT" json:"stage,omitempty"`
 	User    User          `gorm:"foreignKey:CreatedBy;constraint:OnDelete:SET NULL" json:"-"`
+	Owner   User          `gorm:"foreignKey:OwnerID;constraint:OnDelete:RESTRICT" json:"-"`
+	Team    *Team         `gorm:"foreignKey:TeamID;constraint:OnDelete:SET NULL" json:"-"`
 }
 
 // TableName overrides the table name
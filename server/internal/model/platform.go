@@ -0,0 +1,43 @@
+package model
+
+import (
+	"time"
+)
+
+// TenantUsage counts a tenant's members and CRM records for platform operators
+type TenantUsage struct {
+	Users    int64 `json:"users"`
+	Contacts int64 `json:"contacts"`
+	Deals    int64 `json:"deals"`
+}
+
+// Impersonation is a platform operator acting as a tenant admin, e.g. to reproduce a
+// support case. It runs in a session of the admin (Session.OperatorID) and every audit
+// log entry written with it carries the operator (AuditLog.OperatorID).
+type Impersonation struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	OperatorID uint       `gorm:"not null;index" json:"operator_id"`
+	TenantID   uint       `gorm:"not null;index" json:"tenant_id"`
+	UserID     uint       `gorm:"not null" json:"user_id"` // Impersonated admin
+	SessionID  uint       `gorm:"not null" json:"session_id"`
+	Reason     string     `gorm:"type:text;not null" json:"reason"`
+	IPAddress  string     `gorm:"type:varchar(45)" json:"ip_address"`
+	ExpiresAt  time.Time  `gorm:"not null" json:"expires_at"`
+	EndedAt    *time.Time `json:"ended_at,omitempty"` // Ended early by an operator
+
+	// Relationships
+	Operator User   `gorm:"foreignKey:OperatorID" json:"operator,omitempty"`
+	User     User   `gorm:"foreignKey:UserID" json:"user,omitempty"`
+	Tenant   Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func (Impersonation) TableName() string {
+	return "impersonations"
+}
+
+// IsActive reports whether the impersonation can still be used
+func (i *Impersonation) IsActive() bool {
+	return i.EndedAt == nil && time.Now().Before(i.ExpiresAt)
+}
@@ -0,0 +1,156 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Permission keys. A key suffixed with PermissionOwnSuffix grants the same action
+// restricted to records the user owns (e.g. "deals:update:own").
+const (
+	PermTenantUpdate       = "tenant:update"
+	PermUsersRead          = "users:read"
+	PermUsersManage        = "users:manage"
+	PermRolesManage        = "roles:manage"
+	PermAPIKeysManage      = "api_keys:manage"
+	PermTeamsRead          = "teams:read"
+	PermTeamsManage        = "teams:manage"
+	PermCustomFieldsManage = "custom_fields:manage" // Define the tenant's custom contact and deal fields
+	PermAuditLogsRead      = "audit_logs:read"
+	PermDataExport         = "data:export"      // Export all tenant data (users, audit logs, CRM records)
+	PermDataImport         = "data:import"      // Import stages, contacts and deals from an export archive
+	PermReportsRead        = "reports:read"     // Dashboard stats and pipeline value
+	PermRecordsViewAll     = "records:view_all" // Bypass the tenant's record visibility rules
+	PermContactsRead       = "contacts:read"
+	PermContactsCreate     = "contacts:create"
+	PermContactsUpdate     = "contacts:update"
+	PermContactsDelete     = "contacts:delete"
+	PermContactsImport     = "contacts:import" // Create contacts in bulk from CSV/XLSX files
+	PermContactsExport     = "contacts:export" // Download the visible contacts as CSV/XLSX
+	PermPipelineRead       = "pipeline:read"
+	PermPipelineManage     = "pipeline:manage"
+	PermDealsRead          = "deals:read"
+	PermDealsCreate        = "deals:create"
+	PermDealsUpdate        = "deals:update"
+	PermDealsDelete        = "deals:delete"
+
+	PermissionOwnSuffix = ":own"
+)
+
+// Built-in role names seeded for every tenant
+const (
+	RoleAdmin   = "admin"
+	RoleManager = "manager"
+	RoleMember  = "member"
+)
+
+// Permission is a global catalog entry that roles can be granted
+type Permission struct {
+	ID          uint   `gorm:"primarykey" json:"id"`
+	Key         string `gorm:"type:varchar(100);uniqueIndex;not null" json:"key"`
+	Description string `gorm:"type:varchar(255)" json:"description"`
+}
+
+// Role is a tenant-defined set of permissions. TenantUser.Role references Role.Name.
+type Role struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	TenantID    uint   `gorm:"not null;uniqueIndex:idx_tenant_role_name,priority:1,where:deleted_at IS NULL" json:"tenant_id"`
+	Name        string `gorm:"type:varchar(50);not null;uniqueIndex:idx_tenant_role_name,priority:2" json:"name"`
+	Description string `gorm:"type:varchar(255)" json:"description"`
+	IsBuiltIn   bool   `gorm:"default:false" json:"is_built_in"` // Seeded roles cannot be renamed or deleted
+
+	// Relationships
+	Tenant      Tenant       `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+	Permissions []Permission `gorm:"many2many:role_permissions;constraint:OnDelete:CASCADE" json:"permissions"`
+}
+
+func (Permission) TableName() string {
+	return "permissions"
+}
+
+func (Role) TableName() string {
+	return "roles"
+}
+
+// GetTenantID implements TenantScoped interface
+func (r *Role) GetTenantID() uint {
+	return r.TenantID
+}
+
+// PermissionKeys returns the keys of the role's permissions
+func (r *Role) PermissionKeys() []string {
+	keys := make([]string, 0, len(r.Permissions))
+	for _, p := range r.Permissions {
+		keys = append(keys, p.Key)
+	}
+	return keys
+}
+
+// PermissionCatalog lists every permission known to the application
+var PermissionCatalog = []Permission{
+	{Key: PermTenantUpdate, Description: "Update tenant settings"},
+	{Key: PermUsersRead, Description: "View tenant users"},
+	{Key: PermUsersManage, Description: "Add, remove and change roles of tenant users"},
+	{Key: PermRolesManage, Description: "Create, update and delete roles"},
+	{Key: PermAPIKeysManage, Description: "Create and revoke API keys"},
+	{Key: PermTeamsRead, Description: "View teams"},
+	{Key: PermTeamsManage, Description: "Create, update and delete teams"},
+	{Key: PermCustomFieldsManage, Description: "Create, update and delete custom contact and deal fields"},
+	{Key: PermAuditLogsRead, Description: "View audit logs"},
+	{Key: PermDataExport, Description: "Export all tenant data"},
+	{Key: PermDataImport, Description: "Import CRM data from an export archive"},
+	{Key: PermReportsRead, Description: "View dashboard statistics and pipeline value"},
+	{Key: PermRecordsViewAll, Description: "See all contacts and deals regardless of visibility settings"},
+	{Key: PermContactsRead, Description: "View contacts"},
+	{Key: PermContactsCreate, Description: "Create contacts"},
+	{Key: PermContactsUpdate, Description: "Update any contact"},
+	{Key: PermContactsUpdate + PermissionOwnSuffix, Description: "Update own contacts"},
+	{Key: PermContactsDelete, Description: "Delete any contact"},
+	{Key: PermContactsDelete + PermissionOwnSuffix, Description: "Delete own contacts"},
+	{Key: PermContactsImport, Description: "Import contacts from CSV and XLSX files"},
+	{Key: PermContactsExport, Description: "Export contacts to CSV and XLSX files"},
+	{Key: PermPipelineRead, Description: "View pipeline stages"},
+	{Key: PermPipelineManage, Description: "Create, update, reorder and delete pipeline stages"},
+	{Key: PermDealsRead, Description: "View deals"},
+	{Key: PermDealsCreate, Description: "Create deals"},
+	{Key: PermDealsUpdate, Description: "Update, move and change status of any deal"},
+	{Key: PermDealsUpdate + PermissionOwnSuffix, Description: "Update, move and change status of own deals"},
+	{Key: PermDealsDelete, Description: "Delete any deal"},
+	{Key: PermDealsDelete + PermissionOwnSuffix, Description: "Delete own deals"},
+}
+
+// crmPermissions are granted to every built-in role
+var crmPermissions = []string{
+	PermReportsRead, PermTeamsRead,
+	PermContactsRead, PermContactsCreate, PermContactsUpdate, PermContactsDelete,
+	PermPipelineRead, PermPipelineManage,
+	PermDealsRead, PermDealsCreate, PermDealsUpdate, PermDealsDelete,
+}
+
+// BuiltInRolePermissions defines the permissions of the seeded roles
+var BuiltInRolePermissions = map[string][]string{
+	RoleAdmin: allPermissionKeys(),
+	RoleManager: append([]string{
+		PermUsersRead, PermContactsExport,
+	}, crmPermissions...),
+	RoleMember: append([]string{}, crmPermissions...),
+}
+
+// IsBuiltInRole reports whether name is one of the seeded roles
+func IsBuiltInRole(name string) bool {
+	_, ok := BuiltInRolePermissions[name]
+	return ok
+}
+
+func allPermissionKeys() []string {
+	keys := make([]string, 0, len(PermissionCatalog))
+	for _, p := range PermissionCatalog {
+		keys = append(keys, p.Key)
+	}
+	return keys
+}
@@ -0,0 +1,28 @@
+package model
+
+import (
+	"time"
+)
+
+// Security event types
+const (
+	SecurityEventLoginLockout = "login_lockout"
+)
+
+// SecurityEvent records authentication events that happen before a tenant is known
+// (AuditLog requires one), such as lockouts after repeated failed logins
+type SecurityEvent struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"`
+
+	Event     string `gorm:"type:varchar(100);not null;index" json:"event"`
+	Email     string `gorm:"type:varchar(255);index" json:"email,omitempty"` // Email as submitted, the account may not exist
+	UserID    *uint  `gorm:"index" json:"user_id,omitempty"`
+	IPAddress string `gorm:"type:varchar(45);index" json:"ip_address"`
+	UserAgent string `gorm:"type:text" json:"user_agent,omitempty"`
+	Details   string `gorm:"type:text" json:"details,omitempty"`
+}
+
+func (SecurityEvent) TableName() string {
+	return "security_events"
+}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+)
+
+// Session is one login of a user on a device. It outlives the refresh token rotations
+// of the login, follows tenant switches and is referenced by access tokens (sid claim),
+// so revoking it logs the device out immediately.
+type Session struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	UserID     uint       `gorm:"not null;index" json:"user_id"`
+	TenantID   uint       `gorm:"not null;index" json:"tenant_id"` // Tenant the session is currently signed in to
+	IPAddress  string     `gorm:"type:varchar(45)" json:"ip_address"`
+	UserAgent  string     `gorm:"type:text" json:"user_agent"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `gorm:"not null;index" json:"expires_at"` // Expiry of the latest refresh token
+	RevokedAt  *time.Time `json:"revoked_at,omitempty"`
+	RevokedBy  *uint      `json:"revoked_by,omitempty"`  // Admin who forced the logout, nil when the user logged out
+	OperatorID *uint      `json:"operator_id,omitempty"` // Platform operator impersonating the user in this session
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func (Session) TableName() string {
+	return "sessions"
+}
+
+// IsActive reports whether the session can still be used
+func (s *Session) IsActive() bool {
+	return s.RevokedAt == nil && time.Now().Before(s.ExpiresAt)
+}
@@ -0,0 +1,82 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Tenant setting keys
+const (
+	SettingRecordVisibility  = "record_visibility"
+	SettingRequireMFA        = "require_mfa"           // "true" blocks members without two-factor authentication
+	SettingRateLimitTenant   = "rate_limit_tenant_rpm" // Requests per minute for the whole tenant, "0" = server default
+	SettingRateLimitUser     = "rate_limit_user_rpm"   // Requests per minute per member, "0" = server default
+	SettingDuplicateContacts = "duplicate_contacts"    // What creating or importing a duplicate contact does
+)
+
+// Record visibility modes for contacts and deals
+const (
+	VisibilityEveryone = "everyone" // Every member sees every record
+	VisibilityTeam     = "team"     // Members see records they own or that are shared with their teams
+	VisibilityManager  = "manager"  // Members see records they own, team managers also their team's records
+)
+
+// Duplicate contact strategies
+const (
+	DuplicatesBlock = "block" // Refuse the contact
+	DuplicatesWarn  = "warn"  // Create the contact and report its duplicates
+	DuplicatesAllow = "allow" // Do not look for duplicates
+)
+
+// tenantSettingRules lists the settings admins may change with their allowed values
+var tenantSettingRules = map[string][]string{
+	SettingRecordVisibility:  {VisibilityEveryone, VisibilityTeam, VisibilityManager},
+	SettingRequireMFA:        {"true", "false"},
+	SettingRateLimitTenant:   {},
+	SettingRateLimitUser:     {},
+	SettingDuplicateContacts: {DuplicatesBlock, DuplicatesWarn, DuplicatesAllow},
+}
+
+// tenantSettingRanges lists integer settings with their inclusive bounds
+var tenantSettingRanges = map[string][2]int{
+	SettingRateLimitTenant: {0, 1000000},
+	SettingRateLimitUser:   {0, 1000000},
+}
+
+// TenantSettingDefaults are used when a tenant has not set a value
+var TenantSettingDefaults = map[string]string{
+	SettingRecordVisibility:  VisibilityEveryone,
+	SettingRequireMFA:        "false",
+	SettingRateLimitTenant:   "0",
+	SettingRateLimitUser:     "0",
+	SettingDuplicateContacts: DuplicatesWarn,
+}
+
+// ValidateTenantSetting checks that key is a known setting and value is allowed
+func ValidateTenantSetting(key, value string) error {
+	allowed, ok := tenantSettingRules[key]
+	if !ok {
+		return fmt.Errorf("unknown setting: %s", key)
+	}
+
+	if bounds, ok := tenantSettingRanges[key]; ok {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < bounds[0] || n > bounds[1] {
+			return fmt.Errorf("setting %s must be an integer between %d and %d", key, bounds[0], bounds[1])
+		}
+		return nil
+	}
+
+	// Settings without an enumerated list accept any value
+	if len(allowed) == 0 {
+		return nil
+	}
+
+	for _, v := range allowed {
+		if v == value {
+			return nil
+		}
+	}
+	return errors.New("invalid value for setting " + key)
+}
@@ -0,0 +1,98 @@
+package model
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// TenantSSOConfig is a tenant's OpenID Connect identity provider
+type TenantSSOConfig struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	TenantID       uint   `gorm:"not null;uniqueIndex" json:"tenant_id"`
+	Enabled        bool   `gorm:"default:false" json:"enabled"`
+	Issuer         string `gorm:"type:varchar(255);not null" json:"issuer"`
+	ClientID       string `gorm:"type:varchar(255);not null" json:"client_id"`
+	ClientSecret   string `gorm:"type:text" json:"-"`
+	AllowedDomains string `gorm:"type:text" json:"allowed_domains"` // Comma separated, empty = any domain
+	AutoProvision  bool   `gorm:"default:false" json:"auto_provision"`
+	DefaultRole    string `gorm:"type:varchar(50);not null;default:'member'" json:"default_role"` // Role of provisioned users
+
+	// Relationships
+	Tenant Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func (TenantSSOConfig) TableName() string {
+	return "tenant_sso_configs"
+}
+
+// GetTenantID implements TenantScoped interface
+func (s *TenantSSOConfig) GetTenantID() uint {
+	return s.TenantID
+}
+
+// AllowsEmail reports whether the email's domain is one of the allowed domains
+func (s *TenantSSOConfig) AllowsEmail(email string) bool {
+	if strings.TrimSpace(s.AllowedDomains) == "" {
+		return true
+	}
+
+	at := strings.LastIndex(email, "@")
+	if at < 0 {
+		return false
+	}
+	domain := strings.ToLower(email[at+1:])
+
+	for _, allowed := range strings.Split(s.AllowedDomains, ",") {
+		if strings.ToLower(strings.TrimSpace(allowed)) == domain {
+			return true
+		}
+	}
+	return false
+}
+
+// UserIdentity links a user to an account at an external identity provider
+type UserIdentity struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	UserID  uint   `gorm:"not null;index" json:"user_id"`
+	Issuer  string `gorm:"type:varchar(255);not null;uniqueIndex:idx_identity_subject,priority:1" json:"issuer"`
+	Subject string `gorm:"type:varchar(255);not null;uniqueIndex:idx_identity_subject,priority:2" json:"subject"`
+	Email   string `gorm:"type:varchar(255)" json:"email"` // Email reported by the provider at link time
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
+
+// SSOLogin tracks one single sign-on attempt: the state, nonce and PKCE verifier sent to
+// the provider, then the one-time code the frontend exchanges for tokens
+type SSOLogin struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	TenantID     uint      `gorm:"not null;index" json:"tenant_id"`
+	StateHash    string    `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"`
+	Nonce        string    `gorm:"type:varchar(100);not null" json:"-"`
+	CodeVerifier string    `gorm:"type:varchar(100);not null" json:"-"`
+	ExpiresAt    time.Time `gorm:"not null" json:"expires_at"`
+
+	// Set once the provider's callback succeeded
+	UserID           *uint      `json:"user_id,omitempty"`
+	ExchangeCodeHash *string    `gorm:"type:varchar(64);uniqueIndex" json:"-"`
+	CompletedAt      *time.Time `json:"completed_at,omitempty"`
+	ExchangedAt      *time.Time `json:"exchanged_at,omitempty"`
+}
+
+func (SSOLogin) TableName() string {
+	return "sso_logins"
+}
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Team groups tenant users (e.g. a sales territory) under an optional manager.
+// Contacts and deals can be assigned to a team.
+type Team struct {
+	ID        uint           `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
+
+	TenantID    uint   `gorm:"not null;index:idx_tenant_team,priority:1" json:"tenant_id"`
+	Name        string `gorm:"type:varchar(100);not null;index:idx_tenant_team,priority:2" json:"name"`
+	Description string `gorm:"type:varchar(255)" json:"description"`
+	ManagerID   *uint  `gorm:"index" json:"manager_id"`
+
+	// Relationships
+	Tenant  Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
+	Manager *User  `gorm:"foreignKey:ManagerID;constraint:OnDelete:SET NULL" json:"manager,omitempty"`
+	Members []User `gorm:"many2many:team_members;constraint:OnDelete:CASCADE" json:"members"`
+}
+
+func (Team) TableName() string {
+	return "teams"
+}
+
+// GetTenantID implements TenantScoped interface
+func (t *Team) GetTenantID() uint {
+	return t.TenantID
+}
+
+// MemberIDs returns the user IDs of the team's members
+func (t *Team) MemberIDs() []uint {
+	ids := make([]uint, 0, len(t.Members))
+	for _, m := range t.Members {
+		ids = append(ids, m.ID)
+	}
+	return ids
+}
@@ -1,11 +1,21 @@
 package model
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
 )
 
+// Tenant statuses. Suspended tenants and tenants awaiting deletion are read-only.
+const (
+	TenantStatusActive          = "active"
+	TenantStatusSuspended       = "suspended"
+	TenantStatusInactive        = "inactive"
+	TenantStatusPendingDeletion = "pending_deletion" // Deletion requested, can be cancelled until PurgeAfter
+	TenantStatusPurging         = "purging"          // Data is being removed, see TenantTombstone
+)
+
 // Tenant represents a tenant/organization in the multi-tenant system
 type Tenant struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
@@ -14,7 +24,16 @@ type Tenant struct {
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
 	Name   string `gorm:"type:varchar(255);not null;index" json:"name"`
-	Status string `gorm:"type:varchar(20);default:'active';index" json:"status"` // active, suspended, inactive
+	Status string `gorm:"type:varchar(20);default:'active';index" json:"status"` // active, suspended, inactive, pending_deletion, purging
+
+	// The owner is always an admin and cannot be removed or demoted; the designation moves
+	// only through an ownership transfer
+	OwnerID *uint `gorm:"index" json:"owner_id"`
+
+	// Set while a deletion request is pending
+	DeletionRequestedAt *time.Time `json:"deletion_requested_at,omitempty"`
+	DeletionRequestedBy *uint      `json:"deletion_requested_by,omitempty"`
+	PurgeAfter          *time.Time `gorm:"index" json:"purge_after,omitempty"`
 
 	// Relationships
 	Users []TenantUser `gorm:"foreignKey:TenantID" json:"-"`
@@ -27,15 +46,49 @@ type User struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `gorm:"index" json:"-"`
 
-	Email        string `gorm:"type:varchar(255);uniqueIndex;not null" json:"email"` // Indexed for fast login
+	Email        string `gorm:"type:varchar(255);not null;uniqueIndex:idx_users_email_lower,expression:LOWER(email)" json:"email"` // Unique ignoring case, see NormalizeEmail
 	PasswordHash string `gorm:"type:varchar(255);not null" json:"-"`
 	FullName     string `gorm:"type:varchar(255)" json:"full_name"`
 	IsActive     bool   `gorm:"default:true;index" json:"is_active"` // Indexed for filtering
 
+	// Platform operators manage all tenants through /api/platform (PLATFORM_ADMIN_USER_IDS)
+	IsPlatformAdmin bool `gorm:"default:false;index" json:"is_platform_admin"`
+
+	// Profile, editable by the user through /api/me
+	AvatarURL string `gorm:"type:varchar(1024)" json:"avatar_url"`
+	Locale    string `gorm:"type:varchar(35)" json:"locale"`   // BCP 47 language tag, e.g. en-US
+	Timezone  string `gorm:"type:varchar(64)" json:"timezone"` // IANA name, e.g. Europe/Berlin
+	Phone     string `gorm:"type:varchar(32)" json:"phone"`
+
+	// Access tokens issued before this instant are rejected (logout everywhere, password change)
+	TokensValidAfter *time.Time `json:"-"`
+
+	// Two-factor authentication (TOTP)
+	MFAEnabled        bool   `gorm:"default:false" json:"mfa_enabled"`
+	TOTPSecret        string `gorm:"type:varchar(64)" json:"-"`
+	TOTPPendingSecret string `gorm:"type:varchar(64)" json:"-"` // Set during enrollment until confirmed
+	TOTPLastStep      int64  `gorm:"default:0" json:"-"`        // Last accepted time step, prevents code replay
+
 	// Relationships
 	TenantUsers []TenantUser `gorm:"foreignKey:UserID" json:"-"`
 }
 
+// NormalizeEmail trims and lowercases an email address, the form user emails are stored
+// and looked up in
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// IsReadOnly reports whether the tenant's data may only be read
+func (t *Tenant) IsReadOnly() bool {
+	return IsReadOnlyTenantStatus(t.Status)
+}
+
+// IsReadOnlyTenantStatus reports whether tenants in this status accept only reads
+func IsReadOnlyTenantStatus(status string) bool {
+	return status == TenantStatusSuspended || status == TenantStatusPendingDeletion
+}
+
 // TenantUser represents the many-to-many relationship between users and tenants with roles
 type TenantUser struct {
 	ID        uint           `gorm:"primarykey" json:"id"`
@@ -73,16 +126,20 @@ type AuditLog struct {
 	CreatedAt time.Time `gorm:"index" json:"created_at"` // Indexed for time-based queries
 
 	TenantID   uint   `gorm:"not null;index:idx_tenant_audit" json:"tenant_id"` // Indexed for tenant filtering
-	UserID     uint   `gorm:"index" json:"user_id"`                             // Indexed for user activity tracking
+	UserID     uint   `gorm:"index" json:"user_id"`                             // Indexed for user activity tracking (0 for API key actions)
+	APIKeyID   *uint  `gorm:"index" json:"api_key_id,omitempty"`                // Set when the action was performed with an API key
+	OperatorID *uint  `gorm:"index" json:"operator_id,omitempty"`               // Platform operator who performed the action, directly or impersonating UserID
 	Action     string `gorm:"type:varchar(100);index" json:"action"`            // Indexed for action filtering
 	Resource   string `gorm:"type:varchar(100)" json:"resource"`
 	ResourceID uint   `json:"resource_id,omitempty"`
 	IPAddress  string `gorm:"type:varchar(45)" json:"ip_address"`
 	UserAgent  string `gorm:"type:text" json:"user_agent,omitempty"`
 
+	// Action-specific details, e.g. the row count of an export
+	Metadata map[string]interface{} `gorm:"type:text;serializer:json" json:"metadata,omitempty"`
+
 	// Relationships
 	Tenant Tenant `gorm:"foreignKey:TenantID;constraint:OnDelete:CASCADE" json:"-"`
-	User   User   `gorm:"foreignKey:UserID;constraint:OnDelete:SET NULL" json:"-"`
 }
 
 // TableName overrides (optional, if you want custom table names)
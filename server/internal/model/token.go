@@ -0,0 +1,88 @@
+package model
+
+import (
+	"time"
+)
+
+// RefreshToken is a long-lived, single-use credential exchanged for new access tokens.
+// Tokens issued from the same login share a FamilyID so that a replayed (already rotated)
+// token can revoke the whole chain.
+type RefreshToken struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID    uint   `gorm:"not null;index" json:"user_id"`
+	TenantID  uint   `gorm:"not null;index" json:"tenant_id"`
+	FamilyID  string `gorm:"type:varchar(64);not null;index" json:"family_id"`
+	SessionID uint   `gorm:"index" json:"session_id"` // 0 for tokens issued before sessions existed
+
+	TokenHash    string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"` // SHA-256 of the raw token
+	ExpiresAt    time.Time  `gorm:"not null;index" json:"expires_at"`
+	RevokedAt    *time.Time `json:"revoked_at,omitempty"`
+	ReplacedByID *uint      `json:"replaced_by_id,omitempty"` // Set when rotated
+
+	IPAddress string `gorm:"type:varchar(45)" json:"ip_address"`
+	UserAgent string `gorm:"type:text" json:"user_agent,omitempty"`
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// IsActive reports whether the token can still be exchanged
+func (rt *RefreshToken) IsActive() bool {
+	return rt.RevokedAt == nil && time.Now().Before(rt.ExpiresAt)
+}
+
+// PasswordResetToken is a single-use credential emailed to a user who forgot their password
+type PasswordResetToken struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	TokenHash string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"` // SHA-256 of the raw token
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	IPAddress string     `gorm:"type:varchar(45)" json:"ip_address"` // Where the reset was requested
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func (PasswordResetToken) TableName() string {
+	return "password_reset_tokens"
+}
+
+// IsUsable reports whether the token can still be redeemed
+func (t *PasswordResetToken) IsUsable() bool {
+	return t.UsedAt == nil && time.Now().Before(t.ExpiresAt)
+}
+
+// EmailChangeToken confirms that a user controls the new address they asked to switch to
+type EmailChangeToken struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+
+	UserID    uint       `gorm:"not null;index" json:"user_id"`
+	NewEmail  string     `gorm:"type:varchar(255);not null" json:"new_email"`
+	TokenHash string     `gorm:"type:varchar(64);uniqueIndex;not null" json:"-"` // SHA-256 of the raw token
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at,omitempty"`
+	IPAddress string     `gorm:"type:varchar(45)" json:"ip_address"` // Where the change was requested
+
+	// Relationships
+	User User `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+func (EmailChangeToken) TableName() string {
+	return "email_change_tokens"
+}
+
+// IsUsable reports whether the token can still be redeemed
+func (t *EmailChangeToken) IsUsable() bool {
+	return t.UsedAt == nil && time.Now().Before(t.ExpiresAt)
+}
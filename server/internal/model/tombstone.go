@@ -0,0 +1,25 @@
+package model
+
+import (
+	"time"
+)
+
+// TenantTombstone records a tenant that was permanently deleted. It outlives the tenant's
+// data (including its audit logs) as evidence of when, why and by whom it was removed.
+type TenantTombstone struct {
+	ID        uint      `gorm:"primarykey" json:"id"`
+	CreatedAt time.Time `gorm:"index" json:"created_at"` // When the purge finished
+
+	TenantID            uint       `gorm:"not null;uniqueIndex" json:"tenant_id"`
+	Name                string     `gorm:"type:varchar(255)" json:"name"`
+	OwnerID             *uint      `json:"owner_id,omitempty"`
+	TenantCreatedAt     time.Time  `json:"tenant_created_at"`
+	DeletionRequestedAt *time.Time `json:"deletion_requested_at,omitempty"`
+	DeletionRequestedBy *uint      `json:"deletion_requested_by,omitempty"` // User ID
+	PurgeStartedAt      time.Time  `json:"purge_started_at"`
+	RowsPurged          string     `gorm:"type:text" json:"rows_purged"` // JSON object: table name -> deleted rows
+}
+
+func (TenantTombstone) TableName() string {
+	return "tenant_tombstones"
+}
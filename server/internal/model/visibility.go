@@ -0,0 +1,38 @@
+package model
+
+import "gorm.io/gorm"
+
+// RecordAccess describes which owned records (contacts, deals) a user may see
+type RecordAccess struct {
+	Unrestricted bool   // Sees every record in the tenant
+	OwnerIDs     []uint // Otherwise: records owned by one of these users
+	TeamIDs      []uint // ...or assigned to one of these teams
+}
+
+// FullRecordAccess is used for internal lookups and users allowed to see everything
+var FullRecordAccess = RecordAccess{Unrestricted: true}
+
+// OwnRecordAccess restricts visibility to records owned by the user
+func OwnRecordAccess(userID uint) RecordAccess {
+	return RecordAccess{OwnerIDs: []uint{userID}}
+}
+
+// RecordVisibilityScope is a GORM scope that restricts owned records to what the
+// user may see. Combine with TenantScope.
+func RecordVisibilityScope(access RecordAccess) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if access.Unrestricted {
+			return db
+		}
+		switch {
+		case len(access.OwnerIDs) > 0 && len(access.TeamIDs) > 0:
+			return db.Where("(owner_id IN ? OR team_id IN ?)", access.OwnerIDs, access.TeamIDs)
+		case len(access.OwnerIDs) > 0:
+			return db.Where("owner_id IN ?", access.OwnerIDs)
+		case len(access.TeamIDs) > 0:
+			return db.Where("team_id IN ?", access.TeamIDs)
+		default:
+			return db.Where("1 = 0")
+		}
+	}
+}
@@ -0,0 +1,226 @@
+// Package oidc is a minimal OpenID Connect relying party: discovery, the authorization
+// code flow with PKCE and ID token verification against the provider's JWKS.
+package oidc
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// metadataTTL bounds how long discovery documents and signing keys are cached
+const metadataTTL = time.Hour
+
+// ProviderMetadata is the subset of the discovery document the flow needs
+type ProviderMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Claims are the identity claims read from a verified ID token
+type Claims struct {
+	Subject       string
+	Email         string
+	EmailVerified *bool // nil when the provider does not send the claim
+	Name          string
+}
+
+type cachedMetadata struct {
+	metadata  *ProviderMetadata
+	keys      *keySet
+	fetchedAt time.Time
+}
+
+// Client talks to OpenID providers and caches their metadata and keys
+type Client struct {
+	httpClient *http.Client
+	insecure   bool
+
+	mu    sync.Mutex
+	cache map[string]*cachedMetadata
+}
+
+// NewClient returns a client for OpenID providers. An insecure client (for development)
+// also talks to providers over plain http and on internal addresses.
+func NewClient(insecure bool) *Client {
+	return &Client{
+		httpClient: newHTTPClient(insecure),
+		insecure:   insecure,
+		cache:      make(map[string]*cachedMetadata),
+	}
+}
+
+// Discover fetches (or returns the cached) discovery document of an issuer
+func (c *Client) Discover(issuer string) (*ProviderMetadata, error) {
+	entry, err := c.provider(issuer, false)
+	if err != nil {
+		return nil, err
+	}
+	return entry.metadata, nil
+}
+
+// AuthCodeURL builds the authorization request URL (response_type=code, S256 PKCE)
+func AuthCodeURL(metadata *ProviderMetadata, clientID, redirectURI, state, nonce, codeVerifier string) string {
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", clientID)
+	params.Set("redirect_uri", redirectURI)
+	params.Set("scope", "openid email profile")
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+	params.Set("code_challenge", CodeChallenge(codeVerifier))
+	params.Set("code_challenge_method", "S256")
+
+	separator := "?"
+	if strings.Contains(metadata.AuthorizationEndpoint, "?") {
+		separator = "&"
+	}
+	return metadata.AuthorizationEndpoint + separator + params.Encode()
+}
+
+// Exchange redeems an authorization code and returns the raw ID token
+func (c *Client) Exchange(metadata *ProviderMetadata, clientID, clientSecret, code, redirectURI, codeVerifier string) (string, error) {
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", redirectURI)
+	form.Set("code_verifier", codeVerifier)
+	if clientSecret == "" {
+		// Public client
+		form.Set("client_id", clientID)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, metadata.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	if err := checkURL(req.URL, c.insecure); err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if clientSecret != "" {
+		req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", err
+	}
+
+	var tokenResponse struct {
+		IDToken          string `json:"id_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := json.Unmarshal(body, &tokenResponse); err != nil {
+		return "", fmt.Errorf("invalid token response (status %d)", resp.StatusCode)
+	}
+
+	if resp.StatusCode != http.StatusOK || tokenResponse.Error != "" {
+		return "", fmt.Errorf("token request rejected: %s %s", tokenResponse.Error, tokenResponse.ErrorDescription)
+	}
+	if tokenResponse.IDToken == "" {
+		return "", errors.New("token response has no id_token")
+	}
+
+	return tokenResponse.IDToken, nil
+}
+
+// NewRandomString returns a URL-safe random string (state, nonce, PKCE verifier)
+func NewRandomString() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// CodeChallenge derives the S256 PKCE challenge of a verifier
+func CodeChallenge(codeVerifier string) string {
+	sum := sha256.Sum256([]byte(codeVerifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// provider returns cached metadata and keys, refetching when stale or when forced
+// (e.g. an unknown key ID after the provider rotated its keys)
+func (c *Client) provider(issuer string, refresh bool) (*cachedMetadata, error) {
+	issuer = strings.TrimRight(issuer, "/")
+
+	c.mu.Lock()
+	entry, ok := c.cache[issuer]
+	c.mu.Unlock()
+
+	if ok && !refresh && time.Since(entry.fetchedAt) < metadataTTL {
+		return entry, nil
+	}
+
+	var metadata ProviderMetadata
+	if err := c.getJSON(issuer+"/.well-known/openid-configuration", &metadata); err != nil {
+		return nil, fmt.Errorf("discovery failed: %w", err)
+	}
+
+	if strings.TrimRight(metadata.Issuer, "/") != issuer {
+		return nil, fmt.Errorf("discovery document is for issuer %q", metadata.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, errors.New("discovery document is missing endpoints")
+	}
+
+	var jwks jsonWebKeySet
+	if err := c.getJSON(metadata.JWKSURI, &jwks); err != nil {
+		return nil, fmt.Errorf("fetching signing keys failed: %w", err)
+	}
+
+	keys, err := parseKeySet(jwks)
+	if err != nil {
+		return nil, err
+	}
+
+	entry = &cachedMetadata{metadata: &metadata, keys: keys, fetchedAt: time.Now()}
+
+	c.mu.Lock()
+	c.cache[issuer] = entry
+	c.mu.Unlock()
+
+	return entry, nil
+}
+
+func (c *Client) getJSON(rawURL string, v interface{}) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	if err := checkURL(u, c.insecure); err != nil {
+		return err
+	}
+
+	resp, err := c.httpClient.Get(u.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d", rawURL, resp.StatusCode)
+	}
+
+	return json.NewDecoder(io.LimitReader(resp.Body, 1<<20)).Decode(v)
+}
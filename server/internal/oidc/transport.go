@@ -0,0 +1,65 @@
+package oidc
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+// ErrUnsafeURL is returned for provider URLs the client refuses to fetch: anything but
+// https, or an address on the loopback, private or link-local networks
+var ErrUnsafeURL = errors.New("identity provider URLs must use https and a public address")
+
+// newHTTPClient returns the client used for provider requests. Unless insecure, it only
+// fetches https URLs, also across redirects, and only connects to public addresses.
+// The address is checked when dialing, so a host name cannot resolve to an internal one.
+func newHTTPClient(insecure bool) *http.Client {
+	client := &http.Client{Timeout: 10 * time.Second}
+	if insecure {
+		return client
+	}
+
+	dialer := &net.Dialer{
+		Timeout:   10 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control:   publicAddressOnly,
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	// A proxy would be dialed instead of the provider, bypassing the address check
+	transport.Proxy = nil
+
+	client.Transport = transport
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return checkURL(req.URL, false)
+	}
+	return client
+}
+
+// checkURL refuses URLs other than https, and plain http too unless insecure
+func checkURL(u *url.URL, insecure bool) error {
+	if u.Scheme == "https" || (insecure && u.Scheme == "http") {
+		return nil
+	}
+	return ErrUnsafeURL
+}
+
+// publicAddressOnly refuses connections to addresses that are not publicly routable
+func publicAddressOnly(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return ErrUnsafeURL
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified() {
+		return ErrUnsafeURL
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package oidc
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type jsonWebKey struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jsonWebKeySet struct {
+	Keys []jsonWebKey `json:"keys"`
+}
+
+type keySet struct {
+	keys map[string]crypto.PublicKey // By key ID ("" when the provider omits kid)
+}
+
+type idTokenClaims struct {
+	Email         string `json:"email"`
+	EmailVerified *bool  `json:"email_verified,omitempty"`
+	Name          string `json:"name"`
+	Nonce         string `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+// VerifyIDToken checks the signature, issuer, audience, expiry and nonce of an ID token
+func (c *Client) VerifyIDToken(issuer, clientID, rawIDToken, nonce string) (*Claims, error) {
+	entry, err := c.provider(issuer, false)
+	if err != nil {
+		return nil, err
+	}
+
+	parse := func(keys *keySet) (*idTokenClaims, error) {
+		var claims idTokenClaims
+		_, err := jwt.ParseWithClaims(rawIDToken, &claims, func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			if key, ok := keys.keys[kid]; ok {
+				return key, nil
+			}
+			// Tokens without kid are accepted when the provider has a single key
+			if kid == "" && len(keys.keys) == 1 {
+				for _, key := range keys.keys {
+					return key, nil
+				}
+			}
+			return nil, errUnknownKey
+		},
+			jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}),
+			jwt.WithIssuer(entry.metadata.Issuer),
+			jwt.WithAudience(clientID),
+			jwt.WithExpirationRequired(),
+			jwt.WithLeeway(time.Minute),
+		)
+		return &claims, err
+	}
+
+	claims, err := parse(entry.keys)
+	if errors.Is(err, errUnknownKey) {
+		// The provider may have rotated its keys since they were cached
+		if entry, err = c.provider(issuer, true); err != nil {
+			return nil, err
+		}
+		claims, err = parse(entry.keys)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid ID token: %w", err)
+	}
+
+	if claims.Nonce != nonce {
+		return nil, errors.New("invalid ID token: nonce mismatch")
+	}
+	if claims.Subject == "" {
+		return nil, errors.New("invalid ID token: missing subject")
+	}
+
+	return &Claims{
+		Subject:       claims.Subject,
+		Email:         claims.Email,
+		EmailVerified: claims.EmailVerified,
+		Name:          claims.Name,
+	}, nil
+}
+
+var errUnknownKey = errors.New("unknown signing key")
+
+// parseKeySet converts the provider's signature keys; encryption keys are skipped
+func parseKeySet(jwks jsonWebKeySet) (*keySet, error) {
+	set := &keySet{keys: make(map[string]crypto.PublicKey)}
+
+	for _, k := range jwks.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+
+		switch k.Kty {
+		case "RSA":
+			n, err := base64.RawURLEncoding.DecodeString(k.N)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RSA key %q", k.Kid)
+			}
+			e, err := base64.RawURLEncoding.DecodeString(k.E)
+			if err != nil {
+				return nil, fmt.Errorf("invalid RSA key %q", k.Kid)
+			}
+			set.keys[k.Kid] = &rsa.PublicKey{
+				N: new(big.Int).SetBytes(n),
+				E: int(new(big.Int).SetBytes(e).Int64()),
+			}
+		case "EC":
+			var curve elliptic.Curve
+			switch k.Crv {
+			case "P-256":
+				curve = elliptic.P256()
+			case "P-384":
+				curve = elliptic.P384()
+			case "P-521":
+				curve = elliptic.P521()
+			default:
+				continue
+			}
+			x, errX := base64.RawURLEncoding.DecodeString(k.X)
+			y, errY := base64.RawURLEncoding.DecodeString(k.Y)
+			if errX != nil || errY != nil {
+				return nil, fmt.Errorf("invalid EC key %q", k.Kid)
+			}
+			set.keys[k.Kid] = &ecdsa.PublicKey{
+				Curve: curve,
+				X:     new(big.Int).SetBytes(x),
+				Y:     new(big.Int).SetBytes(y),
+			}
+		}
+	}
+
+	if len(set.keys) == 0 {
+		return nil, errors.New("provider publishes no usable signing keys")
+	}
+	return set, nil
+}
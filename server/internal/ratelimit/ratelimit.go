@@ -0,0 +1,196 @@
+// Package ratelimit implements in-process token buckets with counters for monitoring.
+package ratelimit
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Bucket identifies a token bucket and its size. The bucket holds Limit tokens and
+// refills at Limit per minute. A Limit of 0 or less means unlimited.
+type Bucket struct {
+	Scope string // e.g. "tenant" or "user", used for counters
+	Key   string
+	Limit int
+}
+
+// Result describes the most restrictive bucket of a request
+type Result struct {
+	Allowed    bool
+	Scope      string
+	Limit      int
+	Remaining  int
+	Reset      time.Duration // Until the bucket is full again
+	RetryAfter time.Duration // Until the next token, set when rejected
+}
+
+type bucketState struct {
+	tokens   float64
+	lastFill time.Time
+}
+
+type counterKey struct {
+	scope  string
+	result string
+}
+
+// Limiter holds the buckets of all keys
+type Limiter struct {
+	mu       sync.Mutex
+	buckets  map[string]*bucketState
+	counters map[counterKey]uint64
+	rejected map[string]uint64 // Rejections per tenant ID
+}
+
+func New() *Limiter {
+	return &Limiter{
+		buckets:  make(map[string]*bucketState),
+		counters: make(map[counterKey]uint64),
+		rejected: make(map[string]uint64),
+	}
+}
+
+// Allow takes one token from every bucket, or none if any of them is empty. Rejections
+// are counted per tenant ID for monitoring.
+func (l *Limiter) Allow(tenantID string, buckets ...Bucket) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweepLocked(now)
+
+	var result *Result
+	states := make([]*bucketState, len(buckets))
+
+	for i, b := range buckets {
+		if b.Limit <= 0 {
+			continue
+		}
+
+		state := l.fillLocked(b, now)
+		states[i] = state
+
+		r := Result{
+			Allowed:   state.tokens >= 1,
+			Scope:     b.Scope,
+			Limit:     b.Limit,
+			Remaining: int(math.Max(state.tokens-1, 0)),
+		}
+		if !r.Allowed {
+			r.Remaining = 0
+			r.RetryAfter = refillTime(b.Limit, 1-state.tokens)
+		}
+
+		// Report the first empty bucket, otherwise the one with the fewest tokens left
+		if result == nil || (result.Allowed && (!r.Allowed || r.Remaining < result.Remaining)) {
+			result = &r
+		}
+	}
+
+	if result == nil {
+		l.counters[counterKey{scope: "none", result: "allowed"}]++
+		return Result{Allowed: true}
+	}
+
+	if !result.Allowed {
+		l.counters[counterKey{scope: result.Scope, result: "rejected"}]++
+		l.rejected[tenantID]++
+		return *result
+	}
+
+	for i, state := range states {
+		if state == nil {
+			continue
+		}
+		state.tokens--
+		if buckets[i].Scope == result.Scope {
+			result.Reset = refillTime(buckets[i].Limit, float64(buckets[i].Limit)-state.tokens)
+		}
+	}
+
+	l.counters[counterKey{scope: result.Scope, result: "allowed"}]++
+	return *result
+}
+
+// fillLocked returns the bucket with tokens added for the time since its last use
+func (l *Limiter) fillLocked(b Bucket, now time.Time) *bucketState {
+	state, ok := l.buckets[b.Key]
+	if !ok {
+		state = &bucketState{tokens: float64(b.Limit), lastFill: now}
+		l.buckets[b.Key] = state
+		return state
+	}
+
+	elapsed := now.Sub(state.lastFill).Minutes()
+	state.tokens = math.Min(float64(b.Limit), state.tokens+elapsed*float64(b.Limit))
+	state.lastFill = now
+	return state
+}
+
+// sweepLocked drops idle buckets once the map grows large; caller must hold mu.
+// A bucket idle for a minute is full again, so dropping it changes nothing.
+func (l *Limiter) sweepLocked(now time.Time) {
+	if len(l.buckets) < 10000 {
+		return
+	}
+	for key, state := range l.buckets {
+		if now.Sub(state.lastFill) > time.Minute {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// WritePrometheus writes the request counters in the Prometheus text format
+func (l *Limiter) WritePrometheus(w io.Writer) error {
+	l.mu.Lock()
+	counters := make(map[counterKey]uint64, len(l.counters))
+	for k, v := range l.counters {
+		counters[k] = v
+	}
+	rejected := make(map[string]uint64, len(l.rejected))
+	for k, v := range l.rejected {
+		rejected[k] = v
+	}
+	l.mu.Unlock()
+
+	keys := make([]counterKey, 0, len(counters))
+	for k := range counters {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].scope != keys[j].scope {
+			return keys[i].scope < keys[j].scope
+		}
+		return keys[i].result < keys[j].result
+	})
+
+	if _, err := fmt.Fprintln(w, "# HELP ratelimit_requests_total Requests checked by the rate limiter, by limiting scope and result."); err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "# TYPE ratelimit_requests_total counter")
+	for _, k := range keys {
+		fmt.Fprintf(w, "ratelimit_requests_total{scope=%q,result=%q} %d\n", k.scope, k.result, counters[k])
+	}
+
+	tenants := make([]string, 0, len(rejected))
+	for k := range rejected {
+		tenants = append(tenants, k)
+	}
+	sort.Strings(tenants)
+
+	fmt.Fprintln(w, "# HELP ratelimit_rejected_total Requests rejected with 429, by tenant.")
+	fmt.Fprintln(w, "# TYPE ratelimit_rejected_total counter")
+	for _, tenant := range tenants {
+		fmt.Fprintf(w, "ratelimit_rejected_total{tenant_id=%q} %d\n", tenant, rejected[tenant])
+	}
+	return nil
+}
+
+// refillTime is how long a bucket of limit tokens per minute takes to gain tokens
+func refillTime(limit int, tokens float64) time.Duration {
+	return time.Duration(tokens / float64(limit) * float64(time.Minute))
+}
@@ -0,0 +1,93 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiterEmptiesBucket(t *testing.T) {
+	l := New()
+	bucket := Bucket{Scope: "user", Key: "user:1:1", Limit: 3}
+
+	for i := 0; i < 3; i++ {
+		result := l.Allow("1", bucket)
+		if !result.Allowed || result.Remaining != 2-i || result.Scope != "user" || result.Limit != 3 {
+			t.Fatalf("request %d = %+v, want allowed with %d remaining", i+1, result, 2-i)
+		}
+	}
+
+	result := l.Allow("1", bucket)
+	if result.Allowed || result.Remaining != 0 {
+		t.Fatalf("request past the limit = %+v, want rejected", result)
+	}
+	// One token refills in a third of a minute
+	if result.RetryAfter <= 19*time.Second || result.RetryAfter > 20*time.Second {
+		t.Errorf("RetryAfter = %s, want about 20s", result.RetryAfter)
+	}
+}
+
+func TestLimiterRefillsBucket(t *testing.T) {
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		want    int // Requests allowed after the wait
+	}{
+		{"no wait", 0, 0},
+		{"one token", 10 * time.Second, 1},
+		{"half a minute", 30 * time.Second, 3},
+		{"capped at the limit", 10 * time.Minute, 6},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := New()
+			bucket := Bucket{Scope: "tenant", Key: "tenant:1", Limit: 6}
+			for i := 0; i < bucket.Limit; i++ {
+				l.Allow("1", bucket)
+			}
+
+			// Wind the clock of the bucket back instead of sleeping
+			l.buckets[bucket.Key].lastFill = l.buckets[bucket.Key].lastFill.Add(-tt.elapsed)
+
+			allowed := 0
+			for l.Allow("1", bucket).Allowed {
+				allowed++
+			}
+			if allowed != tt.want {
+				t.Errorf("allowed %d requests, want %d", allowed, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimiterChecksEveryBucket(t *testing.T) {
+	l := New()
+	user := Bucket{Scope: "user", Key: "user:1:1", Limit: 10}
+	tenant := Bucket{Scope: "tenant", Key: "tenant:1", Limit: 2}
+
+	for i := 0; i < 2; i++ {
+		if result := l.Allow("1", user, tenant); !result.Allowed || result.Scope != "tenant" {
+			t.Fatalf("request %d = %+v, want allowed by the tenant bucket", i+1, result)
+		}
+	}
+
+	result := l.Allow("1", user, tenant)
+	if result.Allowed || result.Scope != "tenant" {
+		t.Fatalf("request past the tenant limit = %+v, want rejected by the tenant bucket", result)
+	}
+
+	// A rejected request takes no token from the other buckets
+	other := Bucket{Scope: "tenant", Key: "tenant:2", Limit: 100}
+	if result := l.Allow("2", user, other); !result.Allowed || result.Scope != "user" || result.Remaining != 7 {
+		t.Errorf("request with another tenant = %+v, want allowed with 7 left in the user bucket", result)
+	}
+}
+
+func TestLimiterUnlimitedBucket(t *testing.T) {
+	l := New()
+	for i := 0; i < 100; i++ {
+		if result := l.Allow("1", Bucket{Scope: "user", Key: "user:1:1", Limit: 0}); !result.Allowed || result.Limit != 0 {
+			t.Fatalf("request %d = %+v, want allowed without a limit", i+1, result)
+		}
+	}
+}
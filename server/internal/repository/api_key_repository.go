@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"gin-quickstart/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type APIKeyRepository interface {
+	Create(key *model.APIKey) error
+	FindByID(tenantID, id uint) (*model.APIKey, error)
+	FindAll(tenantID uint) ([]model.APIKey, error)
+	FindByHash(keyHash string) (*model.APIKey, error)
+	Revoke(tenantID, id uint) error
+	TouchLastUsed(id uint, ipAddress string) error
+}
+
+type apiKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewAPIKeyRepository(db *gorm.DB) APIKeyRepository {
+	return &apiKeyRepository{db: db}
+}
+
+func (r *apiKeyRepository) Create(key *model.APIKey) error {
+	return r.db.Create(key).Error
+}
+
+func (r *apiKeyRepository) FindByID(tenantID, id uint) (*model.APIKey, error) {
+	var key model.APIKey
+	err := r.db.Scopes(model.TenantScope(tenantID)).
+		Preload("Scopes").
+		First(&key, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+func (r *apiKeyRepository) FindAll(tenantID uint) ([]model.APIKey, error) {
+	var keys []model.APIKey
+	err := r.db.Scopes(model.TenantScope(tenantID), model.OrderByCreatedAt()).
+		Preload("Scopes").
+		Find(&keys).Error
+	return keys, err
+}
+
+// FindByHash uses the unique key_hash index
+func (r *apiKeyRepository) FindByHash(keyHash string) (*model.APIKey, error) {
+	var key model.APIKey
+	err := r.db.Preload("Scopes").
+		Where("key_hash = ?", keyHash).
+		First(&key).Error
+	if err != nil {
+		return nil, err
+	}
+	return &key, nil
+}
+
+// Revoke disables a key; the row is kept so audit entries can still refer to it
+func (r *apiKeyRepository) Revoke(tenantID, id uint) error {
+	return r.db.Model(&model.APIKey{}).
+		Where("tenant_id = ? AND id = ? AND revoked_at IS NULL", tenantID, id).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *apiKeyRepository) TouchLastUsed(id uint, ipAddress string) error {
+	return r.db.Model(&model.APIKey{}).
+		Where("id = ?", id).
+		Updates(map[string]interface{}{
+			"last_used_at": time.Now(),
+			"last_used_ip": ipAddress,
+		}).Error
+}
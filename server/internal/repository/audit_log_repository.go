@@ -11,7 +11,7 @@ type AuditLogRepository interface {
 	Create(log *model.AuditLog) error
 	FindByTenant(tenantID uint, page, pageSize int) ([]model.AuditLog, int64, error)
 	FindByUser(tenantID, userID uint, page, pageSize int) ([]model.AuditLog, int64, error)
-	CountByDateRange(tenantID uint, startDate, endDate time.Time) (int, error)
+	CountByDateRange(tenantID uint, startDate, endDate time.Time, userIDs []uint) (int, error)
 }
 
 type auditLogRepository struct {
@@ -67,11 +67,20 @@ func (r *auditLogRepository) FindByUser(tenantID, userID uint, page, pageSize in
 	return logs, total, err
 }
 
-// CountByDateRange counts audit logs created within date range
-func (r *auditLogRepository) CountByDateRange(tenantID uint, startDate, endDate time.Time) (int, error) {
+// CountByDateRange counts audit logs created within date range; a non-nil userIDs
+// limits the count to actions by those users
+func (r *auditLogRepository) CountByDateRange(tenantID uint, startDate, endDate time.Time, userIDs []uint) (int, error) {
 	var count int64
-	err := r.db.Model(&model.AuditLog{}).
-		Where("tenant_id = ? AND created_at BETWEEN ? AND ?", tenantID, startDate, endDate).
-		Count(&count).Error
+	query := r.db.Model(&model.AuditLog{}).
+		Where("tenant_id = ? AND created_at BETWEEN ? AND ?", tenantID, startDate, endDate)
+
+	if userIDs != nil {
+		if len(userIDs) == 0 {
+			return 0, nil
+		}
+		query = query.Where("user_id IN ?", userIDs)
+	}
+
+	err := query.Count(&count).Error
 	return int(count), err
 }
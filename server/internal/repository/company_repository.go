@@ -0,0 +1,205 @@
+package repository
+
+import (
+	"gin-quickstart/internal/model"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CompanyRepository interface {
+	Create(company *model.Company) error
+	FindOrCreateByName(company *model.Company) (*model.Company, error)
+	FindByID(tenantID, id uint) (*model.Company, error)
+	FindByName(tenantID uint, name string) (*model.Company, error)
+	FindAll(tenantID uint, filter *model.CompanyFilter, page, pageSize int) ([]model.Company, int64, error)
+	Update(company *model.Company) error
+	Delete(company *model.Company) error
+	Stats(tenantID, id uint, access model.RecordAccess) (*model.CompanyStats, error)
+	LinkContacts(tenantID uint) (int64, error)
+}
+
+type companyRepository struct {
+	db *gorm.DB
+}
+
+func NewCompanyRepository(db *gorm.DB) CompanyRepository {
+	return &companyRepository{db: db}
+}
+
+func (r *companyRepository) Create(company *model.Company) error {
+	return r.db.Create(company).Error
+}
+
+// FindOrCreateByName returns the tenant's company named like company (case-insensitive),
+// creating company if there is none
+func (r *companyRepository) FindOrCreateByName(company *model.Company) (*model.Company, error) {
+	if err := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(company).Error; err != nil {
+		return nil, err
+	}
+	if company.ID != 0 {
+		return company, nil
+	}
+	return r.FindByName(company.TenantID, company.Name)
+}
+
+func (r *companyRepository) FindByID(tenantID, id uint) (*model.Company, error) {
+	var company model.Company
+	err := r.db.Scopes(model.TenantScope(tenantID)).First(&company, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &company, nil
+}
+
+// FindByName uses the unique (tenant_id, LOWER(name)) index
+func (r *companyRepository) FindByName(tenantID uint, name string) (*model.Company, error) {
+	var company model.Company
+	err := r.db.Scopes(model.TenantScope(tenantID)).
+		Where("LOWER(name) = ?", strings.ToLower(strings.TrimSpace(name))).
+		First(&company).Error
+	if err != nil {
+		return nil, err
+	}
+	return &company, nil
+}
+
+// FindAll with filtering, ordered by name
+func (r *companyRepository) FindAll(tenantID uint, filter *model.CompanyFilter, page, pageSize int) ([]model.Company, int64, error) {
+	var companies []model.Company
+	var total int64
+
+	query := r.db.Model(&model.Company{}).Scopes(model.TenantScope(tenantID))
+	if filter != nil {
+		if filter.Search != "" {
+			searchPattern := "%" + strings.ToLower(filter.Search) + "%"
+			query = query.Where("LOWER(name) LIKE ? OR LOWER(domain) LIKE ?", searchPattern, searchPattern)
+		}
+		if filter.Industry != "" {
+			query = query.Where("industry = ?", filter.Industry)
+		}
+		if filter.Size != "" {
+			query = query.Where("size = ?", filter.Size)
+		}
+		if filter.OwnerID != nil {
+			query = query.Where("owner_id = ?", *filter.OwnerID)
+		}
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Scopes(model.Paginate(page, pageSize)).
+		Order("LOWER(name) ASC, id ASC").
+		Find(&companies).Error
+
+	return companies, total, err
+}
+
+// Update saves the attributes of a company. A new name is copied to the company name of
+// its contacts, without touching their updated_at.
+func (r *companyRepository) Update(company *model.Company) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Model(company).
+			Select("owner_id", "name", "domain", "industry", "size", "address", "city", "province", "postal_code", "country", "notes").
+			Updates(company).Error
+		if err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Model(&model.Contact{}).
+			Where("tenant_id = ? AND company_id = ? AND company_name <> ?", company.TenantID, company.ID, company.Name).
+			UpdateColumn("company_name", company.Name).Error
+	})
+}
+
+// Delete unlinks the company's contacts and deals, deleted ones included, and soft
+// deletes it. The company name of its contacts is cleared too, so that LinkContacts does
+// not create the company again.
+func (r *companyRepository) Delete(company *model.Company) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.Unscoped().Model(&model.Contact{}).
+			Where("tenant_id = ? AND company_id = ?", company.TenantID, company.ID).
+			UpdateColumns(map[string]interface{}{"company_id": nil, "company_name": ""}).Error
+		if err != nil {
+			return err
+		}
+		err = tx.Unscoped().Model(&model.Deal{}).
+			Where("tenant_id = ? AND company_id = ?", company.TenantID, company.ID).
+			UpdateColumn("company_id", nil).Error
+		if err != nil {
+			return err
+		}
+		return tx.Delete(company).Error
+	})
+}
+
+// Stats counts the company's contacts and sums the value of its active and won deals,
+// among the records the user may see
+func (r *companyRepository) Stats(tenantID, id uint, access model.RecordAccess) (*model.CompanyStats, error) {
+	stats := &model.CompanyStats{}
+
+	err := r.db.Model(&model.Contact{}).
+		Scopes(model.TenantScope(tenantID), model.RecordVisibilityScope(access)).
+		Where("company_id = ?", id).
+		Count(&stats.Contacts).Error
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.db.Model(&model.Deal{}).
+		Select(`COUNT(*) FILTER (WHERE status = 'active') AS open_deals,
+			COALESCE(SUM(value) FILTER (WHERE status = 'active'), 0) AS open_deal_value,
+			COUNT(*) FILTER (WHERE status = 'won') AS won_deals,
+			COALESCE(SUM(value) FILTER (WHERE status = 'won'), 0) AS won_revenue`).
+		Scopes(model.TenantScope(tenantID), model.RecordVisibilityScope(access)).
+		Where("company_id = ?", id).
+		Scan(stats).Error
+	if err != nil {
+		return nil, err
+	}
+	return stats, nil
+}
+
+// LinkContacts creates a company for every distinct company name of contacts without a
+// company (names compared case-insensitively, the first contact's creator and owner
+// becoming the company's), links those contacts to the company of their name, and links
+// their deals without a company to the same company. Deals of contacts linked before are
+// left alone. Runs for every tenant when tenantID is 0. Returns the number of companies
+// created.
+func (r *companyRepository) LinkContacts(tenantID uint) (int64, error) {
+	var created int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Exec(`
+			INSERT INTO companies (tenant_id, created_by, owner_id, name, created_at, updated_at)
+			SELECT DISTINCT ON (c.tenant_id, LOWER(TRIM(c.company_name)))
+				c.tenant_id, c.created_by, c.owner_id, TRIM(c.company_name), NOW(), NOW()
+			FROM contacts c
+			WHERE c.company_id IS NULL AND TRIM(c.company_name) <> '' AND c.deleted_at IS NULL
+				AND (? = 0 OR c.tenant_id = ?)
+			ORDER BY c.tenant_id, LOWER(TRIM(c.company_name)), c.id
+			ON CONFLICT (tenant_id, LOWER(name)) WHERE deleted_at IS NULL DO NOTHING`,
+			tenantID, tenantID)
+		if result.Error != nil {
+			return result.Error
+		}
+		created = result.RowsAffected
+
+		return tx.Exec(`
+			WITH linked AS (
+				UPDATE contacts c SET company_id = co.id
+				FROM companies co
+				WHERE c.company_id IS NULL AND TRIM(c.company_name) <> ''
+					AND co.tenant_id = c.tenant_id AND LOWER(co.name) = LOWER(TRIM(c.company_name))
+					AND co.deleted_at IS NULL AND (? = 0 OR c.tenant_id = ?)
+				RETURNING c.id, c.company_id
+			)
+			UPDATE deals d SET company_id = linked.company_id
+			FROM linked
+			WHERE d.contact_id = linked.id AND d.company_id IS NULL`,
+			tenantID, tenantID).Error
+	})
+	return created, err
+}
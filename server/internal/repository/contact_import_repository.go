@@ -0,0 +1,146 @@
+package repository
+
+import (
+	"gin-quickstart/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ContactImportRepository interface {
+	Create(imp *model.ContactImport) error
+	FindByID(id uint) (*model.ContactImport, error)
+	FindByTenant(tenantID uint, limit int) ([]model.ContactImport, error)
+	FindPending(limit int) ([]model.ContactImport, error)
+	FindAbandoned(uploadedBefore time.Time, limit int) ([]model.ContactImport, error)
+	Queue(id uint, mapping map[string]string, tagDelimiter string) error
+	Claim(id uint) error
+	UpdateProgress(id uint, processed, created, failed, duplicates int) error
+	Complete(id uint, errorReportKey string) error
+	Fail(id uint, message string) error
+	FailStale(startedBefore time.Time) (int64, error)
+	MemberIDsByEmail(tenantID uint) (map[string]uint, error)
+}
+
+type contactImportRepository struct {
+	db *gorm.DB
+}
+
+func NewContactImportRepository(db *gorm.DB) ContactImportRepository {
+	return &contactImportRepository{db: db}
+}
+
+func (r *contactImportRepository) Create(imp *model.ContactImport) error {
+	return r.db.Create(imp).Error
+}
+
+func (r *contactImportRepository) FindByID(id uint) (*model.ContactImport, error) {
+	var imp model.ContactImport
+	err := r.db.First(&imp, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &imp, nil
+}
+
+// FindByTenant returns the tenant's most recent contact imports
+func (r *contactImportRepository) FindByTenant(tenantID uint, limit int) ([]model.ContactImport, error) {
+	var imports []model.ContactImport
+	err := r.db.Scopes(model.TenantScope(tenantID)).
+		Order("id DESC").
+		Limit(limit).
+		Find(&imports).Error
+	return imports, err
+}
+
+// FindPending returns started imports waiting for a worker, oldest first
+func (r *contactImportRepository) FindPending(limit int) ([]model.ContactImport, error) {
+	var imports []model.ContactImport
+	err := r.db.Where("status = ?", model.ContactImportPending).
+		Order("id").
+		Limit(limit).
+		Find(&imports).Error
+	return imports, err
+}
+
+// FindAbandoned returns uploads that were never started
+func (r *contactImportRepository) FindAbandoned(uploadedBefore time.Time, limit int) ([]model.ContactImport, error) {
+	var imports []model.ContactImport
+	err := r.db.Where("status = ? AND created_at < ?", model.ContactImportUploaded, uploadedBefore).
+		Order("id").
+		Limit(limit).
+		Find(&imports).Error
+	return imports, err
+}
+
+// Queue stores the confirmed mapping of an uploaded import and hands it to the workers.
+// Returns gorm.ErrRecordNotFound if the import was already started.
+func (r *contactImportRepository) Queue(id uint, mapping map[string]string, tagDelimiter string) error {
+	return requireRow(r.db.Model(&model.ContactImport{ID: id}).
+		Where("status = ?", model.ContactImportUploaded).
+		Select("Status", "Mapping", "TagDelimiter").
+		Updates(&model.ContactImport{
+			Status:       model.ContactImportPending,
+			Mapping:      mapping,
+			TagDelimiter: tagDelimiter,
+		}))
+}
+
+// Claim moves a pending import to running; only one worker can claim an import
+func (r *contactImportRepository) Claim(id uint) error {
+	return requireRow(r.db.Model(&model.ContactImport{}).
+		Where("id = ? AND status = ?", id, model.ContactImportPending).
+		Updates(map[string]interface{}{
+			"status":     model.ContactImportRunning,
+			"started_at": time.Now(),
+		}))
+}
+
+func (r *contactImportRepository) UpdateProgress(id uint, processed, created, failed, duplicates int) error {
+	return r.db.Model(&model.ContactImport{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"processed_rows": processed,
+		"created_rows":   created,
+		"failed_rows":    failed,
+		"duplicate_rows": duplicates,
+	}).Error
+}
+
+// Complete records the error report (empty when every row was imported) and forgets the
+// deleted upload
+func (r *contactImportRepository) Complete(id uint, errorReportKey string) error {
+	return r.db.Model(&model.ContactImport{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":           model.ContactImportCompleted,
+		"error_report_key": errorReportKey,
+		"blob_key":         "",
+		"completed_at":     time.Now(),
+	}).Error
+}
+
+// Fail records why the file could not be imported. Batches committed before the
+// failure are kept.
+func (r *contactImportRepository) Fail(id uint, message string) error {
+	return r.db.Model(&model.ContactImport{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       model.ContactImportFailed,
+		"error":        message,
+		"blob_key":     "",
+		"completed_at": time.Now(),
+	}).Error
+}
+
+// FailStale fails running imports started before the given time, whose worker was
+// stopped (e.g. by a restart), and returns how many were failed
+func (r *contactImportRepository) FailStale(startedBefore time.Time) (int64, error) {
+	result := r.db.Model(&model.ContactImport{}).
+		Where("status = ? AND started_at < ?", model.ContactImportRunning, startedBefore).
+		Updates(map[string]interface{}{
+			"status":       model.ContactImportFailed,
+			"error":        "import was interrupted",
+			"completed_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// MemberIDsByEmail maps the lowercased email of every tenant member to their user ID
+func (r *contactImportRepository) MemberIDsByEmail(tenantID uint) (map[string]uint, error) {
+	return memberIDsByEmail(r.db, tenantID)
+}
@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"gin-quickstart/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ContactMergeRepository interface {
+	Merge(merge *model.ContactMerge, primary *model.Contact, duplicateUpdatedAt time.Time) error
+	Undo(merge *model.ContactMerge, restored *model.Contact, userID uint) error
+	FindByID(tenantID, id uint, access model.RecordAccess) (*model.ContactMerge, error)
+	FindByTenant(tenantID uint, page, pageSize int, access model.RecordAccess) ([]model.ContactMerge, int64, error)
+}
+
+type contactMergeRepository struct {
+	db *gorm.DB
+}
+
+func NewContactMergeRepository(db *gorm.DB) ContactMergeRepository {
+	return &contactMergeRepository{db: db}
+}
+
+// contactMergeColumns are the contact columns written by a merge and restored by an undo
+func contactMergeColumns() []string {
+	return append([]string{"tags", "custom_fields", "company_id", "phone_e164", "mobile_e164", "updated_at"}, model.ContactMergeFields...)
+}
+
+// Merge stores the merged fields of the primary contact, deletes the duplicate and moves
+// its deals (deleted ones included) to the primary in one transaction, then records the
+// merge. Returns gorm.ErrRecordNotFound if either contact changed since it was loaded.
+func (r *contactMergeRepository) Merge(merge *model.ContactMerge, primary *model.Contact, duplicateUpdatedAt time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := requireRow(tx.Model(primary).
+			Where("tenant_id = ? AND updated_at = ?", merge.TenantID, merge.PrimaryBefore.UpdatedAt).
+			Select(contactMergeColumns()).
+			Updates(primary))
+		if err != nil {
+			return err
+		}
+
+		err = requireRow(tx.Where("tenant_id = ? AND updated_at = ?", merge.TenantID, duplicateUpdatedAt).
+			Delete(&model.Contact{}, merge.DuplicateID))
+		if err != nil {
+			return err
+		}
+
+		var dealIDs []uint
+		err = tx.Unscoped().Model(&model.Deal{}).
+			Where("tenant_id = ? AND contact_id = ?", merge.TenantID, merge.DuplicateID).
+			Order("id").
+			Pluck("id", &dealIDs).Error
+		if err != nil {
+			return err
+		}
+		if len(dealIDs) > 0 {
+			err = tx.Unscoped().Model(&model.Deal{}).
+				Where("id IN ?", dealIDs).
+				Update("contact_id", merge.PrimaryID).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		merge.MovedDealIDs = dealIDs
+		merge.PrimaryUpdatedAt = primary.UpdatedAt
+		return tx.Create(merge).Error
+	})
+}
+
+// Undo restores the primary contact's fields, the duplicate and the moved deals that
+// still belong to the primary. Returns gorm.ErrRecordNotFound if the merge was already
+// undone, the primary was edited since the merge or the duplicate no longer exists.
+func (r *contactMergeRepository) Undo(merge *model.ContactMerge, restored *model.Contact, userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		err := requireRow(tx.Model(&model.ContactMerge{}).
+			Where("id = ? AND undone_at IS NULL", merge.ID).
+			Updates(map[string]interface{}{
+				"undone_at": time.Now(),
+				"undone_by": userID,
+			}))
+		if err != nil {
+			return err
+		}
+
+		err = requireRow(tx.Model(restored).
+			Where("tenant_id = ? AND updated_at = ?", merge.TenantID, merge.PrimaryUpdatedAt).
+			Select(contactMergeColumns()).
+			Updates(restored))
+		if err != nil {
+			return err
+		}
+
+		err = requireRow(tx.Unscoped().Model(&model.Contact{}).
+			Where("id = ? AND tenant_id = ? AND deleted_at IS NOT NULL", merge.DuplicateID, merge.TenantID).
+			Update("deleted_at", nil))
+		if err != nil {
+			return err
+		}
+
+		if len(merge.MovedDealIDs) == 0 {
+			return nil
+		}
+		return tx.Unscoped().Model(&model.Deal{}).
+			Where("id IN ? AND contact_id = ?", merge.MovedDealIDs, merge.PrimaryID).
+			Update("contact_id", merge.DuplicateID).Error
+	})
+}
+
+func (r *contactMergeRepository) FindByID(tenantID, id uint, access model.RecordAccess) (*model.ContactMerge, error) {
+	var merge model.ContactMerge
+	err := r.db.Scopes(model.TenantScope(tenantID), r.visibleScope(tenantID, access)).First(&merge, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &merge, nil
+}
+
+// FindByTenant returns the tenant's merges visible with access, most recent first
+func (r *contactMergeRepository) FindByTenant(tenantID uint, page, pageSize int, access model.RecordAccess) ([]model.ContactMerge, int64, error) {
+	var merges []model.ContactMerge
+	var total int64
+
+	query := r.db.Model(&model.ContactMerge{}).Scopes(model.TenantScope(tenantID), r.visibleScope(tenantID, access))
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Scopes(model.Paginate(page, pageSize)).
+		Order("id DESC").
+		Find(&merges).Error
+	return merges, total, err
+}
+
+// visibleScope restricts merges to those whose primary contact is visible with access,
+// deleted primaries included
+func (r *contactMergeRepository) visibleScope(tenantID uint, access model.RecordAccess) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if access.Unrestricted {
+			return db
+		}
+		return db.Where("primary_id IN (?)", r.db.Unscoped().Model(&model.Contact{}).
+			Scopes(model.TenantScope(tenantID), model.RecordVisibilityScope(access)).
+			Select("id"))
+	}
+}
@@ -10,12 +10,19 @@ import (
 
 type ContactRepository interface {
 	Create(contact *model.Contact) error
-	FindByID(tenantID, id uint) (*model.Contact, error)
-	FindAll(tenantID uint, filter *model.ContactFilter, page, pageSize int) ([]model.Contact, int64, error)
-	Update(contact *model.Contact) error
-	Delete(tenantID, id uint) error
-	Search(tenantID uint, query string, page, pageSize int) ([]model.Contact, int64, error)
-	CountByDateRange(tenantID uint, startDate, endDate time.Time) (int, error)
+	CreateBatch(contacts []model.Contact) error
+	FindByID(tenantID, id uint, access model.RecordAccess) (*model.Contact, error)
+	FindAll(tenantID uint, filter *model.ContactFilter, page, pageSize int, access model.RecordAccess) ([]model.Contact, int64, error)
+	Each(tenantID uint, filter *model.ContactFilter, access model.RecordAccess, fn func(contact *model.Contact) error) error
+	FindDuplicateCandidates(tenantID, excludeID uint, email string, phones []string, companyWord string) ([]model.Contact, error)
+	FindWithoutPhoneKeys(afterID uint, limit int) ([]model.Contact, error)
+	UpdatePhoneKeys(id uint, phoneE164, mobileE164 string) error
+	Update(tenantID uint, contact *model.Contact, access model.RecordAccess) error
+	UpdateOwner(tenantID, id, ownerID uint) error
+	UpdateTeam(tenantID, id uint, teamID *uint) error
+	Delete(tenantID, id uint, access model.RecordAccess) error
+	Search(tenantID uint, query string, page, pageSize int, access model.RecordAccess) ([]model.Contact, int64, error)
+	CountByDateRange(tenantID uint, startDate, endDate time.Time, teamID *uint, access model.RecordAccess) (int, error)
 }
 
 type contactRepository struct {
@@ -30,9 +37,14 @@ func (r *contactRepository) Create(contact *model.Contact) error {
 	return r.db.Create(contact).Error
 }
 
-func (r *contactRepository) FindByID(tenantID, id uint) (*model.Contact, error) {
+// CreateBatch inserts contacts with a single statement, so either all or none are created
+func (r *contactRepository) CreateBatch(contacts []model.Contact) error {
+	return r.db.Create(&contacts).Error
+}
+
+func (r *contactRepository) FindByID(tenantID, id uint, access model.RecordAccess) (*model.Contact, error) {
 	var contact model.Contact
-	err := r.db.Scopes(model.TenantScope(tenantID)).
+	err := r.db.Scopes(model.TenantScope(tenantID), model.RecordVisibilityScope(access)).
 		First(&contact, id).Error
 	if err != nil {
 		return nil, err
@@ -41,85 +53,252 @@ func (r *contactRepository) FindByID(tenantID, id uint) (*model.Contact, error)
 }
 
 // FindAll with advanced filtering
-func (r *contactRepository) FindAll(tenantID uint, filter *model.ContactFilter, page, pageSize int) ([]model.Contact, int64, error) {
+func (r *contactRepository) FindAll(tenantID uint, filter *model.ContactFilter, page, pageSize int, access model.RecordAccess) ([]model.Contact, int64, error) {
 	var contacts []model.Contact
 	var total int64
 
 	// Build query with filters
-	query := r.db.Model(&model.Contact{}).Scopes(model.TenantScope(tenantID))
-
-	// Apply filters
-	if filter != nil {
-		if filter.Search != "" {
-			searchPattern := "%" + strings.ToLower(filter.Search) + "%"
-			query = query.Where(
-				"LOWER(first_name) LIKE ? OR LOWER(last_name) LIKE ? OR LOWER(email) LIKE ? OR LOWER(phone) LIKE ? OR LOWER(company_name) LIKE ?",
-				searchPattern, searchPattern, searchPattern, searchPattern, searchPattern,
-			)
-		}
+	query := r.db.Model(&model.Contact{}).Scopes(model.TenantScope(tenantID), model.RecordVisibilityScope(access))
+	query = applyContactFilter(query, filter)
 
-		if filter.Status != "" {
-			query = query.Where("status = ?", filter.Status)
-		}
+	// Count total
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
 
-		if filter.Source != "" {
-			query = query.Where("source = ?", filter.Source)
-		}
+	// Get paginated results with ordering
+	err := query.Scopes(model.Paginate(page, pageSize)).
+		Order(contactOrder(filter)).
+		Find(&contacts).Error
 
-		if filter.City != "" {
-			query = query.Where("city = ?", filter.City)
-		}
+	return contacts, total, err
+}
+
+// contactEachBatchSize is how many contacts Each loads per query
+const contactEachBatchSize = 500
+
+// Each passes the contacts matching filter to fn, in the order of FindAll, without
+// loading them all into memory. The IDs are read first and the contacts loaded in
+// batches, so that no query stays open while fn waits, e.g. on a slow download.
+// Contacts deleted in the meantime are skipped.
+func (r *contactRepository) Each(tenantID uint, filter *model.ContactFilter, access model.RecordAccess, fn func(contact *model.Contact) error) error {
+	query := r.db.Model(&model.Contact{}).Scopes(model.TenantScope(tenantID), model.RecordVisibilityScope(access))
+	query = applyContactFilter(query, filter)
+
+	var ids []uint
+	if err := query.Order(contactOrder(filter)).Pluck("id", &ids).Error; err != nil {
+		return err
+	}
 
-		if filter.Province != "" {
-			query = query.Where("province = ?", filter.Province)
+	for start := 0; start < len(ids); start += contactEachBatchSize {
+		batch := ids[start:min(start+contactEachBatchSize, len(ids))]
+
+		var contacts []model.Contact
+		err := r.db.Scopes(model.TenantScope(tenantID), model.RecordVisibilityScope(access)).
+			Where("id IN ?", batch).
+			Find(&contacts).Error
+		if err != nil {
+			return err
 		}
 
-		// Tag filtering (simple LIKE for JSON array as string)
-		if len(filter.Tags) > 0 {
-			for _, tag := range filter.Tags {
-				query = query.Where("tags LIKE ?", "%"+tag+"%")
+		byID := make(map[uint]*model.Contact, len(contacts))
+		for i := range contacts {
+			byID[contacts[i].ID] = &contacts[i]
+		}
+		for _, id := range batch {
+			if contact, ok := byID[id]; ok {
+				if err := fn(contact); err != nil {
+					return err
+				}
 			}
 		}
 	}
+	return nil
+}
 
-	// Count total
-	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
+// FindDuplicateCandidates returns contacts of the tenant, regardless of record visibility,
+// with the same email (case-insensitive) or one of the E.164 phone numbers, followed by
+// contacts whose company name contains companyWord. Empty criteria are skipped.
+func (r *contactRepository) FindDuplicateCandidates(tenantID, excludeID uint, email string, phones []string, companyWord string) ([]model.Contact, error) {
+	var candidates []model.Contact
+
+	var exact *gorm.DB
+	if email != "" {
+		exact = r.db.Where("LOWER(email) = ?", strings.ToLower(email))
+	}
+	if len(phones) > 0 {
+		byPhone := r.db.Where("phone_e164 IN ? OR mobile_e164 IN ?", phones, phones)
+		if exact == nil {
+			exact = byPhone
+		} else {
+			exact = exact.Or(byPhone)
+		}
+	}
+	if exact != nil {
+		err := r.db.Scopes(model.TenantScope(tenantID)).
+			Where("id <> ?", excludeID).
+			Where(exact).
+			Order("id").
+			Limit(20).
+			Find(&candidates).Error
+		if err != nil {
+			return nil, err
+		}
 	}
 
-	// Get paginated results with ordering
-	err := query.Scopes(model.Paginate(page, pageSize)).
-		Order("first_name ASC, last_name ASC").
+	if companyWord != "" {
+		var sameCompany []model.Contact
+		err := r.db.Scopes(model.TenantScope(tenantID)).
+			Where("id <> ? AND LOWER(company_name) LIKE ?", excludeID, "%"+companyWord+"%").
+			Order("id").
+			Limit(200).
+			Find(&sameCompany).Error
+		if err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, sameCompany...)
+	}
+
+	return candidates, nil
+}
+
+// FindWithoutPhoneKeys returns contacts after afterID, in ID order, with a phone number
+// whose E.164 key has not been computed (e.g. created before keys existed)
+func (r *contactRepository) FindWithoutPhoneKeys(afterID uint, limit int) ([]model.Contact, error) {
+	var contacts []model.Contact
+	err := r.db.Unscoped().
+		Where("id > ?", afterID).
+		Where("(phone <> '' AND COALESCE(phone_e164, '') = '') OR (mobile <> '' AND COALESCE(mobile_e164, '') = '')").
+		Order("id").
+		Limit(limit).
 		Find(&contacts).Error
+	return contacts, err
+}
 
-	return contacts, total, err
+// UpdatePhoneKeys stores computed E.164 keys without touching updated_at
+func (r *contactRepository) UpdatePhoneKeys(id uint, phoneE164, mobileE164 string) error {
+	return r.db.Unscoped().Model(&model.Contact{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"phone_e164":  phoneE164,
+		"mobile_e164": mobileE164,
+	}).Error
+}
+
+// applyContactFilter narrows a contact query to the filter's criteria
+func applyContactFilter(query *gorm.DB, filter *model.ContactFilter) *gorm.DB {
+	if filter == nil {
+		return query
+	}
+
+	if filter.Search != "" {
+		searchPattern := "%" + strings.ToLower(filter.Search) + "%"
+		query = query.Where(
+			"LOWER(first_name) LIKE ? OR LOWER(last_name) LIKE ? OR LOWER(email) LIKE ? OR LOWER(phone) LIKE ? OR LOWER(company_name) LIKE ?",
+			searchPattern, searchPattern, searchPattern, searchPattern, searchPattern,
+		)
+	}
+
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	if filter.Source != "" {
+		query = query.Where("source = ?", filter.Source)
+	}
+
+	if filter.City != "" {
+		query = query.Where("city = ?", filter.City)
+	}
+
+	if filter.Province != "" {
+		query = query.Where("province = ?", filter.Province)
+	}
+
+	if filter.TeamID != nil {
+		query = query.Where("team_id = ?", *filter.TeamID)
+	}
+
+	if filter.CompanyID != nil {
+		query = query.Where("company_id = ?", *filter.CompanyID)
+	}
+
+	// Tag filtering (simple LIKE for JSON array as string)
+	if len(filter.Tags) > 0 {
+		for _, tag := range filter.Tags {
+			query = query.Where("tags LIKE ?", "%"+tag+"%")
+		}
+	}
+
+	return applyCustomFieldConditions(query, filter.CustomFields)
+}
+
+// contactOrder sorts contact lists by the filter's sort column (checked by the service) or
+// by name, then by ID
+func contactOrder(filter *model.ContactFilter) interface{} {
+	direction := " ASC"
+	if filter != nil && filter.SortOrder == "desc" {
+		direction = " DESC"
+	}
+
+	switch {
+	case filter == nil || filter.SortBy == "":
+		return "first_name" + direction + ", last_name" + direction + ", id" + direction
+	case strings.HasPrefix(filter.SortBy, model.CustomFieldPrefix):
+		return customFieldOrder(strings.TrimPrefix(filter.SortBy, model.CustomFieldPrefix), filter.SortType, direction == " DESC")
+	default:
+		return filter.SortBy + direction + ", id" + direction
+	}
 }
 
-func (r *contactRepository) Update(contact *model.Contact) error {
+func (r *contactRepository) Update(tenantID uint, contact *model.Contact, access model.RecordAccess) error {
 	// Use Updates to only update non-zero fields (for PATCH)
-	return r.db.Model(&model.Contact{}).Where("id = ?", contact.ID).Updates(contact).Error
+	return r.db.Model(&model.Contact{}).
+		Scopes(model.TenantScope(tenantID), model.RecordVisibilityScope(access)).
+		Where("id = ?", contact.ID).
+		Updates(contact).Error
+}
+
+// UpdateOwner reassigns the contact to another user
+func (r *contactRepository) UpdateOwner(tenantID, id, ownerID uint) error {
+	return r.db.Model(&model.Contact{}).
+		Scopes(model.TenantScope(tenantID)).
+		Where("id = ?", id).
+		Update("owner_id", ownerID).Error
+}
+
+// UpdateTeam assigns the contact to a team, or unassigns it when teamID is nil
+func (r *contactRepository) UpdateTeam(tenantID, id uint, teamID *uint) error {
+	return r.db.Model(&model.Contact{}).
+		Scopes(model.TenantScope(tenantID)).
+		Where("id = ?", id).
+		Update("team_id", teamID).Error
 }
 
 // Delete performs soft delete
-func (r *contactRepository) Delete(tenantID, id uint) error {
-	return r.db.Scopes(model.TenantScope(tenantID)).
+func (r *contactRepository) Delete(tenantID, id uint, access model.RecordAccess) error {
+	return r.db.Scopes(model.TenantScope(tenantID), model.RecordVisibilityScope(access)).
 		Delete(&model.Contact{}, id).Error
 }
 
 // Search is a shorthand for FindAll with search filter
-func (r *contactRepository) Search(tenantID uint, query string, page, pageSize int) ([]model.Contact, int64, error) {
+func (r *contactRepository) Search(tenantID uint, query string, page, pageSize int, access model.RecordAccess) ([]model.Contact, int64, error) {
 	filter := &model.ContactFilter{
 		Search: query,
 	}
-	return r.FindAll(tenantID, filter, page, pageSize)
+	return r.FindAll(tenantID, filter, page, pageSize, access)
 }
 
-// CountByDateRange counts contacts created within date range
-func (r *contactRepository) CountByDateRange(tenantID uint, startDate, endDate time.Time) (int, error) {
+// CountByDateRange counts the visible contacts created within date range, optionally for
+// one team
+func (r *contactRepository) CountByDateRange(tenantID uint, startDate, endDate time.Time, teamID *uint, access model.RecordAccess) (int, error) {
 	var count int64
-	err := r.db.Model(&model.Contact{}).
-		Scopes(model.TenantScope(tenantID)).
-		Where("created_at BETWEEN ? AND ?", startDate, endDate).
-		Count(&count).Error
+	query := r.db.Model(&model.Contact{}).
+		Scopes(model.TenantScope(tenantID), model.RecordVisibilityScope(access)).
+		Where("created_at BETWEEN ? AND ?", startDate, endDate)
+
+	if teamID != nil {
+		query = query.Where("team_id = ?", *teamID)
+	}
+
+	err := query.Count(&count).Error
 	return int(count), err
 }
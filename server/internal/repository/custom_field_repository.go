@@ -0,0 +1,140 @@
+package repository
+
+import (
+	"encoding/json"
+	"gin-quickstart/internal/model"
+	"strconv"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CustomFieldRepository interface {
+	Create(field *model.CustomFieldDefinition) error
+	FindByID(tenantID, id uint) (*model.CustomFieldDefinition, error)
+	FindByKey(tenantID uint, entity, key string) (*model.CustomFieldDefinition, error)
+	FindAll(tenantID uint, entity string) ([]model.CustomFieldDefinition, error)
+	Update(field *model.CustomFieldDefinition) error
+	Delete(field *model.CustomFieldDefinition) error
+}
+
+type customFieldRepository struct {
+	db *gorm.DB
+}
+
+func NewCustomFieldRepository(db *gorm.DB) CustomFieldRepository {
+	return &customFieldRepository{db: db}
+}
+
+func (r *customFieldRepository) Create(field *model.CustomFieldDefinition) error {
+	return r.db.Create(field).Error
+}
+
+func (r *customFieldRepository) FindByID(tenantID, id uint) (*model.CustomFieldDefinition, error) {
+	var field model.CustomFieldDefinition
+	err := r.db.Scopes(model.TenantScope(tenantID)).First(&field, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &field, nil
+}
+
+// FindByKey uses the unique (tenant_id, entity, key) index
+func (r *customFieldRepository) FindByKey(tenantID uint, entity, key string) (*model.CustomFieldDefinition, error) {
+	var field model.CustomFieldDefinition
+	err := r.db.Scopes(model.TenantScope(tenantID)).
+		Where("entity = ? AND key = ?", entity, key).
+		First(&field).Error
+	if err != nil {
+		return nil, err
+	}
+	return &field, nil
+}
+
+// FindAll returns the tenant's fields of an entity (all entities when empty) in display order
+func (r *customFieldRepository) FindAll(tenantID uint, entity string) ([]model.CustomFieldDefinition, error) {
+	var fields []model.CustomFieldDefinition
+	query := r.db.Scopes(model.TenantScope(tenantID))
+	if entity != "" {
+		query = query.Where("entity = ?", entity)
+	}
+	err := query.Order("entity, position, id").Find(&fields).Error
+	return fields, err
+}
+
+// Update saves the changeable attributes of a field
+func (r *customFieldRepository) Update(field *model.CustomFieldDefinition) error {
+	return r.db.Model(field).
+		Select("label", "options", "required", "default", "position").
+		Updates(field).Error
+}
+
+// Delete removes a field and its values from every record of the tenant, deleted records
+// included, without touching their updated_at
+func (r *customFieldRepository) Delete(field *model.CustomFieldDefinition) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(field).Error; err != nil {
+			return err
+		}
+
+		var records interface{} = &model.Contact{}
+		if field.Entity == model.CustomFieldEntityDeal {
+			records = &model.Deal{}
+		}
+		return tx.Unscoped().Model(records).
+			Where("tenant_id = ? AND custom_fields->>? IS NOT NULL", field.TenantID, field.Key).
+			UpdateColumn("custom_fields", gorm.Expr("custom_fields - ?", field.Key)).Error
+	})
+}
+
+// applyCustomFieldConditions narrows a contact or deal query to records whose custom
+// field values match every condition. Equality on select, multi_select, boolean and date
+// fields uses JSONB containment, served by the GIN index on custom_fields.
+func applyCustomFieldConditions(query *gorm.DB, conditions []model.CustomFieldCondition) *gorm.DB {
+	for _, condition := range conditions {
+		operator := "="
+		switch condition.Op {
+		case "gte":
+			operator = ">="
+		case "lte":
+			operator = "<="
+		}
+
+		switch {
+		case condition.Type == model.CustomFieldNumber:
+			query = query.Where("(custom_fields->>?)::numeric "+operator+" CAST(? AS numeric)", condition.Key, condition.Value)
+		case condition.Type == model.CustomFieldText:
+			query = query.Where("LOWER(custom_fields->>?) = LOWER(?)", condition.Key, condition.Value)
+		case operator != "=":
+			query = query.Where("custom_fields->>? "+operator+" ?", condition.Key, condition.Value)
+		default:
+			var value interface{} = condition.Value
+			switch condition.Type {
+			case model.CustomFieldBoolean:
+				value, _ = strconv.ParseBool(condition.Value)
+			case model.CustomFieldMultiSelect:
+				value = []string{condition.Value}
+			}
+			contained, _ := json.Marshal(map[string]interface{}{condition.Key: value})
+			query = query.Where("custom_fields @> CAST(? AS jsonb)", string(contained))
+		}
+	}
+	return query
+}
+
+// customFieldOrder orders records by a custom field, records without a value last, then
+// by ID
+func customFieldOrder(key, fieldType string, desc bool) clause.OrderBy {
+	value := "custom_fields->>?"
+	if fieldType == model.CustomFieldNumber {
+		value = "(custom_fields->>?)::numeric"
+	}
+	direction := " ASC"
+	if desc {
+		direction = " DESC"
+	}
+	return clause.OrderBy{Expression: clause.Expr{
+		SQL:  value + direction + " NULLS LAST, id" + direction,
+		Vars: []interface{}{key},
+	}}
+}
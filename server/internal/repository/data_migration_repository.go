@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"gin-quickstart/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type DataMigrationRepository interface {
+	RunOnce(name string, fn func(tx *gorm.DB) error) (bool, error)
+}
+
+type dataMigrationRepository struct {
+	db *gorm.DB
+}
+
+func NewDataMigrationRepository(db *gorm.DB) DataMigrationRepository {
+	return &dataMigrationRepository{db: db}
+}
+
+// RunOnce runs fn in a transaction that records the migration, unless it was recorded
+// already (by another instance too). Reports whether fn ran.
+func (r *dataMigrationRepository) RunOnce(name string, fn func(tx *gorm.DB) error) (bool, error) {
+	ran := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&model.DataMigration{Name: name, AppliedAt: time.Now()})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		ran = true
+		return fn(tx)
+	})
+	if err != nil {
+		return false, err
+	}
+	return ran, nil
+}
@@ -2,21 +2,22 @@ package repository
 
 import (
 	"gin-quickstart/internal/model"
+	"strings"
 
 	"gorm.io/gorm"
 )
 
 type DealRepository interface {
-	FindAll(tenantID uint, filter DealFilter) ([]model.Deal, error)
-	FindByID(tenantID uint, id uint) (*model.Deal, error)
+	FindAll(tenantID uint, filter DealFilter, access model.RecordAccess) ([]model.Deal, error)
+	FindByID(tenantID uint, id uint, access model.RecordAccess) (*model.Deal, error)
 	Create(deal *model.Deal) error
-	Update(deal *model.Deal) error
-	UpdateFields(tenantID uint, dealID uint, updates map[string]interface{}) error
-	Delete(deal *model.Deal) error
+	Update(tenantID uint, deal *model.Deal, access model.RecordAccess) error
+	UpdateFields(tenantID uint, dealID uint, updates map[string]interface{}, access model.RecordAccess) error
+	Delete(tenantID uint, deal *model.Deal, access model.RecordAccess) error
 	MoveToStage(tenantID uint, dealID uint, newStageID uint) error
-	UpdateStatus(tenantID uint, dealID uint, status string) error
-	Count(tenantID uint, filter DealFilter) (int64, error)
-	GetTotalValueByStage(tenantID uint) (map[uint]float64, error)
+	UpdateStatus(tenantID uint, dealID uint, status string, access model.RecordAccess) error
+	Count(tenantID uint, filter DealFilter, access model.RecordAccess) (int64, error)
+	GetTotalValueByStage(tenantID uint, teamID *uint, access model.RecordAccess) (map[uint]float64, error)
 }
 
 type dealRepository struct {
@@ -28,11 +29,11 @@ func NewDealRepository(db *gorm.DB) DealRepository {
 }
 
 // FindAll returns all deals for a tenant with filters
-func (r *dealRepository) FindAll(tenantID uint, filter DealFilter) ([]model.Deal, error) {
+func (r *dealRepository) FindAll(tenantID uint, filter DealFilter, access model.RecordAccess) ([]model.Deal, error) {
 	var deals []model.Deal
-	query := r.db.Scopes(model.TenantScope(tenantID)).
+	query := r.db.Scopes(model.TenantScope(tenantID), model.RecordVisibilityScope(access)).
 		Preload("Stage").
-		Preload("Contact")
+		Preload("Contact", model.RecordVisibilityScope(access))
 
 	// Filter by stage
 	if filter.StageID != nil {
@@ -49,6 +50,16 @@ func (r *dealRepository) FindAll(tenantID uint, filter DealFilter) ([]model.Deal
 		query = query.Where("contact_id = ?", *filter.ContactID)
 	}
 
+	// Filter by team
+	if filter.TeamID != nil {
+		query = query.Where("team_id = ?", *filter.TeamID)
+	}
+
+	// Filter by company
+	if filter.CompanyID != nil {
+		query = query.Where("company_id = ?", *filter.CompanyID)
+	}
+
 	// Filter by value range
 	if filter.MinValue != nil {
 		query = query.Where("value >= ?", *filter.MinValue)
@@ -71,8 +82,14 @@ func (r *dealRepository) FindAll(tenantID uint, filter DealFilter) ([]model.Deal
 		query = query.Where("title ILIKE ? OR description ILIKE ?", searchPattern, searchPattern)
 	}
 
-	// Sort
-	if filter.SortBy != "" {
+	// Filter by custom field values
+	query = applyCustomFieldConditions(query, filter.CustomFields)
+
+	// Sort (SortBy is checked by the service)
+	if strings.HasPrefix(filter.SortBy, model.CustomFieldPrefix) {
+		key := strings.TrimPrefix(filter.SortBy, model.CustomFieldPrefix)
+		query = query.Order(customFieldOrder(key, filter.SortType, filter.SortOrder == "desc"))
+	} else if filter.SortBy != "" {
 		order := filter.SortBy
 		if filter.SortOrder == "desc" {
 			order += " DESC"
@@ -97,11 +114,11 @@ func (r *dealRepository) FindAll(tenantID uint, filter DealFilter) ([]model.Deal
 }
 
 // FindByID returns a single deal by ID
-func (r *dealRepository) FindByID(tenantID uint, id uint) (*model.Deal, error) {
+func (r *dealRepository) FindByID(tenantID uint, id uint, access model.RecordAccess) (*model.Deal, error) {
 	var deal model.Deal
-	err := r.db.Scopes(model.TenantScope(tenantID)).
+	err := r.db.Scopes(model.TenantScope(tenantID), model.RecordVisibilityScope(access)).
 		Preload("Stage").
-		Preload("Contact").
+		Preload("Contact", model.RecordVisibilityScope(access)).
 		First(&deal, id).Error
 	if err != nil {
 		return nil, err
@@ -115,24 +132,29 @@ func (r *dealRepository) Create(deal *model.Deal) error {
 }
 
 // Update updates a deal
-func (r *dealRepository) Update(deal *model.Deal) error {
-	// Preserve immutable fields
+func (r *dealRepository) Update(tenantID uint, deal *model.Deal, access model.RecordAccess) error {
+	// Preserve immutable fields (ownership changes go through UpdateFields)
 	deal.TenantID = 0
 	deal.CreatedBy = 0
-	return r.db.Model(deal).Updates(deal).Error
+	deal.OwnerID = 0
+	return r.db.Model(&model.Deal{}).
+		Scopes(model.TenantScope(tenantID), model.RecordVisibilityScope(access)).
+		Where("id = ?", deal.ID).
+		Updates(deal).Error
 }
 
 // UpdateFields updates specific fields of a deal
-func (r *dealRepository) UpdateFields(tenantID uint, dealID uint, updates map[string]interface{}) error {
+func (r *dealRepository) UpdateFields(tenantID uint, dealID uint, updates map[string]interface{}, access model.RecordAccess) error {
 	return r.db.Model(&model.Deal{}).
-		Scopes(model.TenantScope(tenantID)).
+		Scopes(model.TenantScope(tenantID), model.RecordVisibilityScope(access)).
 		Where("id = ?", dealID).
 		Updates(updates).Error
 }
 
 // Delete deletes a deal (soft delete)
-func (r *dealRepository) Delete(deal *model.Deal) error {
-	return r.db.Delete(deal).Error
+func (r *dealRepository) Delete(tenantID uint, deal *model.Deal, access model.RecordAccess) error {
+	return r.db.Scopes(model.TenantScope(tenantID), model.RecordVisibilityScope(access)).
+		Delete(deal).Error
 }
 
 // MoveToStage moves a deal to a different stage
@@ -144,17 +166,17 @@ func (r *dealRepository) MoveToStage(tenantID uint, dealID uint, newStageID uint
 }
 
 // UpdateStatus updates deal status
-func (r *dealRepository) UpdateStatus(tenantID uint, dealID uint, status string) error {
+func (r *dealRepository) UpdateStatus(tenantID uint, dealID uint, status string, access model.RecordAccess) error {
 	return r.db.Model(&model.Deal{}).
-		Scopes(model.TenantScope(tenantID)).
+		Scopes(model.TenantScope(tenantID), model.RecordVisibilityScope(access)).
 		Where("id = ?", dealID).
 		Update("status", status).Error
 }
 
 // Count returns total number of deals for a tenant with filters
-func (r *dealRepository) Count(tenantID uint, filter DealFilter) (int64, error) {
+func (r *dealRepository) Count(tenantID uint, filter DealFilter, access model.RecordAccess) (int64, error) {
 	var count int64
-	query := r.db.Model(&model.Deal{}).Scopes(model.TenantScope(tenantID))
+	query := r.db.Model(&model.Deal{}).Scopes(model.TenantScope(tenantID), model.RecordVisibilityScope(access))
 
 	// Apply same filters as FindAll
 	if filter.StageID != nil {
@@ -166,6 +188,12 @@ func (r *dealRepository) Count(tenantID uint, filter DealFilter) (int64, error)
 	if filter.ContactID != nil {
 		query = query.Where("contact_id = ?", *filter.ContactID)
 	}
+	if filter.TeamID != nil {
+		query = query.Where("team_id = ?", *filter.TeamID)
+	}
+	if filter.CompanyID != nil {
+		query = query.Where("company_id = ?", *filter.CompanyID)
+	}
 	if filter.MinValue != nil {
 		query = query.Where("value >= ?", *filter.MinValue)
 	}
@@ -182,25 +210,30 @@ func (r *dealRepository) Count(tenantID uint, filter DealFilter) (int64, error)
 		searchPattern := "%" + filter.Search + "%"
 		query = query.Where("title ILIKE ? OR description ILIKE ?", searchPattern, searchPattern)
 	}
+	query = applyCustomFieldConditions(query, filter.CustomFields)
 
 	err := query.Count(&count).Error
 	return count, err
 }
 
-// GetTotalValueByStage returns sum of deal values grouped by stage
-func (r *dealRepository) GetTotalValueByStage(tenantID uint) (map[uint]float64, error) {
+// GetTotalValueByStage returns sum of deal values grouped by stage, optionally for one team
+func (r *dealRepository) GetTotalValueByStage(tenantID uint, teamID *uint, access model.RecordAccess) (map[uint]float64, error) {
 	type StageValue struct {
 		StageID    uint
 		TotalValue float64
 	}
 
 	var results []StageValue
-	err := r.db.Model(&model.Deal{}).
+	query := r.db.Model(&model.Deal{}).
 		Select("stage_id, SUM(value) as total_value").
-		Scopes(model.TenantScope(tenantID)).
-		Where("status = ?", "active").
-		Group("stage_id").
-		Scan(&results).Error
+		Scopes(model.TenantScope(tenantID), model.RecordVisibilityScope(access)).
+		Where("status = ?", "active")
+
+	if teamID != nil {
+		query = query.Where("team_id = ?", *teamID)
+	}
+
+	err := query.Group("stage_id").Scan(&results).Error
 
 	if err != nil {
 		return nil, err
@@ -219,13 +252,17 @@ type DealFilter struct {
 	StageID            *uint
 	Status             string
 	ContactID          *uint
+	TeamID             *uint
+	CompanyID          *uint
 	MinValue           *float64
 	MaxValue           *float64
 	ExpectedCloseStart *string
 	ExpectedCloseEnd   *string
 	Search             string
-	SortBy             string
+	CustomFields       []model.CustomFieldCondition
+	SortBy             string // Column, or model.CustomFieldPrefix + key
 	SortOrder          string
+	SortType           string // Type of the custom field in SortBy, set by the service
 	Limit              int
 	Offset             int
 }
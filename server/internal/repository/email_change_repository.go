@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"gin-quickstart/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type EmailChangeRepository interface {
+	Create(token *model.EmailChangeToken) error
+	FindByHash(tokenHash string) (*model.EmailChangeToken, error)
+	MarkUsed(id uint) error
+	InvalidateAllByUser(userID uint) error
+}
+
+type emailChangeRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailChangeRepository(db *gorm.DB) EmailChangeRepository {
+	return &emailChangeRepository{db: db}
+}
+
+func (r *emailChangeRepository) Create(token *model.EmailChangeToken) error {
+	return r.db.Create(token).Error
+}
+
+// FindByHash uses the unique token_hash index
+func (r *emailChangeRepository) FindByHash(tokenHash string) (*model.EmailChangeToken, error) {
+	var token model.EmailChangeToken
+	err := r.db.Where("token_hash = ?", tokenHash).First(&token).Error
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkUsed redeems the token. Returns gorm.ErrRecordNotFound if it was already used
+// (e.g. by a concurrent request).
+func (r *emailChangeRepository) MarkUsed(id uint) error {
+	result := r.db.Model(&model.EmailChangeToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// InvalidateAllByUser burns every outstanding email change link of the user
+func (r *emailChangeRepository) InvalidateAllByUser(userID uint) error {
+	return r.db.Model(&model.EmailChangeToken{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Update("used_at", time.Now()).Error
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"gin-quickstart/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type ImpersonationRepository interface {
+	Create(impersonation *model.Impersonation) error
+	FindByID(id uint) (*model.Impersonation, error)
+	FindAll(activeOnly bool, page, pageSize int) ([]model.Impersonation, int64, error)
+	End(id uint) error
+}
+
+type impersonationRepository struct {
+	db *gorm.DB
+}
+
+func NewImpersonationRepository(db *gorm.DB) ImpersonationRepository {
+	return &impersonationRepository{db: db}
+}
+
+func (r *impersonationRepository) Create(impersonation *model.Impersonation) error {
+	return r.db.Create(impersonation).Error
+}
+
+func (r *impersonationRepository) FindByID(id uint) (*model.Impersonation, error) {
+	var impersonation model.Impersonation
+	err := r.db.Preload("Operator").Preload("User").First(&impersonation, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &impersonation, nil
+}
+
+// FindAll pages through impersonations across all tenants, most recent first
+func (r *impersonationRepository) FindAll(activeOnly bool, page, pageSize int) ([]model.Impersonation, int64, error) {
+	var impersonations []model.Impersonation
+	var total int64
+
+	query := r.db.Model(&model.Impersonation{})
+	if activeOnly {
+		query = query.Where("ended_at IS NULL AND expires_at > ?", time.Now())
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	err := query.Scopes(model.Paginate(page, pageSize)).
+		Preload("Operator").
+		Preload("User").
+		Order("id DESC").
+		Find(&impersonations).Error
+
+	return impersonations, total, err
+}
+
+// End marks an active impersonation as ended. Returns gorm.ErrRecordNotFound if it
+// already ended.
+func (r *impersonationRepository) End(id uint) error {
+	return requireRow(r.db.Model(&model.Impersonation{}).
+		Where("id = ? AND ended_at IS NULL", id).
+		Update("ended_at", time.Now()))
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"gin-quickstart/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type InvitationRepository interface {
+	Create(invitation *model.Invitation) error
+	FindByID(tenantID, id uint) (*model.Invitation, error)
+	FindByTokenHash(tokenHash string) (*model.Invitation, error)
+	FindAll(tenantID uint, status string) ([]model.Invitation, error)
+	FindPendingByEmail(tenantID uint, email string) (*model.Invitation, error)
+	UpdateToken(tenantID, id uint, tokenHash string, expiresAt time.Time) error
+	Revoke(tenantID, id uint) error
+	Accept(invitation *model.Invitation, user *model.User) error
+}
+
+type invitationRepository struct {
+	db *gorm.DB
+}
+
+func NewInvitationRepository(db *gorm.DB) InvitationRepository {
+	return &invitationRepository{db: db}
+}
+
+func (r *invitationRepository) Create(invitation *model.Invitation) error {
+	return r.db.Create(invitation).Error
+}
+
+func (r *invitationRepository) FindByID(tenantID, id uint) (*model.Invitation, error) {
+	var invitation model.Invitation
+	err := r.db.Scopes(model.TenantScope(tenantID)).First(&invitation, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// FindByTokenHash loads an invitation with its tenant for the public accept flow
+func (r *invitationRepository) FindByTokenHash(tokenHash string) (*model.Invitation, error) {
+	var invitation model.Invitation
+	err := r.db.Preload("Tenant").
+		Where("token_hash = ?", tokenHash).
+		First(&invitation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (r *invitationRepository) FindAll(tenantID uint, status string) ([]model.Invitation, error) {
+	var invitations []model.Invitation
+	query := r.db.Scopes(model.TenantScope(tenantID), model.OrderByCreatedAt())
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	err := query.Find(&invitations).Error
+	return invitations, err
+}
+
+func (r *invitationRepository) FindPendingByEmail(tenantID uint, email string) (*model.Invitation, error) {
+	var invitation model.Invitation
+	err := r.db.Scopes(model.TenantScope(tenantID)).
+		Where("LOWER(email) = LOWER(?) AND status = ?", email, model.InvitationPending).
+		First(&invitation).Error
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// UpdateToken replaces the token of a pending invitation (resend)
+func (r *invitationRepository) UpdateToken(tenantID, id uint, tokenHash string, expiresAt time.Time) error {
+	return r.db.Model(&model.Invitation{}).
+		Where("tenant_id = ? AND id = ? AND status = ?", tenantID, id, model.InvitationPending).
+		Updates(map[string]interface{}{
+			"token_hash": tokenHash,
+			"expires_at": expiresAt,
+		}).Error
+}
+
+func (r *invitationRepository) Revoke(tenantID, id uint) error {
+	return r.db.Model(&model.Invitation{}).
+		Where("tenant_id = ? AND id = ? AND status = ?", tenantID, id, model.InvitationPending).
+		Update("status", model.InvitationRevoked).Error
+}
+
+// Accept creates the user if needed, adds them to the tenant and marks the invitation
+// accepted in one transaction. Returns gorm.ErrRecordNotFound if the invitation was
+// accepted or revoked concurrently.
+func (r *invitationRepository) Accept(invitation *model.Invitation, user *model.User) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&model.Invitation{}).
+			Where("id = ? AND status = ?", invitation.ID, model.InvitationPending).
+			Updates(map[string]interface{}{
+				"status":      model.InvitationAccepted,
+				"accepted_at": now,
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		if user.ID == 0 {
+			if err := tx.Create(user).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Create(&model.TenantUser{
+			TenantID: invitation.TenantID,
+			UserID:   user.ID,
+			Role:     invitation.Role,
+		}).Error; err != nil {
+			return err
+		}
+
+		invitation.Status = model.InvitationAccepted
+		invitation.AcceptedAt = &now
+		invitation.UserID = &user.ID
+		return tx.Model(&model.Invitation{}).
+			Where("id = ?", invitation.ID).
+			Update("user_id", user.ID).Error
+	})
+}
@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"gin-quickstart/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type MFARepository interface {
+	ReplaceRecoveryCodes(userID uint, codeHashes []string) error
+	UseRecoveryCode(userID uint, codeHash string) error
+	CountRecoveryCodes(userID uint) (int64, error)
+	DeleteRecoveryCodes(userID uint) error
+	CreateChallenge(challenge *model.MFAChallenge) error
+	FindChallengeByHash(tokenHash string) (*model.MFAChallenge, error)
+	ClaimChallengeAttempt(id uint, maxAttempts int) error
+	MarkChallengeUsed(id uint) error
+}
+
+type mfaRepository struct {
+	db *gorm.DB
+}
+
+func NewMFARepository(db *gorm.DB) MFARepository {
+	return &mfaRepository{db: db}
+}
+
+// ReplaceRecoveryCodes discards the user's existing codes and stores new ones
+func (r *mfaRepository) ReplaceRecoveryCodes(userID uint, codeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&model.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+
+		codes := make([]model.MFARecoveryCode, 0, len(codeHashes))
+		for _, hash := range codeHashes {
+			codes = append(codes, model.MFARecoveryCode{UserID: userID, CodeHash: hash})
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+// UseRecoveryCode redeems an unused code. Returns gorm.ErrRecordNotFound if no
+// unused code matches.
+func (r *mfaRepository) UseRecoveryCode(userID uint, codeHash string) error {
+	result := r.db.Model(&model.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *mfaRepository) CountRecoveryCodes(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.MFARecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *mfaRepository) DeleteRecoveryCodes(userID uint) error {
+	return r.db.Where("user_id = ?", userID).Delete(&model.MFARecoveryCode{}).Error
+}
+
+func (r *mfaRepository) CreateChallenge(challenge *model.MFAChallenge) error {
+	return r.db.Create(challenge).Error
+}
+
+// FindChallengeByHash uses the unique token_hash index
+func (r *mfaRepository) FindChallengeByHash(tokenHash string) (*model.MFAChallenge, error) {
+	var challenge model.MFAChallenge
+	err := r.db.Where("token_hash = ?", tokenHash).First(&challenge).Error
+	if err != nil {
+		return nil, err
+	}
+	return &challenge, nil
+}
+
+// ClaimChallengeAttempt counts an attempt at an unused challenge that has attempts left,
+// in one statement so parallel attempts cannot exceed maxAttempts. Returns
+// gorm.ErrRecordNotFound if the challenge has none left or was used.
+func (r *mfaRepository) ClaimChallengeAttempt(id uint, maxAttempts int) error {
+	result := r.db.Model(&model.MFAChallenge{}).
+		Where("id = ? AND attempts < ? AND used_at IS NULL", id, maxAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// MarkChallengeUsed consumes the challenge. Returns gorm.ErrRecordNotFound if it was
+// already used.
+func (r *mfaRepository) MarkChallengeUsed(id uint) error {
+	result := r.db.Model(&model.MFAChallenge{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
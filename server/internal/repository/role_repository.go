@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"gin-quickstart/internal/model"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RoleRepository interface {
+	SyncPermissionCatalog(permissions []model.Permission) error
+	GrantAllPermissionsToAdmins() error
+	FindAllPermissions() ([]model.Permission, error)
+	FindPermissionsByKeys(keys []string) ([]model.Permission, error)
+	Create(role *model.Role) error
+	FindByID(tenantID, id uint) (*model.Role, error)
+	FindByName(tenantID uint, name string) (*model.Role, error)
+	FindAll(tenantID uint) ([]model.Role, error)
+	CountByTenant(tenantID uint) (int64, error)
+	Update(role *model.Role, oldName string) error
+	Delete(tenantID, id uint) error
+	CountUsersWithRole(tenantID uint, name string) (int64, error)
+	CreateBuiltInRoles(tenantID uint) error
+}
+
+type roleRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) RoleRepository {
+	return &roleRepository{db: db}
+}
+
+// SyncPermissionCatalog inserts catalog entries that do not exist yet (run at startup)
+func (r *roleRepository) SyncPermissionCatalog(permissions []model.Permission) error {
+	catalog := make([]model.Permission, len(permissions))
+	copy(catalog, permissions)
+
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.AssignmentColumns([]string{"description"}),
+	}).Create(&catalog).Error
+}
+
+// GrantAllPermissionsToAdmins gives seeded admin roles any catalog permission added since they were created
+func (r *roleRepository) GrantAllPermissionsToAdmins() error {
+	return r.db.Exec(`
+		INSERT INTO role_permissions (role_id, permission_id)
+		SELECT roles.id, permissions.id FROM roles CROSS JOIN permissions
+		WHERE roles.is_built_in = ? AND roles.name = ? AND roles.deleted_at IS NULL
+		ON CONFLICT DO NOTHING`, true, model.RoleAdmin).Error
+}
+
+func (r *roleRepository) FindAllPermissions() ([]model.Permission, error) {
+	var permissions []model.Permission
+	err := r.db.Order("key ASC").Find(&permissions).Error
+	return permissions, err
+}
+
+func (r *roleRepository) FindPermissionsByKeys(keys []string) ([]model.Permission, error) {
+	var permissions []model.Permission
+	if len(keys) == 0 {
+		return permissions, nil
+	}
+	err := r.db.Where("key IN ?", keys).Find(&permissions).Error
+	return permissions, err
+}
+
+func (r *roleRepository) Create(role *model.Role) error {
+	return r.db.Create(role).Error
+}
+
+func (r *roleRepository) FindByID(tenantID, id uint) (*model.Role, error) {
+	var role model.Role
+	err := r.db.Scopes(model.TenantScope(tenantID)).
+		Preload("Permissions").
+		First(&role, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// FindByName uses the composite (tenant_id, name) index
+func (r *roleRepository) FindByName(tenantID uint, name string) (*model.Role, error) {
+	var role model.Role
+	err := r.db.Scopes(model.TenantScope(tenantID)).
+		Preload("Permissions").
+		Where("name = ?", name).
+		First(&role).Error
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+func (r *roleRepository) FindAll(tenantID uint) ([]model.Role, error) {
+	var roles []model.Role
+	err := r.db.Scopes(model.TenantScope(tenantID)).
+		Preload("Permissions").
+		Order("is_built_in DESC, name ASC").
+		Find(&roles).Error
+	return roles, err
+}
+
+func (r *roleRepository) CountByTenant(tenantID uint) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.Role{}).
+		Scopes(model.TenantScope(tenantID)).
+		Count(&count).Error
+	return count, err
+}
+
+// Update saves role fields, replaces its permissions and carries a rename over to
+// tenant users holding the role, all in one transaction
+func (r *roleRepository) Update(role *model.Role, oldName string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Role{}).
+			Where("tenant_id = ? AND id = ?", role.TenantID, role.ID).
+			Updates(map[string]interface{}{
+				"name":        role.Name,
+				"description": role.Description,
+			}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(role).Association("Permissions").Replace(role.Permissions); err != nil {
+			return err
+		}
+
+		if oldName != role.Name {
+			return tx.Model(&model.TenantUser{}).
+				Where("tenant_id = ? AND role = ?", role.TenantID, oldName).
+				Update("role", role.Name).Error
+		}
+		return nil
+	})
+}
+
+func (r *roleRepository) Delete(tenantID, id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		role := model.Role{ID: id}
+		if err := tx.Model(&role).Association("Permissions").Clear(); err != nil {
+			return err
+		}
+		return tx.Scopes(model.TenantScope(tenantID)).
+			Delete(&model.Role{}, id).Error
+	})
+}
+
+func (r *roleRepository) CountUsersWithRole(tenantID uint, name string) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.TenantUser{}).
+		Where("tenant_id = ? AND role = ?", tenantID, name).
+		Count(&count).Error
+	return count, err
+}
+
+// CreateBuiltInRoles seeds admin, manager and member for a tenant
+func (r *roleRepository) CreateBuiltInRoles(tenantID uint) error {
+	descriptions := map[string]string{
+		model.RoleAdmin:   "Full access to all tenant operations",
+		model.RoleManager: "Can view users and manage CRM data",
+		model.RoleMember:  "Can manage CRM data",
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, name := range []string{model.RoleAdmin, model.RoleManager, model.RoleMember} {
+			var permissions []model.Permission
+			if err := tx.Where("key IN ?", model.BuiltInRolePermissions[name]).
+				Find(&permissions).Error; err != nil {
+				return err
+			}
+
+			role := model.Role{
+				TenantID:    tenantID,
+				Name:        name,
+				Description: descriptions[name],
+				IsBuiltIn:   true,
+				Permissions: permissions,
+			}
+			if err := tx.Create(&role).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"gin-quickstart/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type SecurityEventRepository interface {
+	Create(event *model.SecurityEvent) error
+}
+
+type securityEventRepository struct {
+	db *gorm.DB
+}
+
+func NewSecurityEventRepository(db *gorm.DB) SecurityEventRepository {
+	return &securityEventRepository{db: db}
+}
+
+func (r *securityEventRepository) Create(event *model.SecurityEvent) error {
+	return r.db.Create(event).Error
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"gin-quickstart/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SessionRepository interface {
+	Create(session *model.Session) error
+	FindByID(id uint) (*model.Session, error)
+	FindActiveByUser(userID uint) ([]model.Session, error)
+	Touch(id uint, ipAddress, userAgent string) error
+	Extend(id uint, expiresAt time.Time) error
+	UpdateTenant(id, tenantID uint) error
+	Revoke(id uint, revokedBy *uint) error
+	RevokeAllByUser(userID uint) error
+	RevokeByTenantAndUser(tenantID, userID uint, revokedBy *uint) (int64, error)
+}
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+func NewSessionRepository(db *gorm.DB) SessionRepository {
+	return &sessionRepository{db: db}
+}
+
+func (r *sessionRepository) Create(session *model.Session) error {
+	return r.db.Create(session).Error
+}
+
+func (r *sessionRepository) FindByID(id uint) (*model.Session, error) {
+	var session model.Session
+	err := r.db.First(&session, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// FindActiveByUser returns unrevoked, unexpired sessions, most recently used first
+func (r *sessionRepository) FindActiveByUser(userID uint) ([]model.Session, error) {
+	var sessions []model.Session
+	err := r.db.Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+// Touch records activity from the session's device
+func (r *sessionRepository) Touch(id uint, ipAddress, userAgent string) error {
+	return r.db.Model(&model.Session{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_seen_at": time.Now(),
+		"ip_address":   ipAddress,
+		"user_agent":   userAgent,
+	}).Error
+}
+
+// Extend moves the expiry along with a newly issued refresh token
+func (r *sessionRepository) Extend(id uint, expiresAt time.Time) error {
+	return r.db.Model(&model.Session{}).Where("id = ?", id).Update("expires_at", expiresAt).Error
+}
+
+func (r *sessionRepository) UpdateTenant(id, tenantID uint) error {
+	return r.db.Model(&model.Session{}).Where("id = ?", id).Update("tenant_id", tenantID).Error
+}
+
+// Revoke ends a session and revokes its refresh tokens
+func (r *sessionRepository) Revoke(id uint, revokedBy *uint) error {
+	_, err := r.revoke(revokedBy, "id = ?", id)
+	return err
+}
+
+// RevokeAllByUser ends every session of a user
+func (r *sessionRepository) RevokeAllByUser(userID uint) error {
+	_, err := r.revoke(nil, "user_id = ?", userID)
+	return err
+}
+
+// RevokeByTenantAndUser ends the user's sessions signed in to a tenant and returns how
+// many were active
+func (r *sessionRepository) RevokeByTenantAndUser(tenantID, userID uint, revokedBy *uint) (int64, error) {
+	return r.revoke(revokedBy, "tenant_id = ? AND user_id = ?", tenantID, userID)
+}
+
+// revoke marks the matching unrevoked sessions and their refresh tokens as revoked and
+// returns the number of sessions ended
+func (r *sessionRepository) revoke(revokedBy *uint, query string, args ...interface{}) (int64, error) {
+	now := time.Now()
+	var ids []uint
+
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Session{}).Where(query, args...).Where("revoked_at IS NULL").Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		if err := tx.Model(&model.Session{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"revoked_at": now,
+			"revoked_by": revokedBy,
+		}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&model.RefreshToken{}).
+			Where("session_id IN ? AND revoked_at IS NULL", ids).
+			Update("revoked_at", now).Error
+	})
+	if err != nil {
+		return 0, err
+	}
+	return int64(len(ids)), nil
+}
@@ -0,0 +1,120 @@
+package repository
+
+import (
+	"gin-quickstart/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SSORepository interface {
+	FindConfig(tenantID uint) (*model.TenantSSOConfig, error)
+	SaveConfig(config *model.TenantSSOConfig) error
+	DeleteConfig(tenantID uint) error
+	FindIdentity(issuer, subject string) (*model.UserIdentity, error)
+	CreateIdentity(identity *model.UserIdentity) error
+	CreateLogin(login *model.SSOLogin) error
+	FindLoginByState(stateHash string) (*model.SSOLogin, error)
+	FindLoginByExchangeCode(codeHash string) (*model.SSOLogin, error)
+	CompleteLogin(id, userID uint, exchangeCodeHash string, expiresAt time.Time) error
+	MarkLoginExchanged(id uint) error
+}
+
+type ssoRepository struct {
+	db *gorm.DB
+}
+
+func NewSSORepository(db *gorm.DB) SSORepository {
+	return &ssoRepository{db: db}
+}
+
+func (r *ssoRepository) FindConfig(tenantID uint) (*model.TenantSSOConfig, error) {
+	var config model.TenantSSOConfig
+	err := r.db.Scopes(model.TenantScope(tenantID)).First(&config).Error
+	if err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+// SaveConfig creates or updates the tenant's configuration
+func (r *ssoRepository) SaveConfig(config *model.TenantSSOConfig) error {
+	return r.db.Save(config).Error
+}
+
+func (r *ssoRepository) DeleteConfig(tenantID uint) error {
+	return r.db.Unscoped().
+		Where("tenant_id = ?", tenantID).
+		Delete(&model.TenantSSOConfig{}).Error
+}
+
+// FindIdentity uses the unique (issuer, subject) index
+func (r *ssoRepository) FindIdentity(issuer, subject string) (*model.UserIdentity, error) {
+	var identity model.UserIdentity
+	err := r.db.Where("issuer = ? AND subject = ?", issuer, subject).First(&identity).Error
+	if err != nil {
+		return nil, err
+	}
+	return &identity, nil
+}
+
+func (r *ssoRepository) CreateIdentity(identity *model.UserIdentity) error {
+	return r.db.Create(identity).Error
+}
+
+func (r *ssoRepository) CreateLogin(login *model.SSOLogin) error {
+	return r.db.Create(login).Error
+}
+
+func (r *ssoRepository) FindLoginByState(stateHash string) (*model.SSOLogin, error) {
+	var login model.SSOLogin
+	err := r.db.Where("state_hash = ?", stateHash).First(&login).Error
+	if err != nil {
+		return nil, err
+	}
+	return &login, nil
+}
+
+func (r *ssoRepository) FindLoginByExchangeCode(codeHash string) (*model.SSOLogin, error) {
+	var login model.SSOLogin
+	err := r.db.Where("exchange_code_hash = ?", codeHash).First(&login).Error
+	if err != nil {
+		return nil, err
+	}
+	return &login, nil
+}
+
+// CompleteLogin records the authenticated user once. Returns gorm.ErrRecordNotFound if
+// the callback was already processed.
+func (r *ssoRepository) CompleteLogin(id, userID uint, exchangeCodeHash string, expiresAt time.Time) error {
+	result := r.db.Model(&model.SSOLogin{}).
+		Where("id = ? AND completed_at IS NULL", id).
+		Updates(map[string]interface{}{
+			"user_id":            userID,
+			"exchange_code_hash": exchangeCodeHash,
+			"expires_at":         expiresAt,
+			"completed_at":       time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// MarkLoginExchanged consumes the one-time code. Returns gorm.ErrRecordNotFound if it
+// was already used.
+func (r *ssoRepository) MarkLoginExchanged(id uint) error {
+	result := r.db.Model(&model.SSOLogin{}).
+		Where("id = ? AND exchanged_at IS NULL", id).
+		Update("exchanged_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"gin-quickstart/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type TeamRepository interface {
+	Create(team *model.Team) error
+	FindByID(tenantID, id uint) (*model.Team, error)
+	FindByName(tenantID uint, name string) (*model.Team, error)
+	FindAll(tenantID uint) ([]model.Team, error)
+	Update(team *model.Team) error
+	Delete(tenantID, id uint) error
+	FindVisibleTeamIDs(tenantID, userID uint) ([]uint, error)
+	FindManagedTeamIDs(tenantID, managerID uint) ([]uint, error)
+	FindManagedMemberIDs(tenantID, managerID uint) ([]uint, error)
+	RemoveUserFromTeams(tenantID, userID uint) error
+}
+
+type teamRepository struct {
+	db *gorm.DB
+}
+
+func NewTeamRepository(db *gorm.DB) TeamRepository {
+	return &teamRepository{db: db}
+}
+
+func (r *teamRepository) Create(team *model.Team) error {
+	return r.db.Create(team).Error
+}
+
+func (r *teamRepository) FindByID(tenantID, id uint) (*model.Team, error) {
+	var team model.Team
+	err := r.db.Scopes(model.TenantScope(tenantID)).
+		Preload("Manager").
+		Preload("Members").
+		First(&team, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+// FindByName uses the composite (tenant_id, name) index
+func (r *teamRepository) FindByName(tenantID uint, name string) (*model.Team, error) {
+	var team model.Team
+	err := r.db.Scopes(model.TenantScope(tenantID)).
+		Where("name = ?", name).
+		First(&team).Error
+	if err != nil {
+		return nil, err
+	}
+	return &team, nil
+}
+
+func (r *teamRepository) FindAll(tenantID uint) ([]model.Team, error) {
+	var teams []model.Team
+	err := r.db.Scopes(model.TenantScope(tenantID)).
+		Preload("Manager").
+		Preload("Members").
+		Order("name ASC").
+		Find(&teams).Error
+	return teams, err
+}
+
+// Update saves team fields and replaces its members in one transaction
+func (r *teamRepository) Update(team *model.Team) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&model.Team{}).
+			Where("tenant_id = ? AND id = ?", team.TenantID, team.ID).
+			Updates(map[string]interface{}{
+				"name":        team.Name,
+				"description": team.Description,
+				"manager_id":  team.ManagerID,
+			}).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(team).Association("Members").Replace(team.Members)
+	})
+}
+
+// Delete removes the team, its memberships and its assignment on contacts and deals
+func (r *teamRepository) Delete(tenantID, id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		team := model.Team{ID: id}
+		if err := tx.Model(&team).Association("Members").Clear(); err != nil {
+			return err
+		}
+
+		for _, record := range []interface{}{&model.Contact{}, &model.Deal{}} {
+			if err := tx.Model(record).
+				Where("tenant_id = ? AND team_id = ?", tenantID, id).
+				Update("team_id", nil).Error; err != nil {
+				return err
+			}
+		}
+
+		return tx.Scopes(model.TenantScope(tenantID)).
+			Delete(&model.Team{}, id).Error
+	})
+}
+
+// FindVisibleTeamIDs returns the teams a user belongs to or manages
+func (r *teamRepository) FindVisibleTeamIDs(tenantID, userID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&model.Team{}).
+		Scopes(model.TenantScope(tenantID)).
+		Where("manager_id = ? OR id IN (?)", userID,
+			r.db.Table("team_members").Select("team_id").Where("user_id = ?", userID)).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// FindManagedTeamIDs returns the teams the user manages
+func (r *teamRepository) FindManagedTeamIDs(tenantID, managerID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Model(&model.Team{}).
+		Scopes(model.TenantScope(tenantID)).
+		Where("manager_id = ?", managerID).
+		Pluck("id", &ids).Error
+	return ids, err
+}
+
+// FindManagedMemberIDs returns the members of every team the user manages
+func (r *teamRepository) FindManagedMemberIDs(tenantID, managerID uint) ([]uint, error) {
+	var ids []uint
+	err := r.db.Table("team_members").
+		Joins("JOIN teams ON teams.id = team_members.team_id").
+		Where("teams.tenant_id = ? AND teams.manager_id = ? AND teams.deleted_at IS NULL", tenantID, managerID).
+		Distinct().
+		Pluck("team_members.user_id", &ids).Error
+	return ids, err
+}
+
+// RemoveUserFromTeams drops a user's memberships and manager roles in a tenant
+func (r *teamRepository) RemoveUserFromTeams(tenantID, userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`
+			DELETE FROM team_members
+			WHERE user_id = ? AND team_id IN (SELECT id FROM teams WHERE tenant_id = ?)`,
+			userID, tenantID).Error; err != nil {
+			return err
+		}
+
+		return tx.Model(&model.Team{}).
+			Where("tenant_id = ? AND manager_id = ?", tenantID, userID).
+			Update("manager_id", nil).Error
+	})
+}
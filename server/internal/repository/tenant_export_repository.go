@@ -0,0 +1,234 @@
+package repository
+
+import (
+	"errors"
+	"gin-quickstart/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// exportDataset is one file pair (JSON and CSV) of a tenant export. Each query takes the
+// tenant ID as its only parameter. Credentials (password hashes, TOTP secrets, token and
+// API key hashes) are never exported.
+type exportDataset struct {
+	name  string
+	query string
+}
+
+var exportDatasets = []exportDataset{
+	{"users", `SELECT u.id, u.email, u.full_name, u.phone, u.locale, u.timezone, u.is_active, u.mfa_enabled,
+		tu.role, tu.created_at AS joined_at
+		FROM tenant_users tu JOIN users u ON u.id = tu.user_id
+		WHERE tu.tenant_id = ? AND tu.deleted_at IS NULL ORDER BY u.id`},
+	{"roles", `SELECT r.id, r.name, r.description, r.is_built_in, r.created_at, r.updated_at,
+		COALESCE(string_agg(p.key, ' ' ORDER BY p.key), '') AS permissions
+		FROM roles r
+		LEFT JOIN role_permissions rp ON rp.role_id = r.id
+		LEFT JOIN permissions p ON p.id = rp.permission_id
+		WHERE r.tenant_id = ? AND r.deleted_at IS NULL GROUP BY r.id ORDER BY r.id`},
+	{"teams", `SELECT id, name, description, manager_id, created_at, updated_at
+		FROM teams WHERE tenant_id = ? AND deleted_at IS NULL ORDER BY id`},
+	{"team_members", `SELECT tm.team_id, tm.user_id
+		FROM team_members tm JOIN teams t ON t.id = tm.team_id
+		WHERE t.tenant_id = ? AND t.deleted_at IS NULL ORDER BY tm.team_id, tm.user_id`},
+	{"settings", `SELECT key, value, updated_at
+		FROM tenant_settings WHERE tenant_id = ? AND deleted_at IS NULL ORDER BY key`},
+	{"pipeline_stages", `SELECT * FROM pipeline_stages WHERE tenant_id = ? AND deleted_at IS NULL ORDER BY id`},
+	{"custom_fields", `SELECT * FROM custom_field_definitions WHERE tenant_id = ? ORDER BY entity, position, id`},
+	{"companies", `SELECT * FROM companies WHERE tenant_id = ? AND deleted_at IS NULL ORDER BY id`},
+	{"contacts", `SELECT * FROM contacts WHERE tenant_id = ? AND deleted_at IS NULL ORDER BY id`},
+	{"deals", `SELECT * FROM deals WHERE tenant_id = ? AND deleted_at IS NULL ORDER BY id`},
+	{"audit_logs", `SELECT * FROM audit_logs WHERE tenant_id = ? ORDER BY id`},
+}
+
+type TenantExportRepository interface {
+	Create(export *model.TenantExport) error
+	FindByID(id uint) (*model.TenantExport, error)
+	FindByTenant(tenantID uint, limit int) ([]model.TenantExport, error)
+	FindInProgress(tenantID uint) (*model.TenantExport, error)
+	FindPending(limit int) ([]model.TenantExport, error)
+	FindExpired(limit int) ([]model.TenantExport, error)
+	Claim(id uint) error
+	Complete(id uint, blobKey string, sizeBytes int64, expiresAt time.Time) error
+	Fail(id uint, message string) error
+	FailStale(startedBefore time.Time) (int64, error)
+	MarkExpired(id uint) error
+
+	Datasets() []string
+	ReadDataset(tenantID uint, dataset string, fn func(columns []string, values []interface{}) error) error
+	Snapshot(fn func(repo TenantExportRepository) error) error
+}
+
+type tenantExportRepository struct {
+	db *gorm.DB
+}
+
+func NewTenantExportRepository(db *gorm.DB) TenantExportRepository {
+	return &tenantExportRepository{db: db}
+}
+
+func (r *tenantExportRepository) Create(export *model.TenantExport) error {
+	return r.db.Create(export).Error
+}
+
+func (r *tenantExportRepository) FindByID(id uint) (*model.TenantExport, error) {
+	var export model.TenantExport
+	err := r.db.First(&export, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// FindByTenant returns the tenant's most recent exports
+func (r *tenantExportRepository) FindByTenant(tenantID uint, limit int) ([]model.TenantExport, error) {
+	var exports []model.TenantExport
+	err := r.db.Scopes(model.TenantScope(tenantID)).
+		Order("id DESC").
+		Limit(limit).
+		Find(&exports).Error
+	return exports, err
+}
+
+// FindInProgress returns the tenant's pending or running export
+func (r *tenantExportRepository) FindInProgress(tenantID uint) (*model.TenantExport, error) {
+	var export model.TenantExport
+	err := r.db.Scopes(model.TenantScope(tenantID)).
+		Where("status IN ?", []string{model.ExportPending, model.ExportRunning}).
+		First(&export).Error
+	if err != nil {
+		return nil, err
+	}
+	return &export, nil
+}
+
+// FindPending returns exports waiting for a worker, oldest first
+func (r *tenantExportRepository) FindPending(limit int) ([]model.TenantExport, error) {
+	var exports []model.TenantExport
+	err := r.db.Where("status = ?", model.ExportPending).
+		Order("id").
+		Limit(limit).
+		Find(&exports).Error
+	return exports, err
+}
+
+// FindExpired returns completed exports past their retention period
+func (r *tenantExportRepository) FindExpired(limit int) ([]model.TenantExport, error) {
+	var exports []model.TenantExport
+	err := r.db.Where("status = ? AND expires_at <= ?", model.ExportCompleted, time.Now()).
+		Order("expires_at").
+		Limit(limit).
+		Find(&exports).Error
+	return exports, err
+}
+
+// Claim moves a pending export to running; only one worker can claim an export
+func (r *tenantExportRepository) Claim(id uint) error {
+	return requireRow(r.db.Model(&model.TenantExport{}).
+		Where("id = ? AND status = ?", id, model.ExportPending).
+		Updates(map[string]interface{}{
+			"status":     model.ExportRunning,
+			"started_at": time.Now(),
+		}))
+}
+
+func (r *tenantExportRepository) Complete(id uint, blobKey string, sizeBytes int64, expiresAt time.Time) error {
+	return r.db.Model(&model.TenantExport{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       model.ExportCompleted,
+		"blob_key":     blobKey,
+		"size_bytes":   sizeBytes,
+		"completed_at": time.Now(),
+		"expires_at":   expiresAt,
+	}).Error
+}
+
+func (r *tenantExportRepository) Fail(id uint, message string) error {
+	return r.db.Model(&model.TenantExport{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":       model.ExportFailed,
+		"error":        message,
+		"completed_at": time.Now(),
+	}).Error
+}
+
+// FailStale fails running exports started before the given time, whose worker was
+// stopped (e.g. by a restart), and returns how many were failed
+func (r *tenantExportRepository) FailStale(startedBefore time.Time) (int64, error) {
+	result := r.db.Model(&model.TenantExport{}).
+		Where("status = ? AND started_at < ?", model.ExportRunning, startedBefore).
+		Updates(map[string]interface{}{
+			"status":       model.ExportFailed,
+			"error":        "export was interrupted",
+			"completed_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// MarkExpired records that the export's archive has been deleted
+func (r *tenantExportRepository) MarkExpired(id uint) error {
+	return r.db.Model(&model.TenantExport{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":   model.ExportExpired,
+		"blob_key": "",
+	}).Error
+}
+
+// Datasets returns the dataset names in export order
+func (r *tenantExportRepository) Datasets() []string {
+	names := make([]string, 0, len(exportDatasets))
+	for _, d := range exportDatasets {
+		names = append(names, d.name)
+	}
+	return names
+}
+
+// ReadDataset streams the rows of a dataset to fn without loading them all into memory.
+// values are only valid during the call.
+func (r *tenantExportRepository) ReadDataset(tenantID uint, dataset string, fn func(columns []string, values []interface{}) error) error {
+	var query string
+	for _, d := range exportDatasets {
+		if d.name == dataset {
+			query = d.query
+		}
+	}
+	if query == "" {
+		return errors.New("unknown export dataset " + dataset)
+	}
+
+	rows, err := r.db.Raw(query, tenantID).Rows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	values := make([]interface{}, len(columns))
+	pointers := make([]interface{}, len(columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	for rows.Next() {
+		if err := rows.Scan(pointers...); err != nil {
+			return err
+		}
+		if err := fn(columns, values); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// Snapshot runs fn in a read-only repeatable read transaction, so that every dataset of
+// an export reflects the same point in time
+func (r *tenantExportRepository) Snapshot(fn func(repo TenantExportRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SET TRANSACTION ISOLATION LEVEL REPEATABLE READ, READ ONLY").Error; err != nil {
+			return err
+		}
+		return fn(&tenantExportRepository{db: tx})
+	})
+}
@@ -0,0 +1,204 @@
+package repository
+
+import (
+	"gin-quickstart/internal/model"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ImportTx holds the CRM repositories bound to one import transaction
+type ImportTx struct {
+	Stages       PipelineStageRepository
+	CustomFields CustomFieldRepository
+	Companies    CompanyRepository
+	Contacts     ContactRepository
+	Deals        DealRepository
+	Imports      TenantImportRepository
+}
+
+type TenantImportRepository interface {
+	Create(imp *model.TenantImport) error
+	FindByID(id uint) (*model.TenantImport, error)
+	FindByTenant(tenantID uint, limit int) ([]model.TenantImport, error)
+	FindInProgress(tenantID uint) (*model.TenantImport, error)
+	FindPending(limit int) ([]model.TenantImport, error)
+	Claim(id uint) error
+	Complete(id uint, result *model.ImportResult) error
+	Fail(id uint, message string, result *model.ImportResult) error
+	FailStale(startedBefore time.Time) (int64, error)
+
+	MemberIDsByEmail(tenantID uint) (map[string]uint, error)
+	ContactIDsByEmail(tenantID uint) (map[string]uint, error)
+	Transaction(fn func(tx ImportTx) error) error
+}
+
+type tenantImportRepository struct {
+	db *gorm.DB
+}
+
+func NewTenantImportRepository(db *gorm.DB) TenantImportRepository {
+	return &tenantImportRepository{db: db}
+}
+
+func (r *tenantImportRepository) Create(imp *model.TenantImport) error {
+	return r.db.Create(imp).Error
+}
+
+func (r *tenantImportRepository) FindByID(id uint) (*model.TenantImport, error) {
+	var imp model.TenantImport
+	err := r.db.First(&imp, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &imp, nil
+}
+
+// FindByTenant returns the tenant's most recent imports
+func (r *tenantImportRepository) FindByTenant(tenantID uint, limit int) ([]model.TenantImport, error) {
+	var imports []model.TenantImport
+	err := r.db.Scopes(model.TenantScope(tenantID)).
+		Order("id DESC").
+		Limit(limit).
+		Find(&imports).Error
+	return imports, err
+}
+
+// FindInProgress returns the tenant's pending or running import
+func (r *tenantImportRepository) FindInProgress(tenantID uint) (*model.TenantImport, error) {
+	var imp model.TenantImport
+	err := r.db.Scopes(model.TenantScope(tenantID)).
+		Where("status IN ?", []string{model.ImportPending, model.ImportRunning}).
+		First(&imp).Error
+	if err != nil {
+		return nil, err
+	}
+	return &imp, nil
+}
+
+// FindPending returns imports waiting for a worker, oldest first
+func (r *tenantImportRepository) FindPending(limit int) ([]model.TenantImport, error) {
+	var imports []model.TenantImport
+	err := r.db.Where("status = ?", model.ImportPending).
+		Order("id").
+		Limit(limit).
+		Find(&imports).Error
+	return imports, err
+}
+
+// Claim moves a pending import to running; only one worker can claim an import
+func (r *tenantImportRepository) Claim(id uint) error {
+	return requireRow(r.db.Model(&model.TenantImport{}).
+		Where("id = ? AND status = ?", id, model.ImportPending).
+		Updates(map[string]interface{}{
+			"status":     model.ImportRunning,
+			"started_at": time.Now(),
+		}))
+}
+
+// Complete records the result and forgets the deleted archive. Struct updates apply the
+// JSON serializer of Result.
+func (r *tenantImportRepository) Complete(id uint, result *model.ImportResult) error {
+	return r.db.Model(&model.TenantImport{ID: id}).Select("Status", "Result", "BlobKey", "CompletedAt").Updates(&model.TenantImport{
+		Status:      model.ImportCompleted,
+		Result:      result,
+		CompletedAt: timePtr(time.Now()),
+	}).Error
+}
+
+// Fail records the error and, when the archive was readable, how far the import got
+// before it was rolled back
+func (r *tenantImportRepository) Fail(id uint, message string, result *model.ImportResult) error {
+	return r.db.Model(&model.TenantImport{ID: id}).Select("Status", "Error", "Result", "BlobKey", "CompletedAt").Updates(&model.TenantImport{
+		Status:      model.ImportFailed,
+		Error:       message,
+		Result:      result,
+		CompletedAt: timePtr(time.Now()),
+	}).Error
+}
+
+// FailStale fails running imports started before the given time, whose worker was
+// stopped (e.g. by a restart), and returns how many were failed. Their transaction was
+// rolled back with the connection.
+func (r *tenantImportRepository) FailStale(startedBefore time.Time) (int64, error) {
+	result := r.db.Model(&model.TenantImport{}).
+		Where("status = ? AND started_at < ?", model.ImportRunning, startedBefore).
+		Updates(map[string]interface{}{
+			"status":       model.ImportFailed,
+			"error":        "import was interrupted",
+			"completed_at": time.Now(),
+		})
+	return result.RowsAffected, result.Error
+}
+
+// MemberIDsByEmail maps the lowercased email of every tenant member to their user ID
+func (r *tenantImportRepository) MemberIDsByEmail(tenantID uint) (map[string]uint, error) {
+	return memberIDsByEmail(r.db, tenantID)
+}
+
+func memberIDsByEmail(db *gorm.DB, tenantID uint) (map[string]uint, error) {
+	var rows []struct {
+		ID    uint
+		Email string
+	}
+	err := db.Table("tenant_users").
+		Select("users.id, users.email").
+		Joins("JOIN users ON users.id = tenant_users.user_id").
+		Where("tenant_users.tenant_id = ? AND tenant_users.deleted_at IS NULL AND users.deleted_at IS NULL", tenantID).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]uint, len(rows))
+	for _, row := range rows {
+		ids[strings.ToLower(row.Email)] = row.ID
+	}
+	return ids, nil
+}
+
+// ContactIDsByEmail maps the lowercased email of the tenant's contacts to their ID
+func (r *tenantImportRepository) ContactIDsByEmail(tenantID uint) (map[string]uint, error) {
+	var rows []struct {
+		ID    uint
+		Email string
+	}
+	err := r.db.Model(&model.Contact{}).
+		Scopes(model.TenantScope(tenantID)).
+		Select("id, email").
+		Where("email <> ''").
+		Order("id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make(map[string]uint, len(rows))
+	for _, row := range rows {
+		email := strings.ToLower(row.Email)
+		if _, exists := ids[email]; !exists {
+			ids[email] = row.ID
+		}
+	}
+	return ids, nil
+}
+
+// Transaction runs fn with repositories bound to one transaction; an error from fn rolls
+// everything back
+func (r *tenantImportRepository) Transaction(fn func(tx ImportTx) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(ImportTx{
+			Stages:       NewPipelineStageRepository(tx),
+			CustomFields: NewCustomFieldRepository(tx),
+			Companies:    NewCompanyRepository(tx),
+			Contacts:     NewContactRepository(tx),
+			Deals:        NewDealRepository(tx),
+			Imports:      &tenantImportRepository{db: tx},
+		})
+	})
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -2,6 +2,8 @@ package repository
 
 import (
 	"gin-quickstart/internal/model"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -9,8 +11,16 @@ import (
 type TenantRepository interface {
 	Create(tenant *model.Tenant) error
 	FindByID(id uint) (*model.Tenant, error)
-	FindAll(page, pageSize int) ([]model.Tenant, int64, error)
+	Search(search, status string, page, pageSize int) ([]model.Tenant, int64, error)
+	CountUsage(tenantIDs []uint) (map[uint]*model.TenantUsage, error)
 	Update(tenant *model.Tenant) error
+	SetOwner(id, ownerID uint, currentOwnerID *uint) error
+	AssignMissingOwners() (int64, error)
+	SetStatus(id uint, status string) error
+	ScheduleDeletion(id, requestedBy uint, purgeAfter time.Time) error
+	CancelDeletion(id uint) error
+	FindDueForPurge(limit int) ([]model.Tenant, error)
+	ClaimForPurge(id uint) error
 	Delete(id uint) error
 }
 
@@ -35,27 +45,174 @@ func (r *tenantRepository) FindByID(id uint) (*model.Tenant, error) {
 	return &tenant, nil
 }
 
-// FindAll with efficient pagination
-func (r *tenantRepository) FindAll(page, pageSize int) ([]model.Tenant, int64, error) {
+// Search pages through all tenants, optionally matching the name or the owner's email
+// and filtering by status
+func (r *tenantRepository) Search(search, status string, page, pageSize int) ([]model.Tenant, int64, error) {
 	var tenants []model.Tenant
 	var total int64
 
-	// Count total (optimized with indexed fields)
-	if err := r.db.Model(&model.Tenant{}).Count(&total).Error; err != nil {
+	query := r.db.Model(&model.Tenant{})
+
+	if search != "" {
+		searchPattern := "%" + strings.ToLower(search) + "%"
+		query = query.Where(
+			"LOWER(tenants.name) LIKE ? OR EXISTS (SELECT 1 FROM users WHERE users.id = tenants.owner_id AND LOWER(users.email) LIKE ?)",
+			searchPattern, searchPattern,
+		)
+	}
+
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
 		return nil, 0, err
 	}
 
-	// Get paginated results
-	err := r.db.Scopes(model.Paginate(page, pageSize), model.OrderByCreatedAt()).
+	err := query.Scopes(model.Paginate(page, pageSize), model.OrderByCreatedAt()).
 		Find(&tenants).Error
 
 	return tenants, total, err
 }
 
+// CountUsage counts the active members, contacts and deals of the given tenants. Every
+// requested tenant has an entry, zero counts included.
+func (r *tenantRepository) CountUsage(tenantIDs []uint) (map[uint]*model.TenantUsage, error) {
+	usage := make(map[uint]*model.TenantUsage, len(tenantIDs))
+	for _, id := range tenantIDs {
+		usage[id] = &model.TenantUsage{}
+	}
+	if len(tenantIDs) == 0 {
+		return usage, nil
+	}
+
+	counters := []struct {
+		table string
+		count func(u *model.TenantUsage) *int64
+	}{
+		{"tenant_users", func(u *model.TenantUsage) *int64 { return &u.Users }},
+		{"contacts", func(u *model.TenantUsage) *int64 { return &u.Contacts }},
+		{"deals", func(u *model.TenantUsage) *int64 { return &u.Deals }},
+	}
+
+	for _, counter := range counters {
+		var rows []struct {
+			TenantID uint
+			Count    int64
+		}
+		err := r.db.Table(counter.table).
+			Select("tenant_id, COUNT(*) AS count").
+			Where("tenant_id IN ? AND deleted_at IS NULL", tenantIDs).
+			Group("tenant_id").
+			Scan(&rows).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, row := range rows {
+			*counter.count(usage[row.TenantID]) = row.Count
+		}
+	}
+
+	return usage, nil
+}
+
+// Update saves the tenant's fields except its owner, which only changes through SetOwner
 func (r *tenantRepository) Update(tenant *model.Tenant) error {
-	return r.db.Save(tenant).Error
+	return r.db.Omit("OwnerID").Save(tenant).Error
+}
+
+// SetOwner moves the owner designation if the tenant is still owned by currentOwnerID
+// (nil: has no owner). Returns gorm.ErrRecordNotFound if it changed in the meantime.
+func (r *tenantRepository) SetOwner(id, ownerID uint, currentOwnerID *uint) error {
+	query := r.db.Model(&model.Tenant{}).Where("id = ?", id)
+	if currentOwnerID != nil {
+		query = query.Where("owner_id = ?", *currentOwnerID)
+	} else {
+		query = query.Where("owner_id IS NULL")
+	}
+
+	return requireRow(query.Update("owner_id", ownerID))
+}
+
+// AssignMissingOwners makes the longest-standing admin the owner of tenants created
+// before owners existed
+func (r *tenantRepository) AssignMissingOwners() (int64, error) {
+	result := r.db.Exec(`
+		UPDATE tenants SET owner_id = (
+			SELECT tu.user_id FROM tenant_users tu
+			WHERE tu.tenant_id = tenants.id AND tu.role = ? AND tu.deleted_at IS NULL
+			ORDER BY tu.created_at, tu.id
+			LIMIT 1
+		)
+		WHERE owner_id IS NULL AND deleted_at IS NULL`, model.RoleAdmin)
+	return result.RowsAffected, result.Error
+}
+
+// SetStatus changes the status of a tenant that is not being deleted. Returns
+// gorm.ErrRecordNotFound otherwise.
+func (r *tenantRepository) SetStatus(id uint, status string) error {
+	return requireRow(r.db.Model(&model.Tenant{}).
+		Where("id = ? AND status NOT IN ?", id, []string{model.TenantStatusPendingDeletion, model.TenantStatusPurging}).
+		Update("status", status))
+}
+
+// ScheduleDeletion puts an active tenant into its deletion grace period. Returns
+// gorm.ErrRecordNotFound if the tenant is not active.
+func (r *tenantRepository) ScheduleDeletion(id, requestedBy uint, purgeAfter time.Time) error {
+	return requireRow(r.db.Model(&model.Tenant{}).
+		Where("id = ? AND status = ?", id, model.TenantStatusActive).
+		Updates(map[string]interface{}{
+			"status":                model.TenantStatusPendingDeletion,
+			"deletion_requested_at": time.Now(),
+			"deletion_requested_by": requestedBy,
+			"purge_after":           purgeAfter,
+		}))
+}
+
+// CancelDeletion reactivates a tenant in its grace period. Returns gorm.ErrRecordNotFound
+// if no deletion is pending (or the purge has already started).
+func (r *tenantRepository) CancelDeletion(id uint) error {
+	return requireRow(r.db.Model(&model.Tenant{}).
+		Where("id = ? AND status = ?", id, model.TenantStatusPendingDeletion).
+		Updates(map[string]interface{}{
+			"status":                model.TenantStatusActive,
+			"deletion_requested_at": nil,
+			"deletion_requested_by": nil,
+			"purge_after":           nil,
+		}))
+}
+
+// FindDueForPurge returns tenants whose grace period has ended, including purges that
+// were interrupted
+func (r *tenantRepository) FindDueForPurge(limit int) ([]model.Tenant, error) {
+	var tenants []model.Tenant
+	err := r.db.Where("status = ? OR (status = ? AND purge_after <= ?)",
+		model.TenantStatusPurging, model.TenantStatusPendingDeletion, time.Now()).
+		Order("purge_after").
+		Limit(limit).
+		Find(&tenants).Error
+	return tenants, err
+}
+
+// ClaimForPurge marks a tenant past its grace period as purging, after which the deletion
+// can no longer be cancelled. Returns gorm.ErrRecordNotFound if it is not due.
+func (r *tenantRepository) ClaimForPurge(id uint) error {
+	return requireRow(r.db.Model(&model.Tenant{}).
+		Where("id = ? AND status = ? AND purge_after <= ?", id, model.TenantStatusPendingDeletion, time.Now()).
+		Update("status", model.TenantStatusPurging))
 }
 
 func (r *tenantRepository) Delete(id uint) error {
 	return r.db.Delete(&model.Tenant{}, id).Error
 }
+
+// requireRow turns a conditional update that matched no row into gorm.ErrRecordNotFound
+func requireRow(result *gorm.DB) error {
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"errors"
+	"gin-quickstart/internal/model"
+
+	"gorm.io/gorm"
+)
+
+type TenantSettingRepository interface {
+	Get(tenantID uint, key string) (*model.TenantSetting, error)
+	FindAll(tenantID uint) ([]model.TenantSetting, error)
+	Set(tenantID uint, key, value string) error
+}
+
+type tenantSettingRepository struct {
+	db *gorm.DB
+}
+
+func NewTenantSettingRepository(db *gorm.DB) TenantSettingRepository {
+	return &tenantSettingRepository{db: db}
+}
+
+// Get uses the composite (tenant_id, key) index
+func (r *tenantSettingRepository) Get(tenantID uint, key string) (*model.TenantSetting, error) {
+	var setting model.TenantSetting
+	err := r.db.Scopes(model.TenantScope(tenantID)).
+		Where("key = ?", key).
+		First(&setting).Error
+	if err != nil {
+		return nil, err
+	}
+	return &setting, nil
+}
+
+func (r *tenantSettingRepository) FindAll(tenantID uint) ([]model.TenantSetting, error) {
+	var settings []model.TenantSetting
+	err := r.db.Scopes(model.TenantScope(tenantID)).
+		Order("key ASC").
+		Find(&settings).Error
+	return settings, err
+}
+
+// Set creates or updates a setting
+func (r *tenantSettingRepository) Set(tenantID uint, key, value string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var setting model.TenantSetting
+		err := tx.Where("tenant_id = ? AND key = ?", tenantID, key).First(&setting).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Create(&model.TenantSetting{
+				TenantID: tenantID,
+				Key:      key,
+				Value:    value,
+			}).Error
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&setting).Update("value", value).Error
+	})
+}
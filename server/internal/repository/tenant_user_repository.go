@@ -4,16 +4,22 @@ import (
 	"gin-quickstart/internal/model"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type TenantUserRepository interface {
 	Create(tenantUser *model.TenantUser) error
 	FindByTenantAndUser(tenantID, userID uint) (*model.TenantUser, error)
+	FindMembership(tenantID, userID uint) (*model.TenantUser, error)
 	FindUsersByTenant(tenantID uint, page, pageSize int) ([]model.TenantUser, int64, error)
 	FindTenantsByUser(userID uint) ([]model.TenantUser, error)
+	CountByRole(tenantID uint, role string) (int64, error)
+	FindByRoleForUpdate(tenantID uint, role string) ([]model.TenantUser, error)
+	FindTenantForUpdate(tenantID uint) (*model.Tenant, error)
 	UpdateRole(tenantID, userID uint, role string) error
 	Delete(tenantID, userID uint) error
 	CheckUserAccess(tenantID, userID uint) bool
+	Transaction(fn func(repo TenantUserRepository) error) error
 }
 
 type tenantUserRepository struct {
@@ -39,6 +45,18 @@ func (r *tenantUserRepository) FindByTenantAndUser(tenantID, userID uint) (*mode
 	return &tenantUser, nil
 }
 
+// FindMembership loads the tenant user row together with its tenant and user
+func (r *tenantUserRepository) FindMembership(tenantID, userID uint) (*model.TenantUser, error) {
+	var tenantUser model.TenantUser
+	err := r.db.Scopes(model.PreloadTenant(), model.PreloadUser()).
+		Where("tenant_id = ? AND user_id = ?", tenantID, userID).
+		First(&tenantUser).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tenantUser, nil
+}
+
 // FindUsersByTenant efficiently fetches users of a tenant with preloading
 func (r *tenantUserRepository) FindUsersByTenant(tenantID uint, page, pageSize int) ([]model.TenantUser, int64, error) {
 	var tenantUsers []model.TenantUser
@@ -71,6 +89,43 @@ func (r *tenantUserRepository) FindTenantsByUser(userID uint) ([]model.TenantUse
 	return tenantUsers, err
 }
 
+// CountByRole counts the tenant's active members holding a role
+func (r *tenantUserRepository) CountByRole(tenantID uint, role string) (int64, error) {
+	var count int64
+	err := r.db.Model(&model.TenantUser{}).
+		Joins(activeUserJoin).
+		Where("tenant_users.tenant_id = ? AND tenant_users.role = ?", tenantID, role).
+		Count(&count).Error
+	return count, err
+}
+
+// FindByRoleForUpdate returns the tenant's active members holding a role and locks their
+// rows until the surrounding transaction ends
+func (r *tenantUserRepository) FindByRoleForUpdate(tenantID uint, role string) ([]model.TenantUser, error) {
+	var tenantUsers []model.TenantUser
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE", Table: clause.Table{Name: "tenant_users"}}).
+		Joins(activeUserJoin).
+		Where("tenant_users.tenant_id = ? AND tenant_users.role = ?", tenantID, role).
+		Order("tenant_users.id").
+		Find(&tenantUsers).Error
+	return tenantUsers, err
+}
+
+// FindTenantForUpdate loads the tenant and locks its row until the surrounding
+// transaction ends
+func (r *tenantUserRepository) FindTenantForUpdate(tenantID uint) (*model.Tenant, error) {
+	var tenant model.Tenant
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&tenant, tenantID).Error
+	if err != nil {
+		return nil, err
+	}
+	return &tenant, nil
+}
+
+// activeUserJoin restricts tenant users to active, not deleted accounts; deactivated
+// users cannot administer a tenant
+const activeUserJoin = "JOIN users ON users.id = tenant_users.user_id AND users.is_active AND users.deleted_at IS NULL"
+
 func (r *tenantUserRepository) UpdateRole(tenantID, userID uint, role string) error {
 	return r.db.Model(&model.TenantUser{}).
 		Where("tenant_id = ? AND user_id = ?", tenantID, userID).
@@ -90,3 +145,10 @@ func (r *tenantUserRepository) CheckUserAccess(tenantID, userID uint) bool {
 		Count(&count)
 	return count > 0
 }
+
+// Transaction runs fn with a repository bound to a single database transaction
+func (r *tenantUserRepository) Transaction(fn func(repo TenantUserRepository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&tenantUserRepository{db: tx})
+	})
+}
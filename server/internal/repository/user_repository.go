@@ -2,6 +2,7 @@ package repository
 
 import (
 	"gin-quickstart/internal/model"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -13,6 +14,11 @@ type UserRepository interface {
 	FindAll(page, pageSize int) ([]model.User, int64, error)
 	Update(user *model.User) error
 	Delete(id uint) error
+	SetTokensValidAfter(id uint, t time.Time) error
+	UpdatePassword(id uint, passwordHash string) error
+	UpdateFields(id uint, updates map[string]interface{}) error
+	UseTOTPStep(id uint, step int64) error
+	SyncPlatformAdmins(ids []uint) error
 }
 
 type userRepository struct {
@@ -36,10 +42,10 @@ func (r *userRepository) FindByID(id uint) (*model.User, error) {
 	return &user, nil
 }
 
-// FindByEmail uses indexed email field for fast login
+// FindByEmail looks the email up ignoring case, on the LOWER(email) index
 func (r *userRepository) FindByEmail(email string) (*model.User, error) {
 	var user model.User
-	err := r.db.Where("email = ?", email).First(&user).Error
+	err := r.db.Where("LOWER(email) = ?", model.NormalizeEmail(email)).First(&user).Error
 	if err != nil {
 		return nil, err
 	}
@@ -67,3 +73,59 @@ func (r *userRepository) Update(user *model.User) error {
 func (r *userRepository) Delete(id uint) error {
 	return r.db.Delete(&model.User{}, id).Error
 }
+
+// SetTokensValidAfter invalidates every access token issued to the user before t
+func (r *userRepository) SetTokensValidAfter(id uint, t time.Time) error {
+	return r.db.Model(&model.User{}).
+		Where("id = ?", id).
+		Update("tokens_valid_after", t).Error
+}
+
+func (r *userRepository) UpdatePassword(id uint, passwordHash string) error {
+	return r.db.Model(&model.User{}).
+		Where("id = ?", id).
+		Update("password_hash", passwordHash).Error
+}
+
+// UpdateFields updates specific columns of a user
+func (r *userRepository) UpdateFields(id uint, updates map[string]interface{}) error {
+	return r.db.Model(&model.User{}).
+		Where("id = ?", id).
+		Updates(updates).Error
+}
+
+// UseTOTPStep records the time step of an accepted TOTP code, in one statement so a code
+// is accepted once even by parallel requests. Returns gorm.ErrRecordNotFound unless step
+// is after the last used one.
+func (r *userRepository) UseTOTPStep(id uint, step int64) error {
+	result := r.db.Model(&model.User{}).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// SyncPlatformAdmins makes exactly the users with the given IDs platform operators
+func (r *userRepository) SyncPlatformAdmins(ids []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		revoke := tx.Model(&model.User{}).Where("is_platform_admin = ?", true)
+		if len(ids) > 0 {
+			revoke = revoke.Where("id NOT IN ?", ids)
+		}
+		if err := revoke.Update("is_platform_admin", false).Error; err != nil {
+			return err
+		}
+
+		if len(ids) == 0 {
+			return nil
+		}
+		return tx.Model(&model.User{}).
+			Where("id IN ? AND is_platform_admin = ?", ids, false).
+			Update("is_platform_admin", true).Error
+	})
+}
@@ -3,24 +3,52 @@ package routes
 import (
 	"gin-quickstart/internal/handler"
 	"gin-quickstart/internal/middleware"
+	"gin-quickstart/internal/model"
 
 	"github.com/gin-gonic/gin"
 )
 
 func SetupRoutes(
 	router *gin.Engine,
+	authMiddleware gin.HandlerFunc,
+	tenantMiddleware gin.HandlerFunc,
+	rateLimitMiddleware gin.HandlerFunc,
+	platformMiddleware gin.HandlerFunc,
 	authHandler *handler.AuthHandler,
 	tenantHandler *handler.TenantHandler,
 	contactHandler *handler.ContactHandler,
 	dashboardHandler *handler.DashboardHandler,
 	pipelineStageHandler *handler.PipelineStageHandler,
 	dealHandler *handler.DealHandler,
+	roleHandler *handler.RoleHandler,
+	teamHandler *handler.TeamHandler,
+	invitationHandler *handler.InvitationHandler,
+	mfaHandler *handler.MFAHandler,
+	apiKeyHandler *handler.APIKeyHandler,
+	ssoHandler *handler.SSOHandler,
+	sessionHandler *handler.SessionHandler,
+	profileHandler *handler.ProfileHandler,
+	exportHandler *handler.ExportHandler,
+	importHandler *handler.ImportHandler,
+	contactImportHandler *handler.ContactImportHandler,
+	contactMergeHandler *handler.ContactMergeHandler,
+	customFieldHandler *handler.CustomFieldHandler,
+	companyHandler *handler.CompanyHandler,
+	platformHandler *handler.PlatformHandler,
+	metricsHandler *handler.MetricsHandler,
+	jwksHandler *handler.JWKSHandler,
 ) {
 	// Health check
 	router.GET("/health", func(c *gin.Context) {
 		c.JSON(200, gin.H{"status": "ok"})
 	})
 
+	// Monitoring counters (Prometheus text format)
+	router.GET("/metrics", metricsHandler.GetMetrics)
+
+	// Public keys for verifying access tokens
+	router.GET("/.well-known/jwks.json", jwksHandler.GetJWKS)
+
 	// API
 	api := router.Group("/api")
 	{
@@ -29,78 +57,217 @@ func SetupRoutes(
 		{
 			auth.POST("/register", authHandler.Register)
 			auth.POST("/login", authHandler.Login)
+			auth.POST("/mfa/verify", authHandler.VerifyMFA)
+			auth.POST("/refresh", authHandler.Refresh)
+			auth.POST("/logout", authHandler.Logout)
+			auth.POST("/password/forgot", authHandler.ForgotPassword)
+			auth.POST("/password/reset", authHandler.ResetPassword)
+			auth.POST("/email/confirm", profileHandler.ConfirmEmail)
+			auth.GET("/invitations/:token", invitationHandler.PreviewInvitation)
+			auth.POST("/invitations/:token/accept", invitationHandler.AcceptInvitation)
+			auth.GET("/sso/:tenant_id/login", ssoHandler.Login)
+			auth.GET("/sso/callback", ssoHandler.Callback)
+			auth.POST("/sso/exchange", authHandler.SSOExchange)
 		}
 
+		// Export archives (authorized by the signed link)
+		api.GET("/exports/:id/download", exportHandler.DownloadExport)
+
 		// Protected routes (authentication required)
 		protected := api.Group("")
-		protected.Use(middleware.AuthMiddleware())
+		protected.Use(authMiddleware)
 		{
 			// User's tenant management
-			protected.GET("/tenants/my", authHandler.GetMyTenants)
-			protected.POST("/tenants/switch/:tenant_id", authHandler.SwitchTenant)
+			protected.GET("/tenants/my", middleware.RequireUserSession(), authHandler.GetMyTenants)
+			protected.POST("/tenants/switch/:tenant_id", middleware.RequireUserSession(), authHandler.SwitchTenant)
+
+			// Outside the tenant middleware, which makes tenants awaiting deletion read-only
+			protected.POST("/tenant/deletion/cancel", middleware.RequireUserSession(), tenantHandler.CancelDeletion)
+
+			// Current user's account
+			me := protected.Group("/me")
+			me.Use(middleware.RequireUserSession())
+			{
+				me.GET("", profileHandler.GetProfile)
+				me.PATCH("", profileHandler.UpdateProfile)
+				me.DELETE("", profileHandler.DeleteAccount)
+				me.POST("/email", profileHandler.ChangeEmail)
+				me.PUT("/password", authHandler.ChangePassword)
+				me.GET("/mfa", mfaHandler.GetStatus)
+				me.POST("/mfa/enroll", mfaHandler.BeginEnrollment)
+				me.POST("/mfa/confirm", mfaHandler.ConfirmEnrollment)
+				me.POST("/mfa/disable", mfaHandler.Disable)
+				me.POST("/mfa/recovery-codes", mfaHandler.RegenerateRecoveryCodes)
+				me.GET("/sessions", sessionHandler.GetSessions)
+				me.DELETE("/sessions/:id", sessionHandler.RevokeSession)
+			}
 
-			// Tenant-specific routes (requires tenant context)
-			tenant := protected.Group("")
-			tenant.Use(middleware.TenantMiddleware())
+			// Platform operators, across all tenants
+			platform := protected.Group("/platform")
+			platform.Use(platformMiddleware)
+			{
+				platform.GET("/tenants", platformHandler.GetTenants)
+				platform.GET("/tenants/:id", platformHandler.GetTenant)
+				platform.GET("/tenants/:id/users", platformHandler.GetTenantUsers)
+				platform.POST("/tenants/:id/suspend", platformHandler.SuspendTenant)
+				platform.POST("/tenants/:id/reactivate", platformHandler.ReactivateTenant)
+				platform.POST("/tenants/:id/impersonate", platformHandler.Impersonate)
+				platform.GET("/impersonations", platformHandler.GetImpersonations)
+				platform.POST("/impersonations/:id/end", platformHandler.EndImpersonation)
+			}
+
+			// Tenant-specific routes (requires tenant context), rate limited before the
+			// authentication looks anything up
+			tenant := api.Group("")
+			tenant.Use(rateLimitMiddleware, authMiddleware, tenantMiddleware)
 			{
 				// Current tenant info
 				tenant.GET("/tenant", tenantHandler.GetTenant)
 
-				// Dashboard statistics (all authenticated users)
-				tenant.GET("/dashboard/stats", dashboardHandler.GetStats)
+				// Dashboard statistics
+				tenant.GET("/dashboard/stats", middleware.RequirePermission(model.PermReportsRead), dashboardHandler.GetStats)
+
+				// Tenant administration
+				tenant.PUT("/tenant", middleware.RequirePermission(model.PermTenantUpdate), tenantHandler.UpdateTenant)
+				tenant.DELETE("/tenant", middleware.RequireUserSession(), middleware.RequirePermission(model.PermTenantUpdate), tenantHandler.DeleteTenant)
+				tenant.POST("/tenant/ownership-transfer", middleware.RequireUserSession(), middleware.RequirePermission(model.PermTenantUpdate), tenantHandler.TransferOwnership)
+				tenant.GET("/tenant/settings", tenantHandler.GetSettings)
+				tenant.PUT("/tenant/settings", middleware.RequirePermission(model.PermTenantUpdate), tenantHandler.UpdateSettings)
+				tenant.GET("/tenant/sso", middleware.RequirePermission(model.PermTenantUpdate), ssoHandler.GetConfig)
+				tenant.PUT("/tenant/sso", middleware.RequirePermission(model.PermTenantUpdate), ssoHandler.UpdateConfig)
+				tenant.DELETE("/tenant/sso", middleware.RequirePermission(model.PermTenantUpdate), ssoHandler.DeleteConfig)
+				tenant.GET("/tenant/audit-logs", middleware.RequirePermission(model.PermAuditLogsRead), tenantHandler.GetAuditLogs)
+
+				// Custom contact and deal fields
+				tenant.GET("/tenant/custom-fields", customFieldHandler.GetFields)
+				tenant.POST("/tenant/custom-fields", middleware.RequirePermission(model.PermCustomFieldsManage), customFieldHandler.CreateField)
+				tenant.PATCH("/tenant/custom-fields/:id", middleware.RequirePermission(model.PermCustomFieldsManage), customFieldHandler.UpdateField)
+				tenant.DELETE("/tenant/custom-fields/:id", middleware.RequirePermission(model.PermCustomFieldsManage), customFieldHandler.DeleteField)
+
+				// Full data export
+				exports := tenant.Group("/tenant/exports")
+				exports.Use(middleware.RequirePermission(model.PermDataExport))
+				{
+					exports.GET("", exportHandler.GetExports)
+					exports.POST("", exportHandler.CreateExport)
+					exports.GET("/:id", exportHandler.GetExport)
+				}
+
+				// Import from an export archive
+				imports := tenant.Group("/tenant/imports")
+				imports.Use(middleware.RequirePermission(model.PermDataImport))
+				{
+					imports.GET("", importHandler.GetImports)
+					imports.POST("", importHandler.CreateImport)
+					imports.GET("/:id", importHandler.GetImport)
+				}
+
+				// User management
+				tenant.GET("/tenant/users", middleware.RequirePermission(model.PermUsersRead), tenantHandler.GetTenantUsers)
+				tenant.GET("/tenant/invitations", middleware.RequirePermission(model.PermUsersManage), invitationHandler.GetInvitations)
+				tenant.POST("/tenant/invitations", middleware.RequirePermission(model.PermUsersManage), invitationHandler.CreateInvitation)
+				tenant.POST("/tenant/invitations/:id/resend", middleware.RequirePermission(model.PermUsersManage), invitationHandler.ResendInvitation)
+				tenant.DELETE("/tenant/invitations/:id", middleware.RequirePermission(model.PermUsersManage), invitationHandler.RevokeInvitation)
+				tenant.DELETE("/tenant/users/:user_id", middleware.RequirePermission(model.PermUsersManage), tenantHandler.RemoveUser)
+				tenant.PUT("/tenant/users/:user_id/role", middleware.RequirePermission(model.PermUsersManage), tenantHandler.UpdateUserRole)
+				tenant.DELETE("/tenant/users/:user_id/sessions", middleware.RequirePermission(model.PermUsersManage), sessionHandler.ForceLogout)
+
+				// Role & permission management
+				roles := tenant.Group("/tenant")
+				roles.Use(middleware.RequirePermission(model.PermRolesManage))
+				{
+					roles.GET("/permissions", roleHandler.GetPermissions)
+					roles.GET("/roles", roleHandler.GetRoles)
+					roles.GET("/roles/:id", roleHandler.GetRole)
+					roles.POST("/roles", roleHandler.CreateRole)
+					roles.PATCH("/roles/:id", roleHandler.UpdateRole)
+					roles.DELETE("/roles/:id", roleHandler.DeleteRole)
+				}
 
-				// Admin only routes
-				adminRoutes := tenant.Group("")
-				adminRoutes.Use(middleware.RoleMiddleware("admin"))
+				// API keys for integrations
+				apiKeys := tenant.Group("/tenant/api-keys")
+				apiKeys.Use(middleware.RequirePermission(model.PermAPIKeysManage))
 				{
-					adminRoutes.PUT("/tenant", tenantHandler.UpdateTenant)
-					adminRoutes.POST("/tenant/users", tenantHandler.AddUser)
-					adminRoutes.DELETE("/tenant/users/:user_id", tenantHandler.RemoveUser)
-					adminRoutes.PUT("/tenant/users/:user_id/role", tenantHandler.UpdateUserRole)
-					adminRoutes.GET("/tenant/audit-logs", tenantHandler.GetAuditLogs)
+					apiKeys.GET("", apiKeyHandler.GetAPIKeys)
+					apiKeys.POST("", apiKeyHandler.CreateAPIKey)
+					apiKeys.DELETE("/:id", apiKeyHandler.RevokeAPIKey)
 				}
 
-				// Manager and Admin routes (can view users)
-				managerRoutes := tenant.Group("")
-				managerRoutes.Use(middleware.RoleMiddleware("admin", "manager"))
+				// Team management
+				teams := tenant.Group("/tenant/teams")
 				{
-					managerRoutes.GET("/tenant/users", tenantHandler.GetTenantUsers)
+					teams.GET("", middleware.RequirePermission(model.PermTeamsRead), teamHandler.GetTeams)
+					teams.GET("/:id", middleware.RequirePermission(model.PermTeamsRead), teamHandler.GetTeam)
+					teams.POST("", middleware.RequirePermission(model.PermTeamsManage), teamHandler.CreateTeam)
+					teams.PUT("/:id", middleware.RequirePermission(model.PermTeamsManage), teamHandler.UpdateTeam)
+					teams.DELETE("/:id", middleware.RequirePermission(model.PermTeamsManage), teamHandler.DeleteTeam)
 				}
 
-				// Contact routes (all authenticated tenant users)
+				// Contact routes
 				contacts := tenant.Group("/contacts")
 				{
-					contacts.POST("", contactHandler.CreateContact)
-					contacts.GET("", contactHandler.GetContacts)
-					contacts.GET("/search", contactHandler.SearchContacts)
-					contacts.GET("/:id", contactHandler.GetContact)
-					contacts.PATCH("/:id", contactHandler.UpdateContact)
-					contacts.DELETE("/:id", contactHandler.DeleteContact)
+					contacts.POST("", middleware.RequirePermission(model.PermContactsCreate), contactHandler.CreateContact)
+					contacts.GET("", middleware.RequirePermission(model.PermContactsRead), contactHandler.GetContacts)
+					contacts.GET("/search", middleware.RequirePermission(model.PermContactsRead), contactHandler.SearchContacts)
+					contacts.GET("/export", middleware.RequirePermission(model.PermContactsExport), contactHandler.ExportContacts)
+
+					// Bulk import from CSV/XLSX files
+					contacts.GET("/imports", middleware.RequirePermission(model.PermContactsImport), contactImportHandler.GetImports)
+					contacts.POST("/imports", middleware.RequirePermission(model.PermContactsImport), contactImportHandler.UploadImport)
+					contacts.GET("/imports/:id", middleware.RequirePermission(model.PermContactsImport), contactImportHandler.GetImport)
+					contacts.POST("/imports/:id/preview", middleware.RequirePermission(model.PermContactsImport), contactImportHandler.PreviewImport)
+					contacts.POST("/imports/:id/start", middleware.RequirePermission(model.PermContactsImport), contactImportHandler.StartImport)
+					contacts.GET("/imports/:id/errors", middleware.RequirePermission(model.PermContactsImport), contactImportHandler.DownloadErrorReport)
+
+					// Merging duplicates (reversible within the undo window)
+					contacts.POST("/merge", middleware.RequirePermission(model.PermContactsUpdate), middleware.RequirePermission(model.PermContactsDelete), contactMergeHandler.MergeContacts)
+					contacts.GET("/merges", middleware.RequirePermission(model.PermContactsRead), contactMergeHandler.GetMerges)
+					contacts.POST("/merges/:id/undo", middleware.RequirePermission(model.PermContactsUpdate), middleware.RequirePermission(model.PermContactsDelete), contactMergeHandler.UndoMerge)
+
+					contacts.GET("/:id", middleware.RequirePermission(model.PermContactsRead), contactHandler.GetContact)
+					contacts.GET("/:id/duplicates", middleware.RequirePermission(model.PermContactsRead), contactHandler.GetDuplicates)
+					contacts.PATCH("/:id", middleware.RequirePermission(model.PermContactsUpdate), contactHandler.UpdateContact)
+					contacts.DELETE("/:id", middleware.RequirePermission(model.PermContactsDelete), contactHandler.DeleteContact)
+					contacts.PUT("/:id/owner", middleware.RequirePermission(model.PermContactsUpdate), contactHandler.ReassignOwner)
+					contacts.PUT("/:id/team", middleware.RequirePermission(model.PermContactsUpdate), contactHandler.AssignTeam)
+				}
+
+				// Company routes (accounts; covered by the contact permissions)
+				companies := tenant.Group("/companies")
+				{
+					companies.POST("", middleware.RequirePermission(model.PermContactsCreate), companyHandler.CreateCompany)
+					companies.GET("", middleware.RequirePermission(model.PermContactsRead), companyHandler.GetCompanies)
+					companies.GET("/search", middleware.RequirePermission(model.PermContactsRead), companyHandler.SearchCompanies)
+					companies.GET("/:id", middleware.RequirePermission(model.PermContactsRead), companyHandler.GetCompany)
+					companies.PATCH("/:id", middleware.RequirePermission(model.PermContactsUpdate), companyHandler.UpdateCompany)
+					companies.DELETE("/:id", middleware.RequirePermission(model.PermContactsDelete), companyHandler.DeleteCompany)
 				}
 
-				// Pipeline routes (all authenticated tenant users)
+				// Pipeline routes
 				pipeline := tenant.Group("/pipeline")
 				{
 					// Stage management
-					pipeline.GET("/stages", pipelineStageHandler.GetStages)
-					pipeline.GET("/stages/:id", pipelineStageHandler.GetStage)
-					pipeline.POST("/stages", pipelineStageHandler.CreateStage)
-					pipeline.PATCH("/stages/:id", pipelineStageHandler.UpdateStage)
-					pipeline.DELETE("/stages/:id", pipelineStageHandler.DeleteStage)
-					pipeline.PUT("/stages/reorder", pipelineStageHandler.ReorderStages)
+					pipeline.GET("/stages", middleware.RequirePermission(model.PermPipelineRead), pipelineStageHandler.GetStages)
+					pipeline.GET("/stages/:id", middleware.RequirePermission(model.PermPipelineRead), pipelineStageHandler.GetStage)
+					pipeline.POST("/stages", middleware.RequirePermission(model.PermPipelineManage), pipelineStageHandler.CreateStage)
+					pipeline.PATCH("/stages/:id", middleware.RequirePermission(model.PermPipelineManage), pipelineStageHandler.UpdateStage)
+					pipeline.DELETE("/stages/:id", middleware.RequirePermission(model.PermPipelineManage), pipelineStageHandler.DeleteStage)
+					pipeline.PUT("/stages/reorder", middleware.RequirePermission(model.PermPipelineManage), pipelineStageHandler.ReorderStages)
 				}
 
-				// Deal routes (all authenticated tenant users)
+				// Deal routes
 				deals := tenant.Group("/deals")
 				{
-					deals.POST("", dealHandler.CreateDeal)
-					deals.GET("", dealHandler.GetDeals)
-					deals.GET("/pipeline-value", dealHandler.GetPipelineValue)
-					deals.GET("/:id", dealHandler.GetDeal)
-					deals.PATCH("/:id", dealHandler.UpdateDeal)
-					deals.DELETE("/:id", dealHandler.DeleteDeal)
-					deals.PUT("/:id/move", dealHandler.MoveToStage)
-					deals.PUT("/:id/status", dealHandler.UpdateStatus)
+					deals.POST("", middleware.RequirePermission(model.PermDealsCreate), dealHandler.CreateDeal)
+					deals.GET("", middleware.RequirePermission(model.PermDealsRead), dealHandler.GetDeals)
+					deals.GET("/pipeline-value", middleware.RequirePermission(model.PermReportsRead), dealHandler.GetPipelineValue)
+					deals.GET("/:id", middleware.RequirePermission(model.PermDealsRead), dealHandler.GetDeal)
+					deals.PATCH("/:id", middleware.RequirePermission(model.PermDealsUpdate), dealHandler.UpdateDeal)
+					deals.DELETE("/:id", middleware.RequirePermission(model.PermDealsDelete), dealHandler.DeleteDeal)
+					deals.PUT("/:id/move", middleware.RequirePermission(model.PermDealsUpdate), dealHandler.MoveToStage)
+					deals.PUT("/:id/status", middleware.RequirePermission(model.PermDealsUpdate), dealHandler.UpdateStatus)
+					deals.PUT("/:id/owner", middleware.RequirePermission(model.PermDealsUpdate), dealHandler.ReassignOwner)
+					deals.PUT("/:id/team", middleware.RequirePermission(model.PermDealsUpdate), dealHandler.AssignTeam)
 				}
 			}
 		}
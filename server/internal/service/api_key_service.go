@@ -0,0 +1,106 @@
+package service
+
+import (
+	"errors"
+	"gin-quickstart/internal/middleware"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/repository"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type APIKeyService interface {
+	GetAPIKeys(tenantID uint) ([]model.APIKey, error)
+	CreateAPIKey(tenantID, createdBy uint, name string, scopes []string, expiresAt *time.Time, creatorPermissions map[string]bool) (*model.APIKey, string, error)
+	RevokeAPIKey(tenantID, id uint) error
+}
+
+type apiKeyService struct {
+	apiKeyRepo repository.APIKeyRepository
+	roleRepo   repository.RoleRepository
+}
+
+func NewAPIKeyService(apiKeyRepo repository.APIKeyRepository, roleRepo repository.RoleRepository) APIKeyService {
+	return &apiKeyService{
+		apiKeyRepo: apiKeyRepo,
+		roleRepo:   roleRepo,
+	}
+}
+
+func (s *apiKeyService) GetAPIKeys(tenantID uint) ([]model.APIKey, error) {
+	return s.apiKeyRepo.FindAll(tenantID)
+}
+
+// CreateAPIKey stores a new key and returns it together with the raw key, which is
+// shown to the caller once. Scopes must be grantable to keys and held by the creator.
+func (s *apiKeyService) CreateAPIKey(tenantID, createdBy uint, name string, scopes []string, expiresAt *time.Time, creatorPermissions map[string]bool) (*model.APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, "", errors.New("name is required")
+	}
+
+	if len(scopes) == 0 {
+		return nil, "", errors.New("at least one scope is required")
+	}
+
+	for _, scope := range scopes {
+		if !model.APIKeyScopes[scope] {
+			return nil, "", errors.New("scope cannot be granted to API keys: " + scope)
+		}
+		if !creatorPermissions[scope] && !creatorPermissions[strings.TrimSuffix(scope, model.PermissionOwnSuffix)] {
+			return nil, "", errors.New("you cannot grant a scope you do not have: " + scope)
+		}
+	}
+
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", errors.New("expiry must be in the future")
+	}
+
+	permissions, err := s.roleRepo.FindPermissionsByKeys(scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(permissions) != len(uniqueStrings(scopes)) {
+		return nil, "", errors.New("one or more scopes are invalid")
+	}
+
+	token, err := generateRandomToken(32)
+	if err != nil {
+		return nil, "", err
+	}
+	rawKey := model.APIKeyPrefix + token
+
+	apiKey := &model.APIKey{
+		TenantID:  tenantID,
+		Name:      name,
+		Prefix:    rawKey[:len(model.APIKeyPrefix)+8],
+		KeyHash:   middleware.HashAPIKey(rawKey),
+		CreatedBy: createdBy,
+		ExpiresAt: expiresAt,
+		Scopes:    permissions,
+	}
+
+	if err := s.apiKeyRepo.Create(apiKey); err != nil {
+		return nil, "", err
+	}
+
+	return apiKey, rawKey, nil
+}
+
+func (s *apiKeyService) RevokeAPIKey(tenantID, id uint) error {
+	apiKey, err := s.apiKeyRepo.FindByID(tenantID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("API key not found")
+		}
+		return err
+	}
+
+	if apiKey.RevokedAt != nil {
+		return errors.New("API key is already revoked")
+	}
+
+	return s.apiKeyRepo.Revoke(tenantID, id)
+}
@@ -1,28 +1,76 @@
 package service
 
 import (
+	"gin-quickstart/internal/middleware"
 	"gin-quickstart/internal/model"
 	"gin-quickstart/internal/repository"
+
+	"github.com/gin-gonic/gin"
 )
 
+// Requester is who requested a background job, so that its audit entries are attributed
+// like those of the request itself
+type Requester struct {
+	UserID     uint
+	APIKeyID   *uint
+	OperatorID *uint
+}
+
 type AuditService interface {
-	Log(log *model.AuditLog) error
+	Log(c *gin.Context, log *model.AuditLog) error
+	LogForUser(c *gin.Context, userID uint, log *model.AuditLog) error
+	LogSecurityEvent(event *model.SecurityEvent) error
 	GetTenantLogs(tenantID uint, page, pageSize int) ([]model.AuditLog, int64, error)
 	GetUserLogs(tenantID, userID uint, page, pageSize int) ([]model.AuditLog, int64, error)
 }
 
 type auditService struct {
-	auditLogRepo repository.AuditLogRepository
+	auditLogRepo      repository.AuditLogRepository
+	tenantUserRepo    repository.TenantUserRepository
+	securityEventRepo repository.SecurityEventRepository
 }
 
-func NewAuditService(auditLogRepo repository.AuditLogRepository) AuditService {
+func NewAuditService(
+	auditLogRepo repository.AuditLogRepository,
+	tenantUserRepo repository.TenantUserRepository,
+	securityEventRepo repository.SecurityEventRepository,
+) AuditService {
 	return &auditService{
-		auditLogRepo: auditLogRepo,
+		auditLogRepo:      auditLogRepo,
+		tenantUserRepo:    tenantUserRepo,
+		securityEventRepo: securityEventRepo,
+	}
+}
+
+// Log records an action of the request c. Actions performed with an API key are
+// attributed to the key rather than to the user who created it, and actions during an
+// impersonation to the operator too.
+func (s *auditService) Log(c *gin.Context, log *model.AuditLog) error {
+	return s.auditLogRepo.Create(attributeRequest(c, log))
+}
+
+// LogForUser records an account-level event (e.g. password change) of the request c in
+// the audit log of every tenant the user belongs to. TenantID on log is ignored.
+func (s *auditService) LogForUser(c *gin.Context, userID uint, log *model.AuditLog) error {
+	tenantUsers, err := s.tenantUserRepo.FindTenantsByUser(userID)
+	if err != nil {
+		return err
+	}
+	attributeRequest(c, log)
+
+	for _, tu := range tenantUsers {
+		entry := *log
+		entry.TenantID = tu.TenantID
+		if err := s.auditLogRepo.Create(&entry); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func (s *auditService) Log(log *model.AuditLog) error {
-	return s.auditLogRepo.Create(log)
+// LogSecurityEvent records an event that is not tied to a tenant (e.g. a login lockout)
+func (s *auditService) LogSecurityEvent(event *model.SecurityEvent) error {
+	return s.securityEventRepo.Create(event)
 }
 
 func (s *auditService) GetTenantLogs(tenantID uint, page, pageSize int) ([]model.AuditLog, int64, error) {
@@ -32,3 +80,30 @@ func (s *auditService) GetTenantLogs(tenantID uint, page, pageSize int) ([]model
 func (s *auditService) GetUserLogs(tenantID, userID uint, page, pageSize int) ([]model.AuditLog, int64, error) {
 	return s.auditLogRepo.FindByUser(tenantID, userID, page, pageSize)
 }
+
+// attributeRequest attributes an audit entry to the API key and impersonating operator of
+// the request, unless set already
+func attributeRequest(c *gin.Context, log *model.AuditLog) *model.AuditLog {
+	if log.APIKeyID == nil {
+		log.APIKeyID = middleware.GetAPIKeyID(c)
+	}
+	if log.OperatorID == nil {
+		log.OperatorID = middleware.GetOperatorID(c)
+	}
+	if log.APIKeyID != nil {
+		log.UserID = 0
+	}
+	return log
+}
+
+// attributeJob attributes the audit entry of a background job to the user, API key and
+// operator that requested it. As with Log, an API key replaces the user.
+func attributeJob(log *model.AuditLog, userID uint, apiKeyID, operatorID *uint) *model.AuditLog {
+	log.UserID = userID
+	log.APIKeyID = apiKeyID
+	log.OperatorID = operatorID
+	if apiKeyID != nil {
+		log.UserID = 0
+	}
+	return log
+}
@@ -4,11 +4,14 @@ import (
 	"errors"
 	"gin-quickstart/internal/model"
 	"gin-quickstart/internal/repository"
+	"log"
 
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+var ErrInvalidCredentials = errors.New("invalid email or password")
+
 type AuthService interface {
 	Register(email, password, fullName string) (*model.User, error)
 	Login(email, password string) (*model.User, error)
@@ -20,21 +23,26 @@ type authService struct {
 	userRepo       repository.UserRepository
 	tenantRepo     repository.TenantRepository
 	tenantUserRepo repository.TenantUserRepository
+	roleRepo       repository.RoleRepository
 }
 
 func NewAuthService(
 	userRepo repository.UserRepository,
 	tenantRepo repository.TenantRepository,
 	tenantUserRepo repository.TenantUserRepository,
+	roleRepo repository.RoleRepository,
 ) AuthService {
 	return &authService{
 		userRepo:       userRepo,
 		tenantRepo:     tenantRepo,
 		tenantUserRepo: tenantUserRepo,
+		roleRepo:       roleRepo,
 	}
 }
 
 func (s *authService) Register(email, password, fullName string) (*model.User, error) {
+	email = model.NormalizeEmail(email)
+
 	// Check if user already exists
 	existingUser, err := s.userRepo.FindByEmail(email)
 	if err == nil && existingUser != nil {
@@ -65,7 +73,7 @@ func (s *authService) Login(email, password string) (*model.User, error) {
 	user, err := s.userRepo.FindByEmail(email)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.New("invalid email or password")
+			return nil, ErrInvalidCredentials
 		}
 		return nil, err
 	}
@@ -76,7 +84,7 @@ func (s *authService) Login(email, password string) (*model.User, error) {
 
 	// Verify password
 	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		return nil, errors.New("invalid email or password")
+		return nil, ErrInvalidCredentials
 	}
 
 	return user, nil
@@ -84,19 +92,25 @@ func (s *authService) Login(email, password string) (*model.User, error) {
 
 func (s *authService) CreateTenantForUser(userID uint, tenantName string) (*model.Tenant, error) {
 	tenant := &model.Tenant{
-		Name:   tenantName,
-		Status: "active",
+		Name:    tenantName,
+		Status:  "active",
+		OwnerID: &userID,
 	}
 
 	if err := s.tenantRepo.Create(tenant); err != nil {
 		return nil, err
 	}
 
+	// Seed built-in roles; tenants without them fall back to the built-in defaults
+	if err := s.roleRepo.CreateBuiltInRoles(tenant.ID); err != nil {
+		log.Printf("⚠️  Failed to seed built-in roles for tenant %d: %v", tenant.ID, err)
+	}
+
 	// Add user as admin of the new tenant
 	tenantUser := &model.TenantUser{
 		TenantID: tenant.ID,
 		UserID:   userID,
-		Role:     "admin",
+		Role:     model.RoleAdmin,
 	}
 
 	if err := s.tenantUserRepo.Create(tenantUser); err != nil {
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"errors"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/repository"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrCompanyNotFound is returned when a company does not exist in the tenant, or is not
+// owned by the user when they may only change their own
+var ErrCompanyNotFound = errors.New("company not found")
+
+// companyDetailContacts is how many contacts a company detail lists
+const companyDetailContacts = 50
+
+var companyDomainPattern = regexp.MustCompile(`^[a-z0-9-]+(\.[a-z0-9-]+)+$`)
+
+// CompanyUpdate changes the attributes of a company; nil members are unchanged
+type CompanyUpdate struct {
+	Name       *string `json:"name"`
+	Domain     *string `json:"domain"`
+	Industry   *string `json:"industry"`
+	Size       *string `json:"size"`
+	Address    *string `json:"address"`
+	City       *string `json:"city"`
+	Province   *string `json:"province"`
+	PostalCode *string `json:"postal_code"`
+	Country    *string `json:"country"`
+	Notes      *string `json:"notes"`
+	OwnerID    *uint   `json:"owner_id"`
+}
+
+// CompanyDetail is a company with the aggregates of its contacts and deals and its first
+// contacts, among those visible to the user
+type CompanyDetail struct {
+	Company  *model.Company      `json:"company"`
+	Stats    *model.CompanyStats `json:"stats"`
+	Contacts []model.Contact     `json:"contacts"`
+}
+
+type CompanyService interface {
+	GetCompanies(tenantID uint, filter *model.CompanyFilter, page, pageSize int) ([]model.Company, int64, error)
+	SearchCompanies(tenantID uint, query string, page, pageSize int) ([]model.Company, int64, error)
+	GetCompany(tenantID, id uint) (*model.Company, error)
+	GetCompanyDetail(tenantID, id uint, access model.RecordAccess) (*CompanyDetail, error)
+	CreateCompany(company *model.Company) error
+	UpdateCompany(tenantID, id, ownedBy uint, update *CompanyUpdate) (*model.Company, error)
+	DeleteCompany(tenantID, id, ownedBy uint) (*model.Company, error)
+}
+
+type companyService struct {
+	companyRepo    repository.CompanyRepository
+	contactRepo    repository.ContactRepository
+	tenantUserRepo repository.TenantUserRepository
+}
+
+func NewCompanyService(
+	companyRepo repository.CompanyRepository,
+	contactRepo repository.ContactRepository,
+	tenantUserRepo repository.TenantUserRepository,
+) CompanyService {
+	return &companyService{
+		companyRepo:    companyRepo,
+		contactRepo:    contactRepo,
+		tenantUserRepo: tenantUserRepo,
+	}
+}
+
+func (s *companyService) GetCompanies(tenantID uint, filter *model.CompanyFilter, page, pageSize int) ([]model.Company, int64, error) {
+	return s.companyRepo.FindAll(tenantID, filter, page, pageSize)
+}
+
+// SearchCompanies matches query against company names and domains
+func (s *companyService) SearchCompanies(tenantID uint, query string, page, pageSize int) ([]model.Company, int64, error) {
+	return s.companyRepo.FindAll(tenantID, &model.CompanyFilter{Search: query}, page, pageSize)
+}
+
+func (s *companyService) GetCompany(tenantID, id uint) (*model.Company, error) {
+	company, err := s.companyRepo.FindByID(tenantID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCompanyNotFound
+		}
+		return nil, err
+	}
+	return company, nil
+}
+
+// GetCompanyDetail returns a company with its contact count, open deal value and won
+// revenue, and its first contacts by name
+func (s *companyService) GetCompanyDetail(tenantID, id uint, access model.RecordAccess) (*CompanyDetail, error) {
+	company, err := s.GetCompany(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	stats, err := s.companyRepo.Stats(tenantID, id, access)
+	if err != nil {
+		return nil, err
+	}
+
+	contacts, _, err := s.contactRepo.FindAll(tenantID, &model.ContactFilter{CompanyID: &id}, 1, companyDetailContacts, access)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CompanyDetail{Company: company, Stats: stats, Contacts: contacts}, nil
+}
+
+// CreateCompany validates and creates a company, owned by its creator unless another
+// tenant member is given
+func (s *companyService) CreateCompany(company *model.Company) error {
+	if company.OwnerID == 0 {
+		company.OwnerID = company.CreatedBy
+	} else if !s.tenantUserRepo.CheckUserAccess(company.TenantID, company.OwnerID) {
+		return errors.New("invalid owner_id: user is not a member of this tenant")
+	}
+
+	if err := s.validateCompany(company); err != nil {
+		return err
+	}
+
+	return s.companyRepo.Create(company)
+}
+
+// UpdateCompany applies update to a company. With ownedBy set, only a company owned by
+// that user can be updated.
+func (s *companyService) UpdateCompany(tenantID, id, ownedBy uint, update *CompanyUpdate) (*model.Company, error) {
+	company, err := s.findOwned(tenantID, id, ownedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	for field, value := range map[*string]*string{
+		&company.Name:       update.Name,
+		&company.Domain:     update.Domain,
+		&company.Industry:   update.Industry,
+		&company.Size:       update.Size,
+		&company.Address:    update.Address,
+		&company.City:       update.City,
+		&company.Province:   update.Province,
+		&company.PostalCode: update.PostalCode,
+		&company.Country:    update.Country,
+		&company.Notes:      update.Notes,
+	} {
+		if value != nil {
+			*field = *value
+		}
+	}
+
+	if update.OwnerID != nil && *update.OwnerID != company.OwnerID {
+		if !s.tenantUserRepo.CheckUserAccess(tenantID, *update.OwnerID) {
+			return nil, errors.New("invalid owner_id: user is not a member of this tenant")
+		}
+		company.OwnerID = *update.OwnerID
+	}
+
+	if err := s.validateCompany(company); err != nil {
+		return nil, err
+	}
+
+	if err := s.companyRepo.Update(company); err != nil {
+		return nil, err
+	}
+	return company, nil
+}
+
+// DeleteCompany deletes a company, unlinking its contacts and deals. With ownedBy set,
+// only a company owned by that user can be deleted.
+func (s *companyService) DeleteCompany(tenantID, id, ownedBy uint) (*model.Company, error) {
+	company, err := s.findOwned(tenantID, id, ownedBy)
+	if err != nil {
+		return nil, err
+	}
+	return company, s.companyRepo.Delete(company)
+}
+
+func (s *companyService) findOwned(tenantID, id, ownedBy uint) (*model.Company, error) {
+	company, err := s.GetCompany(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if ownedBy != 0 && company.OwnerID != ownedBy {
+		return nil, ErrCompanyNotFound
+	}
+	return company, nil
+}
+
+// validateCompany checks the attributes of a company to be saved, normalizing its name
+// and domain
+func (s *companyService) validateCompany(company *model.Company) error {
+	company.Name = strings.TrimSpace(company.Name)
+	if company.Name == "" {
+		return errors.New("company name is required")
+	}
+	if len(company.Name) > 255 {
+		return errors.New("company name must be at most 255 characters")
+	}
+
+	existing, err := s.companyRepo.FindByName(company.TenantID, company.Name)
+	if err == nil && existing.ID != company.ID {
+		return errors.New("a company with this name already exists")
+	} else if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+
+	company.Domain = normalizeCompanyDomain(company.Domain)
+	if company.Domain != "" && !companyDomainPattern.MatchString(company.Domain) {
+		return errors.New("invalid domain")
+	}
+
+	if company.Size != "" && !containsString(model.CompanySizes, company.Size) {
+		return errors.New("invalid size. must be one of: " + strings.Join(model.CompanySizes, ", "))
+	}
+
+	return nil
+}
+
+// normalizeCompanyDomain reduces a website or domain to its lowercase host without www,
+// e.g. "https://www.Example.co.id/about" to "example.co.id"
+func normalizeCompanyDomain(domain string) string {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	if i := strings.Index(domain, "://"); i >= 0 {
+		domain = domain[i+3:]
+	}
+	if i := strings.IndexAny(domain, "/?#:"); i >= 0 {
+		domain = domain[:i]
+	}
+	return strings.TrimPrefix(domain, "www.")
+}
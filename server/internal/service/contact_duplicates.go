@@ -0,0 +1,253 @@
+package service
+
+import (
+	"errors"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/repository"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// Reasons a contact is considered a duplicate of another
+const (
+	DuplicateByEmail       = "email"        // Same email, ignoring case
+	DuplicateByPhone       = "phone"        // Same phone or mobile number in E.164 format
+	DuplicateByNameCompany = "name_company" // Similar full name at the same company
+)
+
+// minNameSimilarity is how similar two full names must be, from 0 to 1, for contacts at
+// the same company to be considered duplicates
+const minNameSimilarity = 0.85
+
+var ErrDuplicateContact = errors.New("a matching contact already exists")
+
+// ContactDuplicate is an existing contact matching a new one. Only its ID is given, as the
+// user may not be allowed to see it.
+type ContactDuplicate struct {
+	ContactID uint     `json:"contact_id"`
+	Reasons   []string `json:"reasons"`
+}
+
+// countryCallingCodes maps contact countries (lowercased) to their calling codes, for
+// phone numbers written in national format. Contacts without a country are in Indonesia,
+// the column's default.
+var countryCallingCodes = map[string]string{
+	"indonesia":      "62",
+	"malaysia":       "60",
+	"singapore":      "65",
+	"thailand":       "66",
+	"philippines":    "63",
+	"vietnam":        "84",
+	"australia":      "61",
+	"new zealand":    "64",
+	"india":          "91",
+	"china":          "86",
+	"japan":          "81",
+	"south korea":    "82",
+	"united kingdom": "44",
+	"uk":             "44",
+	"germany":        "49",
+	"france":         "33",
+	"netherlands":    "31",
+	"united states":  "1",
+	"usa":            "1",
+	"canada":         "1",
+}
+
+// companyLegalForms are dropped when comparing company names ("PT Maju Jaya Tbk" is "maju jaya")
+var companyLegalForms = map[string]bool{
+	"pt": true, "cv": true, "tbk": true, "persero": true,
+	"inc": true, "ltd": true, "llc": true, "corp": true, "corporation": true,
+	"co": true, "company": true, "limited": true, "plc": true,
+	"gmbh": true, "ag": true, "bv": true, "sa": true, "sdn": true, "bhd": true,
+}
+
+// normalizePhone returns a phone number in E.164 format, or "" if it does not look like
+// one. Numbers starting with + or 00 are international; numbers starting with a single 0
+// get the calling code of the contact's country; other numbers are taken to include their
+// calling code (e.g. 6281234567890).
+func normalizePhone(phone, country string) string {
+	var digits strings.Builder
+	international := false
+scan:
+	for _, r := range phone {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && digits.Len() == 0:
+			international = true
+		case unicode.IsLetter(r) && digits.Len() > 0:
+			// Extensions such as "ext. 12" are not part of the number
+			break scan
+		}
+	}
+	number := digits.String()
+
+	switch {
+	case international:
+	case strings.HasPrefix(number, "00"):
+		number = number[2:]
+	case strings.HasPrefix(number, "0"):
+		code, ok := countryCallingCodes[strings.ToLower(strings.TrimSpace(country))]
+		if !ok && strings.TrimSpace(country) == "" {
+			code, ok = countryCallingCodes["indonesia"], true
+		}
+		if !ok {
+			return ""
+		}
+		number = code + number[1:]
+	}
+
+	if len(number) < 8 || len(number) > 15 || number[0] == '0' {
+		return ""
+	}
+	return "+" + number
+}
+
+// setContactPhoneKeys computes the E.164 keys of a contact's phone numbers
+func setContactPhoneKeys(contact *model.Contact) {
+	contact.PhoneE164 = normalizePhone(contact.Phone, contact.Country)
+	contact.MobileE164 = normalizePhone(contact.Mobile, contact.Country)
+}
+
+// nameWords lowercases text and splits it into words of letters and digits
+func nameWords(text string) []string {
+	return strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// fullNameKey normalizes a contact's name with its words sorted, so that "Smith, John"
+// and "john smith" compare equal
+func fullNameKey(contact *model.Contact) string {
+	words := nameWords(contact.FirstName + " " + contact.LastName)
+	sort.Strings(words)
+	return strings.Join(words, " ")
+}
+
+// companyKey normalizes a company name without its legal form
+func companyKey(company string) string {
+	var words []string
+	for _, word := range nameWords(company) {
+		if !companyLegalForms[word] {
+			words = append(words, word)
+		}
+	}
+	return strings.Join(words, " ")
+}
+
+// longestWord returns the longest word of a key, the most selective one to search for
+func longestWord(key string) string {
+	longest := ""
+	for _, word := range strings.Fields(key) {
+		if len(word) > len(longest) {
+			longest = word
+		}
+	}
+	return longest
+}
+
+// similarity returns 1 minus the Levenshtein distance of a and b relative to the longer one
+func similarity(a, b string) float64 {
+	ra, rb := []rune(a), []rune(b)
+	longest := len(ra)
+	if len(rb) > longest {
+		longest = len(rb)
+	}
+	if longest == 0 {
+		return 1
+	}
+
+	previous := make([]int, len(rb)+1)
+	current := make([]int, len(rb)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		current[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+	return 1 - float64(previous[len(rb)])/float64(longest)
+}
+
+// duplicateFinder looks for existing contacts matching a contact, following the tenant's
+// duplicate_contacts setting
+type duplicateFinder struct {
+	contactRepo repository.ContactRepository
+	settingRepo repository.TenantSettingRepository
+}
+
+// strategy returns the tenant's duplicate_contacts setting
+func (f *duplicateFinder) strategy(tenantID uint) (string, error) {
+	setting, err := f.settingRepo.Get(tenantID, model.SettingDuplicateContacts)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return model.TenantSettingDefaults[model.SettingDuplicateContacts], nil
+		}
+		return "", err
+	}
+	return setting.Value, nil
+}
+
+// find returns the contacts of the tenant matching contact, other than contact itself.
+// Phone keys must be set.
+func (f *duplicateFinder) find(tenantID uint, contact *model.Contact) ([]ContactDuplicate, error) {
+	var phones []string
+	for _, phone := range []string{contact.PhoneE164, contact.MobileE164} {
+		if phone != "" {
+			phones = append(phones, phone)
+		}
+	}
+
+	company := companyKey(contact.CompanyName)
+	name := fullNameKey(contact)
+	companyWord := ""
+	if company != "" && name != "" {
+		companyWord = longestWord(company)
+	}
+
+	candidates, err := f.contactRepo.FindDuplicateCandidates(tenantID, contact.ID, strings.TrimSpace(contact.Email), phones, companyWord)
+	if err != nil {
+		return nil, err
+	}
+
+	var duplicates []ContactDuplicate
+	seen := make(map[uint]bool)
+	for i := range candidates {
+		candidate := &candidates[i]
+		if seen[candidate.ID] {
+			continue
+		}
+		seen[candidate.ID] = true
+
+		var reasons []string
+		if contact.Email != "" && strings.EqualFold(strings.TrimSpace(candidate.Email), strings.TrimSpace(contact.Email)) {
+			reasons = append(reasons, DuplicateByEmail)
+		}
+		for _, phone := range phones {
+			if phone == candidate.PhoneE164 || phone == candidate.MobileE164 {
+				reasons = append(reasons, DuplicateByPhone)
+				break
+			}
+		}
+		if companyWord != "" && companyKey(candidate.CompanyName) == company &&
+			similarity(fullNameKey(candidate), name) >= minNameSimilarity {
+			reasons = append(reasons, DuplicateByNameCompany)
+		}
+
+		if len(reasons) > 0 {
+			duplicates = append(duplicates, ContactDuplicate{ContactID: candidate.ID, Reasons: reasons})
+		}
+	}
+	return duplicates, nil
+}
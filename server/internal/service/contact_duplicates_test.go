@@ -0,0 +1,108 @@
+package service
+
+import (
+	"gin-quickstart/internal/model"
+	"math"
+	"testing"
+)
+
+func TestNormalizePhone(t *testing.T) {
+	tests := []struct {
+		name    string
+		phone   string
+		country string
+		want    string
+	}{
+		{"international", "+62 812-3456-7890", "", "+6281234567890"},
+		{"international ignores country", "+44 20 7946 0958", "Indonesia", "+442079460958"},
+		{"00 prefix", "0062 812 3456 7890", "", "+6281234567890"},
+		{"00 prefix ignores country", "0044 20 7946 0958", "Japan", "+442079460958"},
+		{"national without country", "0812-3456-7890", "", "+6281234567890"},
+		{"national without country, blank", "0812 3456 7890", "  ", "+6281234567890"},
+		{"national with country", "020 7946 0958", "United Kingdom", "+442079460958"},
+		{"national country case and spaces", "(03) 1234 5678", " MALAYSIA ", "+60312345678"},
+		{"national unknown country", "0812 3456 7890", "Atlantis", ""},
+		{"calling code without prefix", "6281234567890", "", "+6281234567890"},
+		{"extension", "+62 21 555 1234 ext. 12", "", "+62215551234"},
+		{"extension abbreviated", "021 555 1234 x99", "", "+62215551234"},
+		{"too short", "+62 812", "", ""},
+		{"too long", "+62 8123 4567 8901 2345", "", ""},
+		{"only zeros after prefix", "000812345678", "", ""},
+		{"empty", "", "", ""},
+		{"letters only", "n/a", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizePhone(tt.phone, tt.country); got != tt.want {
+				t.Errorf("normalizePhone(%q, %q) = %q, want %q", tt.phone, tt.country, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompanyKey(t *testing.T) {
+	tests := []struct {
+		company string
+		want    string
+	}{
+		{"PT Maju Jaya Tbk", "maju jaya"},
+		{"PT. Maju Jaya (Persero)", "maju jaya"},
+		{"maju jaya", "maju jaya"},
+		{"Acme, Inc.", "acme"},
+		{"Acme Corp", "acme"},
+		{"Globex Sdn Bhd", "globex"},
+		{"CV", ""},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		if got := companyKey(tt.company); got != tt.want {
+			t.Errorf("companyKey(%q) = %q, want %q", tt.company, got, tt.want)
+		}
+	}
+}
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want float64
+	}{
+		{"", "", 1},
+		{"john smith", "john smith", 1},
+		{"john smith", "jon smith", 0.9},
+		{"john smith", "", 0},
+		{"abc", "xyz", 0},
+		{"budi", "budí", 0.75}, // Compared by rune, not byte
+		// Legal forms are stripped before companies are compared
+		{companyKey("PT Maju Jaya Tbk"), companyKey("Maju Jaya"), 1},
+		{companyKey("PT Maju Jaya"), companyKey("CV Maju Jaya"), 1},
+	}
+
+	for _, tt := range tests {
+		if got := similarity(tt.a, tt.b); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("similarity(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+		if got := similarity(tt.b, tt.a); math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("similarity(%q, %q) = %v, want %v", tt.b, tt.a, got, tt.want)
+		}
+	}
+}
+
+func TestFullNameKeySimilarity(t *testing.T) {
+	tests := []struct {
+		a, b model.Contact
+		want bool
+	}{
+		{model.Contact{FirstName: "John", LastName: "Smith"}, model.Contact{FirstName: "Smith,", LastName: "John"}, true},
+		{model.Contact{FirstName: "Jon", LastName: "Smith"}, model.Contact{FirstName: "John", LastName: "Smith"}, true},
+		{model.Contact{FirstName: "Jane", LastName: "Doe"}, model.Contact{FirstName: "John", LastName: "Smith"}, false},
+	}
+
+	for _, tt := range tests {
+		a, b := fullNameKey(&tt.a), fullNameKey(&tt.b)
+		if got := similarity(a, b) >= minNameSimilarity; got != tt.want {
+			t.Errorf("similar(%q, %q) = %v, want %v", a, b, got, tt.want)
+		}
+	}
+}
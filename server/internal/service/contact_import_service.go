@@ -0,0 +1,965 @@
+package service
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"gin-quickstart/internal/blobstore"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/repository"
+	"gin-quickstart/internal/spreadsheet"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+const (
+	contactImportListLimit      = 20             // Imports returned by ListImports
+	contactImportJobInterval    = time.Minute    // How often the job looks for queued imports
+	staleContactImportAfter     = 6 * time.Hour  // Running imports older than this were interrupted
+	abandonedContactImportAfter = 24 * time.Hour // Uploads not started within this are discarded
+	contactImportBatchSize      = 500            // Contacts inserted per statement
+	contactImportPreviewRows    = 10
+	maxContactImportRows        = 100000
+	maxTagDelimiterLength       = 4
+)
+
+var (
+	ErrContactImportNotFound = errors.New("import not found")
+	ErrContactImportStarted  = errors.New("the import has already been started")
+)
+
+// errStopRows ends a row iteration early without an error
+var errStopRows = errors.New("stop")
+
+// contactFieldLengths are the column sizes of the imported contact fields
+var contactFieldLengths = map[string]int{
+	"first_name":   100,
+	"last_name":    100,
+	"email":        255,
+	"phone":        50,
+	"mobile":       50,
+	"company_name": 255,
+	"position":     100,
+	"department":   100,
+	"city":         100,
+	"province":     100,
+	"postal_code":  20,
+	"country":      100,
+	"source":       50,
+}
+
+// contactColumnAliases suggest a field for common spreadsheet column names, normalized
+// by normalizeColumnName. Field names themselves are matched too.
+var contactColumnAliases = map[string]string{
+	"firstname": "first_name", "given_name": "first_name",
+	"lastname": "last_name", "surname": "last_name", "family_name": "last_name",
+	"e_mail": "email", "email_address": "email", "mail": "email",
+	"phone_number": "phone", "telephone": "phone", "tel": "phone",
+	"mobile_phone": "mobile", "cell": "mobile", "cell_phone": "mobile", "handphone": "mobile",
+	"company": "company_name", "organization": "company_name", "organisation": "company_name",
+	"job_title": "position", "title": "position",
+	"street": "address", "street_address": "address",
+	"town":  "city",
+	"state": "province", "region": "province",
+	"zip": "postal_code", "zip_code": "postal_code", "postcode": "postal_code",
+	"lead_source": "source",
+	"labels":      "tags",
+	"note":        "notes", "comments": "notes",
+	"owner": "owner_email",
+}
+
+// ContactImportOptions confirm how the columns of an upload become contacts. Omitted
+// values keep the ones stored with the import (initially the suggested mapping).
+type ContactImportOptions struct {
+	Mapping      map[string]string `json:"mapping"`       // Column name -> contact field; unmapped columns are ignored
+	TagDelimiter string            `json:"tag_delimiter"` // Separates tags within the tags column
+}
+
+// ContactImportPreviewRow is a data row as it would be imported
+type ContactImportPreviewRow struct {
+	Row     int            `json:"row"` // Row number in the file, the header being row 1
+	Contact *model.Contact `json:"contact"`
+	Errors  []string       `json:"errors,omitempty"` // The row would be skipped and reported
+
+	// Existing contact or earlier row the contact matches, when the tenant's
+	// duplicate_contacts setting is "warn" (with "block" it is one of the errors)
+	Duplicate string `json:"duplicate,omitempty"`
+}
+
+type ContactImportService interface {
+	Fields(tenantID uint) ([]string, error)
+	Upload(tenantID uint, requester Requester, filename string, file io.Reader, delimiter string) (*model.ContactImport, error)
+	Preview(tenantID, id uint, options ContactImportOptions) ([]ContactImportPreviewRow, error)
+	Start(tenantID, id uint, options ContactImportOptions) (*model.ContactImport, error)
+	GetImport(tenantID, id uint) (*model.ContactImport, error)
+	ListImports(tenantID uint) ([]model.ContactImport, error)
+	OpenErrorReport(tenantID, id uint) (*model.ContactImport, io.ReadCloser, error)
+	StartContactImportJob()
+}
+
+type contactImportService struct {
+	importRepo      repository.ContactImportRepository
+	contactRepo     repository.ContactRepository
+	auditLogRepo    repository.AuditLogRepository
+	customFieldRepo repository.CustomFieldRepository
+	companyRepo     repository.CompanyRepository
+	blobs           blobstore.Store
+	duplicates      duplicateFinder
+}
+
+func NewContactImportService(
+	importRepo repository.ContactImportRepository,
+	contactRepo repository.ContactRepository,
+	auditLogRepo repository.AuditLogRepository,
+	settingRepo repository.TenantSettingRepository,
+	customFieldRepo repository.CustomFieldRepository,
+	companyRepo repository.CompanyRepository,
+	blobs blobstore.Store,
+) ContactImportService {
+	return &contactImportService{
+		importRepo:      importRepo,
+		contactRepo:     contactRepo,
+		auditLogRepo:    auditLogRepo,
+		customFieldRepo: customFieldRepo,
+		companyRepo:     companyRepo,
+		blobs:           blobs,
+		duplicates:      duplicateFinder{contactRepo: contactRepo, settingRepo: settingRepo},
+	}
+}
+
+// Fields returns the fields a column can be mapped to: ContactImportFields and the
+// tenant's custom contact fields
+func (s *contactImportService) Fields(tenantID uint) ([]string, error) {
+	customFields, err := loadCustomFields(s.customFieldRepo, tenantID, model.CustomFieldEntityContact)
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]string{}, model.ContactImportFields...), customFields.columns()...), nil
+}
+
+// Upload stores a CSV or XLSX file, reads its column names and counts its rows. The
+// import starts once the column mapping is confirmed with Start.
+func (s *contactImportService) Upload(tenantID uint, requester Requester, filename string, file io.Reader, delimiter string) (*model.ContactImport, error) {
+	format, err := spreadsheet.FormatOf(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	imp := &model.ContactImport{
+		TenantID:     tenantID,
+		RequestedBy:  requester.UserID,
+		APIKeyID:     requester.APIKeyID,
+		OperatorID:   requester.OperatorID,
+		Status:       model.ContactImportUploaded,
+		Filename:     filename,
+		Format:       format,
+		TagDelimiter: ",",
+	}
+	if format == spreadsheet.FormatCSV {
+		comma, err := spreadsheet.ParseDelimiter(delimiter)
+		if err != nil {
+			return nil, err
+		}
+		imp.Delimiter = string(comma)
+	}
+
+	name, err := generateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	imp.BlobKey = tenantBlobPrefix(tenantID) + "contact-imports/" + name + "." + format
+
+	imp.SizeBytes, err = s.blobs.Put(imp.BlobKey, file)
+	if err != nil {
+		return nil, errors.New("failed to store the file")
+	}
+
+	if err := s.scan(imp); err != nil {
+		s.blobs.Delete(imp.BlobKey)
+		return nil, err
+	}
+
+	customFields, err := loadCustomFields(s.customFieldRepo, tenantID, model.CustomFieldEntityContact)
+	if err != nil {
+		s.blobs.Delete(imp.BlobKey)
+		return nil, err
+	}
+	imp.Mapping = suggestContactMapping(imp.Columns, customFields)
+
+	if err := s.importRepo.Create(imp); err != nil {
+		s.blobs.Delete(imp.BlobKey)
+		return nil, errors.New("failed to create import")
+	}
+	return imp, nil
+}
+
+// scan reads the column names and counts the data rows of an uploaded file
+func (s *contactImportService) scan(imp *model.ContactImport) error {
+	reader, closeSheet, err := s.openSheet(imp)
+	if err != nil {
+		return err
+	}
+	defer closeSheet()
+
+	imp.Columns, err = readColumns(reader)
+	if err != nil {
+		return err
+	}
+
+	imp.TotalRows = 0
+	err = forEachDataRow(reader, func(int, []string) error {
+		imp.TotalRows++
+		if imp.TotalRows > maxContactImportRows {
+			return fmt.Errorf("the file has more than %d rows, split it into several imports", maxContactImportRows)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if imp.TotalRows == 0 {
+		return errors.New("the file has no rows below the column names")
+	}
+	return nil
+}
+
+// Preview maps the first rows of an upload to contacts and validates them
+func (s *contactImportService) Preview(tenantID, id uint, options ContactImportOptions) ([]ContactImportPreviewRow, error) {
+	imp, err := s.GetImport(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if imp.Status != model.ContactImportUploaded {
+		return nil, ErrContactImportStarted
+	}
+
+	mapper, err := s.newRowMapper(imp, options)
+	if err != nil {
+		return nil, err
+	}
+
+	reader, closeSheet, err := s.openSheet(imp)
+	if err != nil {
+		return nil, err
+	}
+	defer closeSheet()
+
+	if _, err := readColumns(reader); err != nil {
+		return nil, err
+	}
+
+	strategy, err := s.duplicates.strategy(tenantID)
+	if err != nil {
+		return nil, err
+	}
+	accepted := importedContacts{}
+
+	rows := []ContactImportPreviewRow{}
+	err = forEachDataRow(reader, func(number int, row []string) error {
+		contact, rowErrors := mapper.contact(row)
+		preview := ContactImportPreviewRow{Row: number, Contact: contact, Errors: rowErrors}
+		if len(rowErrors) == 0 && strategy != model.DuplicatesAllow {
+			duplicate, err := s.findDuplicate(tenantID, contact, accepted)
+			if err != nil {
+				return err
+			}
+			if duplicate != "" && strategy == model.DuplicatesBlock {
+				preview.Errors = append(preview.Errors, duplicate)
+			} else {
+				preview.Duplicate = duplicate
+			}
+		}
+		if len(preview.Errors) == 0 {
+			accepted.add(contact, number)
+		}
+		rows = append(rows, preview)
+		if len(rows) == contactImportPreviewRows {
+			return errStopRows
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// Start confirms the column mapping and imports the file in the background
+func (s *contactImportService) Start(tenantID, id uint, options ContactImportOptions) (*model.ContactImport, error) {
+	imp, err := s.GetImport(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if imp.Status != model.ContactImportUploaded {
+		return nil, ErrContactImportStarted
+	}
+
+	mapper, err := s.newRowMapper(imp, options)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.importRepo.Queue(imp.ID, mapper.mapping, mapper.tagDelimiter); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrContactImportStarted
+		}
+		return nil, err
+	}
+
+	go s.run(imp.ID)
+
+	return s.importRepo.FindByID(imp.ID)
+}
+
+func (s *contactImportService) GetImport(tenantID, id uint) (*model.ContactImport, error) {
+	imp, err := s.importRepo.FindByID(id)
+	if err != nil || imp.TenantID != tenantID {
+		return nil, ErrContactImportNotFound
+	}
+	return imp, nil
+}
+
+// ListImports returns the tenant's most recent contact imports
+func (s *contactImportService) ListImports(tenantID uint) ([]model.ContactImport, error) {
+	return s.importRepo.FindByTenant(tenantID, contactImportListLimit)
+}
+
+// OpenErrorReport opens the CSV listing the rows that were not imported and why
+func (s *contactImportService) OpenErrorReport(tenantID, id uint) (*model.ContactImport, io.ReadCloser, error) {
+	imp, err := s.GetImport(tenantID, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !imp.HasErrorReport() {
+		return nil, nil, errors.New("this import has no error report")
+	}
+
+	report, err := s.blobs.Open(imp.ErrorReportKey)
+	if err != nil {
+		return nil, nil, errors.New("the error report is no longer available")
+	}
+	return imp, report, nil
+}
+
+// StartContactImportJob picks up queued imports (e.g. after a restart) and discards
+// uploads that were never started
+func (s *contactImportService) StartContactImportJob() {
+	go func() {
+		ticker := time.NewTicker(contactImportJobInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if failed, err := s.importRepo.FailStale(time.Now().Add(-staleContactImportAfter)); err != nil {
+				log.Printf("⚠️  Failed to mark interrupted contact imports: %v", err)
+			} else if failed > 0 {
+				log.Printf("⚠️  Marked %d interrupted contact imports as failed", failed)
+			}
+
+			abandoned, err := s.importRepo.FindAbandoned(time.Now().Add(-abandonedContactImportAfter), 100)
+			if err != nil {
+				log.Printf("⚠️  Failed to load abandoned contact imports: %v", err)
+			}
+			for _, imp := range abandoned {
+				if err := s.blobs.Delete(imp.BlobKey); err != nil {
+					log.Printf("⚠️  Failed to delete the file of contact import %d: %v", imp.ID, err)
+					continue
+				}
+				s.importRepo.Fail(imp.ID, "the import was not started within 24 hours")
+			}
+
+			pending, err := s.importRepo.FindPending(10)
+			if err != nil {
+				log.Printf("⚠️  Failed to load pending contact imports: %v", err)
+				continue
+			}
+			for _, imp := range pending {
+				s.run(imp.ID)
+			}
+		}
+	}()
+}
+
+// run claims a pending import, creates its contacts and deletes the uploaded file.
+// Another worker may have claimed it already, in which case run does nothing.
+func (s *contactImportService) run(id uint) {
+	if err := s.importRepo.Claim(id); err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("⚠️  Failed to claim contact import %d: %v", id, err)
+		}
+		return
+	}
+
+	imp, err := s.importRepo.FindByID(id)
+	if err != nil {
+		log.Printf("⚠️  Failed to load contact import %d: %v", id, err)
+		return
+	}
+
+	action := "contact_import_completed"
+	reportKey, err := s.process(imp)
+	if err != nil {
+		log.Printf("⚠️  Contact import %d of tenant %d failed: %v", imp.ID, imp.TenantID, err)
+		s.importRepo.Fail(imp.ID, err.Error())
+		action = "contact_import_failed"
+	} else if err := s.importRepo.Complete(imp.ID, reportKey); err != nil {
+		log.Printf("⚠️  Failed to complete contact import %d: %v", imp.ID, err)
+	}
+
+	// Link the imported contacts, of a failed import too, to the companies of their names
+	if _, err := s.companyRepo.LinkContacts(imp.TenantID); err != nil {
+		log.Printf("⚠️  Failed to link the contacts of contact import %d to companies: %v", imp.ID, err)
+	}
+
+	if err := s.blobs.Delete(imp.BlobKey); err != nil {
+		log.Printf("⚠️  Failed to delete the file of contact import %d: %v", imp.ID, err)
+	}
+
+	s.auditLogRepo.Create(attributeJob(&model.AuditLog{
+		TenantID:   imp.TenantID,
+		Action:     action,
+		Resource:   "contact_import",
+		ResourceID: imp.ID,
+	}, imp.RequestedBy, imp.APIKeyID, imp.OperatorID))
+}
+
+// process creates the contacts of every valid row in batches and returns the key of the
+// error report, empty when no row failed. Batches are committed as they are inserted.
+func (s *contactImportService) process(imp *model.ContactImport) (string, error) {
+	mapper, err := s.newRowMapper(imp, ContactImportOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	reader, closeSheet, err := s.openSheet(imp)
+	if err != nil {
+		return "", err
+	}
+	defer closeSheet()
+
+	if _, err := readColumns(reader); err != nil {
+		return "", err
+	}
+
+	strategy, err := s.duplicates.strategy(imp.TenantID)
+	if err != nil {
+		return "", err
+	}
+	accepted := importedContacts{}
+
+	report, err := newImportErrorReport(imp.Columns)
+	if err != nil {
+		return "", err
+	}
+	defer report.close()
+
+	var processed, created, duplicates int
+	var batch []model.Contact
+	var batchRows []importedRow
+
+	flush := func() error {
+		if len(batch) > 0 {
+			if err := s.contactRepo.CreateBatch(batch); err != nil {
+				// Insert one by one to report the rows the database refused
+				for i := range batch {
+					if err := s.contactRepo.Create(&batch[i]); err != nil {
+						log.Printf("⚠️  Contact import %d: row %d was refused: %v", imp.ID, batchRows[i].number, err)
+						if err := report.add(batchRows[i].number, batchRows[i].values, []string{"the contact could not be saved"}); err != nil {
+							return err
+						}
+						continue
+					}
+					created++
+				}
+			} else {
+				created += len(batch)
+			}
+			batch, batchRows = nil, nil
+		}
+		return s.importRepo.UpdateProgress(imp.ID, processed, created, report.rows, duplicates)
+	}
+
+	err = forEachDataRow(reader, func(number int, row []string) error {
+		processed++
+		contact, rowErrors := mapper.contact(row)
+		if len(rowErrors) == 0 && strategy != model.DuplicatesAllow {
+			duplicate, err := s.findDuplicate(imp.TenantID, contact, accepted)
+			if err != nil {
+				return err
+			}
+			if duplicate != "" {
+				duplicates++
+				if strategy == model.DuplicatesBlock {
+					rowErrors = append(rowErrors, duplicate)
+				}
+			}
+		}
+
+		if len(rowErrors) > 0 {
+			if err := report.add(number, row, rowErrors); err != nil {
+				return err
+			}
+		} else {
+			batch = append(batch, *contact)
+			batchRows = append(batchRows, importedRow{number: number, values: row})
+			accepted.add(contact, number)
+		}
+
+		if processed%contactImportBatchSize == 0 {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := flush(); err != nil {
+		return "", err
+	}
+
+	if report.rows == 0 {
+		return "", nil
+	}
+	key := tenantBlobPrefix(imp.TenantID) + "contact-imports/" + strconv.FormatUint(uint64(imp.ID), 10) + "-errors.csv"
+	if err := report.save(s.blobs, key); err != nil {
+		return "", err
+	}
+	return key, nil
+}
+
+// openSheet opens the uploaded file of an import. XLSX workbooks are copied to a
+// temporary file for random access.
+func (s *contactImportService) openSheet(imp *model.ContactImport) (spreadsheet.Reader, func(), error) {
+	blob, err := s.blobs.Open(imp.BlobKey)
+	if err != nil {
+		return nil, nil, errors.New("the uploaded file is no longer available")
+	}
+
+	if imp.Format == spreadsheet.FormatCSV {
+		comma, _ := utf8.DecodeRuneInString(imp.Delimiter)
+		return spreadsheet.NewCSVReader(blob, comma), func() { blob.Close() }, nil
+	}
+	defer blob.Close()
+
+	file, err := os.CreateTemp("", "contact-import-*.xlsx")
+	if err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() {
+		file.Close()
+		os.Remove(file.Name())
+	}
+
+	size, err := io.Copy(file, blob)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+
+	reader, err := spreadsheet.NewXLSXReader(file, size)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return reader, cleanup, nil
+}
+
+// readColumns reads the header row. Unnamed columns are called "Column N" and repeated
+// names get a " (2)" suffix, so that every column can be mapped by name.
+func readColumns(reader spreadsheet.Reader) ([]string, error) {
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("the file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("the file could not be read: %v", err)
+	}
+	if isEmptyRow(header) {
+		return nil, errors.New("the first row must contain the column names")
+	}
+
+	columns := make([]string, len(header))
+	seen := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			name = fmt.Sprintf("Column %d", i+1)
+		}
+		seen[name]++
+		if seen[name] > 1 {
+			name = fmt.Sprintf("%s (%d)", name, seen[name])
+		}
+		columns[i] = name
+	}
+	return columns, nil
+}
+
+// forEachDataRow calls fn for every non-empty row after the header with its row number
+// in the file. fn may return errStopRows to stop early.
+func forEachDataRow(reader spreadsheet.Reader, fn func(number int, row []string) error) error {
+	number := 1
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		number++
+		if err != nil {
+			return fmt.Errorf("row %d could not be read: %v", number, err)
+		}
+		if isEmptyRow(row) {
+			continue
+		}
+		if err := fn(number, row); err != nil {
+			if errors.Is(err, errStopRows) {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func isEmptyRow(row []string) bool {
+	for _, value := range row {
+		if strings.TrimSpace(value) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeColumnName lowercases a column name and joins its words with underscores
+func normalizeColumnName(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "_")
+}
+
+// suggestContactMapping maps columns named like a contact field (or a common alias) to
+// that field, and columns named like a custom field's key, label or export column to the
+// custom field. Each field is suggested for its first matching column only.
+func suggestContactMapping(columns []string, customFields *customFieldSet) map[string]string {
+	fields := make(map[string]bool, len(model.ContactImportFields))
+	for _, field := range model.ContactImportFields {
+		fields[field] = true
+	}
+
+	custom := make(map[string]string)
+	for _, field := range customFields.fields {
+		column := model.CustomFieldPrefix + field.Key
+		for _, name := range []string{column, field.Key, field.Label} {
+			if _, ok := custom[normalizeColumnName(name)]; !ok {
+				custom[normalizeColumnName(name)] = column
+			}
+		}
+	}
+
+	mapping := make(map[string]string)
+	used := make(map[string]bool)
+	for _, column := range columns {
+		name := normalizeColumnName(column)
+		field := contactColumnAliases[name]
+		if fields[name] {
+			field = name
+		} else if field == "" {
+			field = custom[name]
+		}
+		if field != "" && !used[field] {
+			mapping[column] = field
+			used[field] = true
+		}
+	}
+	return mapping
+}
+
+// contactRowMapper turns the rows of one import into contacts
+type contactRowMapper struct {
+	tenantID     uint
+	userID       uint
+	mapping      map[string]string
+	tagDelimiter string
+	columns      map[string]int  // Field -> column index
+	members      map[string]uint // Lowercased email -> user ID, for owner_email
+	customFields *customFieldSet
+}
+
+// newRowMapper validates the mapping of options, falling back to the import's stored
+// mapping and tag delimiter
+func (s *contactImportService) newRowMapper(imp *model.ContactImport, options ContactImportOptions) (*contactRowMapper, error) {
+	mapper := &contactRowMapper{
+		tenantID:     imp.TenantID,
+		userID:       imp.RequestedBy,
+		mapping:      options.Mapping,
+		tagDelimiter: options.TagDelimiter,
+		columns:      make(map[string]int),
+	}
+	if mapper.mapping == nil {
+		mapper.mapping = imp.Mapping
+	}
+	if mapper.tagDelimiter == "" {
+		mapper.tagDelimiter = imp.TagDelimiter
+	}
+	if mapper.tagDelimiter == "" {
+		mapper.tagDelimiter = ","
+	}
+	if utf8.RuneCountInString(mapper.tagDelimiter) > maxTagDelimiterLength {
+		return nil, fmt.Errorf("tag_delimiter must be at most %d characters", maxTagDelimiterLength)
+	}
+
+	indexes := make(map[string]int, len(imp.Columns))
+	for i, column := range imp.Columns {
+		indexes[column] = i
+	}
+	customFields, err := loadCustomFields(s.customFieldRepo, imp.TenantID, model.CustomFieldEntityContact)
+	if err != nil {
+		return nil, err
+	}
+	mapper.customFields = customFields
+
+	fields := make(map[string]bool, len(model.ContactImportFields))
+	for _, field := range model.ContactImportFields {
+		fields[field] = true
+	}
+	for _, field := range customFields.columns() {
+		fields[field] = true
+	}
+
+	for column, field := range mapper.mapping {
+		if field == "" {
+			continue
+		}
+		index, ok := indexes[column]
+		if !ok {
+			return nil, fmt.Errorf("mapping: the file has no column %q", column)
+		}
+		if !fields[field] {
+			return nil, fmt.Errorf("mapping: unknown contact field %q", field)
+		}
+		if _, mapped := mapper.columns[field]; mapped {
+			return nil, fmt.Errorf("mapping: more than one column is mapped to %q", field)
+		}
+		mapper.columns[field] = index
+	}
+	if _, ok := mapper.columns["first_name"]; !ok {
+		return nil, errors.New("mapping: a column must be mapped to first_name")
+	}
+
+	if _, ok := mapper.columns["owner_email"]; ok {
+		members, err := s.importRepo.MemberIDsByEmail(imp.TenantID)
+		if err != nil {
+			return nil, err
+		}
+		mapper.members = members
+	}
+
+	return mapper, nil
+}
+
+// contact builds the contact of a row and lists what prevents creating it
+func (m *contactRowMapper) contact(row []string) (*model.Contact, []string) {
+	value := func(field string) string {
+		index, ok := m.columns[field]
+		if !ok || index >= len(row) {
+			return ""
+		}
+		// Cells of a contact export are escaped against formulas
+		return strings.TrimSpace(spreadsheet.UnescapeFormula(strings.TrimSpace(row[index])))
+	}
+
+	contact := &model.Contact{
+		TenantID:    m.tenantID,
+		CreatedBy:   m.userID,
+		OwnerID:     m.userID,
+		FirstName:   value("first_name"),
+		LastName:    value("last_name"),
+		Email:       value("email"),
+		Phone:       value("phone"),
+		Mobile:      value("mobile"),
+		CompanyName: value("company_name"),
+		Position:    value("position"),
+		Department:  value("department"),
+		Address:     value("address"),
+		City:        value("city"),
+		Province:    value("province"),
+		PostalCode:  value("postal_code"),
+		Country:     value("country"),
+		Status:      strings.ToLower(value("status")),
+		Source:      value("source"),
+		Tags:        splitTags(value("tags"), m.tagDelimiter),
+		Notes:       value("notes"),
+	}
+
+	var rowErrors []string
+	if email := value("owner_email"); email != "" {
+		if ownerID, ok := m.members[strings.ToLower(email)]; ok {
+			contact.OwnerID = ownerID
+		} else {
+			rowErrors = append(rowErrors, fmt.Sprintf("owner_email %s is not a member of this tenant", email))
+		}
+	}
+
+	if err := validateNewContact(contact); err != nil {
+		rowErrors = append(rowErrors, err.Error())
+	}
+
+	for _, field := range model.ContactImportFields {
+		if limit, ok := contactFieldLengths[field]; ok && utf8.RuneCountInString(value(field)) > limit {
+			rowErrors = append(rowErrors, fmt.Sprintf("%s is longer than %d characters", field, limit))
+		}
+	}
+
+	// Custom fields, multi_select options being separated like tags
+	values := model.CustomFieldValues{}
+	valid := true
+	for _, field := range m.customFields.fields {
+		column := model.CustomFieldPrefix + field.Key
+		if _, ok := m.columns[column]; !ok {
+			continue
+		}
+		parsed, err := m.customFields.parseCell(field.Key, value(column), m.tagDelimiter)
+		if err != nil {
+			rowErrors = append(rowErrors, fmt.Sprintf("%s %v", column, err))
+			valid = false
+		} else if parsed != nil {
+			values[field.Key] = parsed
+		}
+	}
+	if valid {
+		customValues, err := m.customFields.validateNew(values)
+		if err != nil {
+			rowErrors = append(rowErrors, err.Error())
+		}
+		contact.CustomFields = customValues
+	}
+
+	setContactPhoneKeys(contact)
+
+	return contact, rowErrors
+}
+
+// findDuplicate describes the earlier row of the file or the existing contact that a
+// row's contact duplicates, or returns "" if there is none
+func (s *contactImportService) findDuplicate(tenantID uint, contact *model.Contact, accepted importedContacts) (string, error) {
+	if number, reason := accepted.match(contact); number > 0 {
+		return fmt.Sprintf("duplicate of row %d (%s)", number, reason), nil
+	}
+
+	duplicates, err := s.duplicates.find(tenantID, contact)
+	if err != nil || len(duplicates) == 0 {
+		return "", err
+	}
+	return fmt.Sprintf("duplicate of contact %d (%s)", duplicates[0].ContactID, strings.Join(duplicates[0].Reasons, ", ")), nil
+}
+
+// importedContacts remembers the rows of a file accepted so far by their email, phone
+// keys and exact name at company, to find duplicates within the file. Rows waiting in a
+// batch are not in the database yet.
+type importedContacts map[string]int // Key -> row number
+
+func importedContactKeys(contact *model.Contact) map[string]string {
+	keys := make(map[string]string)
+	if email := strings.ToLower(strings.TrimSpace(contact.Email)); email != "" {
+		keys["email:"+email] = DuplicateByEmail
+	}
+	for _, phone := range []string{contact.PhoneE164, contact.MobileE164} {
+		if phone != "" {
+			keys["phone:"+phone] = DuplicateByPhone
+		}
+	}
+	if name, company := fullNameKey(contact), companyKey(contact.CompanyName); name != "" && company != "" {
+		keys["name:"+name+"@"+company] = DuplicateByNameCompany
+	}
+	return keys
+}
+
+// match returns the first accepted row sharing a key with contact and why, or 0
+func (c importedContacts) match(contact *model.Contact) (int, string) {
+	number, reason := 0, ""
+	for key, keyReason := range importedContactKeys(contact) {
+		if row, ok := c[key]; ok && (number == 0 || row < number) {
+			number, reason = row, keyReason
+		}
+	}
+	return number, reason
+}
+
+func (c importedContacts) add(contact *model.Contact, number int) {
+	for key := range importedContactKeys(contact) {
+		if _, ok := c[key]; !ok {
+			c[key] = number
+		}
+	}
+}
+
+// splitTags splits a tags cell, dropping empty and repeated tags
+func splitTags(value, delimiter string) model.StringArray {
+	tags := model.StringArray{}
+	seen := make(map[string]bool)
+	for _, tag := range strings.Split(value, delimiter) {
+		tag = strings.TrimSpace(tag)
+		if tag != "" && !seen[tag] {
+			tags = append(tags, tag)
+			seen[tag] = true
+		}
+	}
+	return tags
+}
+
+// importedRow is a row waiting in a batch, kept for the error report
+type importedRow struct {
+	number int
+	values []string
+}
+
+// importErrorReport collects failed rows in a CSV file: row number, errors and the
+// row's original values
+type importErrorReport struct {
+	file   *os.File
+	writer *csv.Writer
+	rows   int
+}
+
+func newImportErrorReport(columns []string) (*importErrorReport, error) {
+	file, err := os.CreateTemp("", "contact-import-errors-*.csv")
+	if err != nil {
+		return nil, err
+	}
+
+	report := &importErrorReport{file: file, writer: csv.NewWriter(file)}
+	if err := report.writer.Write(append([]string{"row", "errors"}, columns...)); err != nil {
+		report.close()
+		return nil, err
+	}
+	return report, nil
+}
+
+func (r *importErrorReport) add(number int, values, rowErrors []string) error {
+	r.rows++
+	return r.writer.Write(append([]string{strconv.Itoa(number), strings.Join(rowErrors, "; ")}, values...))
+}
+
+// save uploads the report to the blob store
+func (r *importErrorReport) save(blobs blobstore.Store, key string) error {
+	r.writer.Flush()
+	if err := r.writer.Error(); err != nil {
+		return err
+	}
+	if _, err := r.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	_, err := blobs.Put(key, r.file)
+	return err
+}
+
+func (r *importErrorReport) close() {
+	r.file.Close()
+	os.Remove(r.file.Name())
+}
@@ -0,0 +1,277 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/repository"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrContactMergeNotFound = errors.New("merge not found")
+	ErrContactMergeConflict = errors.New("the contacts were changed in the meantime, please retry")
+	ErrMergeNotUndoable     = errors.New("the merge was already undone or can no longer be undone")
+	ErrMergeUndoConflict    = errors.New("the merge cannot be undone: a contact was edited or deleted since")
+)
+
+// ContactMergeRequest merges the duplicate contact into the primary contact
+type ContactMergeRequest struct {
+	PrimaryID   uint `json:"primary_id" binding:"required"`
+	DuplicateID uint `json:"duplicate_id" binding:"required"`
+
+	// Field (see model.ContactMergeFields, or model.CustomFieldPrefix + key) -> "primary"
+	// or "duplicate". Fields not listed keep the primary's value unless it is empty.
+	Fields map[string]string `json:"fields"`
+}
+
+type ContactMergeService interface {
+	Merge(tenantID, userID uint, req *ContactMergeRequest, access model.RecordAccess) (*model.ContactMerge, *model.Contact, error)
+	Undo(tenantID, userID, id uint, access model.RecordAccess) (*model.ContactMerge, error)
+	GetMerge(tenantID, id uint, access model.RecordAccess) (*model.ContactMerge, error)
+	ListMerges(tenantID uint, page, pageSize int, access model.RecordAccess) ([]model.ContactMerge, int64, error)
+}
+
+type contactMergeService struct {
+	mergeRepo       repository.ContactMergeRepository
+	contactRepo     repository.ContactRepository
+	tenantUserRepo  repository.TenantUserRepository
+	customFieldRepo repository.CustomFieldRepository
+	undoWindow      time.Duration
+}
+
+func NewContactMergeService(
+	mergeRepo repository.ContactMergeRepository,
+	contactRepo repository.ContactRepository,
+	tenantUserRepo repository.TenantUserRepository,
+	customFieldRepo repository.CustomFieldRepository,
+	undoWindow time.Duration,
+) ContactMergeService {
+	return &contactMergeService{
+		mergeRepo:       mergeRepo,
+		contactRepo:     contactRepo,
+		tenantUserRepo:  tenantUserRepo,
+		customFieldRepo: customFieldRepo,
+		undoWindow:      undoWindow,
+	}
+}
+
+// Merge fills the primary contact field by field from both contacts, unions their tags,
+// moves the duplicate's deals to the primary and deletes the duplicate. Both contacts
+// must be visible to the user.
+func (s *contactMergeService) Merge(tenantID, userID uint, req *ContactMergeRequest, access model.RecordAccess) (*model.ContactMerge, *model.Contact, error) {
+	if req.PrimaryID == req.DuplicateID {
+		return nil, nil, errors.New("a contact cannot be merged into itself")
+	}
+
+	customFields, err := loadCustomFields(s.customFieldRepo, tenantID, model.CustomFieldEntityContact)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	mergeable := make(map[string]bool, len(model.ContactMergeFields))
+	for _, field := range append(customFields.columns(), model.ContactMergeFields...) {
+		mergeable[field] = true
+	}
+	for field, source := range req.Fields {
+		if !mergeable[field] {
+			return nil, nil, fmt.Errorf("fields: %q cannot be merged", field)
+		}
+		if source != "primary" && source != "duplicate" {
+			return nil, nil, fmt.Errorf("fields: %s must be \"primary\" or \"duplicate\"", field)
+		}
+	}
+
+	primary, err := s.contactRepo.FindByID(tenantID, req.PrimaryID, access)
+	if err != nil {
+		return nil, nil, errors.New("primary contact not found")
+	}
+	duplicate, err := s.contactRepo.FindByID(tenantID, req.DuplicateID, access)
+	if err != nil {
+		return nil, nil, errors.New("duplicate contact not found")
+	}
+
+	merged := *primary
+	for _, field := range model.ContactMergeFields {
+		source := req.Fields[field]
+		if source == "duplicate" || (source == "" && contactFieldEmpty(primary, field)) {
+			copyContactField(&merged, duplicate, field)
+		}
+	}
+	merged.Tags = unionTags(primary.Tags, duplicate.Tags)
+	merged.CustomFields = mergeCustomFields(primary.CustomFields, duplicate.CustomFields, req.Fields)
+	setContactPhoneKeys(&merged)
+
+	if merged.FirstName == "" {
+		return nil, nil, errors.New("first name is required")
+	}
+	if merged.OwnerID != primary.OwnerID && !s.tenantUserRepo.CheckUserAccess(tenantID, merged.OwnerID) {
+		return nil, nil, errors.New("the duplicate's owner is no longer a member of this tenant")
+	}
+
+	merge := &model.ContactMerge{
+		TenantID:      tenantID,
+		PrimaryID:     primary.ID,
+		DuplicateID:   duplicate.ID,
+		MergedBy:      userID,
+		PrimaryBefore: *primary,
+		UndoExpiresAt: time.Now().Add(s.undoWindow),
+	}
+	if err := s.mergeRepo.Merge(merge, &merged, duplicate.UpdatedAt); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrContactMergeConflict
+		}
+		return nil, nil, err
+	}
+
+	return merge, &merged, nil
+}
+
+// Undo restores both contacts and moves the deals back to the duplicate. Refused once the
+// undo window has passed or the primary has been edited since the merge.
+func (s *contactMergeService) Undo(tenantID, userID, id uint, access model.RecordAccess) (*model.ContactMerge, error) {
+	merge, err := s.GetMerge(tenantID, id, access)
+	if err != nil {
+		return nil, err
+	}
+	if !merge.CanUndo() {
+		return nil, ErrMergeNotUndoable
+	}
+
+	if _, err := s.contactRepo.FindByID(tenantID, merge.PrimaryID, access); err != nil {
+		return nil, errors.New("primary contact not found")
+	}
+
+	restored := merge.PrimaryBefore
+	restored.ID = merge.PrimaryID
+	setContactPhoneKeys(&restored)
+
+	if err := s.mergeRepo.Undo(merge, &restored, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrMergeUndoConflict
+		}
+		return nil, err
+	}
+
+	return s.mergeRepo.FindByID(tenantID, id, access)
+}
+
+// GetMerge returns a merge whose primary contact is visible with access
+func (s *contactMergeService) GetMerge(tenantID, id uint, access model.RecordAccess) (*model.ContactMerge, error) {
+	merge, err := s.mergeRepo.FindByID(tenantID, id, access)
+	if err != nil {
+		return nil, ErrContactMergeNotFound
+	}
+	return merge, nil
+}
+
+// ListMerges returns the tenant's contact merges whose primary contact is visible with
+// access, most recent first
+func (s *contactMergeService) ListMerges(tenantID uint, page, pageSize int, access model.RecordAccess) ([]model.ContactMerge, int64, error) {
+	return s.mergeRepo.FindByTenant(tenantID, page, pageSize, access)
+}
+
+// contactStringField returns the string field of a contact named by a merge field, or nil
+// for owner_id and team_id
+func contactStringField(contact *model.Contact, field string) *string {
+	switch field {
+	case "first_name":
+		return &contact.FirstName
+	case "last_name":
+		return &contact.LastName
+	case "email":
+		return &contact.Email
+	case "phone":
+		return &contact.Phone
+	case "mobile":
+		return &contact.Mobile
+	case "company_name":
+		return &contact.CompanyName
+	case "position":
+		return &contact.Position
+	case "department":
+		return &contact.Department
+	case "address":
+		return &contact.Address
+	case "city":
+		return &contact.City
+	case "province":
+		return &contact.Province
+	case "postal_code":
+		return &contact.PostalCode
+	case "country":
+		return &contact.Country
+	case "status":
+		return &contact.Status
+	case "source":
+		return &contact.Source
+	case "notes":
+		return &contact.Notes
+	}
+	return nil
+}
+
+func contactFieldEmpty(contact *model.Contact, field string) bool {
+	switch field {
+	case "owner_id":
+		return contact.OwnerID == 0
+	case "team_id":
+		return contact.TeamID == nil
+	}
+	return *contactStringField(contact, field) == ""
+}
+
+func copyContactField(dst, src *model.Contact, field string) {
+	switch field {
+	case "owner_id":
+		dst.OwnerID = src.OwnerID
+	case "team_id":
+		dst.TeamID = src.TeamID
+	case "company_name":
+		dst.CompanyID, dst.CompanyName = src.CompanyID, src.CompanyName
+	default:
+		*contactStringField(dst, field) = *contactStringField(src, field)
+	}
+}
+
+// mergeCustomFields returns the custom field values of the primary, with the values of
+// the duplicate for the fields the primary has none of or that are picked from it
+func mergeCustomFields(primary, duplicate model.CustomFieldValues, fields map[string]string) model.CustomFieldValues {
+	merged := make(model.CustomFieldValues, len(primary))
+	for key, value := range primary {
+		merged[key] = value
+	}
+	for key, value := range duplicate {
+		if _, ok := merged[key]; !ok {
+			merged[key] = value
+		}
+	}
+	for field, source := range fields {
+		if !strings.HasPrefix(field, model.CustomFieldPrefix) || source != "duplicate" {
+			continue
+		}
+		key := strings.TrimPrefix(field, model.CustomFieldPrefix)
+		if value, ok := duplicate[key]; ok {
+			merged[key] = value
+		} else {
+			delete(merged, key)
+		}
+	}
+	return merged
+}
+
+// unionTags returns the tags of a followed by the tags of b it lacks
+func unionTags(a, b model.StringArray) model.StringArray {
+	tags := model.StringArray{}
+	seen := make(map[string]bool)
+	for _, tag := range append(append([]string{}, a...), b...) {
+		if !seen[tag] {
+			tags = append(tags, tag)
+			seen[tag] = true
+		}
+	}
+	return tags
+}
@@ -4,35 +4,157 @@ import (
 	"errors"
 	"gin-quickstart/internal/model"
 	"gin-quickstart/internal/repository"
+	"gin-quickstart/internal/spreadsheet"
+	"strconv"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 type ContactService interface {
-	CreateContact(contact *model.Contact) error
-	GetContact(tenantID, id uint) (*model.Contact, error)
-	GetContacts(tenantID uint, filter *model.ContactFilter, page, pageSize int) ([]model.Contact, int64, error)
-	UpdateContact(tenantID uint, contact *model.Contact) error
-	DeleteContact(tenantID, id uint) error
-	SearchContacts(tenantID uint, query string, page, pageSize int) ([]model.Contact, int64, error)
+	CreateContact(contact *model.Contact) ([]ContactDuplicate, error)
+	GetContact(tenantID, id uint, access model.RecordAccess) (*model.Contact, error)
+	GetContacts(tenantID uint, filter *model.ContactFilter, page, pageSize int, access model.RecordAccess) ([]model.Contact, int64, error)
+	UpdateContact(tenantID uint, contact *model.Contact, access model.RecordAccess) error
+	ReassignOwner(tenantID, id, ownerID uint, access model.RecordAccess) error
+	AssignTeam(tenantID, id uint, teamID *uint, access model.RecordAccess) error
+	DeleteContact(tenantID, id uint, access model.RecordAccess) error
+	SearchContacts(tenantID uint, query string, page, pageSize int, access model.RecordAccess) ([]model.Contact, int64, error)
+	CheckFilter(tenantID uint, filter *model.ContactFilter) error
+	ExportColumns(tenantID uint) ([]string, error)
+	ExportContacts(tenantID uint, filter *model.ContactFilter, columns []string, access model.RecordAccess, w spreadsheet.Writer) (int, error)
+	FindDuplicates(tenantID, id uint, access model.RecordAccess) ([]ContactDuplicate, error)
+	BackfillPhoneKeys() (int, error)
 }
 
 type contactService struct {
-	contactRepo  repository.ContactRepository
-	auditLogRepo repository.AuditLogRepository
+	contactRepo     repository.ContactRepository
+	tenantUserRepo  repository.TenantUserRepository
+	teamRepo        repository.TeamRepository
+	auditLogRepo    repository.AuditLogRepository
+	customFieldRepo repository.CustomFieldRepository
+	companyRepo     repository.CompanyRepository
+	duplicates      duplicateFinder
+}
+
+// contactSortColumns are the contact columns lists can be sorted by, besides custom fields
+var contactSortColumns = map[string]bool{
+	"first_name": true, "last_name": true, "email": true, "company_name": true, "city": true,
+	"status": true, "source": true, "created_at": true, "updated_at": true,
 }
 
 func NewContactService(
 	contactRepo repository.ContactRepository,
+	tenantUserRepo repository.TenantUserRepository,
+	teamRepo repository.TeamRepository,
 	auditLogRepo repository.AuditLogRepository,
+	settingRepo repository.TenantSettingRepository,
+	customFieldRepo repository.CustomFieldRepository,
+	companyRepo repository.CompanyRepository,
 ) ContactService {
 	return &contactService{
-		contactRepo:  contactRepo,
-		auditLogRepo: auditLogRepo,
+		contactRepo:     contactRepo,
+		tenantUserRepo:  tenantUserRepo,
+		teamRepo:        teamRepo,
+		auditLogRepo:    auditLogRepo,
+		customFieldRepo: customFieldRepo,
+		companyRepo:     companyRepo,
+		duplicates:      duplicateFinder{contactRepo: contactRepo, settingRepo: settingRepo},
+	}
+}
+
+// CreateContact creates a contact and returns the existing contacts it duplicates. With the
+// tenant's duplicate_contacts setting on "block", a duplicate is refused with
+// ErrDuplicateContact.
+func (s *contactService) CreateContact(contact *model.Contact) ([]ContactDuplicate, error) {
+	if err := validateNewContact(contact); err != nil {
+		return nil, err
+	}
+
+	// The creator owns the contact unless another tenant member is given
+	if contact.OwnerID == 0 {
+		contact.OwnerID = contact.CreatedBy
+	} else if !s.tenantUserRepo.CheckUserAccess(contact.TenantID, contact.OwnerID) {
+		return nil, errors.New("invalid owner_id: user is not a member of this tenant")
+	}
+
+	if contact.TeamID != nil {
+		if _, err := s.teamRepo.FindByID(contact.TenantID, *contact.TeamID); err != nil {
+			return nil, errors.New("invalid team_id: team not found")
+		}
+	}
+
+	fields, err := loadCustomFields(s.customFieldRepo, contact.TenantID, model.CustomFieldEntityContact)
+	if err != nil {
+		return nil, err
+	}
+	if contact.CustomFields, err = fields.validateNew(contact.CustomFields); err != nil {
+		return nil, err
+	}
+
+	setContactPhoneKeys(contact)
+
+	strategy, err := s.duplicates.strategy(contact.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	var duplicates []ContactDuplicate
+	if strategy != model.DuplicatesAllow {
+		duplicates, err = s.duplicates.find(contact.TenantID, contact)
+		if err != nil {
+			return nil, err
+		}
+		if len(duplicates) > 0 && strategy == model.DuplicatesBlock {
+			return duplicates, ErrDuplicateContact
+		}
 	}
+
+	if err := s.linkCompany(contact.TenantID, contact, contact.OwnerID); err != nil {
+		return nil, err
+	}
+
+	return duplicates, s.contactRepo.Create(contact)
+}
+
+// linkCompany links a contact to the company of its company_id, copying the company's
+// name, or else to the company named by its company_name, which is created (owned by
+// ownerID) if the tenant has none
+func (s *contactService) linkCompany(tenantID uint, contact *model.Contact, ownerID uint) error {
+	if contact.CompanyID != nil {
+		company, err := s.companyRepo.FindByID(tenantID, *contact.CompanyID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("invalid company_id: company not found")
+			}
+			return err
+		}
+		contact.CompanyName = company.Name
+		return nil
+	}
+
+	contact.CompanyName = strings.TrimSpace(contact.CompanyName)
+	if contact.CompanyName == "" {
+		return nil
+	}
+	company, err := s.companyRepo.FindOrCreateByName(&model.Company{
+		TenantID:  tenantID,
+		CreatedBy: ownerID,
+		OwnerID:   ownerID,
+		Name:      contact.CompanyName,
+	})
+	if err != nil {
+		return err
+	}
+	contact.CompanyID = &company.ID
+	contact.CompanyName = company.Name
+	return nil
 }
 
-func (s *contactService) CreateContact(contact *model.Contact) error {
+// validateNewContact checks the fields of a contact about to be created, defaulting its
+// status. Also applied to every row of a contact import.
+func validateNewContact(contact *model.Contact) error {
 	// Validate required fields
 	if contact.FirstName == "" {
 		return errors.New("first name is required")
@@ -49,11 +171,11 @@ func (s *contactService) CreateContact(contact *model.Contact) error {
 		return errors.New("invalid status. must be: active, inactive, or blocked")
 	}
 
-	return s.contactRepo.Create(contact)
+	return nil
 }
 
-func (s *contactService) GetContact(tenantID, id uint) (*model.Contact, error) {
-	contact, err := s.contactRepo.FindByID(tenantID, id)
+func (s *contactService) GetContact(tenantID, id uint, access model.RecordAccess) (*model.Contact, error) {
+	contact, err := s.contactRepo.FindByID(tenantID, id, access)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("contact not found")
@@ -63,13 +185,35 @@ func (s *contactService) GetContact(tenantID, id uint) (*model.Contact, error) {
 	return contact, nil
 }
 
-func (s *contactService) GetContacts(tenantID uint, filter *model.ContactFilter, page, pageSize int) ([]model.Contact, int64, error) {
-	return s.contactRepo.FindAll(tenantID, filter, page, pageSize)
+func (s *contactService) GetContacts(tenantID uint, filter *model.ContactFilter, page, pageSize int, access model.RecordAccess) ([]model.Contact, int64, error) {
+	if err := s.CheckFilter(tenantID, filter); err != nil {
+		return nil, 0, err
+	}
+	return s.contactRepo.FindAll(tenantID, filter, page, pageSize, access)
 }
 
-func (s *contactService) UpdateContact(tenantID uint, contact *model.Contact) error {
-	// Verify contact exists and belongs to tenant
-	existing, err := s.contactRepo.FindByID(tenantID, contact.ID)
+// CheckFilter checks the sort column and custom field conditions of a filter against the
+// tenant's custom contact fields, and prepares them for the repository. Errors wrap
+// ErrInvalidFilter.
+func (s *contactService) CheckFilter(tenantID uint, filter *model.ContactFilter) error {
+	if filter == nil || (len(filter.CustomFields) == 0 && filter.SortBy == "" && filter.SortOrder == "") {
+		return nil
+	}
+
+	fields, err := loadCustomFields(s.customFieldRepo, tenantID, model.CustomFieldEntityContact)
+	if err != nil {
+		return err
+	}
+	if err := fields.resolveConditions(filter.CustomFields); err != nil {
+		return err
+	}
+	filter.SortType, err = fields.resolveSort(filter.SortBy, filter.SortOrder, contactSortColumns)
+	return err
+}
+
+func (s *contactService) UpdateContact(tenantID uint, contact *model.Contact, access model.RecordAccess) error {
+	// Verify contact exists and is visible to the user
+	existing, err := s.contactRepo.FindByID(tenantID, contact.ID, access)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("contact not found")
@@ -80,6 +224,8 @@ func (s *contactService) UpdateContact(tenantID uint, contact *model.Contact) er
 	// Preserve immutable fields (set to zero so Updates() won't change them)
 	contact.TenantID = 0
 	contact.CreatedBy = 0
+	contact.OwnerID = 0      // Reassigned through ReassignOwner only
+	contact.TeamID = nil     // Assigned through AssignTeam only
 	contact.ID = existing.ID // Ensure ID is set for WHERE clause
 
 	// Validate if status is being changed
@@ -90,12 +236,74 @@ func (s *contactService) UpdateContact(tenantID uint, contact *model.Contact) er
 		}
 	}
 
-	return s.contactRepo.Update(contact)
+	// Keep the phone keys in line with the numbers and country being saved
+	if contact.Phone != "" || contact.Mobile != "" || contact.Country != "" {
+		updated := *existing
+		if contact.Phone != "" {
+			updated.Phone = contact.Phone
+		}
+		if contact.Mobile != "" {
+			updated.Mobile = contact.Mobile
+		}
+		if contact.Country != "" {
+			updated.Country = contact.Country
+		}
+		setContactPhoneKeys(&updated)
+		contact.PhoneE164, contact.MobileE164 = updated.PhoneE164, updated.MobileE164
+	}
+
+	// The company is changed by company_id, or else by company_name
+	if contact.CompanyID != nil || contact.CompanyName != "" {
+		if err := s.linkCompany(tenantID, contact, existing.OwnerID); err != nil {
+			return err
+		}
+	}
+
+	// Custom fields left out of the request are unchanged
+	if contact.CustomFields != nil {
+		fields, err := loadCustomFields(s.customFieldRepo, tenantID, model.CustomFieldEntityContact)
+		if err != nil {
+			return err
+		}
+		if contact.CustomFields, err = fields.validatePatch(existing.CustomFields, contact.CustomFields); err != nil {
+			return err
+		}
+	}
+
+	return s.contactRepo.Update(tenantID, contact, access)
+}
+
+// ReassignOwner hands the contact to another tenant member; the creator is unchanged
+func (s *contactService) ReassignOwner(tenantID, id, ownerID uint, access model.RecordAccess) error {
+	if _, err := s.GetContact(tenantID, id, access); err != nil {
+		return err
+	}
+
+	if !s.tenantUserRepo.CheckUserAccess(tenantID, ownerID) {
+		return errors.New("invalid owner_id: user is not a member of this tenant")
+	}
+
+	return s.contactRepo.UpdateOwner(tenantID, id, ownerID)
 }
 
-func (s *contactService) DeleteContact(tenantID, id uint) error {
+// AssignTeam shares the contact with a team, or unassigns it when teamID is nil
+func (s *contactService) AssignTeam(tenantID, id uint, teamID *uint, access model.RecordAccess) error {
+	if _, err := s.GetContact(tenantID, id, access); err != nil {
+		return err
+	}
+
+	if teamID != nil {
+		if _, err := s.teamRepo.FindByID(tenantID, *teamID); err != nil {
+			return errors.New("invalid team_id: team not found")
+		}
+	}
+
+	return s.contactRepo.UpdateTeam(tenantID, id, teamID)
+}
+
+func (s *contactService) DeleteContact(tenantID, id uint, access model.RecordAccess) error {
 	// Verify contact exists
-	_, err := s.contactRepo.FindByID(tenantID, id)
+	_, err := s.contactRepo.FindByID(tenantID, id, access)
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return errors.New("contact not found")
@@ -103,9 +311,134 @@ func (s *contactService) DeleteContact(tenantID, id uint) error {
 		return err
 	}
 
-	return s.contactRepo.Delete(tenantID, id)
+	return s.contactRepo.Delete(tenantID, id, access)
+}
+
+func (s *contactService) SearchContacts(tenantID uint, query string, page, pageSize int, access model.RecordAccess) ([]model.Contact, int64, error) {
+	return s.contactRepo.Search(tenantID, query, page, pageSize, access)
+}
+
+// FindDuplicates returns the other contacts of the tenant matching a visible contact
+func (s *contactService) FindDuplicates(tenantID, id uint, access model.RecordAccess) ([]ContactDuplicate, error) {
+	contact, err := s.GetContact(tenantID, id, access)
+	if err != nil {
+		return nil, err
+	}
+	return s.duplicates.find(tenantID, contact)
+}
+
+// BackfillPhoneKeys computes the E.164 keys of contacts saved before keys existed (run at
+// startup) and returns how many contacts were updated
+func (s *contactService) BackfillPhoneKeys() (int, error) {
+	updated := 0
+	afterID := uint(0)
+	for {
+		contacts, err := s.contactRepo.FindWithoutPhoneKeys(afterID, 500)
+		if err != nil || len(contacts) == 0 {
+			return updated, err
+		}
+
+		for i := range contacts {
+			contact := &contacts[i]
+			setContactPhoneKeys(contact)
+			if contact.PhoneE164 == "" && contact.MobileE164 == "" {
+				continue // Not a phone number
+			}
+			if err := s.contactRepo.UpdatePhoneKeys(contact.ID, contact.PhoneE164, contact.MobileE164); err != nil {
+				return updated, err
+			}
+			updated++
+		}
+		afterID = contacts[len(contacts)-1].ID
+	}
+}
+
+// ExportColumns returns the tenant's custom contact fields as export columns
+func (s *contactService) ExportColumns(tenantID uint) ([]string, error) {
+	fields, err := loadCustomFields(s.customFieldRepo, tenantID, model.CustomFieldEntityContact)
+	if err != nil {
+		return nil, err
+	}
+	return fields.columns(), nil
+}
+
+// ExportContacts writes a header row and the contacts matching filter to w, and returns
+// how many contacts were written. columns must be valid ContactExportColumns or
+// ExportColumns, and filter checked with CheckFilter.
+func (s *contactService) ExportContacts(tenantID uint, filter *model.ContactFilter, columns []string, access model.RecordAccess, w spreadsheet.Writer) (int, error) {
+	if err := w.Write(columns); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	row := make([]string, len(columns))
+	err := s.contactRepo.Each(tenantID, filter, access, func(contact *model.Contact) error {
+		for i, column := range columns {
+			row[i] = contactExportValue(contact, column)
+		}
+		count++
+		return w.Write(row)
+	})
+	if err != nil {
+		return count, err
+	}
+	return count, w.Close()
 }
 
-func (s *contactService) SearchContacts(tenantID uint, query string, page, pageSize int) ([]model.Contact, int64, error) {
-	return s.contactRepo.Search(tenantID, query, page, pageSize)
+// contactExportValue formats a column of a contact as a spreadsheet cell. Tags and
+// multi_select values are joined with commas, as the contact import splits them by default.
+func contactExportValue(contact *model.Contact, column string) string {
+	switch column {
+	case "id":
+		return strconv.FormatUint(uint64(contact.ID), 10)
+	case "first_name":
+		return contact.FirstName
+	case "last_name":
+		return contact.LastName
+	case "email":
+		return contact.Email
+	case "phone":
+		return contact.Phone
+	case "mobile":
+		return contact.Mobile
+	case "company_name":
+		return contact.CompanyName
+	case "position":
+		return contact.Position
+	case "department":
+		return contact.Department
+	case "address":
+		return contact.Address
+	case "city":
+		return contact.City
+	case "province":
+		return contact.Province
+	case "postal_code":
+		return contact.PostalCode
+	case "country":
+		return contact.Country
+	case "status":
+		return contact.Status
+	case "source":
+		return contact.Source
+	case "tags":
+		return strings.Join(contact.Tags, ", ")
+	case "notes":
+		return contact.Notes
+	case "owner_id":
+		return strconv.FormatUint(uint64(contact.OwnerID), 10)
+	case "team_id":
+		if contact.TeamID == nil {
+			return ""
+		}
+		return strconv.FormatUint(uint64(*contact.TeamID), 10)
+	case "created_at":
+		return contact.CreatedAt.UTC().Format(time.RFC3339)
+	case "updated_at":
+		return contact.UpdatedAt.UTC().Format(time.RFC3339)
+	}
+	if strings.HasPrefix(column, model.CustomFieldPrefix) {
+		return formatCustomValue(contact.CustomFields[strings.TrimPrefix(column, model.CustomFieldPrefix)])
+	}
+	return ""
 }
@@ -0,0 +1,548 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/repository"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrCustomFieldNotFound = errors.New("custom field not found")
+	ErrInvalidFilter       = errors.New("invalid filter")
+)
+
+const (
+	maxCustomFieldsPerEntity = 100
+	maxCustomFieldOptions    = 200
+	maxCustomFieldOptionLen  = 100
+	maxCustomFieldTextLen    = 1000
+)
+
+var customFieldKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]{0,49}$`)
+
+var customFieldTypes = map[string]bool{
+	model.CustomFieldText:        true,
+	model.CustomFieldNumber:      true,
+	model.CustomFieldDate:        true,
+	model.CustomFieldSelect:      true,
+	model.CustomFieldMultiSelect: true,
+	model.CustomFieldBoolean:     true,
+}
+
+// CustomFieldUpdate changes the attributes of a custom field; nil members are unchanged.
+// The key and type of a field are fixed once created.
+type CustomFieldUpdate struct {
+	Label    *string         `json:"label"`
+	Options  []string        `json:"options"`
+	Required *bool           `json:"required"`
+	Default  json.RawMessage `json:"default"` // null removes the default
+	Position *int            `json:"position"`
+}
+
+type CustomFieldService interface {
+	ListFields(tenantID uint, entity string) ([]model.CustomFieldDefinition, error)
+	CreateField(field *model.CustomFieldDefinition) error
+	UpdateField(tenantID, id uint, update *CustomFieldUpdate) (*model.CustomFieldDefinition, error)
+	DeleteField(tenantID, id uint) (*model.CustomFieldDefinition, error)
+}
+
+type customFieldService struct {
+	customFieldRepo repository.CustomFieldRepository
+}
+
+func NewCustomFieldService(customFieldRepo repository.CustomFieldRepository) CustomFieldService {
+	return &customFieldService{customFieldRepo: customFieldRepo}
+}
+
+// ListFields returns the tenant's custom fields of an entity, or of both when empty
+func (s *customFieldService) ListFields(tenantID uint, entity string) ([]model.CustomFieldDefinition, error) {
+	if entity != "" && !validCustomFieldEntity(entity) {
+		return nil, errors.New("entity must be contact or deal")
+	}
+	return s.customFieldRepo.FindAll(tenantID, entity)
+}
+
+func (s *customFieldService) CreateField(field *model.CustomFieldDefinition) error {
+	if !validCustomFieldEntity(field.Entity) {
+		return errors.New("entity must be contact or deal")
+	}
+	field.Key = strings.TrimSpace(field.Key)
+	if !customFieldKeyPattern.MatchString(field.Key) {
+		return errors.New("key must start with a letter and contain only lowercase letters, digits and underscores (max 50)")
+	}
+	if !customFieldTypes[field.Type] {
+		return errors.New("type must be text, number, date, select, multi_select or boolean")
+	}
+
+	field.Label = strings.TrimSpace(field.Label)
+	if err := validateCustomFieldLabel(field.Label); err != nil {
+		return err
+	}
+
+	options, err := validateCustomFieldOptions(field.Type, field.Options)
+	if err != nil {
+		return err
+	}
+	field.Options = options
+
+	if field.Default, err = normalizeCustomValue(field, field.Default); err != nil {
+		return fmt.Errorf("default: %w", err)
+	}
+
+	existing, err := s.customFieldRepo.FindAll(field.TenantID, field.Entity)
+	if err != nil {
+		return err
+	}
+	if len(existing) >= maxCustomFieldsPerEntity {
+		return fmt.Errorf("a tenant can have at most %d custom %s fields", maxCustomFieldsPerEntity, field.Entity)
+	}
+	for _, other := range existing {
+		if other.Key == field.Key {
+			return fmt.Errorf("a custom %s field with key %q already exists", field.Entity, field.Key)
+		}
+	}
+	if field.Position == 0 && len(existing) > 0 {
+		field.Position = existing[len(existing)-1].Position + 1
+	}
+
+	return s.customFieldRepo.Create(field)
+}
+
+func (s *customFieldService) UpdateField(tenantID, id uint, update *CustomFieldUpdate) (*model.CustomFieldDefinition, error) {
+	field, err := s.getField(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if update.Label != nil {
+		field.Label = strings.TrimSpace(*update.Label)
+		if err := validateCustomFieldLabel(field.Label); err != nil {
+			return nil, err
+		}
+	}
+	if update.Options != nil {
+		if field.Options, err = validateCustomFieldOptions(field.Type, update.Options); err != nil {
+			return nil, err
+		}
+	}
+	if update.Required != nil {
+		field.Required = *update.Required
+	}
+	if update.Position != nil {
+		field.Position = *update.Position
+	}
+
+	// The default is checked again, as the options it was picked from may have changed
+	if update.Default != nil {
+		var value interface{}
+		if err := json.Unmarshal(update.Default, &value); err != nil {
+			return nil, errors.New("default: invalid JSON")
+		}
+		field.Default = value
+	}
+	if field.Default, err = normalizeCustomValue(field, field.Default); err != nil {
+		return nil, fmt.Errorf("default: %w", err)
+	}
+
+	if err := s.customFieldRepo.Update(field); err != nil {
+		return nil, err
+	}
+	return field, nil
+}
+
+// DeleteField removes a field along with its values on every record
+func (s *customFieldService) DeleteField(tenantID, id uint) (*model.CustomFieldDefinition, error) {
+	field, err := s.getField(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	return field, s.customFieldRepo.Delete(field)
+}
+
+func (s *customFieldService) getField(tenantID, id uint) (*model.CustomFieldDefinition, error) {
+	field, err := s.customFieldRepo.FindByID(tenantID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrCustomFieldNotFound
+		}
+		return nil, err
+	}
+	return field, nil
+}
+
+func validCustomFieldEntity(entity string) bool {
+	return entity == model.CustomFieldEntityContact || entity == model.CustomFieldEntityDeal
+}
+
+func validateCustomFieldLabel(label string) error {
+	if label == "" {
+		return errors.New("label is required")
+	}
+	if len(label) > 100 {
+		return errors.New("label must be at most 100 characters")
+	}
+	return nil
+}
+
+// validateCustomFieldOptions trims and deduplicates the options of a select or
+// multi_select field; other types take none
+func validateCustomFieldOptions(fieldType string, options []string) (model.StringArray, error) {
+	if fieldType != model.CustomFieldSelect && fieldType != model.CustomFieldMultiSelect {
+		if len(options) > 0 {
+			return nil, errors.New("options are only allowed for select and multi_select fields")
+		}
+		return nil, nil
+	}
+
+	seen := make(map[string]bool, len(options))
+	result := model.StringArray{}
+	for _, option := range options {
+		option = strings.TrimSpace(option)
+		if option == "" || seen[strings.ToLower(option)] {
+			continue
+		}
+		if len(option) > maxCustomFieldOptionLen {
+			return nil, fmt.Errorf("options must be at most %d characters", maxCustomFieldOptionLen)
+		}
+		seen[strings.ToLower(option)] = true
+		result = append(result, option)
+	}
+	if len(result) == 0 {
+		return nil, errors.New("select and multi_select fields need at least one option")
+	}
+	if len(result) > maxCustomFieldOptions {
+		return nil, fmt.Errorf("a field can have at most %d options", maxCustomFieldOptions)
+	}
+	return result, nil
+}
+
+// normalizeCustomValue checks a value, as decoded from JSON, against the field's type and
+// returns it in its stored form: a trimmed string (text, date, select, with select values
+// matched case-insensitively to an option), a float64, a bool or a []string of options.
+// Empty values are returned as nil.
+func normalizeCustomValue(field *model.CustomFieldDefinition, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	switch field.Type {
+	case model.CustomFieldNumber:
+		switch number := value.(type) {
+		case float64:
+			// JSON cannot hold NaN or infinities, the values could not be saved
+			if math.IsNaN(number) || math.IsInf(number, 0) {
+				return nil, errors.New("must be a finite number")
+			}
+			return number, nil
+		case int:
+			return float64(number), nil
+		}
+		return nil, errors.New("must be a number")
+
+	case model.CustomFieldBoolean:
+		if b, ok := value.(bool); ok {
+			return b, nil
+		}
+		return nil, errors.New("must be true or false")
+
+	case model.CustomFieldMultiSelect:
+		var items []string
+		switch list := value.(type) {
+		case []string:
+			items = list
+		case []interface{}:
+			for _, item := range list {
+				s, ok := item.(string)
+				if !ok {
+					return nil, errors.New("must be a list of options")
+				}
+				items = append(items, s)
+			}
+		case model.StringArray:
+			items = list
+		default:
+			return nil, errors.New("must be a list of options")
+		}
+
+		selected := []string{}
+		for _, item := range items {
+			if strings.TrimSpace(item) == "" {
+				continue
+			}
+			option, ok := customFieldOption(field, item)
+			if !ok {
+				return nil, fmt.Errorf("%q is not one of the options", item)
+			}
+			if !containsString(selected, option) {
+				selected = append(selected, option)
+			}
+		}
+		if len(selected) == 0 {
+			return nil, nil
+		}
+		return selected, nil
+	}
+
+	text, ok := value.(string)
+	if !ok {
+		return nil, errors.New("must be a string")
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		return nil, nil
+	}
+
+	switch field.Type {
+	case model.CustomFieldDate:
+		if _, err := time.Parse("2006-01-02", text); err != nil {
+			return nil, errors.New("must be a date (YYYY-MM-DD)")
+		}
+	case model.CustomFieldSelect:
+		option, ok := customFieldOption(field, text)
+		if !ok {
+			return nil, fmt.Errorf("%q is not one of the options", text)
+		}
+		return option, nil
+	default:
+		if len(text) > maxCustomFieldTextLen {
+			return nil, fmt.Errorf("must be at most %d characters", maxCustomFieldTextLen)
+		}
+	}
+	return text, nil
+}
+
+// customFieldOption returns the option of a field matching value case-insensitively
+func customFieldOption(field *model.CustomFieldDefinition, value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	for _, option := range field.Options {
+		if strings.EqualFold(option, value) {
+			return option, true
+		}
+	}
+	return "", false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// formatCustomValue formats a stored custom field value as a spreadsheet cell.
+// multi_select options are joined with commas, like tags.
+func formatCustomValue(value interface{}) string {
+	switch value := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(value)
+	case []interface{}:
+		items := make([]string, 0, len(value))
+		for _, item := range value {
+			items = append(items, fmt.Sprint(item))
+		}
+		return strings.Join(items, ", ")
+	case []string:
+		return strings.Join(value, ", ")
+	}
+	return fmt.Sprint(value)
+}
+
+// customFieldSet holds a tenant's custom fields of one entity, to validate, filter and
+// sort the records' values
+type customFieldSet struct {
+	fields []model.CustomFieldDefinition
+	byKey  map[string]*model.CustomFieldDefinition
+}
+
+func loadCustomFields(repo repository.CustomFieldRepository, tenantID uint, entity string) (*customFieldSet, error) {
+	fields, err := repo.FindAll(tenantID, entity)
+	if err != nil {
+		return nil, err
+	}
+	set := &customFieldSet{fields: fields, byKey: make(map[string]*model.CustomFieldDefinition, len(fields))}
+	for i := range set.fields {
+		set.byKey[set.fields[i].Key] = &set.fields[i]
+	}
+	return set, nil
+}
+
+// columns returns the fields as prefixed column names, in display order
+func (set *customFieldSet) columns() []string {
+	columns := make([]string, 0, len(set.fields))
+	for _, field := range set.fields {
+		columns = append(columns, model.CustomFieldPrefix+field.Key)
+	}
+	return columns
+}
+
+// validateNew checks the values of a record about to be created and returns them in their
+// stored form, with defaults filled in
+func (set *customFieldSet) validateNew(values model.CustomFieldValues) (model.CustomFieldValues, error) {
+	result, err := set.apply(model.CustomFieldValues{}, values)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range set.fields {
+		field := &set.fields[i]
+		if _, ok := result[field.Key]; ok {
+			continue
+		}
+		if field.Default != nil {
+			result[field.Key] = field.Default
+		} else if field.Required {
+			return nil, fmt.Errorf("%s%s is required", model.CustomFieldPrefix, field.Key)
+		}
+	}
+	return result, nil
+}
+
+// validatePatch applies changed values over the current values of a record, a null value
+// clearing the field, and returns the values to store
+func (set *customFieldSet) validatePatch(current, patch model.CustomFieldValues) (model.CustomFieldValues, error) {
+	result := make(model.CustomFieldValues, len(current))
+	for key, value := range current {
+		result[key] = value
+	}
+	return set.apply(result, patch)
+}
+
+func (set *customFieldSet) apply(result, values model.CustomFieldValues) (model.CustomFieldValues, error) {
+	// Sorted for a deterministic first error
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		field, ok := set.byKey[key]
+		if !ok {
+			return nil, fmt.Errorf("%s%s is not a custom field", model.CustomFieldPrefix, key)
+		}
+		value, err := normalizeCustomValue(field, values[key])
+		if err != nil {
+			return nil, fmt.Errorf("%s%s %w", model.CustomFieldPrefix, key, err)
+		}
+		if value == nil {
+			if field.Required {
+				return nil, fmt.Errorf("%s%s is required", model.CustomFieldPrefix, key)
+			}
+			delete(result, key)
+			continue
+		}
+		result[key] = value
+	}
+	return result, nil
+}
+
+// parseCell converts a spreadsheet cell to a value of the field. multi_select options are
+// split by delimiter.
+func (set *customFieldSet) parseCell(key, text, delimiter string) (interface{}, error) {
+	field := set.byKey[key]
+	text = strings.TrimSpace(text)
+	if field == nil || text == "" {
+		return nil, nil
+	}
+
+	var value interface{} = text
+	switch field.Type {
+	case model.CustomFieldNumber:
+		number, err := strconv.ParseFloat(text, 64)
+		if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+			return nil, errors.New("must be a number")
+		}
+		value = number
+	case model.CustomFieldBoolean:
+		switch strings.ToLower(text) {
+		case "true", "yes", "y", "1":
+			value = true
+		case "false", "no", "n", "0":
+			value = false
+		default:
+			return nil, errors.New("must be true or false")
+		}
+	case model.CustomFieldMultiSelect:
+		value = strings.Split(text, delimiter)
+	}
+	return normalizeCustomValue(field, value)
+}
+
+// resolveConditions checks filter conditions against the fields, normalizing their values
+// and setting their types. Errors wrap ErrInvalidFilter.
+func (set *customFieldSet) resolveConditions(conditions []model.CustomFieldCondition) error {
+	for i := range conditions {
+		condition := &conditions[i]
+		field, ok := set.byKey[condition.Key]
+		if !ok {
+			return fmt.Errorf("%w: %s%s is not a custom field", ErrInvalidFilter, model.CustomFieldPrefix, condition.Key)
+		}
+		condition.Type = field.Type
+
+		if condition.Op != "eq" && field.Type != model.CustomFieldNumber && field.Type != model.CustomFieldDate {
+			return fmt.Errorf("%w: %s%s only supports equality", ErrInvalidFilter, model.CustomFieldPrefix, condition.Key)
+		}
+
+		switch field.Type {
+		case model.CustomFieldNumber:
+			number, err := strconv.ParseFloat(condition.Value, 64)
+			if err != nil || math.IsNaN(number) || math.IsInf(number, 0) {
+				return fmt.Errorf("%w: %s%s must be a number", ErrInvalidFilter, model.CustomFieldPrefix, condition.Key)
+			}
+			condition.Value = strconv.FormatFloat(number, 'f', -1, 64)
+		case model.CustomFieldDate:
+			if _, err := time.Parse("2006-01-02", condition.Value); err != nil {
+				return fmt.Errorf("%w: %s%s must be a date (YYYY-MM-DD)", ErrInvalidFilter, model.CustomFieldPrefix, condition.Key)
+			}
+		case model.CustomFieldBoolean:
+			b, err := strconv.ParseBool(condition.Value)
+			if err != nil {
+				return fmt.Errorf("%w: %s%s must be true or false", ErrInvalidFilter, model.CustomFieldPrefix, condition.Key)
+			}
+			condition.Value = strconv.FormatBool(b)
+		case model.CustomFieldSelect, model.CustomFieldMultiSelect:
+			if option, ok := customFieldOption(field, condition.Value); ok {
+				condition.Value = option
+			}
+		}
+	}
+	return nil
+}
+
+// resolveSort checks a sort column against columns and the custom fields, and returns the
+// type of the custom field sorted by, if any. Errors wrap ErrInvalidFilter.
+func (set *customFieldSet) resolveSort(sortBy, sortOrder string, columns map[string]bool) (string, error) {
+	if sortOrder != "" && sortOrder != "asc" && sortOrder != "desc" {
+		return "", fmt.Errorf("%w: sort_order must be asc or desc", ErrInvalidFilter)
+	}
+	if sortBy == "" || columns[sortBy] {
+		return "", nil
+	}
+
+	field, ok := set.byKey[strings.TrimPrefix(sortBy, model.CustomFieldPrefix)]
+	if !ok || !strings.HasPrefix(sortBy, model.CustomFieldPrefix) {
+		return "", fmt.Errorf("%w: cannot sort by %q", ErrInvalidFilter, sortBy)
+	}
+	if field.Type == model.CustomFieldMultiSelect {
+		return "", fmt.Errorf("%w: multi_select fields cannot be sorted", ErrInvalidFilter)
+	}
+	return field.Type, nil
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"gin-quickstart/internal/model"
+	"math"
+	"testing"
+)
+
+func TestParseNumberCell(t *testing.T) {
+	set := &customFieldSet{byKey: map[string]*model.CustomFieldDefinition{
+		"budget": {Key: "budget", Type: model.CustomFieldNumber},
+	}}
+
+	tests := []struct {
+		text    string
+		want    interface{}
+		wantErr bool
+	}{
+		{"42.5", 42.5, false},
+		{" -5 ", -5.0, false},
+		{"", nil, false},
+		{"NaN", nil, true},
+		{"Inf", nil, true},
+		{"-Inf", nil, true},
+		{"+infinity", nil, true},
+		{"1e400", nil, true},
+		{"abc", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := set.parseCell("budget", tt.text, ",")
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseCell(%q) error = %v, wantErr %v", tt.text, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseCell(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestNormalizeCustomValueRejectsNonFiniteNumbers(t *testing.T) {
+	field := &model.CustomFieldDefinition{Key: "budget", Type: model.CustomFieldNumber}
+
+	for _, value := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		if _, err := normalizeCustomValue(field, value); err == nil {
+			t.Errorf("normalizeCustomValue(%v) accepted a non-finite number", value)
+		}
+	}
+	if got, err := normalizeCustomValue(field, 3); err != nil || got != 3.0 {
+		t.Errorf("normalizeCustomValue(3) = %v, %v, want 3", got, err)
+	}
+}
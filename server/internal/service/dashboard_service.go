@@ -1,26 +1,34 @@
 package service
 
 import (
+	"errors"
+	"gin-quickstart/internal/model"
 	"gin-quickstart/internal/repository"
+	"slices"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type DashboardService interface {
-	GetDashboardStats(tenantID uint, period string) (*DashboardStats, error)
+	GetDashboardStats(tenantID uint, period string, teamID *uint, access model.RecordAccess) (*DashboardStats, error)
 }
 
 type dashboardService struct {
 	contactRepo  repository.ContactRepository
 	auditLogRepo repository.AuditLogRepository
+	teamRepo     repository.TeamRepository
 }
 
 func NewDashboardService(
 	contactRepo repository.ContactRepository,
 	auditLogRepo repository.AuditLogRepository,
+	teamRepo repository.TeamRepository,
 ) DashboardService {
 	return &dashboardService{
 		contactRepo:  contactRepo,
 		auditLogRepo: auditLogRepo,
+		teamRepo:     teamRepo,
 	}
 }
 
@@ -36,18 +44,40 @@ type MetricData struct {
 	GrowthPercentage float64 `json:"growth_percentage"`
 }
 
-func (s *dashboardService) GetDashboardStats(tenantID uint, period string) (*DashboardStats, error) {
+// GetDashboardStats returns stats of the records and users visible with access, or of a
+// single visible team when teamID is set
+func (s *dashboardService) GetDashboardStats(tenantID uint, period string, teamID *uint, access model.RecordAccess) (*DashboardStats, error) {
+	// Activities are the actions of the users whose records are visible, or of a team's
+	// members; nil counts everyone's
+	var memberIDs []uint
+	if !access.Unrestricted {
+		memberIDs = append([]uint{}, access.OwnerIDs...)
+	}
+	if teamID != nil {
+		if !access.Unrestricted && !slices.Contains(access.TeamIDs, *teamID) {
+			return nil, ErrTeamNotFound
+		}
+		team, err := s.teamRepo.FindByID(tenantID, *teamID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, ErrTeamNotFound
+			}
+			return nil, err
+		}
+		memberIDs = team.MemberIDs()
+	}
+
 	// Calculate time ranges based on period
 	currentStart, currentEnd, previousStart, previousEnd := s.calculateTimeRanges(period)
 
 	// Get total contacts stats
-	totalContacts, err := s.getTotalContactsStats(tenantID, currentStart, currentEnd, previousStart, previousEnd)
+	totalContacts, err := s.getTotalContactsStats(tenantID, teamID, access, currentStart, currentEnd, previousStart, previousEnd)
 	if err != nil {
 		return nil, err
 	}
 
 	// Get recent activities stats
-	recentActivities, err := s.getRecentActivitiesStats(tenantID, currentStart, currentEnd, previousStart, previousEnd)
+	recentActivities, err := s.getRecentActivitiesStats(tenantID, memberIDs, currentStart, currentEnd, previousStart, previousEnd)
 	if err != nil {
 		return nil, err
 	}
@@ -92,15 +122,15 @@ func (s *dashboardService) calculateTimeRanges(period string) (currentStart, cur
 	return currentStart, currentEnd, previousStart, previousEnd
 }
 
-func (s *dashboardService) getTotalContactsStats(tenantID uint, currentStart, currentEnd, previousStart, previousEnd time.Time) (MetricData, error) {
+func (s *dashboardService) getTotalContactsStats(tenantID uint, teamID *uint, access model.RecordAccess, currentStart, currentEnd, previousStart, previousEnd time.Time) (MetricData, error) {
 	// Count contacts in current period
-	currentCount, err := s.contactRepo.CountByDateRange(tenantID, currentStart, currentEnd)
+	currentCount, err := s.contactRepo.CountByDateRange(tenantID, currentStart, currentEnd, teamID, access)
 	if err != nil {
 		return MetricData{}, err
 	}
 
 	// Count contacts in previous period
-	previousCount, err := s.contactRepo.CountByDateRange(tenantID, previousStart, previousEnd)
+	previousCount, err := s.contactRepo.CountByDateRange(tenantID, previousStart, previousEnd, teamID, access)
 	if err != nil {
 		return MetricData{}, err
 	}
@@ -115,15 +145,15 @@ func (s *dashboardService) getTotalContactsStats(tenantID uint, currentStart, cu
 	}, nil
 }
 
-func (s *dashboardService) getRecentActivitiesStats(tenantID uint, currentStart, currentEnd, previousStart, previousEnd time.Time) (MetricData, error) {
+func (s *dashboardService) getRecentActivitiesStats(tenantID uint, userIDs []uint, currentStart, currentEnd, previousStart, previousEnd time.Time) (MetricData, error) {
 	// Count audit logs (activities) in current period
-	currentCount, err := s.auditLogRepo.CountByDateRange(tenantID, currentStart, currentEnd)
+	currentCount, err := s.auditLogRepo.CountByDateRange(tenantID, currentStart, currentEnd, userIDs)
 	if err != nil {
 		return MetricData{}, err
 	}
 
 	// Count audit logs in previous period
-	previousCount, err := s.auditLogRepo.CountByDateRange(tenantID, previousStart, previousEnd)
+	previousCount, err := s.auditLogRepo.CountByDateRange(tenantID, previousStart, previousEnd, userIDs)
 	if err != nil {
 		return MetricData{}, err
 	}
@@ -7,31 +7,60 @@ import (
 )
 
 type DealService struct {
-	dealRepo    repository.DealRepository
-	stageRepo   repository.PipelineStageRepository
-	contactRepo repository.ContactRepository
+	dealRepo        repository.DealRepository
+	stageRepo       repository.PipelineStageRepository
+	contactRepo     repository.ContactRepository
+	tenantUserRepo  repository.TenantUserRepository
+	teamRepo        repository.TeamRepository
+	customFieldRepo repository.CustomFieldRepository
+	companyRepo     repository.CompanyRepository
+}
+
+// dealSortColumns are the deal columns lists can be sorted by, besides custom fields
+var dealSortColumns = map[string]bool{
+	"title": true, "value": true, "probability": true, "status": true, "stage_order": true,
+	"expected_close_date": true, "created_at": true, "updated_at": true,
 }
 
 func NewDealService(
 	dealRepo repository.DealRepository,
 	stageRepo repository.PipelineStageRepository,
 	contactRepo repository.ContactRepository,
+	tenantUserRepo repository.TenantUserRepository,
+	teamRepo repository.TeamRepository,
+	customFieldRepo repository.CustomFieldRepository,
+	companyRepo repository.CompanyRepository,
 ) *DealService {
 	return &DealService{
-		dealRepo:    dealRepo,
-		stageRepo:   stageRepo,
-		contactRepo: contactRepo,
+		dealRepo:        dealRepo,
+		stageRepo:       stageRepo,
+		contactRepo:     contactRepo,
+		tenantUserRepo:  tenantUserRepo,
+		teamRepo:        teamRepo,
+		customFieldRepo: customFieldRepo,
+		companyRepo:     companyRepo,
 	}
 }
 
 // GetDeals returns all deals with filters
-func (s *DealService) GetDeals(tenantID uint, filter repository.DealFilter) ([]model.Deal, int64, error) {
-	deals, err := s.dealRepo.FindAll(tenantID, filter)
+func (s *DealService) GetDeals(tenantID uint, filter repository.DealFilter, access model.RecordAccess) ([]model.Deal, int64, error) {
+	fields, err := loadCustomFields(s.customFieldRepo, tenantID, model.CustomFieldEntityDeal)
 	if err != nil {
 		return nil, 0, err
 	}
+	if err := fields.resolveConditions(filter.CustomFields); err != nil {
+		return nil, 0, err
+	}
+	if filter.SortType, err = fields.resolveSort(filter.SortBy, filter.SortOrder, dealSortColumns); err != nil {
+		return nil, 0, err
+	}
 
-	count, err := s.dealRepo.Count(tenantID, filter)
+	deals, err := s.dealRepo.FindAll(tenantID, filter, access)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := s.dealRepo.Count(tenantID, filter, access)
 	if err != nil {
 		return deals, 0, err
 	}
@@ -40,16 +69,16 @@ func (s *DealService) GetDeals(tenantID uint, filter repository.DealFilter) ([]m
 }
 
 // GetDealByID returns a single deal by ID
-func (s *DealService) GetDealByID(tenantID uint, id uint) (*model.Deal, error) {
-	deal, err := s.dealRepo.FindByID(tenantID, id)
+func (s *DealService) GetDealByID(tenantID uint, id uint, access model.RecordAccess) (*model.Deal, error) {
+	deal, err := s.dealRepo.FindByID(tenantID, id, access)
 	if err != nil {
 		return nil, errors.New("deal not found")
 	}
 	return deal, nil
 }
 
-// CreateDeal creates a new deal
-func (s *DealService) CreateDeal(deal *model.Deal) error {
+// CreateDeal creates a new deal with a contact visible with access
+func (s *DealService) CreateDeal(deal *model.Deal, access model.RecordAccess) error {
 	// Validate required fields
 	if deal.Title == "" {
 		return errors.New("deal title is required")
@@ -65,12 +94,42 @@ func (s *DealService) CreateDeal(deal *model.Deal) error {
 		return errors.New("invalid stage_id: stage not found")
 	}
 
-	// Validate contact if provided
+	// Validate contact if provided; the deal is with the contact's company by default
 	if deal.ContactID > 0 {
-		_, err := s.contactRepo.FindByID(deal.TenantID, deal.ContactID)
+		contact, err := s.contactRepo.FindByID(deal.TenantID, deal.ContactID, access)
 		if err != nil {
 			return errors.New("invalid contact_id: contact not found")
 		}
+		if deal.CompanyID == nil {
+			deal.CompanyID = contact.CompanyID
+		}
+	}
+
+	// Validate company if provided
+	if err := s.validateCompany(deal.TenantID, deal.CompanyID); err != nil {
+		return err
+	}
+
+	// The creator owns the deal unless another tenant member is given
+	if deal.OwnerID == 0 {
+		deal.OwnerID = deal.CreatedBy
+	} else if !s.tenantUserRepo.CheckUserAccess(deal.TenantID, deal.OwnerID) {
+		return errors.New("invalid owner_id: user is not a member of this tenant")
+	}
+
+	// Validate team if provided
+	if deal.TeamID != nil {
+		if _, err := s.teamRepo.FindByID(deal.TenantID, *deal.TeamID); err != nil {
+			return errors.New("invalid team_id: team not found")
+		}
+	}
+
+	fields, err := loadCustomFields(s.customFieldRepo, deal.TenantID, model.CustomFieldEntityDeal)
+	if err != nil {
+		return err
+	}
+	if deal.CustomFields, err = fields.validateNew(deal.CustomFields); err != nil {
+		return err
 	}
 
 	// Set probability from stage if not provided
@@ -87,13 +146,16 @@ func (s *DealService) CreateDeal(deal *model.Deal) error {
 }
 
 // UpdateDeal updates a deal
-func (s *DealService) UpdateDeal(tenantID uint, deal *model.Deal) error {
+func (s *DealService) UpdateDeal(tenantID uint, deal *model.Deal, access model.RecordAccess) error {
 	// Check if deal exists
-	existing, err := s.dealRepo.FindByID(tenantID, deal.ID)
+	existing, err := s.dealRepo.FindByID(tenantID, deal.ID, access)
 	if err != nil {
 		return errors.New("deal not found")
 	}
 
+	// Team is assigned through AssignTeam only
+	deal.TeamID = nil
+
 	// Validate title if provided
 	if deal.Title != "" && deal.Title != existing.Title {
 		// Title is being updated
@@ -111,31 +173,88 @@ func (s *DealService) UpdateDeal(tenantID uint, deal *model.Deal) error {
 		}
 	}
 
-	// Validate contact if provided
+	// Validate contact if provided; a new contact brings its company unless one is given
 	if deal.ContactID > 0 && deal.ContactID != existing.ContactID {
-		_, err := s.contactRepo.FindByID(tenantID, deal.ContactID)
+		contact, err := s.contactRepo.FindByID(tenantID, deal.ContactID, access)
 		if err != nil {
 			return errors.New("invalid contact_id: contact not found")
 		}
+		if deal.CompanyID == nil {
+			deal.CompanyID = contact.CompanyID
+		}
+	}
+
+	// Validate company if provided
+	if err := s.validateCompany(tenantID, deal.CompanyID); err != nil {
+		return err
+	}
+
+	// Custom fields left out of the request are unchanged
+	if deal.CustomFields != nil {
+		fields, err := loadCustomFields(s.customFieldRepo, tenantID, model.CustomFieldEntityDeal)
+		if err != nil {
+			return err
+		}
+		if deal.CustomFields, err = fields.validatePatch(existing.CustomFields, deal.CustomFields); err != nil {
+			return err
+		}
+	}
+
+	return s.dealRepo.Update(tenantID, deal, access)
+}
+
+func (s *DealService) validateCompany(tenantID uint, companyID *uint) error {
+	if companyID == nil {
+		return nil
+	}
+	if _, err := s.companyRepo.FindByID(tenantID, *companyID); err != nil {
+		return errors.New("invalid company_id: company not found")
+	}
+	return nil
+}
+
+// ReassignOwner hands the deal to another tenant member; the creator is unchanged
+func (s *DealService) ReassignOwner(tenantID uint, dealID uint, ownerID uint, access model.RecordAccess) error {
+	if _, err := s.dealRepo.FindByID(tenantID, dealID, access); err != nil {
+		return errors.New("deal not found")
+	}
+
+	if !s.tenantUserRepo.CheckUserAccess(tenantID, ownerID) {
+		return errors.New("invalid owner_id: user is not a member of this tenant")
+	}
+
+	return s.dealRepo.UpdateFields(tenantID, dealID, map[string]interface{}{"owner_id": ownerID}, access)
+}
+
+// AssignTeam shares the deal with a team, or unassigns it when teamID is nil
+func (s *DealService) AssignTeam(tenantID uint, dealID uint, teamID *uint, access model.RecordAccess) error {
+	if _, err := s.dealRepo.FindByID(tenantID, dealID, access); err != nil {
+		return errors.New("deal not found")
+	}
+
+	if teamID != nil {
+		if _, err := s.teamRepo.FindByID(tenantID, *teamID); err != nil {
+			return errors.New("invalid team_id: team not found")
+		}
 	}
 
-	return s.dealRepo.Update(deal)
+	return s.dealRepo.UpdateFields(tenantID, dealID, map[string]interface{}{"team_id": teamID}, access)
 }
 
 // DeleteDeal deletes a deal
-func (s *DealService) DeleteDeal(tenantID uint, id uint) error {
-	deal, err := s.dealRepo.FindByID(tenantID, id)
+func (s *DealService) DeleteDeal(tenantID uint, id uint, access model.RecordAccess) error {
+	deal, err := s.dealRepo.FindByID(tenantID, id, access)
 	if err != nil {
 		return errors.New("deal not found")
 	}
 
-	return s.dealRepo.Delete(deal)
+	return s.dealRepo.Delete(tenantID, deal, access)
 }
 
 // MoveToStage moves a deal to a different stage
-func (s *DealService) MoveToStage(tenantID uint, dealID uint, newStageID uint) (*model.Deal, error) {
+func (s *DealService) MoveToStage(tenantID uint, dealID uint, newStageID uint, access model.RecordAccess) (*model.Deal, error) {
 	// Verify deal exists
-	_, err := s.dealRepo.FindByID(tenantID, dealID)
+	_, err := s.dealRepo.FindByID(tenantID, dealID, access)
 	if err != nil {
 		return nil, errors.New("deal not found")
 	}
@@ -160,16 +279,16 @@ func (s *DealService) MoveToStage(tenantID uint, dealID uint, newStageID uint) (
 	}
 
 	// Update all fields in one query using map
-	if err := s.dealRepo.UpdateFields(tenantID, dealID, updates); err != nil {
+	if err := s.dealRepo.UpdateFields(tenantID, dealID, updates, access); err != nil {
 		return nil, err
 	}
 
 	// Fetch updated deal with preloaded relations
-	return s.dealRepo.FindByID(tenantID, dealID)
+	return s.dealRepo.FindByID(tenantID, dealID, access)
 }
 
 // UpdateStatus updates deal status
-func (s *DealService) UpdateStatus(tenantID uint, dealID uint, status string) error {
+func (s *DealService) UpdateStatus(tenantID uint, dealID uint, status string, access model.RecordAccess) error {
 	// Validate status
 	validStatuses := []string{"active", "won", "lost", "cancelled"}
 	isValid := false
@@ -185,15 +304,15 @@ func (s *DealService) UpdateStatus(tenantID uint, dealID uint, status string) er
 	}
 
 	// Verify deal exists
-	_, err := s.dealRepo.FindByID(tenantID, dealID)
+	_, err := s.dealRepo.FindByID(tenantID, dealID, access)
 	if err != nil {
 		return errors.New("deal not found")
 	}
 
-	return s.dealRepo.UpdateStatus(tenantID, dealID, status)
+	return s.dealRepo.UpdateStatus(tenantID, dealID, status, access)
 }
 
-// GetPipelineValue returns total value of deals by stage
-func (s *DealService) GetPipelineValue(tenantID uint) (map[uint]float64, error) {
-	return s.dealRepo.GetTotalValueByStage(tenantID)
+// GetPipelineValue returns total value of deals by stage, optionally for one team
+func (s *DealService) GetPipelineValue(tenantID uint, teamID *uint, access model.RecordAccess) (map[uint]float64, error) {
+	return s.dealRepo.GetTotalValueByStage(tenantID, teamID, access)
 }
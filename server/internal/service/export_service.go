@@ -0,0 +1,429 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin-quickstart/config"
+	"gin-quickstart/internal/blobstore"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/repository"
+	"gin-quickstart/internal/spreadsheet"
+	"io"
+	"log"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	exportListLimit   = 20            // Exports returned by ListExports
+	exportJobInterval = time.Minute   // How often the export job looks for work
+	staleExportAfter  = 6 * time.Hour // Running exports older than this were interrupted
+	exportFormat      = 1             // Archive layout version recorded in manifest.json
+)
+
+var (
+	ErrExportInProgress    = errors.New("an export is already in progress for this tenant")
+	ErrInvalidDownloadLink = errors.New("download link is invalid or has expired")
+)
+
+type ExportService interface {
+	RequestExport(tenantID uint, requester Requester) (*model.TenantExport, error)
+	GetExport(tenantID, id uint) (*model.TenantExport, error)
+	ListExports(tenantID uint) ([]model.TenantExport, error)
+	DownloadURL(export *model.TenantExport) (string, time.Time)
+	OpenDownload(id uint, expires, signature string) (*model.TenantExport, io.ReadCloser, error)
+	StartExportJob()
+}
+
+type exportService struct {
+	exportRepo   repository.TenantExportRepository
+	auditLogRepo repository.AuditLogRepository
+	blobs        blobstore.Store
+	cfg          config.ExportConfig
+	publicURL    string
+	signingKey   []byte
+}
+
+func NewExportService(
+	exportRepo repository.TenantExportRepository,
+	auditLogRepo repository.AuditLogRepository,
+	blobs blobstore.Store,
+	cfg config.ExportConfig,
+	publicURL string,
+) ExportService {
+	signingKey := []byte(cfg.URLSigningKey)
+	if len(signingKey) == 0 {
+		signingKey = make([]byte, 32)
+		if _, err := rand.Read(signingKey); err != nil {
+			log.Fatalf("❌ Failed to generate export signing key: %v", err)
+		}
+		log.Println("⚠️  EXPORT_URL_SIGNING_KEY is not set, download links are only valid on this instance until it restarts")
+	}
+
+	return &exportService{
+		exportRepo:   exportRepo,
+		auditLogRepo: auditLogRepo,
+		blobs:        blobs,
+		cfg:          cfg,
+		publicURL:    publicURL,
+		signingKey:   signingKey,
+	}
+}
+
+// tenantBlobPrefix is the blob store prefix holding all files of a tenant
+func tenantBlobPrefix(tenantID uint) string {
+	return fmt.Sprintf("tenants/%d/", tenantID)
+}
+
+// RequestExport queues an export of all tenant data and starts it in the background.
+// A tenant has at most one export in progress.
+func (s *exportService) RequestExport(tenantID uint, requester Requester) (*model.TenantExport, error) {
+	if _, err := s.exportRepo.FindInProgress(tenantID); err == nil {
+		return nil, ErrExportInProgress
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	export := &model.TenantExport{
+		TenantID:    tenantID,
+		RequestedBy: requester.UserID,
+		APIKeyID:    requester.APIKeyID,
+		OperatorID:  requester.OperatorID,
+		Status:      model.ExportPending,
+	}
+	if err := s.exportRepo.Create(export); err != nil {
+		return nil, errors.New("failed to create export")
+	}
+
+	go s.run(export.ID)
+
+	return export, nil
+}
+
+func (s *exportService) GetExport(tenantID, id uint) (*model.TenantExport, error) {
+	export, err := s.exportRepo.FindByID(id)
+	if err != nil || export.TenantID != tenantID {
+		return nil, errors.New("export not found")
+	}
+	return export, nil
+}
+
+// ListExports returns the tenant's most recent exports
+func (s *exportService) ListExports(tenantID uint) ([]model.TenantExport, error) {
+	return s.exportRepo.FindByTenant(tenantID, exportListLimit)
+}
+
+// DownloadURL returns a signed link to a completed export's archive and its expiry
+func (s *exportService) DownloadURL(export *model.TenantExport) (string, time.Time) {
+	expiresAt := time.Now().Add(s.cfg.URLExpiry)
+	if export.ExpiresAt != nil && export.ExpiresAt.Before(expiresAt) {
+		expiresAt = *export.ExpiresAt
+	}
+
+	expires := strconv.FormatInt(expiresAt.Unix(), 10)
+	url := fmt.Sprintf("%s/api/exports/%d/download?expires=%s&signature=%s",
+		s.publicURL, export.ID, expires, s.sign(export.ID, expires))
+	return url, expiresAt
+}
+
+// OpenDownload checks a signed download link and opens the export's archive. The
+// signature is the only credential, so links must not be shared.
+func (s *exportService) OpenDownload(id uint, expires, signature string) (*model.TenantExport, io.ReadCloser, error) {
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > expiresAt {
+		return nil, nil, ErrInvalidDownloadLink
+	}
+	if !hmac.Equal([]byte(signature), []byte(s.sign(id, expires))) {
+		return nil, nil, ErrInvalidDownloadLink
+	}
+
+	export, err := s.exportRepo.FindByID(id)
+	if err != nil || export.Status != model.ExportCompleted {
+		return nil, nil, errors.New("export not found or no longer available")
+	}
+
+	archive, err := s.blobs.Open(export.BlobKey)
+	if err != nil {
+		return nil, nil, errors.New("export not found or no longer available")
+	}
+	return export, archive, nil
+}
+
+// StartExportJob picks up queued exports (e.g. after a restart) and deletes archives
+// past their retention period in the background
+func (s *exportService) StartExportJob() {
+	go func() {
+		ticker := time.NewTicker(exportJobInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if failed, err := s.exportRepo.FailStale(time.Now().Add(-staleExportAfter)); err != nil {
+				log.Printf("⚠️  Failed to mark interrupted exports: %v", err)
+			} else if failed > 0 {
+				log.Printf("⚠️  Marked %d interrupted exports as failed", failed)
+			}
+
+			if pending, err := s.exportRepo.FindPending(10); err != nil {
+				log.Printf("⚠️  Failed to load pending exports: %v", err)
+			} else {
+				for _, export := range pending {
+					s.run(export.ID)
+				}
+			}
+
+			if err := s.deleteExpired(); err != nil {
+				log.Printf("⚠️  Failed to delete expired exports: %v", err)
+			}
+		}
+	}()
+}
+
+// run claims a pending export and builds its archive. Another worker may have claimed
+// it already, in which case run does nothing.
+func (s *exportService) run(id uint) {
+	if err := s.exportRepo.Claim(id); err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("⚠️  Failed to claim export %d: %v", id, err)
+		}
+		return
+	}
+
+	export, err := s.exportRepo.FindByID(id)
+	if err != nil {
+		log.Printf("⚠️  Failed to load export %d: %v", id, err)
+		return
+	}
+
+	action := "export_completed"
+	if err := s.build(export); err != nil {
+		log.Printf("⚠️  Export %d of tenant %d failed: %v", export.ID, export.TenantID, err)
+		s.exportRepo.Fail(export.ID, "export failed, please try again")
+		action = "export_failed"
+	}
+
+	s.auditLogRepo.Create(attributeJob(&model.AuditLog{
+		TenantID:   export.TenantID,
+		Action:     action,
+		Resource:   "tenant_export",
+		ResourceID: export.ID,
+	}, export.RequestedBy, export.APIKeyID, export.OperatorID))
+}
+
+// build streams the archive into the blob store and marks the export completed
+func (s *exportService) build(export *model.TenantExport) error {
+	key := fmt.Sprintf("%sexports/%d.zip", tenantBlobPrefix(export.TenantID), export.ID)
+
+	pr, pw := io.Pipe()
+	archiveErr := make(chan error, 1)
+	go func() {
+		err := s.writeArchive(pw, export)
+		pw.CloseWithError(err)
+		archiveErr <- err
+	}()
+
+	size, err := s.blobs.Put(key, pr)
+	pr.CloseWithError(err) // Unblocks the writer if the store gave up early
+	if err := <-archiveErr; err != nil {
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := s.exportRepo.Complete(export.ID, key, size, time.Now().Add(s.cfg.Retention)); err != nil {
+		s.blobs.Delete(key)
+		return err
+	}
+	return nil
+}
+
+// writeArchive writes one JSON and one CSV file per dataset plus a manifest.json with
+// the row counts
+func (s *exportService) writeArchive(w io.Writer, export *model.TenantExport) error {
+	zw := zip.NewWriter(w)
+	rowCounts := make(map[string]int64)
+
+	err := s.exportRepo.Snapshot(func(repo repository.TenantExportRepository) error {
+		for _, dataset := range repo.Datasets() {
+			count, err := writeJSONDataset(zw, repo, export.TenantID, dataset)
+			if err != nil {
+				return err
+			}
+			if err := writeCSVDataset(zw, repo, export.TenantID, dataset); err != nil {
+				return err
+			}
+			rowCounts[dataset] = count
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	manifest, err := json.MarshalIndent(map[string]interface{}{
+		"format_version": exportFormat,
+		"tenant_id":      export.TenantID,
+		"export_id":      export.ID,
+		"exported_at":    time.Now().UTC(),
+		"row_counts":     rowCounts,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	f, err := zw.Create("manifest.json")
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(manifest); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// writeJSONDataset writes a dataset as a JSON array of objects, one row per line, and
+// returns the number of rows
+func writeJSONDataset(zw *zip.Writer, repo repository.TenantExportRepository, tenantID uint, dataset string) (int64, error) {
+	f, err := zw.Create(dataset + ".json")
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	var line bytes.Buffer
+	if _, err := io.WriteString(f, "["); err != nil {
+		return 0, err
+	}
+
+	err = repo.ReadDataset(tenantID, dataset, func(columns []string, values []interface{}) error {
+		line.Reset()
+		if count > 0 {
+			line.WriteString(",")
+		}
+		line.WriteString("\n  {")
+		for i, column := range columns {
+			if i > 0 {
+				line.WriteString(", ")
+			}
+			key, _ := json.Marshal(column)
+			value, err := json.Marshal(exportValue(values[i]))
+			if err != nil {
+				return err
+			}
+			line.Write(key)
+			line.WriteString(": ")
+			line.Write(value)
+		}
+		line.WriteString("}")
+		count++
+
+		_, err := f.Write(line.Bytes())
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	_, err = io.WriteString(f, "\n]\n")
+	return count, err
+}
+
+// writeCSVDataset writes a dataset as CSV with a header row. Empty datasets produce an
+// empty file since their columns are unknown.
+func writeCSVDataset(zw *zip.Writer, repo repository.TenantExportRepository, tenantID uint, dataset string) error {
+	f, err := zw.Create(dataset + ".csv")
+	if err != nil {
+		return err
+	}
+
+	cw := csv.NewWriter(f)
+	header := false
+	record := []string{}
+
+	err = repo.ReadDataset(tenantID, dataset, func(columns []string, values []interface{}) error {
+		if !header {
+			if err := cw.Write(columns); err != nil {
+				return err
+			}
+			header = true
+		}
+
+		record = record[:0]
+		for _, value := range values {
+			record = append(record, csvValue(value))
+		}
+		return cw.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+
+	cw.Flush()
+	return cw.Error()
+}
+
+// exportValue converts a scanned column value into its JSON form
+func exportValue(value interface{}) interface{} {
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return value
+}
+
+// csvValue formats a scanned column value for CSV; NULL becomes an empty field. Text is
+// escaped like contact exports so that spreadsheet applications do not run it as a formula.
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case []byte:
+		return spreadsheet.EscapeFormula(string(v))
+	case string:
+		return spreadsheet.EscapeFormula(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	case int64, int32, float64, float32, bool:
+		return fmt.Sprint(v)
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(b)
+	}
+}
+
+// deleteExpired removes archives past their retention period
+func (s *exportService) deleteExpired() error {
+	exports, err := s.exportRepo.FindExpired(100)
+	if err != nil {
+		return err
+	}
+
+	for _, export := range exports {
+		if err := s.blobs.Delete(export.BlobKey); err != nil {
+			return err
+		}
+		if err := s.exportRepo.MarkExpired(export.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// sign returns the signature of a download link
+func (s *exportService) sign(id uint, expires string) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	fmt.Fprintf(mac, "tenant_export:%d:%s", id, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,764 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"gin-quickstart/internal/blobstore"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/repository"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	importListLimit   = 20            // Imports returned by ListImports
+	importJobInterval = time.Minute   // How often the import job looks for queued imports
+	staleImportAfter  = 6 * time.Hour // Running imports older than this were interrupted
+	maxImportWarnings = 50
+)
+
+var ErrImportInProgress = errors.New("an import is already in progress for this tenant")
+
+// errDryRun rolls back the transaction of a dry run
+var errDryRun = errors.New("dry run")
+
+type ImportService interface {
+	RequestImport(tenantID uint, requester Requester, filename string, archive io.Reader, dryRun bool) (*model.TenantImport, error)
+	GetImport(tenantID, id uint) (*model.TenantImport, error)
+	ListImports(tenantID uint) ([]model.TenantImport, error)
+	StartImportJob()
+}
+
+type importService struct {
+	importRepo   repository.TenantImportRepository
+	auditLogRepo repository.AuditLogRepository
+	blobs        blobstore.Store
+}
+
+func NewImportService(
+	importRepo repository.TenantImportRepository,
+	auditLogRepo repository.AuditLogRepository,
+	blobs blobstore.Store,
+) ImportService {
+	return &importService{
+		importRepo:   importRepo,
+		auditLogRepo: auditLogRepo,
+		blobs:        blobs,
+	}
+}
+
+// RequestImport stores an uploaded export archive and imports it in the background. A
+// tenant has at most one import in progress.
+func (s *importService) RequestImport(tenantID uint, requester Requester, filename string, archive io.Reader, dryRun bool) (*model.TenantImport, error) {
+	if _, err := s.importRepo.FindInProgress(tenantID); err == nil {
+		return nil, ErrImportInProgress
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	name, err := generateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	key := tenantBlobPrefix(tenantID) + "imports/" + name + ".zip"
+
+	size, err := s.blobs.Put(key, archive)
+	if err != nil {
+		return nil, errors.New("failed to store the archive")
+	}
+
+	imp := &model.TenantImport{
+		TenantID:    tenantID,
+		RequestedBy: requester.UserID,
+		APIKeyID:    requester.APIKeyID,
+		OperatorID:  requester.OperatorID,
+		DryRun:      dryRun,
+		Status:      model.ImportPending,
+		Filename:    filename,
+		SizeBytes:   size,
+		BlobKey:     key,
+	}
+	if err := s.importRepo.Create(imp); err != nil {
+		s.blobs.Delete(key)
+		return nil, errors.New("failed to create import")
+	}
+
+	go s.run(imp.ID)
+
+	return imp, nil
+}
+
+func (s *importService) GetImport(tenantID, id uint) (*model.TenantImport, error) {
+	imp, err := s.importRepo.FindByID(id)
+	if err != nil || imp.TenantID != tenantID {
+		return nil, errors.New("import not found")
+	}
+	return imp, nil
+}
+
+// ListImports returns the tenant's most recent imports
+func (s *importService) ListImports(tenantID uint) ([]model.TenantImport, error) {
+	return s.importRepo.FindByTenant(tenantID, importListLimit)
+}
+
+// StartImportJob picks up queued imports (e.g. after a restart) in the background
+func (s *importService) StartImportJob() {
+	go func() {
+		ticker := time.NewTicker(importJobInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if failed, err := s.importRepo.FailStale(time.Now().Add(-staleImportAfter)); err != nil {
+				log.Printf("⚠️  Failed to mark interrupted imports: %v", err)
+			} else if failed > 0 {
+				log.Printf("⚠️  Marked %d interrupted imports as failed", failed)
+			}
+
+			pending, err := s.importRepo.FindPending(10)
+			if err != nil {
+				log.Printf("⚠️  Failed to load pending imports: %v", err)
+				continue
+			}
+			for _, imp := range pending {
+				s.run(imp.ID)
+			}
+		}
+	}()
+}
+
+// run claims a pending import, applies it and deletes the uploaded archive. Another
+// worker may have claimed it already, in which case run does nothing.
+func (s *importService) run(id uint) {
+	if err := s.importRepo.Claim(id); err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("⚠️  Failed to claim import %d: %v", id, err)
+		}
+		return
+	}
+
+	imp, err := s.importRepo.FindByID(id)
+	if err != nil {
+		log.Printf("⚠️  Failed to load import %d: %v", id, err)
+		return
+	}
+
+	action := "import_completed"
+	if imp.DryRun {
+		action = "import_previewed"
+	}
+
+	result, err := s.apply(imp)
+	if err != nil {
+		log.Printf("⚠️  Import %d of tenant %d failed: %v", imp.ID, imp.TenantID, err)
+		s.importRepo.Fail(imp.ID, err.Error(), result)
+		action = "import_failed"
+	} else if err := s.importRepo.Complete(imp.ID, result); err != nil {
+		log.Printf("⚠️  Failed to complete import %d: %v", imp.ID, err)
+	}
+
+	if err := s.blobs.Delete(imp.BlobKey); err != nil {
+		log.Printf("⚠️  Failed to delete archive of import %d: %v", imp.ID, err)
+	}
+
+	s.auditLogRepo.Create(attributeJob(&model.AuditLog{
+		TenantID:   imp.TenantID,
+		Action:     action,
+		Resource:   "tenant_import",
+		ResourceID: imp.ID,
+	}, imp.RequestedBy, imp.APIKeyID, imp.OperatorID))
+}
+
+// apply imports the archive's pipeline stages, contacts and deals in one transaction,
+// which a dry run rolls back. The result is returned even when the import fails.
+func (s *importService) apply(imp *model.TenantImport) (*model.ImportResult, error) {
+	archive, err := s.openArchive(imp.BlobKey)
+	if err != nil {
+		return nil, err
+	}
+	defer archive.close()
+
+	if err := checkManifest(archive.zip); err != nil {
+		return nil, err
+	}
+
+	im := &importer{
+		archive:        archive.zip,
+		tenantID:       imp.TenantID,
+		fallbackUserID: imp.RequestedBy,
+		userIDs:        make(map[uint]uint),
+		stageIDs:       make(map[uint]uint),
+		companyIDs:     make(map[uint]uint),
+		contactIDs:     make(map[uint]uint),
+		result: &model.ImportResult{
+			Created:  make(map[string]int),
+			Matched:  make(map[string]int),
+			Skipped:  make(map[string]int),
+			Warnings: []string{},
+		},
+	}
+
+	err = s.importRepo.Transaction(func(tx repository.ImportTx) error {
+		im.tx = tx
+		if err := im.importUsers(); err != nil {
+			return err
+		}
+		if err := im.importStages(); err != nil {
+			return err
+		}
+		if err := im.importCustomFields(); err != nil {
+			return err
+		}
+		if err := im.importCompanies(); err != nil {
+			return err
+		}
+		if err := im.importContacts(); err != nil {
+			return err
+		}
+		if err := im.importDeals(); err != nil {
+			return err
+		}
+		// Contacts of archives without companies are linked by their company name
+		if _, err := tx.Companies.LinkContacts(im.tenantID); err != nil {
+			return err
+		}
+		if imp.DryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if errors.Is(err, errDryRun) {
+		err = nil
+	}
+	return im.result, err
+}
+
+// openedArchive is an archive copied to a temporary file for random access
+type openedArchive struct {
+	zip  *zip.Reader
+	file *os.File
+}
+
+func (a *openedArchive) close() {
+	a.file.Close()
+	os.Remove(a.file.Name())
+}
+
+func (s *importService) openArchive(key string) (*openedArchive, error) {
+	blob, err := s.blobs.Open(key)
+	if err != nil {
+		return nil, errors.New("the uploaded archive is no longer available")
+	}
+	defer blob.Close()
+
+	file, err := os.CreateTemp("", "tenant-import-*.zip")
+	if err != nil {
+		return nil, err
+	}
+	archive := &openedArchive{file: file}
+
+	size, err := io.Copy(file, blob)
+	if err != nil {
+		archive.close()
+		return nil, err
+	}
+
+	archive.zip, err = zip.NewReader(file, size)
+	if err != nil {
+		archive.close()
+		return nil, errors.New("the file is not a ZIP archive")
+	}
+	return archive, nil
+}
+
+// checkManifest accepts archives written by a tenant export of a supported format
+func checkManifest(archive *zip.Reader) error {
+	f, err := archive.Open("manifest.json")
+	if err != nil {
+		return errors.New("the archive is not a tenant export (manifest.json is missing)")
+	}
+	defer f.Close()
+
+	var manifest struct {
+		FormatVersion int `json:"format_version"`
+	}
+	if err := json.NewDecoder(f).Decode(&manifest); err != nil {
+		return errors.New("the archive's manifest.json is invalid")
+	}
+	if manifest.FormatVersion != exportFormat {
+		return fmt.Errorf("unsupported export format version %d", manifest.FormatVersion)
+	}
+	return nil
+}
+
+// readArchiveDataset decodes the JSON array of a dataset one row at a time. A dataset
+// missing from the archive is empty.
+func readArchiveDataset(archive *zip.Reader, dataset string, fn func(dec *json.Decoder) error) error {
+	f, err := archive.Open(dataset + ".json")
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := json.NewDecoder(f)
+	if token, err := dec.Token(); err != nil || token != json.Delim('[') {
+		return fmt.Errorf("%s.json is not a JSON array", dataset)
+	}
+	for dec.More() {
+		if err := fn(dec); err != nil {
+			return fmt.Errorf("%s.json: %w", dataset, err)
+		}
+	}
+	_, err = dec.Token()
+	return err
+}
+
+// importer remaps the IDs of one archive into the current tenant
+type importer struct {
+	archive        *zip.Reader
+	tx             repository.ImportTx
+	tenantID       uint
+	fallbackUserID uint // Owns records of users who are not members of the tenant
+	result         *model.ImportResult
+
+	userIDs    map[uint]uint // Archived ID -> current ID
+	stageIDs   map[uint]uint
+	companyIDs map[uint]uint
+	contactIDs map[uint]uint
+
+	customFields map[string]*customFieldSet // Entity -> the tenant's fields after importCustomFields
+}
+
+func (im *importer) warn(format string, args ...interface{}) {
+	if len(im.result.Warnings) < maxImportWarnings {
+		im.result.Warnings = append(im.result.Warnings, fmt.Sprintf(format, args...))
+	}
+}
+
+// userID maps an archived user to the tenant member with the same email
+func (im *importer) userID(archivedID uint) uint {
+	if id, ok := im.userIDs[archivedID]; ok {
+		return id
+	}
+	return im.fallbackUserID
+}
+
+// importUsers matches archived users to members by email; users are never created
+func (im *importer) importUsers() error {
+	members, err := im.tx.Imports.MemberIDsByEmail(im.tenantID)
+	if err != nil {
+		return err
+	}
+
+	return readArchiveDataset(im.archive, "users", func(dec *json.Decoder) error {
+		var user struct {
+			ID    uint   `json:"id"`
+			Email string `json:"email"`
+		}
+		if err := dec.Decode(&user); err != nil {
+			return err
+		}
+
+		if id, ok := members[strings.ToLower(user.Email)]; ok {
+			im.userIDs[user.ID] = id
+			im.result.Matched["users"]++
+			return nil
+		}
+		im.result.Skipped["users"]++
+		im.warn("user %s is not a member of this tenant, their records are assigned to the importing user", user.Email)
+		return nil
+	})
+}
+
+// importStages maps archived stages to existing stages with the same name and appends
+// the others to the pipeline
+func (im *importer) importStages() error {
+	existing, err := im.tx.Stages.FindAll(im.tenantID)
+	if err != nil {
+		return err
+	}
+
+	byName := make(map[string]uint, len(existing))
+	lastOrder := 0
+	for _, stage := range existing {
+		byName[strings.ToLower(stage.Name)] = stage.ID
+		if stage.Order > lastOrder {
+			lastOrder = stage.Order
+		}
+	}
+
+	var archived []model.PipelineStage
+	err = readArchiveDataset(im.archive, "pipeline_stages", func(dec *json.Decoder) error {
+		var stage model.PipelineStage
+		if err := dec.Decode(&stage); err != nil {
+			return err
+		}
+		archived = append(archived, stage)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(archived, func(i, j int) bool { return archived[i].Order < archived[j].Order })
+
+	for _, stage := range archived {
+		if id, ok := byName[strings.ToLower(stage.Name)]; ok {
+			im.stageIDs[stage.ID] = id
+			im.result.Matched["pipeline_stages"]++
+			continue
+		}
+
+		archivedID := stage.ID
+		lastOrder++
+		stage.ID = 0
+		stage.TenantID = im.tenantID
+		stage.Order = lastOrder
+		stage.IsDefault = false
+		if err := im.tx.Stages.Create(&stage); err != nil {
+			return err
+		}
+		im.stageIDs[archivedID] = stage.ID
+		byName[strings.ToLower(stage.Name)] = stage.ID
+		im.result.Created["pipeline_stages"]++
+	}
+	return nil
+}
+
+// archivedCustomField is a custom_fields.json row; options and default are stored as
+// JSON text
+type archivedCustomField struct {
+	model.CustomFieldDefinition
+	Options archivedTags `json:"options"`
+	Default archivedJSON `json:"default"`
+}
+
+// importCustomFields maps archived custom fields to existing fields with the same entity
+// and key, and creates the others. Values of fields whose type differs from the existing
+// field's are not imported.
+func (im *importer) importCustomFields() error {
+	existing, err := im.tx.CustomFields.FindAll(im.tenantID, "")
+	if err != nil {
+		return err
+	}
+
+	byKey := make(map[string]*model.CustomFieldDefinition, len(existing))
+	for i := range existing {
+		byKey[existing[i].Entity+":"+existing[i].Key] = &existing[i]
+	}
+
+	err = readArchiveDataset(im.archive, "custom_fields", func(dec *json.Decoder) error {
+		var row archivedCustomField
+		if err := dec.Decode(&row); err != nil {
+			return err
+		}
+
+		if field, ok := byKey[row.Entity+":"+row.Key]; ok {
+			if field.Type != row.Type {
+				im.result.Skipped["custom_fields"]++
+				im.warn("custom %s field %s is a %s field in this tenant, its %s values are not imported", row.Entity, row.Key, field.Type, row.Type)
+				return nil
+			}
+			im.result.Matched["custom_fields"]++
+			return nil
+		}
+		if !validCustomFieldEntity(row.Entity) || !customFieldTypes[row.Type] || !customFieldKeyPattern.MatchString(row.Key) {
+			im.result.Skipped["custom_fields"]++
+			im.warn("custom field %d is not valid", row.ID)
+			return nil
+		}
+
+		field := row.CustomFieldDefinition
+		field.ID = 0
+		field.TenantID = im.tenantID
+		field.Options = model.StringArray(row.Options)
+		field.Default = row.Default.value
+		if err := im.tx.CustomFields.Create(&field); err != nil {
+			return err
+		}
+		byKey[field.Entity+":"+field.Key] = &field
+		im.result.Created["custom_fields"]++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	im.customFields = make(map[string]*customFieldSet)
+	for _, entity := range []string{model.CustomFieldEntityContact, model.CustomFieldEntityDeal} {
+		if im.customFields[entity], err = loadCustomFields(im.tx.CustomFields, im.tenantID, entity); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// customFieldValues keeps the archived values matching a custom field of the tenant
+func (im *importer) customFieldValues(entity string, values archivedCustomFields) model.CustomFieldValues {
+	fields := im.customFields[entity]
+	result := model.CustomFieldValues{}
+	for key, value := range values {
+		field, ok := fields.byKey[key]
+		if !ok {
+			continue
+		}
+		if value, err := normalizeCustomValue(field, value); err == nil && value != nil {
+			result[key] = value
+		}
+	}
+	return result
+}
+
+// importCompanies maps archived companies to existing companies with the same name and
+// creates the others
+func (im *importer) importCompanies() error {
+	return readArchiveDataset(im.archive, "companies", func(dec *json.Decoder) error {
+		var row model.Company
+		if err := dec.Decode(&row); err != nil {
+			return err
+		}
+
+		name := strings.TrimSpace(row.Name)
+		if name == "" {
+			im.result.Skipped["companies"]++
+			im.warn("company %d has no name", row.ID)
+			return nil
+		}
+		if company, err := im.tx.Companies.FindByName(im.tenantID, name); err == nil {
+			im.companyIDs[row.ID] = company.ID
+			im.result.Matched["companies"]++
+			return nil
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		company := row
+		company.ID = 0
+		company.TenantID = im.tenantID
+		company.CreatedBy = im.userID(row.CreatedBy)
+		company.OwnerID = im.userID(row.OwnerID)
+		company.Name = name
+		if err := im.tx.Companies.Create(&company); err != nil {
+			return err
+		}
+
+		im.companyIDs[row.ID] = company.ID
+		im.result.Created["companies"]++
+		return nil
+	})
+}
+
+// companyID maps an archived company, nil when it was not imported
+func (im *importer) companyID(archivedID *uint) *uint {
+	if archivedID == nil {
+		return nil
+	}
+	id, ok := im.companyIDs[*archivedID]
+	if !ok {
+		return nil
+	}
+	return &id
+}
+
+// archivedContact is a contacts.json row; tags and custom fields are stored as JSON text
+type archivedContact struct {
+	model.Contact
+	Tags         archivedTags         `json:"tags"`
+	CustomFields archivedCustomFields `json:"custom_fields"`
+}
+
+// importContacts maps archived contacts to existing contacts with the same email and
+// creates the others. Team assignments are not imported.
+func (im *importer) importContacts() error {
+	byEmail, err := im.tx.Imports.ContactIDsByEmail(im.tenantID)
+	if err != nil {
+		return err
+	}
+
+	return readArchiveDataset(im.archive, "contacts", func(dec *json.Decoder) error {
+		var row archivedContact
+		if err := dec.Decode(&row); err != nil {
+			return err
+		}
+
+		email := strings.ToLower(strings.TrimSpace(row.Email))
+		if id, ok := byEmail[email]; ok && email != "" {
+			im.contactIDs[row.ID] = id
+			im.result.Matched["contacts"]++
+			return nil
+		}
+		if strings.TrimSpace(row.FirstName) == "" {
+			im.result.Skipped["contacts"]++
+			im.warn("contact %d has no first name", row.ID)
+			return nil
+		}
+
+		contact := row.Contact
+		contact.ID = 0
+		contact.TenantID = im.tenantID
+		contact.CreatedBy = im.userID(row.CreatedBy)
+		contact.OwnerID = im.userID(row.OwnerID)
+		contact.TeamID = nil
+		contact.CompanyID = im.companyID(row.CompanyID)
+		contact.Tags = model.StringArray(row.Tags)
+		contact.CustomFields = im.customFieldValues(model.CustomFieldEntityContact, row.CustomFields)
+		setContactPhoneKeys(&contact)
+		if err := im.tx.Contacts.Create(&contact); err != nil {
+			return err
+		}
+
+		im.contactIDs[row.ID] = contact.ID
+		if email != "" {
+			byEmail[email] = contact.ID
+		}
+		im.result.Created["contacts"]++
+		return nil
+	})
+}
+
+// archivedDeal is a deals.json row; decimals may be exported as strings
+type archivedDeal struct {
+	model.Deal
+	Value        archivedNumber       `json:"value"`
+	Tags         archivedTags         `json:"tags"`
+	CustomFields archivedCustomFields `json:"custom_fields"`
+}
+
+// importDeals creates the archived deals on their remapped contact and stage
+func (im *importer) importDeals() error {
+	return readArchiveDataset(im.archive, "deals", func(dec *json.Decoder) error {
+		var row archivedDeal
+		if err := dec.Decode(&row); err != nil {
+			return err
+		}
+
+		contactID, ok := im.contactIDs[row.ContactID]
+		if !ok {
+			im.result.Skipped["deals"]++
+			im.warn("deal %d: contact %d was not imported", row.ID, row.ContactID)
+			return nil
+		}
+		stageID, ok := im.stageIDs[row.StageID]
+		if !ok {
+			im.result.Skipped["deals"]++
+			im.warn("deal %d: pipeline stage %d is not in the archive", row.ID, row.StageID)
+			return nil
+		}
+
+		deal := row.Deal
+		deal.ID = 0
+		deal.TenantID = im.tenantID
+		deal.CreatedBy = im.userID(row.CreatedBy)
+		deal.OwnerID = im.userID(row.OwnerID)
+		deal.TeamID = nil
+		deal.CompanyID = im.companyID(row.CompanyID)
+		deal.ContactID = contactID
+		deal.StageID = stageID
+		deal.Value = float64(row.Value)
+		deal.Tags = model.StringArray(row.Tags)
+		deal.CustomFields = im.customFieldValues(model.CustomFieldEntityDeal, row.CustomFields)
+		if err := im.tx.Deals.Create(&deal); err != nil {
+			return err
+		}
+
+		im.result.Created["deals"]++
+		return nil
+	})
+}
+
+// archivedTags reads tags exported as a JSON array or as the JSON text of the tags column
+type archivedTags []string
+
+func (t *archivedTags) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		if text == "" {
+			*t = nil
+			return nil
+		}
+		data = []byte(text)
+	}
+
+	var tags []string
+	if err := json.Unmarshal(data, &tags); err != nil {
+		return err
+	}
+	*t = tags
+	return nil
+}
+
+// archivedCustomFields reads custom field values exported as a JSON object or as the
+// JSON text of the custom_fields column
+type archivedCustomFields map[string]interface{}
+
+func (f *archivedCustomFields) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		if text == "" {
+			*f = nil
+			return nil
+		}
+		data = []byte(text)
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	*f = values
+	return nil
+}
+
+// archivedJSON reads a value stored as JSON text in a text column. Strings that are not
+// JSON are taken as they are.
+type archivedJSON struct {
+	value interface{}
+}
+
+func (j *archivedJSON) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return json.Unmarshal(data, &j.value)
+	}
+	if err := json.Unmarshal([]byte(text), &j.value); err != nil {
+		j.value = text
+	}
+	return nil
+}
+
+// archivedNumber reads a decimal exported as a JSON number or string
+type archivedNumber float64
+
+func (n *archivedNumber) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		if text == "" {
+			*n = 0
+			return nil
+		}
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return err
+		}
+		*n = archivedNumber(value)
+		return nil
+	}
+
+	var value float64
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+	*n = archivedNumber(value)
+	return nil
+}
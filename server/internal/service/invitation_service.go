@@ -0,0 +1,285 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"gin-quickstart/config"
+	"gin-quickstart/internal/mailer"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/repository"
+	"log"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidInvitation = errors.New("invitation is invalid or has expired")
+	ErrInvitationNotSent = errors.New("invitation saved but the email could not be sent, try resending it")
+)
+
+// InvitationPreview is shown to the invitee before accepting
+type InvitationPreview struct {
+	Email           string    `json:"email"`
+	Role            string    `json:"role"`
+	TenantName      string    `json:"tenant_name"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	ExistingAccount bool      `json:"existing_account"` // Accept with the account password instead of choosing one
+}
+
+type InvitationService interface {
+	CreateInvitation(tenantID, invitedBy uint, email, role string, grantor Grantor) (*model.Invitation, error)
+	GetInvitations(tenantID uint, status string) ([]model.Invitation, error)
+	ResendInvitation(tenantID, id uint) (*model.Invitation, error)
+	RevokeInvitation(tenantID, id uint) error
+	PreviewInvitation(token string) (*InvitationPreview, error)
+	AcceptInvitation(token, password, fullName string) (*model.User, *model.Invitation, error)
+}
+
+type invitationService struct {
+	invitationRepo repository.InvitationRepository
+	userRepo       repository.UserRepository
+	tenantRepo     repository.TenantRepository
+	tenantUserRepo repository.TenantUserRepository
+	roleService    RoleService
+	mailer         mailer.Mailer
+	memberships    MembershipInvalidator
+}
+
+func NewInvitationService(
+	invitationRepo repository.InvitationRepository,
+	userRepo repository.UserRepository,
+	tenantRepo repository.TenantRepository,
+	tenantUserRepo repository.TenantUserRepository,
+	roleService RoleService,
+	mailer mailer.Mailer,
+	memberships MembershipInvalidator,
+) InvitationService {
+	return &invitationService{
+		invitationRepo: invitationRepo,
+		userRepo:       userRepo,
+		tenantRepo:     tenantRepo,
+		tenantUserRepo: tenantUserRepo,
+		roleService:    roleService,
+		mailer:         mailer,
+		memberships:    memberships,
+	}
+}
+
+// CreateInvitation invites email with a role the grantor holds every permission of
+func (s *invitationService) CreateInvitation(tenantID, invitedBy uint, email, role string, grantor Grantor) (*model.Invitation, error) {
+	email = model.NormalizeEmail(email)
+
+	exists, err := s.roleService.RoleExists(tenantID, role)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New("role does not exist in this tenant")
+	}
+	if err := s.roleService.CheckGrantable(tenantID, role, grantor); err != nil {
+		return nil, err
+	}
+
+	if user, err := s.userRepo.FindByEmail(email); err == nil {
+		if s.tenantUserRepo.CheckUserAccess(tenantID, user.ID) {
+			return nil, errors.New("user already exists in this tenant")
+		}
+	}
+
+	if _, err := s.invitationRepo.FindPendingByEmail(tenantID, email); err == nil {
+		return nil, errors.New("a pending invitation already exists for this email, resend it instead")
+	}
+
+	token, err := generateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation := &model.Invitation{
+		TenantID:  tenantID,
+		Email:     email,
+		Role:      role,
+		InvitedBy: invitedBy,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(config.AppConfig.Auth.InvitationExpiry),
+		Status:    model.InvitationPending,
+	}
+
+	if err := s.invitationRepo.Create(invitation); err != nil {
+		return nil, err
+	}
+
+	if err := s.send(invitation, token); err != nil {
+		return invitation, err
+	}
+
+	return invitation, nil
+}
+
+func (s *invitationService) GetInvitations(tenantID uint, status string) ([]model.Invitation, error) {
+	return s.invitationRepo.FindAll(tenantID, status)
+}
+
+// ResendInvitation issues a fresh token (the old link stops working) and extends the expiry
+func (s *invitationService) ResendInvitation(tenantID, id uint) (*model.Invitation, error) {
+	invitation, err := s.getInvitation(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if invitation.Status != model.InvitationPending {
+		return nil, errors.New("only pending invitations can be resent")
+	}
+
+	token, err := generateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	invitation.TokenHash = hashToken(token)
+	invitation.ExpiresAt = time.Now().Add(config.AppConfig.Auth.InvitationExpiry)
+
+	if err := s.invitationRepo.UpdateToken(tenantID, id, invitation.TokenHash, invitation.ExpiresAt); err != nil {
+		return nil, err
+	}
+
+	if err := s.send(invitation, token); err != nil {
+		return invitation, err
+	}
+
+	return invitation, nil
+}
+
+func (s *invitationService) RevokeInvitation(tenantID, id uint) error {
+	invitation, err := s.getInvitation(tenantID, id)
+	if err != nil {
+		return err
+	}
+
+	if invitation.Status != model.InvitationPending {
+		return errors.New("only pending invitations can be revoked")
+	}
+
+	return s.invitationRepo.Revoke(tenantID, id)
+}
+
+func (s *invitationService) PreviewInvitation(token string) (*InvitationPreview, error) {
+	invitation, err := s.findUsable(token)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.userRepo.FindByEmail(invitation.Email)
+
+	return &InvitationPreview{
+		Email:           invitation.Email,
+		Role:            invitation.Role,
+		TenantName:      invitation.Tenant.Name,
+		ExpiresAt:       invitation.ExpiresAt,
+		ExistingAccount: err == nil,
+	}, nil
+}
+
+// AcceptInvitation adds the invitee to the tenant. Existing users must confirm with their
+// password; new users choose one (and optionally their name).
+func (s *invitationService) AcceptInvitation(token, password, fullName string) (*model.User, *model.Invitation, error) {
+	invitation, err := s.findUsable(token)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	user, err := s.userRepo.FindByEmail(invitation.Email)
+	switch {
+	case err == nil:
+		if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+			return nil, nil, errors.New("invalid credentials")
+		}
+		if !user.IsActive {
+			return nil, nil, errors.New("user account is inactive")
+		}
+		if s.tenantUserRepo.CheckUserAccess(invitation.TenantID, user.ID) {
+			return nil, nil, errors.New("user already exists in this tenant")
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if len(password) < 6 {
+			return nil, nil, errors.New("password must be at least 6 characters")
+		}
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, nil, err
+		}
+		user = &model.User{
+			Email:        model.NormalizeEmail(invitation.Email),
+			PasswordHash: string(hashedPassword),
+			FullName:     fullName,
+			IsActive:     true,
+		}
+	default:
+		return nil, nil, err
+	}
+
+	if err := s.invitationRepo.Accept(invitation, user); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidInvitation
+		}
+		return nil, nil, err
+	}
+
+	s.memberships.Invalidate(invitation.TenantID, user.ID)
+	return user, invitation, nil
+}
+
+func (s *invitationService) getInvitation(tenantID, id uint) (*model.Invitation, error) {
+	invitation, err := s.invitationRepo.FindByID(tenantID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("invitation not found")
+		}
+		return nil, err
+	}
+	return invitation, nil
+}
+
+// findUsable resolves a raw token to a pending, unexpired invitation of a live tenant
+func (s *invitationService) findUsable(token string) (*model.Invitation, error) {
+	invitation, err := s.invitationRepo.FindByTokenHash(hashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidInvitation
+		}
+		return nil, err
+	}
+
+	// Tenant.ID is zero when the tenant was soft deleted
+	if !invitation.IsUsable() || invitation.Tenant.ID == 0 || invitation.Tenant.Status != "active" {
+		return nil, ErrInvalidInvitation
+	}
+
+	return invitation, nil
+}
+
+func (s *invitationService) send(invitation *model.Invitation, token string) error {
+	tenant, err := s.tenantRepo.FindByID(invitation.TenantID)
+	if err != nil {
+		return err
+	}
+
+	link := fmt.Sprintf("%s/invitations/%s", strings.TrimRight(config.AppConfig.Server.FrontendURL, "/"), token)
+
+	err = s.mailer.Send(mailer.Message{
+		To:      invitation.Email,
+		Subject: fmt.Sprintf("You have been invited to join %s", tenant.Name),
+		Body: fmt.Sprintf(
+			"You have been invited to join %s as %s.\n\nAccept the invitation here:\n%s\n\nThis link expires on %s.\n",
+			tenant.Name, invitation.Role, link, invitation.ExpiresAt.Format(time.RFC1123),
+		),
+	})
+	if err != nil {
+		log.Printf("⚠️  Failed to send invitation %d: %v", invitation.ID, err)
+		return ErrInvitationNotSent
+	}
+	return nil
+}
@@ -0,0 +1,380 @@
+package service
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"gin-quickstart/config"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/repository"
+	"gin-quickstart/internal/totp"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidMFACode      = errors.New("invalid two-factor authentication code")
+	ErrInvalidMFAChallenge = errors.New("two-factor challenge is invalid or has expired, please login again")
+	ErrIncorrectPassword   = errors.New("password is incorrect")
+)
+
+const (
+	recoveryCodeCount       = 10
+	maxMFAChallengeAttempts = 5
+)
+
+// MFAStatus describes a user's two-factor setup
+type MFAStatus struct {
+	Enabled                bool  `json:"enabled"`
+	RecoveryCodesRemaining int64 `json:"recovery_codes_remaining"`
+}
+
+// MFAEnrollment is returned when enrollment starts; the secret is shown once
+type MFAEnrollment struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// URI to render as a QR code
+}
+
+// MFAVerification is the outcome of a successfully answered login challenge
+type MFAVerification struct {
+	User             *model.User
+	TenantID         uint
+	UsedRecoveryCode bool
+}
+
+type MFAService interface {
+	GetStatus(userID uint) (*MFAStatus, error)
+	BeginEnrollment(userID uint, password string) (*MFAEnrollment, error)
+	ConfirmEnrollment(userID uint, code string) ([]string, error)
+	CodeUser(userID uint) (*model.User, error)
+	Disable(userID uint, password, code string) error
+	RegenerateRecoveryCodes(userID uint, code string) ([]string, error)
+	CreateChallenge(userID, tenantID uint) (string, error)
+	ChallengeUser(token string) (*model.User, error)
+	VerifyChallenge(token, code string) (*MFAVerification, error)
+}
+
+type mfaService struct {
+	mfaRepo     repository.MFARepository
+	userRepo    repository.UserRepository
+	memberships MembershipInvalidator
+}
+
+func NewMFAService(
+	mfaRepo repository.MFARepository,
+	userRepo repository.UserRepository,
+	memberships MembershipInvalidator,
+) MFAService {
+	return &mfaService{
+		mfaRepo:     mfaRepo,
+		userRepo:    userRepo,
+		memberships: memberships,
+	}
+}
+
+func (s *mfaService) GetStatus(userID uint) (*MFAStatus, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	status := &MFAStatus{Enabled: user.MFAEnabled}
+	if user.MFAEnabled {
+		if status.RecoveryCodesRemaining, err = s.mfaRepo.CountRecoveryCodes(userID); err != nil {
+			return nil, err
+		}
+	}
+	return status, nil
+}
+
+// BeginEnrollment generates a pending secret. MFA is only switched on once a code from
+// the authenticator app has been confirmed.
+func (s *mfaService) BeginEnrollment(userID uint, password string) (*MFAEnrollment, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.MFAEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrIncorrectPassword
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.UpdateFields(userID, map[string]interface{}{"totp_pending_secret": secret}); err != nil {
+		return nil, err
+	}
+
+	return &MFAEnrollment{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(config.AppConfig.Auth.MFAIssuer, user.Email, secret),
+	}, nil
+}
+
+// ConfirmEnrollment enables MFA and returns freshly generated recovery codes
+func (s *mfaService) ConfirmEnrollment(userID uint, code string) ([]string, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.MFAEnabled {
+		return nil, errors.New("two-factor authentication is already enabled")
+	}
+	if user.TOTPPendingSecret == "" {
+		return nil, errors.New("two-factor enrollment has not been started")
+	}
+
+	step, ok := totp.Validate(user.TOTPPendingSecret, code, time.Now())
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, err := s.replaceRecoveryCodes(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.UpdateFields(userID, map[string]interface{}{
+		"mfa_enabled":         true,
+		"totp_secret":         user.TOTPPendingSecret,
+		"totp_pending_secret": "",
+		"totp_last_step":      step,
+	}); err != nil {
+		return nil, err
+	}
+
+	s.memberships.InvalidateUser(userID)
+	return codes, nil
+}
+
+// CodeUser returns the user with two-factor authentication whose codes Disable and
+// RegenerateRecoveryCodes check, so the caller can reserve a login attempt first
+func (s *mfaService) CodeUser(userID uint) (*model.User, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.MFAEnabled {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+	return user, nil
+}
+
+// Disable turns MFA off after re-checking the password and a current code
+func (s *mfaService) Disable(userID uint, password, code string) error {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if !user.MFAEnabled {
+		return errors.New("two-factor authentication is not enabled")
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return ErrIncorrectPassword
+	}
+
+	if _, err := s.verifyCode(user, code); err != nil {
+		return err
+	}
+
+	if err := s.userRepo.UpdateFields(userID, map[string]interface{}{
+		"mfa_enabled":         false,
+		"totp_secret":         "",
+		"totp_pending_secret": "",
+		"totp_last_step":      0,
+	}); err != nil {
+		return err
+	}
+
+	if err := s.mfaRepo.DeleteRecoveryCodes(userID); err != nil {
+		return err
+	}
+
+	s.memberships.InvalidateUser(userID)
+	return nil
+}
+
+// RegenerateRecoveryCodes replaces all recovery codes; the old ones stop working
+func (s *mfaService) RegenerateRecoveryCodes(userID uint, code string) ([]string, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.MFAEnabled {
+		return nil, errors.New("two-factor authentication is not enabled")
+	}
+
+	if _, err := s.verifyCode(user, code); err != nil {
+		return nil, err
+	}
+
+	return s.replaceRecoveryCodes(userID)
+}
+
+// CreateChallenge starts the second login step for a user whose password was verified
+func (s *mfaService) CreateChallenge(userID, tenantID uint) (string, error) {
+	token, err := generateRandomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	challenge := &model.MFAChallenge{
+		UserID:    userID,
+		TenantID:  tenantID,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(config.AppConfig.Auth.MFAChallengeExpiry),
+	}
+
+	if err := s.mfaRepo.CreateChallenge(challenge); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// ChallengeUser returns the user a usable login challenge was issued to, so the caller can
+// reserve a login attempt before answering it
+func (s *mfaService) ChallengeUser(token string) (*model.User, error) {
+	_, user, err := s.findChallenge(token)
+	return user, err
+}
+
+// VerifyChallenge answers a login challenge with a TOTP or recovery code. Each challenge
+// allows a limited number of attempts, after which the user must login again. On
+// ErrInvalidMFACode the returned verification identifies the user so the caller can
+// count the failure.
+func (s *mfaService) VerifyChallenge(token, code string) (*MFAVerification, error) {
+	challenge, user, err := s.findChallenge(token)
+	if err != nil {
+		return nil, err
+	}
+
+	// Count the attempt before checking the code, so parallel guesses share the limit
+	if err := s.mfaRepo.ClaimChallengeAttempt(challenge.ID, maxMFAChallengeAttempts); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidMFAChallenge
+		}
+		return nil, err
+	}
+
+	usedRecoveryCode, err := s.verifyCode(user, code)
+	if err != nil {
+		if errors.Is(err, ErrInvalidMFACode) {
+			return &MFAVerification{User: user, TenantID: challenge.TenantID}, err
+		}
+		return nil, err
+	}
+
+	// Claim the challenge so it cannot be exchanged twice
+	if err := s.mfaRepo.MarkChallengeUsed(challenge.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidMFAChallenge
+		}
+		return nil, err
+	}
+
+	return &MFAVerification{
+		User:             user,
+		TenantID:         challenge.TenantID,
+		UsedRecoveryCode: usedRecoveryCode,
+	}, nil
+}
+
+// findChallenge returns a usable login challenge and the active user with two-factor
+// authentication it was issued to
+func (s *mfaService) findChallenge(token string) (*model.MFAChallenge, *model.User, error) {
+	challenge, err := s.mfaRepo.FindChallengeByHash(hashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil, ErrInvalidMFAChallenge
+		}
+		return nil, nil, err
+	}
+
+	if !challenge.IsUsable(maxMFAChallengeAttempts) {
+		return nil, nil, ErrInvalidMFAChallenge
+	}
+
+	user, err := s.userRepo.FindByID(challenge.UserID)
+	if err != nil || !user.IsActive || !user.MFAEnabled {
+		return nil, nil, ErrInvalidMFAChallenge
+	}
+	return challenge, user, nil
+}
+
+// verifyCode accepts a TOTP code newer than the last one used, or an unused recovery
+// code. It reports whether a recovery code was consumed.
+func (s *mfaService) verifyCode(user *model.User, code string) (bool, error) {
+	code = strings.TrimSpace(code)
+
+	if step, ok := totp.Validate(user.TOTPSecret, code, time.Now()); ok {
+		// Only the first request with a code advances the step, also among parallel ones
+		if err := s.userRepo.UseTOTPStep(user.ID, step); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return false, ErrInvalidMFACode
+			}
+			return false, err
+		}
+		return false, nil
+	}
+
+	if err := s.mfaRepo.UseRecoveryCode(user.ID, hashToken(normalizeRecoveryCode(code))); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, ErrInvalidMFACode
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (s *mfaService) replaceRecoveryCodes(userID uint) ([]string, error) {
+	codes := make([]string, 0, recoveryCodeCount)
+	hashes := make([]string, 0, recoveryCodeCount)
+
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		raw := hex.EncodeToString(b)
+		codes = append(codes, raw[:5]+"-"+raw[5:])
+		hashes = append(hashes, hashToken(raw))
+	}
+
+	if err := s.mfaRepo.ReplaceRecoveryCodes(userID, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+func (s *mfaService) findUser(userID uint) (*model.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	return user, nil
+}
+
+// normalizeRecoveryCode ignores case, dashes and spaces so codes can be typed loosely
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
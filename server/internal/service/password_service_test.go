@@ -0,0 +1,37 @@
+package service
+
+import (
+	"errors"
+	"gin-quickstart/internal/model"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCheckPassword(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("SecurePass123"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withPassword := &model.User{PasswordHash: string(hash)}
+	ssoOnly := &model.User{}
+
+	tests := []struct {
+		name     string
+		user     *model.User
+		password string
+		want     error
+	}{
+		{"correct password", withPassword, "SecurePass123", nil},
+		{"wrong password", withPassword, "WrongPass123", ErrIncorrectPassword},
+		{"empty password", withPassword, "", ErrIncorrectPassword},
+		{"SSO account", ssoOnly, "", ErrPasswordNotSet},
+		{"SSO account with a password", ssoOnly, "SecurePass123", ErrPasswordNotSet},
+	}
+
+	for _, tt := range tests {
+		if err := checkPassword(tt.user, tt.password); !errors.Is(err, tt.want) {
+			t.Errorf("%s: checkPassword() = %v, want %v", tt.name, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"errors"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/repository"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrImpersonationTarget = errors.New("only an active admin of the tenant can be impersonated")
+	ErrImpersonationEnded  = errors.New("impersonation has already ended")
+)
+
+// PlatformTenant is a tenant with its usage, as listed to platform operators
+type PlatformTenant struct {
+	model.Tenant
+	Usage model.TenantUsage `json:"usage"`
+}
+
+// ImpersonationGrant is returned to the operator when an impersonation starts
+type ImpersonationGrant struct {
+	Impersonation *model.Impersonation `json:"impersonation"`
+	AccessToken   string               `json:"token"`
+	ExpiresIn     int64                `json:"expires_in"` // Access token lifetime in seconds
+}
+
+// PlatformService lets platform operators manage tenants across the whole installation
+type PlatformService interface {
+	ListTenants(search, status string, page, pageSize int) ([]PlatformTenant, int64, error)
+	GetTenant(id uint) (*PlatformTenant, error)
+	GetTenantUsers(tenantID uint, page, pageSize int) ([]model.TenantUser, int64, error)
+	SetTenantStatus(tenantID uint, status string) error
+	Impersonate(operatorID, tenantID, userID uint, reason, ipAddress, userAgent string) (*ImpersonationGrant, error)
+	ListImpersonations(activeOnly bool, page, pageSize int) ([]model.Impersonation, int64, error)
+	EndImpersonation(id, operatorID uint) (*model.Impersonation, error)
+}
+
+type platformService struct {
+	tenantRepo        repository.TenantRepository
+	tenantUserRepo    repository.TenantUserRepository
+	userRepo          repository.UserRepository
+	sessionRepo       repository.SessionRepository
+	impersonationRepo repository.ImpersonationRepository
+	lifecycleService  TenantLifecycleService
+	tokenService      TokenService
+	expiry            time.Duration
+}
+
+func NewPlatformService(
+	tenantRepo repository.TenantRepository,
+	tenantUserRepo repository.TenantUserRepository,
+	userRepo repository.UserRepository,
+	sessionRepo repository.SessionRepository,
+	impersonationRepo repository.ImpersonationRepository,
+	lifecycleService TenantLifecycleService,
+	tokenService TokenService,
+	impersonationExpiry time.Duration,
+) PlatformService {
+	return &platformService{
+		tenantRepo:        tenantRepo,
+		tenantUserRepo:    tenantUserRepo,
+		userRepo:          userRepo,
+		sessionRepo:       sessionRepo,
+		impersonationRepo: impersonationRepo,
+		lifecycleService:  lifecycleService,
+		tokenService:      tokenService,
+		expiry:            impersonationExpiry,
+	}
+}
+
+// ListTenants searches all tenants by name or owner email and adds their usage
+func (s *platformService) ListTenants(search, status string, page, pageSize int) ([]PlatformTenant, int64, error) {
+	tenants, total, err := s.tenantRepo.Search(strings.TrimSpace(search), status, page, pageSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	ids := make([]uint, 0, len(tenants))
+	for _, tenant := range tenants {
+		ids = append(ids, tenant.ID)
+	}
+	usage, err := s.tenantRepo.CountUsage(ids)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	result := make([]PlatformTenant, 0, len(tenants))
+	for _, tenant := range tenants {
+		result = append(result, PlatformTenant{Tenant: tenant, Usage: *usage[tenant.ID]})
+	}
+	return result, total, nil
+}
+
+func (s *platformService) GetTenant(id uint) (*PlatformTenant, error) {
+	tenant, err := s.tenantRepo.FindByID(id)
+	if err != nil {
+		return nil, errors.New("tenant not found")
+	}
+
+	usage, err := s.tenantRepo.CountUsage([]uint{id})
+	if err != nil {
+		return nil, err
+	}
+	return &PlatformTenant{Tenant: *tenant, Usage: *usage[id]}, nil
+}
+
+func (s *platformService) GetTenantUsers(tenantID uint, page, pageSize int) ([]model.TenantUser, int64, error) {
+	if _, err := s.tenantRepo.FindByID(tenantID); err != nil {
+		return nil, 0, errors.New("tenant not found")
+	}
+	return s.tenantUserRepo.FindUsersByTenant(tenantID, page, pageSize)
+}
+
+// SetTenantStatus suspends or reactivates a tenant
+func (s *platformService) SetTenantStatus(tenantID uint, status string) error {
+	return s.lifecycleService.SetStatus(tenantID, status)
+}
+
+// Impersonate signs the operator in as an admin of the tenant, the owner unless userID
+// names another admin. The session ends at the configured expiry, when an operator ends
+// it or when the admin revokes it.
+func (s *platformService) Impersonate(operatorID, tenantID, userID uint, reason, ipAddress, userAgent string) (*ImpersonationGrant, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, errors.New("a reason is required")
+	}
+
+	tenant, err := s.tenantRepo.FindByID(tenantID)
+	if err != nil {
+		return nil, errors.New("tenant not found")
+	}
+	if tenant.Status == model.TenantStatusPurging {
+		return nil, errors.New("tenant is being deleted")
+	}
+
+	if userID == 0 {
+		if tenant.OwnerID == nil {
+			return nil, errors.New("tenant has no owner, choose an admin to impersonate")
+		}
+		userID = *tenant.OwnerID
+	}
+
+	membership, err := s.tenantUserRepo.FindByTenantAndUser(tenantID, userID)
+	if err != nil || membership.Role != model.RoleAdmin {
+		return nil, ErrImpersonationTarget
+	}
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil || !user.IsActive {
+		return nil, ErrImpersonationTarget
+	}
+
+	expiresAt := time.Now().Add(s.expiry)
+	token, session, err := s.tokenService.IssueImpersonationToken(operatorID, user, tenantID, membership.Role, expiresAt, ipAddress, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	impersonation := &model.Impersonation{
+		OperatorID: operatorID,
+		TenantID:   tenantID,
+		UserID:     userID,
+		SessionID:  session.ID,
+		Reason:     reason,
+		IPAddress:  ipAddress,
+		ExpiresAt:  expiresAt,
+	}
+	if err := s.impersonationRepo.Create(impersonation); err != nil {
+		s.sessionRepo.Revoke(session.ID, &operatorID)
+		return nil, err
+	}
+
+	impersonation, err = s.impersonationRepo.FindByID(impersonation.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ImpersonationGrant{
+		Impersonation: impersonation,
+		AccessToken:   token,
+		ExpiresIn:     int64(s.expiry.Seconds()),
+	}, nil
+}
+
+func (s *platformService) ListImpersonations(activeOnly bool, page, pageSize int) ([]model.Impersonation, int64, error) {
+	return s.impersonationRepo.FindAll(activeOnly, page, pageSize)
+}
+
+// EndImpersonation revokes the impersonation's session, which invalidates its token
+// immediately
+func (s *platformService) EndImpersonation(id, operatorID uint) (*model.Impersonation, error) {
+	impersonation, err := s.impersonationRepo.FindByID(id)
+	if err != nil {
+		return nil, errors.New("impersonation not found")
+	}
+	if !impersonation.IsActive() {
+		return nil, ErrImpersonationEnded
+	}
+
+	if err := s.impersonationRepo.End(id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrImpersonationEnded
+		}
+		return nil, err
+	}
+	if err := s.sessionRepo.Revoke(impersonation.SessionID, &operatorID); err != nil {
+		return nil, err
+	}
+
+	return s.impersonationRepo.FindByID(id)
+}
@@ -0,0 +1,377 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"gin-quickstart/config"
+	"gin-quickstart/internal/mailer"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/repository"
+	"log"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+var ErrInvalidEmailChangeToken = errors.New("email confirmation link is invalid or has expired")
+
+var (
+	localePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+	phonePattern  = regexp.MustCompile(`^\+?[0-9][0-9 ().-]{3,30}$`)
+)
+
+// ProfileUpdate holds the profile fields to change; nil fields are left untouched and
+// empty strings clear optional fields
+type ProfileUpdate struct {
+	FullName  *string
+	AvatarURL *string
+	Locale    *string
+	Timezone  *string
+	Phone     *string
+}
+
+type ProfileService interface {
+	GetProfile(userID uint) (*model.User, error)
+	UpdateProfile(userID uint, update ProfileUpdate) (*model.User, error)
+	RequestEmailChange(userID uint, password, newEmail, ipAddress string) (*model.EmailChangeToken, error)
+	ConfirmEmailChange(token string) (*model.User, error)
+	DeleteAccount(userID uint, password string) ([]uint, error)
+}
+
+type profileService struct {
+	userRepo        repository.UserRepository
+	emailChangeRepo repository.EmailChangeRepository
+	tenantRepo      repository.TenantRepository
+	tenantUserRepo  repository.TenantUserRepository
+	teamRepo        repository.TeamRepository
+	tokenService    TokenService
+	mailer          mailer.Mailer
+	memberships     MembershipInvalidator
+}
+
+func NewProfileService(
+	userRepo repository.UserRepository,
+	emailChangeRepo repository.EmailChangeRepository,
+	tenantRepo repository.TenantRepository,
+	tenantUserRepo repository.TenantUserRepository,
+	teamRepo repository.TeamRepository,
+	tokenService TokenService,
+	mailer mailer.Mailer,
+	memberships MembershipInvalidator,
+) ProfileService {
+	return &profileService{
+		userRepo:        userRepo,
+		emailChangeRepo: emailChangeRepo,
+		tenantRepo:      tenantRepo,
+		tenantUserRepo:  tenantUserRepo,
+		teamRepo:        teamRepo,
+		tokenService:    tokenService,
+		mailer:          mailer,
+		memberships:     memberships,
+	}
+}
+
+func (s *profileService) GetProfile(userID uint) (*model.User, error) {
+	return s.findUser(userID)
+}
+
+// UpdateProfile validates and stores the given profile fields
+func (s *profileService) UpdateProfile(userID uint, update ProfileUpdate) (*model.User, error) {
+	updates := make(map[string]interface{})
+
+	if update.FullName != nil {
+		name := strings.TrimSpace(*update.FullName)
+		if name == "" || len(name) > 255 {
+			return nil, errors.New("full name must be between 1 and 255 characters")
+		}
+		updates["full_name"] = name
+	}
+
+	if update.AvatarURL != nil {
+		avatarURL := strings.TrimSpace(*update.AvatarURL)
+		if avatarURL != "" {
+			parsed, err := url.Parse(avatarURL)
+			if err != nil || (parsed.Scheme != "https" && parsed.Scheme != "http") || parsed.Host == "" || len(avatarURL) > 1024 {
+				return nil, errors.New("avatar URL must be an http(s) URL of at most 1024 characters")
+			}
+		}
+		updates["avatar_url"] = avatarURL
+	}
+
+	if update.Locale != nil {
+		locale := strings.TrimSpace(*update.Locale)
+		if locale != "" && (len(locale) > 35 || !localePattern.MatchString(locale)) {
+			return nil, errors.New("locale must be a language tag such as en or en-US")
+		}
+		updates["locale"] = locale
+	}
+
+	if update.Timezone != nil {
+		timezone := strings.TrimSpace(*update.Timezone)
+		if timezone != "" {
+			// LoadLocation also accepts "Local", which means nothing to other clients
+			if _, err := time.LoadLocation(timezone); err != nil || timezone == "Local" {
+				return nil, errors.New("timezone must be an IANA time zone such as Europe/Berlin")
+			}
+		}
+		updates["timezone"] = timezone
+	}
+
+	if update.Phone != nil {
+		phone := strings.TrimSpace(*update.Phone)
+		if phone != "" && !phonePattern.MatchString(phone) {
+			return nil, errors.New("phone must contain digits, spaces, dashes or parentheses and may start with +")
+		}
+		updates["phone"] = phone
+	}
+
+	if len(updates) > 0 {
+		if err := s.userRepo.UpdateFields(userID, updates); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.findUser(userID)
+}
+
+// RequestEmailChange emails a confirmation link to the new address. The address only
+// changes once the link is opened, and the current address is told about the request.
+func (s *profileService) RequestEmailChange(userID uint, password, newEmail, ipAddress string) (*model.EmailChangeToken, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, errors.New("password is incorrect")
+	}
+
+	newEmail = model.NormalizeEmail(newEmail)
+	if _, err := mail.ParseAddress(newEmail); err != nil {
+		return nil, errors.New("invalid email address")
+	}
+	if strings.EqualFold(newEmail, user.Email) {
+		return nil, errors.New("new email must be different from the current email")
+	}
+	if err := s.checkEmailAvailable(newEmail); err != nil {
+		return nil, err
+	}
+
+	// Only the most recent link works
+	if err := s.emailChangeRepo.InvalidateAllByUser(user.ID); err != nil {
+		return nil, err
+	}
+
+	token, err := generateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	changeToken := &model.EmailChangeToken{
+		UserID:    user.ID,
+		NewEmail:  newEmail,
+		TokenHash: hashToken(token),
+		ExpiresAt: time.Now().Add(config.AppConfig.Auth.EmailChangeExpiry),
+		IPAddress: ipAddress,
+	}
+
+	if err := s.emailChangeRepo.Create(changeToken); err != nil {
+		return nil, err
+	}
+
+	link := fmt.Sprintf("%s/confirm-email?token=%s", strings.TrimRight(config.AppConfig.Server.FrontendURL, "/"), token)
+
+	err = s.mailer.Send(mailer.Message{
+		To:      newEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf(
+			"Please confirm that you want to use this address for your account:\n%s\n\nThis link expires on %s. If you did not request this change, you can ignore this email.\n",
+			link, changeToken.ExpiresAt.Format(time.RFC1123),
+		),
+	})
+	if err != nil {
+		return nil, errors.New("failed to send confirmation email")
+	}
+
+	// The old address is informed so a hijacked session cannot quietly take over the account
+	err = s.mailer.Send(mailer.Message{
+		To:      user.Email,
+		Subject: "Email change requested",
+		Body: fmt.Sprintf(
+			"A change of your account's email address to %s was requested. It takes effect once confirmed from the new address.\n\nIf this was not you, change your password and sign out of all sessions.\n",
+			newEmail,
+		),
+	})
+	if err != nil {
+		log.Printf("⚠️  Failed to send email change notice for user %d: %v", user.ID, err)
+	}
+
+	return changeToken, nil
+}
+
+// ConfirmEmailChange redeems a confirmation token, switches the address and signs the
+// user out everywhere (access tokens carry the old address)
+func (s *profileService) ConfirmEmailChange(token string) (*model.User, error) {
+	changeToken, err := s.emailChangeRepo.FindByHash(hashToken(token))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidEmailChangeToken
+		}
+		return nil, err
+	}
+
+	if !changeToken.IsUsable() {
+		return nil, ErrInvalidEmailChangeToken
+	}
+
+	user, err := s.userRepo.FindByID(changeToken.UserID)
+	if err != nil {
+		return nil, ErrInvalidEmailChangeToken
+	}
+	if !user.IsActive {
+		return nil, errors.New("user account is inactive")
+	}
+
+	// The address may have been taken since the link was sent
+	if err := s.checkEmailAvailable(changeToken.NewEmail); err != nil {
+		return nil, err
+	}
+
+	// Claim the token before changing anything so it cannot be used twice
+	if err := s.emailChangeRepo.MarkUsed(changeToken.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidEmailChangeToken
+		}
+		return nil, err
+	}
+
+	if err := s.userRepo.UpdateFields(user.ID, map[string]interface{}{"email": changeToken.NewEmail}); err != nil {
+		return nil, err
+	}
+
+	if err := s.tokenService.RevokeAllForUser(user.ID); err != nil {
+		return nil, err
+	}
+
+	user.Email = changeToken.NewEmail
+	return user, nil
+}
+
+// DeleteAccount removes the user from every tenant, signs them out everywhere and
+// deletes the account after clearing its personal data. It refuses while the user owns a
+// tenant or is its only admin. Returns the IDs of the tenants the user was removed from.
+func (s *profileService) DeleteAccount(userID uint, password string) ([]uint, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, errors.New("password is incorrect")
+	}
+
+	tenantUsers, err := s.tenantUserRepo.FindTenantsByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Report every blocking tenant at once; the removals below enforce the same rules
+	var ownerOf, soleAdminOf []string
+	for _, tu := range tenantUsers {
+		if tu.Tenant.OwnerID != nil && *tu.Tenant.OwnerID == userID {
+			ownerOf = append(ownerOf, tu.Tenant.Name)
+			continue
+		}
+		if tu.Role != model.RoleAdmin {
+			continue
+		}
+		admins, err := s.tenantUserRepo.CountByRole(tu.TenantID, model.RoleAdmin)
+		if err != nil {
+			return nil, err
+		}
+		if admins <= 1 {
+			soleAdminOf = append(soleAdminOf, tu.Tenant.Name)
+		}
+	}
+	if len(ownerOf) > 0 {
+		return nil, fmt.Errorf("%w (you own %s)", ErrTenantOwner, strings.Join(ownerOf, ", "))
+	}
+	if len(soleAdminOf) > 0 {
+		return nil, fmt.Errorf("%w (you are the only admin of %s), make another member admin first", ErrLastAdmin, strings.Join(soleAdminOf, ", "))
+	}
+
+	tenantIDs := make([]uint, 0, len(tenantUsers))
+	for _, tu := range tenantUsers {
+		tenantID := tu.TenantID
+		err := guardAdmins(s.tenantUserRepo, tenantID, userID, false, func(repo repository.TenantUserRepository) error {
+			return repo.Delete(tenantID, userID)
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := s.teamRepo.RemoveUserFromTeams(tenantID, userID); err != nil {
+			return nil, err
+		}
+		s.memberships.InvalidateTenant(tenantID)
+		tenantIDs = append(tenantIDs, tenantID)
+	}
+
+	if err := s.tokenService.RevokeAllForUser(userID); err != nil {
+		return nil, err
+	}
+
+	if err := s.emailChangeRepo.InvalidateAllByUser(userID); err != nil {
+		return nil, err
+	}
+
+	// Free the address for a new registration and drop personal data; the row stays
+	// (soft deleted) so records owned by the user keep a valid reference
+	err = s.userRepo.UpdateFields(userID, map[string]interface{}{
+		"email":               fmt.Sprintf("deleted-%d@deleted.invalid", userID),
+		"full_name":           "",
+		"avatar_url":          "",
+		"phone":               "",
+		"is_active":           false,
+		"mfa_enabled":         false,
+		"totp_secret":         "",
+		"totp_pending_secret": "",
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.userRepo.Delete(userID); err != nil {
+		return nil, err
+	}
+
+	s.memberships.InvalidateUser(userID)
+	return tenantIDs, nil
+}
+
+// checkEmailAvailable fails when another account already uses the address
+func (s *profileService) checkEmailAvailable(email string) error {
+	_, err := s.userRepo.FindByEmail(email)
+	if err == nil {
+		return errors.New("email address is already in use")
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (s *profileService) findUser(userID uint) (*model.User, error) {
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("user not found")
+		}
+		return nil, err
+	}
+	return user, nil
+}
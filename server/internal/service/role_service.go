@@ -0,0 +1,277 @@
+package service
+
+import (
+	"errors"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/repository"
+	"regexp"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z0-9_]{2,50}$`)
+
+// ErrRoleNotGrantable is returned when a member assigns, invites, creates or edits a role
+// with permissions they do not hold themselves
+var ErrRoleNotGrantable = errors.New("you cannot grant a role with permissions you do not have")
+
+// Grantor is the member assigning, inviting or editing a role: their role and the
+// permissions resolved for the request
+type Grantor struct {
+	Role        string
+	Permissions map[string]bool
+}
+
+type RoleService interface {
+	GetPermissions() ([]model.Permission, error)
+	GetRoles(tenantID uint) ([]model.Role, error)
+	GetRoleByID(tenantID, id uint) (*model.Role, error)
+	CreateRole(tenantID uint, name, description string, permissionKeys []string, grantor Grantor) (*model.Role, error)
+	UpdateRole(tenantID, id uint, name, description string, permissionKeys []string, grantor Grantor) (*model.Role, error)
+	DeleteRole(tenantID, id uint) error
+	RoleExists(tenantID uint, name string) (bool, error)
+	CheckGrantable(tenantID uint, name string, grantor Grantor) error
+}
+
+type roleService struct {
+	roleRepo    repository.RoleRepository
+	memberships MembershipInvalidator
+}
+
+func NewRoleService(roleRepo repository.RoleRepository, memberships MembershipInvalidator) RoleService {
+	return &roleService{
+		roleRepo:    roleRepo,
+		memberships: memberships,
+	}
+}
+
+func (s *roleService) GetPermissions() ([]model.Permission, error) {
+	return s.roleRepo.FindAllPermissions()
+}
+
+// GetRoles returns all roles for tenant, seeds the built-in roles if none exist (lazy loading)
+func (s *roleService) GetRoles(tenantID uint) ([]model.Role, error) {
+	if err := s.ensureBuiltInRoles(tenantID); err != nil {
+		return nil, err
+	}
+	return s.roleRepo.FindAll(tenantID)
+}
+
+func (s *roleService) GetRoleByID(tenantID, id uint) (*model.Role, error) {
+	role, err := s.roleRepo.FindByID(tenantID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("role not found")
+		}
+		return nil, err
+	}
+	return role, nil
+}
+
+// CreateRole creates a custom role with permissions the grantor holds
+func (s *roleService) CreateRole(tenantID uint, name, description string, permissionKeys []string, grantor Grantor) (*model.Role, error) {
+	if !roleNamePattern.MatchString(name) {
+		return nil, errors.New("role name must be 2-50 characters of lowercase letters, digits or underscores")
+	}
+
+	if err := s.ensureBuiltInRoles(tenantID); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.roleRepo.FindByName(tenantID, name); err == nil {
+		return nil, errors.New("role with this name already exists")
+	}
+
+	permissions, err := s.resolvePermissions(permissionKeys)
+	if err != nil {
+		return nil, err
+	}
+	if !grantor.holds(permissions) {
+		return nil, ErrRoleNotGrantable
+	}
+
+	role := &model.Role{
+		TenantID:    tenantID,
+		Name:        name,
+		Description: description,
+		Permissions: permissions,
+	}
+
+	if err := s.roleRepo.Create(role); err != nil {
+		return nil, err
+	}
+
+	return role, nil
+}
+
+// UpdateRole changes a role. The grantor must hold every permission of the role, so a
+// role ranking above them cannot be changed, as well as any permissions given to it.
+func (s *roleService) UpdateRole(tenantID, id uint, name, description string, permissionKeys []string, grantor Grantor) (*model.Role, error) {
+	role, err := s.GetRoleByID(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+	if (role.Name == model.RoleAdmin && grantor.Role != model.RoleAdmin) || !grantor.holds(role.Permissions) {
+		return nil, ErrRoleNotGrantable
+	}
+
+	oldName := role.Name
+
+	if name != "" && name != role.Name {
+		if role.IsBuiltIn {
+			return nil, errors.New("built-in roles cannot be renamed")
+		}
+		if !roleNamePattern.MatchString(name) {
+			return nil, errors.New("role name must be 2-50 characters of lowercase letters, digits or underscores")
+		}
+		if _, err := s.roleRepo.FindByName(tenantID, name); err == nil {
+			return nil, errors.New("role with this name already exists")
+		}
+		role.Name = name
+	}
+
+	if description != "" {
+		role.Description = description
+	}
+
+	if permissionKeys != nil {
+		// Admins must never be able to lock themselves out
+		if role.Name == model.RoleAdmin {
+			return nil, errors.New("permissions of the admin role cannot be changed")
+		}
+		permissions, err := s.resolvePermissions(permissionKeys)
+		if err != nil {
+			return nil, err
+		}
+		if !grantor.holds(permissions) {
+			return nil, ErrRoleNotGrantable
+		}
+		role.Permissions = permissions
+	}
+
+	if err := s.roleRepo.Update(role, oldName); err != nil {
+		return nil, err
+	}
+
+	s.memberships.InvalidateTenant(tenantID)
+	return role, nil
+}
+
+func (s *roleService) DeleteRole(tenantID, id uint) error {
+	role, err := s.GetRoleByID(tenantID, id)
+	if err != nil {
+		return err
+	}
+
+	if role.IsBuiltIn {
+		return errors.New("built-in roles cannot be deleted")
+	}
+
+	usersCount, err := s.roleRepo.CountUsersWithRole(tenantID, role.Name)
+	if err != nil {
+		return err
+	}
+
+	if usersCount > 0 {
+		return errors.New("cannot delete role assigned to users")
+	}
+
+	return s.roleRepo.Delete(tenantID, id)
+}
+
+// RoleExists reports whether a role name can be assigned to tenant users
+func (s *roleService) RoleExists(tenantID uint, name string) (bool, error) {
+	if model.IsBuiltInRole(name) {
+		return true, nil
+	}
+
+	_, err := s.roleRepo.FindByName(tenantID, name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// CheckGrantable refuses a role unless the grantor holds every permission of it (a
+// permission also covers its ":own" variant). The admin role is granted by admins only.
+func (s *roleService) CheckGrantable(tenantID uint, name string, grantor Grantor) error {
+	if name == model.RoleAdmin || grantor.Role == model.RoleAdmin {
+		if grantor.Role != model.RoleAdmin {
+			return ErrRoleNotGrantable
+		}
+		return nil
+	}
+
+	if err := s.ensureBuiltInRoles(tenantID); err != nil {
+		return err
+	}
+	role, err := s.roleRepo.FindByName(tenantID, name)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("invalid role: role does not exist in this tenant")
+		}
+		return err
+	}
+
+	if !grantor.holds(role.Permissions) {
+		return ErrRoleNotGrantable
+	}
+	return nil
+}
+
+// holds reports whether the grantor holds every one of permissions (a permission also
+// covers its ":own" variant). Admins hold all.
+func (g Grantor) holds(permissions []model.Permission) bool {
+	if g.Role == model.RoleAdmin {
+		return true
+	}
+	for _, permission := range permissions {
+		key := permission.Key
+		if !g.Permissions[key] && !g.Permissions[strings.TrimSuffix(key, model.PermissionOwnSuffix)] {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *roleService) ensureBuiltInRoles(tenantID uint) error {
+	count, err := s.roleRepo.CountByTenant(tenantID)
+	if err != nil {
+		return err
+	}
+
+	if count == 0 {
+		return s.roleRepo.CreateBuiltInRoles(tenantID)
+	}
+	return nil
+}
+
+// resolvePermissions maps permission keys to catalog rows, rejecting unknown keys
+func (s *roleService) resolvePermissions(keys []string) ([]model.Permission, error) {
+	permissions, err := s.roleRepo.FindPermissionsByKeys(keys)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(permissions) != len(uniqueStrings(keys)) {
+		return nil, errors.New("one or more permissions are invalid")
+	}
+
+	return permissions, nil
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	unique := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			unique = append(unique, v)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"gin-quickstart/internal/model"
+	"testing"
+)
+
+func TestGrantorHolds(t *testing.T) {
+	own := model.PermDealsUpdate + model.PermissionOwnSuffix
+
+	tests := []struct {
+		name        string
+		grantor     Grantor
+		permissions []string
+		want        bool
+	}{
+		{
+			name:        "holds every permission",
+			grantor:     Grantor{Role: model.RoleMember, Permissions: map[string]bool{model.PermDealsRead: true, model.PermDealsUpdate: true}},
+			permissions: []string{model.PermDealsRead, model.PermDealsUpdate},
+			want:        true,
+		},
+		{
+			name:        "missing one permission",
+			grantor:     Grantor{Role: model.RoleMember, Permissions: map[string]bool{model.PermDealsRead: true}},
+			permissions: []string{model.PermDealsRead, model.PermDealsDelete},
+		},
+		{
+			name:        "unscoped permission covers own",
+			grantor:     Grantor{Role: model.RoleMember, Permissions: map[string]bool{model.PermDealsUpdate: true}},
+			permissions: []string{own},
+			want:        true,
+		},
+		{
+			name:        "own permission does not cover unscoped",
+			grantor:     Grantor{Role: model.RoleMember, Permissions: map[string]bool{own: true}},
+			permissions: []string{model.PermDealsUpdate},
+		},
+		{
+			name:        "own permission covers own",
+			grantor:     Grantor{Role: model.RoleMember, Permissions: map[string]bool{own: true}},
+			permissions: []string{own},
+			want:        true,
+		},
+		{
+			name:        "own permission of another action",
+			grantor:     Grantor{Role: model.RoleMember, Permissions: map[string]bool{model.PermDealsUpdate: true}},
+			permissions: []string{model.PermDealsDelete + model.PermissionOwnSuffix},
+		},
+		{
+			name:        "admin holds all",
+			grantor:     Grantor{Role: model.RoleAdmin},
+			permissions: []string{model.PermRolesManage, model.PermDealsDelete},
+			want:        true,
+		},
+		{
+			name:    "no permissions",
+			grantor: Grantor{Role: model.RoleMember},
+			want:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		permissions := make([]model.Permission, len(tt.permissions))
+		for i, key := range tt.permissions {
+			permissions[i] = model.Permission{Key: key}
+		}
+		if got := tt.grantor.holds(permissions); got != tt.want {
+			t.Errorf("%s: holds(%v) = %v, want %v", tt.name, tt.permissions, got, tt.want)
+		}
+	}
+}
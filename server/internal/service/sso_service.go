@@ -0,0 +1,440 @@
+package service
+
+import (
+	"errors"
+	"gin-quickstart/config"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/oidc"
+	"gin-quickstart/internal/repository"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSSONotConfigured = errors.New("single sign-on is not enabled for this tenant")
+	ErrInvalidSSOLogin  = errors.New("single sign-on request is invalid or has expired, please try again")
+)
+
+// ssoExchangeExpiry bounds how long the frontend may take to redeem the callback code
+const ssoExchangeExpiry = 2 * time.Minute
+
+// SSOCallbackResult is the outcome of a successful provider callback
+type SSOCallbackResult struct {
+	ExchangeCode string // One-time code handed to the frontend
+	User         *model.User
+	TenantID     uint
+	Provisioned  bool // The user account was created by this login
+	Joined       bool // The user was added to the tenant by this login
+	Linked       bool // An existing account was linked to the provider identity
+}
+
+type SSOService interface {
+	GetConfig(tenantID uint) (*model.TenantSSOConfig, error)
+	SaveConfig(tenantID uint, input *model.TenantSSOConfig) (*model.TenantSSOConfig, error)
+	DeleteConfig(tenantID uint) error
+	StartLogin(tenantID uint) (string, error)
+	HandleCallback(state, code string) (*SSOCallbackResult, error)
+	ExchangeCode(code string) (*model.User, uint, error)
+}
+
+type ssoService struct {
+	ssoRepo        repository.SSORepository
+	userRepo       repository.UserRepository
+	tenantRepo     repository.TenantRepository
+	tenantUserRepo repository.TenantUserRepository
+	roleService    RoleService
+	client         *oidc.Client
+	memberships    MembershipInvalidator
+}
+
+func NewSSOService(
+	ssoRepo repository.SSORepository,
+	userRepo repository.UserRepository,
+	tenantRepo repository.TenantRepository,
+	tenantUserRepo repository.TenantUserRepository,
+	roleService RoleService,
+	client *oidc.Client,
+	memberships MembershipInvalidator,
+) SSOService {
+	return &ssoService{
+		ssoRepo:        ssoRepo,
+		userRepo:       userRepo,
+		tenantRepo:     tenantRepo,
+		tenantUserRepo: tenantUserRepo,
+		roleService:    roleService,
+		client:         client,
+		memberships:    memberships,
+	}
+}
+
+func (s *ssoService) GetConfig(tenantID uint) (*model.TenantSSOConfig, error) {
+	ssoConfig, err := s.ssoRepo.FindConfig(tenantID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("single sign-on is not configured")
+		}
+		return nil, err
+	}
+	return ssoConfig, nil
+}
+
+// SaveConfig creates or replaces the tenant's provider configuration. The issuer must
+// serve a valid discovery document. An empty client secret keeps the stored one.
+func (s *ssoService) SaveConfig(tenantID uint, input *model.TenantSSOConfig) (*model.TenantSSOConfig, error) {
+	issuer := strings.TrimRight(strings.TrimSpace(input.Issuer), "/")
+	clientID := strings.TrimSpace(input.ClientID)
+	if issuer == "" || clientID == "" {
+		return nil, errors.New("issuer and client_id are required")
+	}
+
+	if input.DefaultRole == "" {
+		input.DefaultRole = model.RoleMember
+	}
+	exists, err := s.roleService.RoleExists(tenantID, input.DefaultRole)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, errors.New("default role does not exist in this tenant")
+	}
+
+	domains := make([]string, 0)
+	for _, domain := range strings.Split(input.AllowedDomains, ",") {
+		domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+		if domain == "" {
+			continue
+		}
+		if strings.ContainsAny(domain, "@ /") || !strings.Contains(domain, ".") {
+			return nil, errors.New("invalid allowed domain: " + domain)
+		}
+		domains = append(domains, domain)
+	}
+
+	// The issuer is fetched from the server, so its errors are logged rather than shown
+	if _, err := s.client.Discover(issuer); err != nil {
+		if errors.Is(err, oidc.ErrUnsafeURL) {
+			return nil, oidc.ErrUnsafeURL
+		}
+		log.Printf("⚠️  SSO discovery failed for tenant %d: %v", tenantID, err)
+		return nil, errors.New("identity provider discovery failed, check the issuer URL")
+	}
+
+	ssoConfig, err := s.ssoRepo.FindConfig(tenantID)
+	switch {
+	case err == nil:
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		ssoConfig = &model.TenantSSOConfig{TenantID: tenantID}
+	default:
+		return nil, err
+	}
+
+	ssoConfig.Enabled = input.Enabled
+	ssoConfig.Issuer = issuer
+	ssoConfig.ClientID = clientID
+	ssoConfig.AllowedDomains = strings.Join(domains, ",")
+	ssoConfig.AutoProvision = input.AutoProvision
+	ssoConfig.DefaultRole = input.DefaultRole
+	if input.ClientSecret != "" {
+		ssoConfig.ClientSecret = input.ClientSecret
+	}
+
+	if err := s.ssoRepo.SaveConfig(ssoConfig); err != nil {
+		return nil, err
+	}
+	return ssoConfig, nil
+}
+
+func (s *ssoService) DeleteConfig(tenantID uint) error {
+	if _, err := s.GetConfig(tenantID); err != nil {
+		return err
+	}
+	return s.ssoRepo.DeleteConfig(tenantID)
+}
+
+// StartLogin records a new login attempt and returns the provider's authorization URL
+func (s *ssoService) StartLogin(tenantID uint) (string, error) {
+	ssoConfig, err := s.enabledConfig(tenantID)
+	if err != nil {
+		return "", err
+	}
+
+	metadata, err := s.client.Discover(ssoConfig.Issuer)
+	if err != nil {
+		return "", err
+	}
+
+	state, err := oidc.NewRandomString()
+	if err != nil {
+		return "", err
+	}
+	nonce, err := oidc.NewRandomString()
+	if err != nil {
+		return "", err
+	}
+	verifier, err := oidc.NewRandomString()
+	if err != nil {
+		return "", err
+	}
+
+	login := &model.SSOLogin{
+		TenantID:     tenantID,
+		StateHash:    hashToken(state),
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    time.Now().Add(config.AppConfig.Auth.SSOLoginExpiry),
+	}
+	if err := s.ssoRepo.CreateLogin(login); err != nil {
+		return "", err
+	}
+
+	return oidc.AuthCodeURL(metadata, ssoConfig.ClientID, SSORedirectURI(), state, nonce, verifier), nil
+}
+
+// HandleCallback redeems the provider's authorization code, verifies the ID token and
+// maps the identity to a user and tenant membership. Returns a one-time exchange code.
+func (s *ssoService) HandleCallback(state, code string) (*SSOCallbackResult, error) {
+	login, err := s.ssoRepo.FindLoginByState(hashToken(state))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidSSOLogin
+		}
+		return nil, err
+	}
+	if login.CompletedAt != nil || time.Now().After(login.ExpiresAt) {
+		return nil, ErrInvalidSSOLogin
+	}
+
+	ssoConfig, err := s.enabledConfig(login.TenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := s.client.Discover(ssoConfig.Issuer)
+	if err != nil {
+		log.Printf("⚠️  SSO discovery failed for tenant %d: %v", login.TenantID, err)
+		return nil, errors.New("the identity provider could not be reached, please try again")
+	}
+
+	idToken, err := s.client.Exchange(metadata, ssoConfig.ClientID, ssoConfig.ClientSecret, code, SSORedirectURI(), login.CodeVerifier)
+	if err != nil {
+		log.Printf("⚠️  SSO code exchange failed for tenant %d: %v", login.TenantID, err)
+		return nil, errors.New("the identity provider rejected the login")
+	}
+
+	claims, err := s.client.VerifyIDToken(ssoConfig.Issuer, ssoConfig.ClientID, idToken, login.Nonce)
+	if err != nil {
+		log.Printf("⚠️  SSO ID token rejected for tenant %d: %v", login.TenantID, err)
+		return nil, errors.New("the identity provider returned an invalid ID token")
+	}
+
+	result, err := s.resolveUser(ssoConfig, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	exchangeCode, err := generateRandomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	// Claim the login so the callback cannot be replayed
+	if err := s.ssoRepo.CompleteLogin(login.ID, result.User.ID, hashToken(exchangeCode), time.Now().Add(ssoExchangeExpiry)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidSSOLogin
+		}
+		return nil, err
+	}
+
+	result.ExchangeCode = exchangeCode
+	result.TenantID = login.TenantID
+	return result, nil
+}
+
+// ExchangeCode redeems the one-time code from the callback redirect
+func (s *ssoService) ExchangeCode(code string) (*model.User, uint, error) {
+	login, err := s.ssoRepo.FindLoginByExchangeCode(hashToken(code))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, 0, ErrInvalidSSOLogin
+		}
+		return nil, 0, err
+	}
+	if login.UserID == nil || login.ExchangedAt != nil || time.Now().After(login.ExpiresAt) {
+		return nil, 0, ErrInvalidSSOLogin
+	}
+
+	if err := s.ssoRepo.MarkLoginExchanged(login.ID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, 0, ErrInvalidSSOLogin
+		}
+		return nil, 0, err
+	}
+
+	user, err := s.userRepo.FindByID(*login.UserID)
+	if err != nil || !user.IsActive {
+		return nil, 0, ErrInvalidSSOLogin
+	}
+
+	return user, login.TenantID, nil
+}
+
+// resolveUser finds or provisions the user for a verified identity and makes sure they
+// are a member of the tenant.
+//
+// The email must be in an allowed domain on every login, also for identities linked
+// before, e.g. through another tenant using the same issuer, and explicitly verified by
+// the provider to link an identity or join the tenant. Accounts are only signed in, or
+// linked when they have no identity yet, while they belong to no other tenant and are not
+// a platform operator: the tenant controls its provider, so it must not be able to sign
+// in to accounts that also hold access elsewhere, also once they joined another tenant
+// after the identity was linked.
+func (s *ssoService) resolveUser(ssoConfig *model.TenantSSOConfig, claims *oidc.Claims) (*SSOCallbackResult, error) {
+	email := model.NormalizeEmail(claims.Email)
+	if email == "" {
+		return nil, errors.New("the identity provider did not return an email address")
+	}
+	errUnverified := errors.New("the email address is not verified by the identity provider")
+	if claims.EmailVerified != nil && !*claims.EmailVerified {
+		return nil, errUnverified
+	}
+	verified := claims.EmailVerified != nil
+	if !ssoConfig.AllowsEmail(email) {
+		return nil, errors.New("your email domain is not allowed to sign in to this tenant")
+	}
+
+	tenantID := ssoConfig.TenantID
+	result := &SSOCallbackResult{}
+
+	identity, err := s.ssoRepo.FindIdentity(ssoConfig.Issuer, claims.Subject)
+	switch {
+	case err == nil:
+		if result.User, err = s.userRepo.FindByID(identity.UserID); err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return nil, errors.New("the linked account no longer exists")
+			}
+			return nil, err
+		}
+		elsewhere, err := s.holdsAccessElsewhere(result.User, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		if elsewhere {
+			return nil, errors.New("this account has access outside this tenant, sign in with your password instead")
+		}
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		if !verified {
+			return nil, errUnverified
+		}
+
+		user, err := s.userRepo.FindByEmail(email)
+		switch {
+		case err == nil:
+			elsewhere, err := s.holdsAccessElsewhere(user, tenantID)
+			if err != nil {
+				return nil, err
+			}
+			if elsewhere {
+				return nil, errors.New("an account with this email already exists, sign in with your password instead")
+			}
+			result.User = user
+			result.Linked = true
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			if !ssoConfig.AutoProvision {
+				return nil, errors.New("no account exists for this email, ask your administrator for an invitation")
+			}
+			// No password: the account signs in through the provider until one is set
+			result.User = &model.User{
+				Email:    email,
+				FullName: claims.Name,
+				IsActive: true,
+			}
+			if err := s.userRepo.Create(result.User); err != nil {
+				return nil, err
+			}
+			result.Provisioned = true
+		default:
+			return nil, err
+		}
+
+		if err := s.ssoRepo.CreateIdentity(&model.UserIdentity{
+			UserID:  result.User.ID,
+			Issuer:  ssoConfig.Issuer,
+			Subject: claims.Subject,
+			Email:   email,
+		}); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, err
+	}
+
+	if !result.User.IsActive {
+		return nil, errors.New("user account is inactive")
+	}
+
+	if !s.tenantUserRepo.CheckUserAccess(tenantID, result.User.ID) {
+		if !ssoConfig.AutoProvision {
+			return nil, errors.New("you are not a member of this tenant, ask your administrator for an invitation")
+		}
+		if !verified {
+			return nil, errUnverified
+		}
+		if err := s.tenantUserRepo.Create(&model.TenantUser{
+			TenantID: tenantID,
+			UserID:   result.User.ID,
+			Role:     ssoConfig.DefaultRole,
+		}); err != nil {
+			return nil, err
+		}
+		result.Joined = true
+		s.memberships.Invalidate(tenantID, result.User.ID)
+	}
+
+	return result, nil
+}
+
+// holdsAccessElsewhere reports whether the user is a platform operator or a member of a
+// tenant other than tenantID
+func (s *ssoService) holdsAccessElsewhere(user *model.User, tenantID uint) (bool, error) {
+	if user.IsPlatformAdmin {
+		return true, nil
+	}
+	tenantUsers, err := s.tenantUserRepo.FindTenantsByUser(user.ID)
+	if err != nil {
+		return false, err
+	}
+	for _, tu := range tenantUsers {
+		if tu.TenantID != tenantID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// enabledConfig returns the configuration of an active tenant with SSO switched on
+func (s *ssoService) enabledConfig(tenantID uint) (*model.TenantSSOConfig, error) {
+	tenant, err := s.tenantRepo.FindByID(tenantID)
+	if err != nil || tenant.Status != "active" {
+		return nil, ErrSSONotConfigured
+	}
+
+	ssoConfig, err := s.ssoRepo.FindConfig(tenantID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrSSONotConfigured
+		}
+		return nil, err
+	}
+	if !ssoConfig.Enabled {
+		return nil, ErrSSONotConfigured
+	}
+	return ssoConfig, nil
+}
+
+// SSORedirectURI is the callback URL registered with every tenant's provider
+func SSORedirectURI() string {
+	return strings.TrimRight(config.AppConfig.Server.PublicURL, "/") + "/api/auth/sso/callback"
+}
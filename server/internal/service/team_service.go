@@ -0,0 +1,179 @@
+package service
+
+import (
+	"errors"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/repository"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// ErrTeamNotFound is returned when a team does not exist in the tenant
+var ErrTeamNotFound = errors.New("team not found")
+
+type TeamService interface {
+	GetTeams(tenantID uint) ([]model.Team, error)
+	GetTeam(tenantID, id uint) (*model.Team, error)
+	CreateTeam(tenantID uint, name, description string, managerID *uint, memberIDs []uint) (*model.Team, error)
+	UpdateTeam(tenantID, id uint, name, description string, managerID *uint, memberIDs []uint) (*model.Team, error)
+	DeleteTeam(tenantID, id uint) error
+}
+
+type teamService struct {
+	teamRepo       repository.TeamRepository
+	userRepo       repository.UserRepository
+	tenantUserRepo repository.TenantUserRepository
+	memberships    MembershipInvalidator
+}
+
+func NewTeamService(
+	teamRepo repository.TeamRepository,
+	userRepo repository.UserRepository,
+	tenantUserRepo repository.TenantUserRepository,
+	memberships MembershipInvalidator,
+) TeamService {
+	return &teamService{
+		teamRepo:       teamRepo,
+		userRepo:       userRepo,
+		tenantUserRepo: tenantUserRepo,
+		memberships:    memberships,
+	}
+}
+
+func (s *teamService) GetTeams(tenantID uint) ([]model.Team, error) {
+	return s.teamRepo.FindAll(tenantID)
+}
+
+func (s *teamService) GetTeam(tenantID, id uint) (*model.Team, error) {
+	team, err := s.teamRepo.FindByID(tenantID, id)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrTeamNotFound
+		}
+		return nil, err
+	}
+	return team, nil
+}
+
+func (s *teamService) CreateTeam(tenantID uint, name, description string, managerID *uint, memberIDs []uint) (*model.Team, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("team name is required")
+	}
+
+	if _, err := s.teamRepo.FindByName(tenantID, name); err == nil {
+		return nil, errors.New("team with this name already exists")
+	}
+
+	if err := s.validateManager(tenantID, managerID); err != nil {
+		return nil, err
+	}
+
+	members, err := s.resolveMembers(tenantID, memberIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	team := &model.Team{
+		TenantID:    tenantID,
+		Name:        name,
+		Description: description,
+		ManagerID:   managerID,
+		Members:     members,
+	}
+
+	if err := s.teamRepo.Create(team); err != nil {
+		return nil, err
+	}
+
+	// Team visibility is cached with memberships
+	s.memberships.InvalidateTenant(tenantID)
+	return s.GetTeam(tenantID, team.ID)
+}
+
+// UpdateTeam replaces the team's name, description, manager and members
+func (s *teamService) UpdateTeam(tenantID, id uint, name, description string, managerID *uint, memberIDs []uint) (*model.Team, error) {
+	team, err := s.GetTeam(tenantID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("team name is required")
+	}
+
+	if name != team.Name {
+		if _, err := s.teamRepo.FindByName(tenantID, name); err == nil {
+			return nil, errors.New("team with this name already exists")
+		}
+	}
+
+	if err := s.validateManager(tenantID, managerID); err != nil {
+		return nil, err
+	}
+
+	members, err := s.resolveMembers(tenantID, memberIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	team.Name = name
+	team.Description = description
+	team.ManagerID = managerID
+	team.Members = members
+
+	if err := s.teamRepo.Update(team); err != nil {
+		return nil, err
+	}
+
+	s.memberships.InvalidateTenant(tenantID)
+	return s.GetTeam(tenantID, id)
+}
+
+// DeleteTeam deletes a team; its contacts and deals become unassigned
+func (s *teamService) DeleteTeam(tenantID, id uint) error {
+	if _, err := s.GetTeam(tenantID, id); err != nil {
+		return err
+	}
+
+	if err := s.teamRepo.Delete(tenantID, id); err != nil {
+		return err
+	}
+
+	s.memberships.InvalidateTenant(tenantID)
+	return nil
+}
+
+func (s *teamService) validateManager(tenantID uint, managerID *uint) error {
+	if managerID != nil && !s.tenantUserRepo.CheckUserAccess(tenantID, *managerID) {
+		return errors.New("invalid manager_id: user is not a member of this tenant")
+	}
+	return nil
+}
+
+// resolveMembers loads the given users, rejecting anyone outside the tenant
+func (s *teamService) resolveMembers(tenantID uint, memberIDs []uint) ([]model.User, error) {
+	seen := make(map[uint]bool, len(memberIDs))
+	members := make([]model.User, 0, len(memberIDs))
+
+	for _, userID := range memberIDs {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+
+		if !s.tenantUserRepo.CheckUserAccess(tenantID, userID) {
+			return nil, errors.New("invalid member_ids: user is not a member of this tenant")
+		}
+
+		user, err := s.userRepo.FindByID(userID)
+		if err != nil {
+			return nil, errors.New("invalid member_ids: user not found")
+		}
+		members = append(members, *user)
+	}
+
+	return members, nil
+}
@@ -0,0 +1,217 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"gin-quickstart/config"
+	"gin-quickstart/internal/blobstore"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/repository"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// purgeTenantsPerRun bounds how many tenants one run of the purge job processes
+const purgeTenantsPerRun = 10
+
+type TenantLifecycleService interface {
+	SetStatus(tenantID uint, status string) error
+	RequestDeletion(tenantID, userID uint, password string) (*model.Tenant, error)
+	CancelDeletion(tenantID, userID uint) (*model.Tenant, error)
+	PurgeDueTenants() (int, error)
+	StartPurgeJob()
+}
+
+type tenantLifecycleService struct {
+	tenantRepo     repository.TenantRepository
+	tenantUserRepo repository.TenantUserRepository
+	userRepo       repository.UserRepository
+	purgeRepo      repository.TenantPurgeRepository
+	blobs          blobstore.Store
+	memberships    MembershipInvalidator
+	cfg            config.TenantConfig
+}
+
+func NewTenantLifecycleService(
+	tenantRepo repository.TenantRepository,
+	tenantUserRepo repository.TenantUserRepository,
+	userRepo repository.UserRepository,
+	purgeRepo repository.TenantPurgeRepository,
+	blobs blobstore.Store,
+	memberships MembershipInvalidator,
+	cfg config.TenantConfig,
+) TenantLifecycleService {
+	return &tenantLifecycleService{
+		tenantRepo:     tenantRepo,
+		tenantUserRepo: tenantUserRepo,
+		userRepo:       userRepo,
+		purgeRepo:      purgeRepo,
+		blobs:          blobs,
+		memberships:    memberships,
+		cfg:            cfg,
+	}
+}
+
+// SetStatus suspends, reactivates or deactivates a tenant. Deletion has its own flow.
+func (s *tenantLifecycleService) SetStatus(tenantID uint, status string) error {
+	switch status {
+	case model.TenantStatusActive, model.TenantStatusSuspended, model.TenantStatusInactive:
+	default:
+		return errors.New("status must be active, suspended or inactive")
+	}
+
+	if err := s.tenantRepo.SetStatus(tenantID, status); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("tenant not found or being deleted")
+		}
+		return err
+	}
+	s.memberships.InvalidateTenant(tenantID)
+	return nil
+}
+
+// RequestDeletion starts the tenant's deletion grace period. Only the owner can request
+// it, confirming with their password. The tenant is read-only until the deletion is
+// cancelled or the purge job removes it.
+func (s *tenantLifecycleService) RequestDeletion(tenantID, userID uint, password string) (*model.Tenant, error) {
+	tenant, err := s.tenantRepo.FindByID(tenantID)
+	if err != nil {
+		return nil, errors.New("tenant not found")
+	}
+
+	if tenant.OwnerID == nil || *tenant.OwnerID != userID {
+		return nil, errors.New("only the tenant owner can delete the tenant")
+	}
+
+	user, err := s.userRepo.FindByID(userID)
+	if err != nil {
+		return nil, errors.New("user not found")
+	}
+	if err := checkPassword(user, password); err != nil {
+		return nil, err
+	}
+
+	purgeAfter := time.Now().Add(s.cfg.DeletionGracePeriod)
+	if err := s.tenantRepo.ScheduleDeletion(tenantID, userID, purgeAfter); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("only active tenants can be deleted")
+		}
+		return nil, err
+	}
+	s.memberships.InvalidateTenant(tenantID)
+
+	return s.tenantRepo.FindByID(tenantID)
+}
+
+// CancelDeletion reactivates a tenant during its grace period. Any admin can cancel.
+func (s *tenantLifecycleService) CancelDeletion(tenantID, userID uint) (*model.Tenant, error) {
+	membership, err := s.tenantUserRepo.FindByTenantAndUser(tenantID, userID)
+	if err != nil || membership.Role != model.RoleAdmin {
+		return nil, errors.New("only an admin can cancel the deletion")
+	}
+
+	if err := s.tenantRepo.CancelDeletion(tenantID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("no deletion is pending for this tenant")
+		}
+		return nil, err
+	}
+	s.memberships.InvalidateTenant(tenantID)
+
+	return s.tenantRepo.FindByID(tenantID)
+}
+
+// PurgeDueTenants permanently deletes tenants whose grace period has ended and returns
+// how many were purged
+func (s *tenantLifecycleService) PurgeDueTenants() (int, error) {
+	tenants, err := s.tenantRepo.FindDueForPurge(purgeTenantsPerRun)
+	if err != nil {
+		return 0, err
+	}
+
+	purged := 0
+	for i := range tenants {
+		tenant := &tenants[i]
+
+		if tenant.Status == model.TenantStatusPendingDeletion {
+			if err := s.tenantRepo.ClaimForPurge(tenant.ID); err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					continue // Cancelled in the meantime
+				}
+				return purged, err
+			}
+		}
+		s.memberships.InvalidateTenant(tenant.ID)
+
+		if err := s.purge(tenant); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// StartPurgeJob runs PurgeDueTenants in the background every PurgeInterval
+func (s *tenantLifecycleService) StartPurgeJob() {
+	go func() {
+		ticker := time.NewTicker(s.cfg.PurgeInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			purged, err := s.PurgeDueTenants()
+			if err != nil {
+				log.Printf("⚠️  Tenant purge failed: %v", err)
+			}
+			if purged > 0 {
+				log.Printf("🗑️  Purged %d tenants", purged)
+			}
+		}
+	}()
+}
+
+// purge deletes the tenant's files and its rows table by table in batches, then replaces
+// the tenant with a tombstone. An interrupted purge resumes where it stopped on the next run.
+func (s *tenantLifecycleService) purge(tenant *model.Tenant) error {
+	startedAt := time.Now()
+
+	if err := s.blobs.DeletePrefix(tenantBlobPrefix(tenant.ID)); err != nil {
+		return err
+	}
+	rowsPurged := make(map[string]int64)
+
+	batchSize := s.cfg.PurgeBatchSize
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	for _, table := range s.purgeRepo.Tables() {
+		for {
+			deleted, err := s.purgeRepo.PurgeBatch(table, tenant.ID, batchSize)
+			if err != nil {
+				return err
+			}
+			rowsPurged[table] += deleted
+			if deleted < int64(batchSize) {
+				break
+			}
+		}
+	}
+
+	summary, err := json.Marshal(rowsPurged)
+	if err != nil {
+		return err
+	}
+
+	return s.purgeRepo.Finish(&model.TenantTombstone{
+		TenantID:            tenant.ID,
+		Name:                tenant.Name,
+		OwnerID:             tenant.OwnerID,
+		TenantCreatedAt:     tenant.CreatedAt,
+		DeletionRequestedAt: tenant.DeletionRequestedAt,
+		DeletionRequestedBy: tenant.DeletionRequestedBy,
+		PurgeStartedAt:      startedAt,
+		RowsPurged:          string(summary),
+	})
+}
@@ -2,22 +2,36 @@ package service
 
 import (
 	"errors"
+	"fmt"
+	"gin-quickstart/config"
 	"gin-quickstart/internal/model"
 	"gin-quickstart/internal/repository"
+	"strconv"
 
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
+var (
+	ErrLastAdmin   = errors.New("a tenant must keep at least one admin")
+	ErrTenantOwner = errors.New("the tenant owner cannot be removed or demoted, transfer ownership first")
+)
+
 type TenantService interface {
 	GetTenantByID(id uint) (*model.Tenant, error)
-	GetAllTenants(page, pageSize int) ([]model.Tenant, int64, error)
 	UpdateTenant(tenant *model.Tenant) error
-	DeleteTenant(id uint) error
 	GetTenantUsers(tenantID uint, page, pageSize int) ([]model.TenantUser, int64, error)
-	AddUser(tenantID uint, email, password, fullName, role string) (*model.User, bool, error)
 	RemoveUserFromTenant(tenantID, userID uint) error
-	UpdateUserRole(tenantID, userID uint, role string) error
+	UpdateUserRole(tenantID, userID uint, role string, grantor Grantor) error
+	TransferOwnership(tenantID, ownerID, newOwnerID uint, password string) error
+	GetSettings(tenantID uint) (map[string]string, error)
+	UpdateSettings(tenantID uint, settings map[string]string) error
+}
+
+// MembershipInvalidator drops cached tenant memberships after role or membership changes
+type MembershipInvalidator interface {
+	Invalidate(tenantID, userID uint)
+	InvalidateTenant(tenantID uint)
+	InvalidateUser(userID uint)
 }
 
 type tenantService struct {
@@ -25,6 +39,10 @@ type tenantService struct {
 	userRepo       repository.UserRepository
 	tenantUserRepo repository.TenantUserRepository
 	auditLogRepo   repository.AuditLogRepository
+	settingRepo    repository.TenantSettingRepository
+	teamRepo       repository.TeamRepository
+	roleService    RoleService
+	memberships    MembershipInvalidator
 }
 
 func NewTenantService(
@@ -32,12 +50,20 @@ func NewTenantService(
 	userRepo repository.UserRepository,
 	tenantUserRepo repository.TenantUserRepository,
 	auditLogRepo repository.AuditLogRepository,
+	settingRepo repository.TenantSettingRepository,
+	teamRepo repository.TeamRepository,
+	roleService RoleService,
+	memberships MembershipInvalidator,
 ) TenantService {
 	return &tenantService{
 		tenantRepo:     tenantRepo,
 		userRepo:       userRepo,
 		tenantUserRepo: tenantUserRepo,
 		auditLogRepo:   auditLogRepo,
+		settingRepo:    settingRepo,
+		teamRepo:       teamRepo,
+		roleService:    roleService,
+		memberships:    memberships,
 	}
 }
 
@@ -45,88 +71,217 @@ func (s *tenantService) GetTenantByID(id uint) (*model.Tenant, error) {
 	return s.tenantRepo.FindByID(id)
 }
 
-func (s *tenantService) GetAllTenants(page, pageSize int) ([]model.Tenant, int64, error) {
-	return s.tenantRepo.FindAll(page, pageSize)
-}
-
 func (s *tenantService) UpdateTenant(tenant *model.Tenant) error {
 	return s.tenantRepo.Update(tenant)
 }
 
-func (s *tenantService) DeleteTenant(id uint) error {
-	return s.tenantRepo.Delete(id)
-}
-
 func (s *tenantService) GetTenantUsers(tenantID uint, page, pageSize int) ([]model.TenantUser, int64, error) {
 	return s.tenantUserRepo.FindUsersByTenant(tenantID, page, pageSize)
 }
 
-// AddUser creates a new user or adds existing user to tenant
-// Returns: (user, isNewUser, error)
-func (s *tenantService) AddUser(tenantID uint, email, password, fullName, role string) (*model.User, bool, error) {
-	// Validate role
-	validRoles := map[string]bool{"admin": true, "manager": true, "member": true}
-	if !validRoles[role] {
-		return nil, false, errors.New("invalid role. must be: admin, manager, or member")
+// RemoveUserFromTenant removes a member unless they own the tenant or are its last admin
+func (s *tenantService) RemoveUserFromTenant(tenantID, userID uint) error {
+	err := guardAdmins(s.tenantUserRepo, tenantID, userID, false, func(repo repository.TenantUserRepository) error {
+		return repo.Delete(tenantID, userID)
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.teamRepo.RemoveUserFromTeams(tenantID, userID); err != nil {
+		return err
 	}
+	// Team managers' visibility depends on team membership
+	s.memberships.InvalidateTenant(tenantID)
+	return nil
+}
 
-	// Check if user exists by email
-	user, err := s.userRepo.FindByEmail(email)
-	isNewUser := false
+// UpdateUserRole changes a member's role. Admins cannot be demoted while they own the
+// tenant or are its last admin, and the grantor must hold every permission of the role.
+func (s *tenantService) UpdateUserRole(tenantID, userID uint, role string, grantor Grantor) error {
+	if err := s.validateRole(tenantID, role); err != nil {
+		return err
+	}
+	if err := s.roleService.CheckGrantable(tenantID, role, grantor); err != nil {
+		return err
+	}
 
+	err := guardAdmins(s.tenantUserRepo, tenantID, userID, role == model.RoleAdmin, func(repo repository.TenantUserRepository) error {
+		return repo.UpdateRole(tenantID, userID, role)
+	})
 	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			// User doesn't exist, create new user
-			if password == "" {
-				return nil, false, errors.New("password is required for new user")
-			}
+		return err
+	}
+	s.memberships.Invalidate(tenantID, userID)
+	return nil
+}
 
-			hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-			if err != nil {
-				return nil, false, err
-			}
+// TransferOwnership makes another member the owner after the current owner confirmed with
+// their password. The new owner becomes an admin; the previous owner stays admin. Tenants
+// without an owner can be claimed for a member by any of their admins.
+func (s *tenantService) TransferOwnership(tenantID, ownerID, newOwnerID uint, password string) error {
+	tenant, err := s.tenantRepo.FindByID(tenantID)
+	if err != nil {
+		return errors.New("tenant not found")
+	}
 
-			user = &model.User{
-				Email:        email,
-				PasswordHash: string(hashedPassword),
-				FullName:     fullName,
-				IsActive:     true,
-			}
+	if tenant.OwnerID != nil && *tenant.OwnerID != ownerID {
+		return errors.New("only the tenant owner can transfer ownership")
+	}
+	if tenant.OwnerID == nil {
+		membership, err := s.tenantUserRepo.FindByTenantAndUser(tenantID, ownerID)
+		if err != nil || membership.Role != model.RoleAdmin {
+			return errors.New("only an admin can assign the owner of this tenant")
+		}
+	}
+	if newOwnerID == ownerID && tenant.OwnerID != nil {
+		return errors.New("you already own this tenant")
+	}
 
-			if err := s.userRepo.Create(user); err != nil {
-				return nil, false, err
-			}
-			isNewUser = true
-		} else {
-			// Database error
-			return nil, false, err
+	owner, err := s.userRepo.FindByID(ownerID)
+	if err != nil {
+		return errors.New("user not found")
+	}
+	if err := checkPassword(owner, password); err != nil {
+		return err
+	}
+
+	newOwner, err := s.tenantUserRepo.FindMembership(tenantID, newOwnerID)
+	if err != nil {
+		return errors.New("new owner must be a member of this tenant")
+	}
+	if !newOwner.User.IsActive {
+		return errors.New("new owner's account is inactive")
+	}
+
+	// Promote first: an additional admin never breaks the invariant, so a failed transfer
+	// leaves the tenant in a valid state
+	if newOwner.Role != model.RoleAdmin {
+		if err := s.tenantUserRepo.UpdateRole(tenantID, newOwnerID, model.RoleAdmin); err != nil {
+			return err
 		}
+		s.memberships.Invalidate(tenantID, newOwnerID)
 	}
 
-	// Check if user already in tenant
-	existingTenantUser, err := s.tenantUserRepo.FindByTenantAndUser(tenantID, user.ID)
-	if err == nil && existingTenantUser != nil {
-		return nil, false, errors.New("user already exists in this tenant")
+	if err := s.tenantRepo.SetOwner(tenantID, newOwnerID, tenant.OwnerID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return errors.New("ownership changed in the meantime, please retry")
+		}
+		return err
 	}
+	return nil
+}
 
-	// Add user to tenant
-	tenantUser := &model.TenantUser{
-		TenantID: tenantID,
-		UserID:   user.ID,
-		Role:     role,
+// GetSettings returns the tenant's settings merged over the defaults
+func (s *tenantService) GetSettings(tenantID uint) (map[string]string, error) {
+	settings := make(map[string]string, len(model.TenantSettingDefaults))
+	for key, value := range model.TenantSettingDefaults {
+		settings[key] = value
 	}
 
-	if err := s.tenantUserRepo.Create(tenantUser); err != nil {
-		return nil, false, err
+	stored, err := s.settingRepo.FindAll(tenantID)
+	if err != nil {
+		return nil, err
 	}
 
-	return user, isNewUser, nil
+	for _, setting := range stored {
+		settings[setting.Key] = setting.Value
+	}
+
+	return settings, nil
 }
 
-func (s *tenantService) RemoveUserFromTenant(tenantID, userID uint) error {
-	return s.tenantUserRepo.Delete(tenantID, userID)
+// UpdateSettings validates and stores the given settings
+func (s *tenantService) UpdateSettings(tenantID uint, settings map[string]string) error {
+	// Validate everything before writing anything
+	for key, value := range settings {
+		if err := model.ValidateTenantSetting(key, value); err != nil {
+			return err
+		}
+		// Rate limits above the server's maximum would be capped silently
+		if maxRPM := rateLimitSettingMax(key); maxRPM > 0 {
+			if n, _ := strconv.Atoi(value); n > maxRPM {
+				return fmt.Errorf("setting %s must be at most %d", key, maxRPM)
+			}
+		}
+	}
+
+	for key, value := range settings {
+		if err := s.settingRepo.Set(tenantID, key, value); err != nil {
+			return err
+		}
+	}
+
+	// Settings such as record visibility are cached with memberships
+	s.memberships.InvalidateTenant(tenantID)
+	return nil
+}
+
+// rateLimitSettingMax returns the configured upper bound of a rate limit setting, 0 for
+// other settings or when the server sets no bound
+func rateLimitSettingMax(key string) int {
+	switch key {
+	case model.SettingRateLimitTenant:
+		return config.AppConfig.RateLimit.MaxTenantRPM
+	case model.SettingRateLimitUser:
+		return config.AppConfig.RateLimit.MaxUserRPM
+	}
+	return 0
 }
 
-func (s *tenantService) UpdateUserRole(tenantID, userID uint, role string) error {
-	return s.tenantUserRepo.UpdateRole(tenantID, userID, role)
+// guardAdmins applies change to a member's tenant membership in a transaction that locks
+// the tenant and its active admins, refusing it if the member owns the tenant or would
+// leave it without an active admin. keepsAdmin reports whether the member is still an
+// admin after the change.
+func guardAdmins(
+	tenantUserRepo repository.TenantUserRepository,
+	tenantID, userID uint,
+	keepsAdmin bool,
+	change func(repo repository.TenantUserRepository) error,
+) error {
+	return tenantUserRepo.Transaction(func(repo repository.TenantUserRepository) error {
+		// Read the owner under the lock, so that it cannot change before the check
+		tenant, err := repo.FindTenantForUpdate(tenantID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				// Deleted tenants have nobody left to administer
+				return change(repo)
+			}
+			return err
+		}
+
+		if !keepsAdmin && tenant.OwnerID != nil && *tenant.OwnerID == userID {
+			return ErrTenantOwner
+		}
+
+		admins, err := repo.FindByRoleForUpdate(tenantID, model.RoleAdmin)
+		if err != nil {
+			return err
+		}
+
+		if !keepsAdmin {
+			remaining := 0
+			for _, admin := range admins {
+				if admin.UserID != userID {
+					remaining++
+				}
+			}
+			if remaining == 0 && len(admins) > 0 {
+				return ErrLastAdmin
+			}
+		}
+
+		return change(repo)
+	})
+}
+
+// validateRole checks that the role is built in or defined for the tenant
+func (s *tenantService) validateRole(tenantID uint, role string) error {
+	exists, err := s.roleService.RoleExists(tenantID, role)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return errors.New("invalid role: role does not exist in this tenant")
+	}
+	return nil
 }
@@ -0,0 +1,332 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"gin-quickstart/config"
+	"gin-quickstart/internal/jwtkeys"
+	"gin-quickstart/internal/middleware"
+	"gin-quickstart/internal/model"
+	"gin-quickstart/internal/repository"
+	"log"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected, please login again")
+	ErrSessionNotFound     = errors.New("session not found")
+)
+
+// TokenPair is returned to clients after login, registration, tenant switch and refresh
+type TokenPair struct {
+	AccessToken      string    `json:"token"`
+	RefreshToken     string    `json:"refresh_token"`
+	ExpiresIn        int64     `json:"expires_in"` // Access token lifetime in seconds
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+type TokenService interface {
+	IssueTokens(user *model.User, tenantID uint, role, ipAddress, userAgent string) (*TokenPair, error)
+	IssueImpersonationToken(operatorID uint, user *model.User, tenantID uint, role string, expiresAt time.Time, ipAddress, userAgent string) (string, *model.Session, error)
+	SwitchTenant(sessionID uint, user *model.User, tenantID uint, role, ipAddress, userAgent string) (*TokenPair, error)
+	Refresh(refreshToken, ipAddress, userAgent string) (*TokenPair, error)
+	Logout(refreshToken string, allSessions bool) error
+	RevokeAllForUser(userID uint) error
+	GetSessions(userID uint) ([]model.Session, error)
+	RevokeSession(userID, sessionID uint) error
+	RevokeTenantSessions(tenantID, userID, revokedBy uint) (int64, error)
+}
+
+type tokenService struct {
+	refreshTokenRepo repository.RefreshTokenRepository
+	sessionRepo      repository.SessionRepository
+	userRepo         repository.UserRepository
+	tenantUserRepo   repository.TenantUserRepository
+	keys             *jwtkeys.Manager
+}
+
+func NewTokenService(
+	refreshTokenRepo repository.RefreshTokenRepository,
+	sessionRepo repository.SessionRepository,
+	userRepo repository.UserRepository,
+	tenantUserRepo repository.TenantUserRepository,
+	keys *jwtkeys.Manager,
+) TokenService {
+	return &tokenService{
+		refreshTokenRepo: refreshTokenRepo,
+		sessionRepo:      sessionRepo,
+		userRepo:         userRepo,
+		tenantUserRepo:   tenantUserRepo,
+		keys:             keys,
+	}
+}
+
+// IssueTokens starts a new session and token family (one per login)
+func (s *tokenService) IssueTokens(user *model.User, tenantID uint, role, ipAddress, userAgent string) (*TokenPair, error) {
+	now := time.Now()
+	session := &model.Session{
+		UserID:     user.ID,
+		TenantID:   tenantID,
+		IPAddress:  ipAddress,
+		UserAgent:  userAgent,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(config.AppConfig.JWT.RefreshExpiry),
+	}
+	if err := s.sessionRepo.Create(session); err != nil {
+		return nil, err
+	}
+
+	return s.startFamily(session.ID, user, tenantID, role, ipAddress, userAgent)
+}
+
+// IssueImpersonationToken starts a session of the user for a platform operator. The
+// session appears in the user's session list, where it can be revoked, and its access
+// token cannot be refreshed.
+func (s *tokenService) IssueImpersonationToken(operatorID uint, user *model.User, tenantID uint, role string, expiresAt time.Time, ipAddress, userAgent string) (string, *model.Session, error) {
+	session := &model.Session{
+		UserID:     user.ID,
+		TenantID:   tenantID,
+		IPAddress:  ipAddress,
+		UserAgent:  userAgent,
+		LastSeenAt: time.Now(),
+		ExpiresAt:  expiresAt,
+		OperatorID: &operatorID,
+	}
+	if err := s.sessionRepo.Create(session); err != nil {
+		return "", nil, err
+	}
+
+	token, err := middleware.GenerateImpersonationToken(s.keys, user.ID, tenantID, session.ID, operatorID, user.Email, role, expiresAt)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, session, nil
+}
+
+// SwitchTenant moves a session to another tenant. The refresh tokens issued for the
+// previous tenant are revoked and a new family is started. Tokens without a session
+// (issued before sessions existed) start a new one.
+func (s *tokenService) SwitchTenant(sessionID uint, user *model.User, tenantID uint, role, ipAddress, userAgent string) (*TokenPair, error) {
+	if sessionID == 0 {
+		return s.IssueTokens(user, tenantID, role, ipAddress, userAgent)
+	}
+
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil || session.UserID != user.ID || !session.IsActive() {
+		return nil, ErrSessionNotFound
+	}
+
+	if err := s.refreshTokenRepo.RevokeAllBySession(session.ID); err != nil {
+		return nil, err
+	}
+	if err := s.sessionRepo.UpdateTenant(session.ID, tenantID); err != nil {
+		return nil, err
+	}
+
+	return s.startFamily(session.ID, user, tenantID, role, ipAddress, userAgent)
+}
+
+// startFamily issues the first refresh token of a new family within a session
+func (s *tokenService) startFamily(sessionID uint, user *model.User, tenantID uint, role, ipAddress, userAgent string) (*TokenPair, error) {
+	familyID, err := generateRandomToken(16)
+	if err != nil {
+		return nil, err
+	}
+
+	rawRefresh, refreshToken, err := s.newRefreshToken(user.ID, tenantID, sessionID, familyID, ipAddress, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.refreshTokenRepo.Create(refreshToken); err != nil {
+		return nil, err
+	}
+
+	if err := s.sessionRepo.Extend(sessionID, refreshToken.ExpiresAt); err != nil {
+		return nil, err
+	}
+
+	return s.buildPair(user, tenantID, sessionID, role, rawRefresh, refreshToken)
+}
+
+// Refresh rotates a refresh token: the presented token is revoked and a new one issued
+// in the same family. Presenting an already-rotated token revokes the entire family.
+func (s *tokenService) Refresh(rawToken, ipAddress, userAgent string) (*TokenPair, error) {
+	existing, err := s.refreshTokenRepo.FindByHash(hashToken(rawToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrInvalidRefreshToken
+		}
+		return nil, err
+	}
+
+	if existing.RevokedAt != nil && existing.ReplacedByID == nil {
+		// Revoked by logout or session revocation
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if existing.RevokedAt != nil {
+		// A rotated token is being replayed: assume it was stolen
+		log.Printf("⚠️  Refresh token reuse detected for user %d (family %s)", existing.UserID, existing.FamilyID)
+		if err := s.revokeFamily(existing); err != nil {
+			return nil, err
+		}
+		return nil, ErrRefreshTokenReused
+	}
+
+	if !existing.IsActive() {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.userRepo.FindByID(existing.UserID)
+	if err != nil || !user.IsActive {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Role may have changed since the last refresh, so always read it fresh
+	tenantUser, err := s.tenantUserRepo.FindByTenantAndUser(existing.TenantID, existing.UserID)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	rawRefresh, refreshToken, err := s.newRefreshToken(user.ID, existing.TenantID, existing.SessionID, existing.FamilyID, ipAddress, userAgent)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.refreshTokenRepo.Rotate(existing.ID, refreshToken); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Lost a race with a concurrent refresh of the same token
+			if err := s.revokeFamily(existing); err != nil {
+				return nil, err
+			}
+			return nil, ErrRefreshTokenReused
+		}
+		return nil, err
+	}
+
+	if existing.SessionID != 0 {
+		if err := s.sessionRepo.Extend(existing.SessionID, refreshToken.ExpiresAt); err != nil {
+			return nil, err
+		}
+		if err := s.sessionRepo.Touch(existing.SessionID, ipAddress, userAgent); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.buildPair(user, existing.TenantID, existing.SessionID, tenantUser.Role, rawRefresh, refreshToken)
+}
+
+// Logout revokes the whole family the given refresh token belongs to.
+// With allSessions every other session of the same user is revoked as well.
+func (s *tokenService) Logout(rawToken string, allSessions bool) error {
+	existing, err := s.refreshTokenRepo.FindByHash(hashToken(rawToken))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrInvalidRefreshToken
+		}
+		return err
+	}
+
+	if allSessions {
+		return s.RevokeAllForUser(existing.UserID)
+	}
+
+	return s.revokeFamily(existing)
+}
+
+// RevokeAllForUser ends every session, revokes every refresh token and invalidates
+// outstanding access tokens
+func (s *tokenService) RevokeAllForUser(userID uint) error {
+	if err := s.sessionRepo.RevokeAllByUser(userID); err != nil {
+		return err
+	}
+	if err := s.refreshTokenRepo.RevokeAllByUser(userID); err != nil {
+		return err
+	}
+	return s.userRepo.SetTokensValidAfter(userID, time.Now())
+}
+
+// GetSessions returns the user's active sessions
+func (s *tokenService) GetSessions(userID uint) ([]model.Session, error) {
+	return s.sessionRepo.FindActiveByUser(userID)
+}
+
+// RevokeSession logs one of the user's own sessions out
+func (s *tokenService) RevokeSession(userID, sessionID uint) error {
+	session, err := s.sessionRepo.FindByID(sessionID)
+	if err != nil || session.UserID != userID || !session.IsActive() {
+		return ErrSessionNotFound
+	}
+	return s.sessionRepo.Revoke(sessionID, nil)
+}
+
+// RevokeTenantSessions force-logs a tenant member out of every session signed in to the
+// tenant. Sessions in the member's other tenants are not affected.
+func (s *tokenService) RevokeTenantSessions(tenantID, userID, revokedBy uint) (int64, error) {
+	if !s.tenantUserRepo.CheckUserAccess(tenantID, userID) {
+		return 0, errors.New("user not found in this tenant")
+	}
+	return s.sessionRepo.RevokeByTenantAndUser(tenantID, userID, &revokedBy)
+}
+
+// revokeFamily revokes a token family, and with it the session it belongs to
+func (s *tokenService) revokeFamily(token *model.RefreshToken) error {
+	if token.SessionID != 0 {
+		return s.sessionRepo.Revoke(token.SessionID, nil)
+	}
+	return s.refreshTokenRepo.RevokeFamily(token.FamilyID)
+}
+
+func (s *tokenService) newRefreshToken(userID, tenantID, sessionID uint, familyID, ipAddress, userAgent string) (string, *model.RefreshToken, error) {
+	raw, err := generateRandomToken(32)
+	if err != nil {
+		return "", nil, err
+	}
+
+	return raw, &model.RefreshToken{
+		UserID:    userID,
+		TenantID:  tenantID,
+		FamilyID:  familyID,
+		SessionID: sessionID,
+		TokenHash: hashToken(raw),
+		ExpiresAt: time.Now().Add(config.AppConfig.JWT.RefreshExpiry),
+		IPAddress: ipAddress,
+		UserAgent: userAgent,
+	}, nil
+}
+
+func (s *tokenService) buildPair(user *model.User, tenantID, sessionID uint, role, rawRefresh string, refreshToken *model.RefreshToken) (*TokenPair, error) {
+	accessToken, err := middleware.GenerateToken(s.keys, user.ID, tenantID, sessionID, user.Email, role)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:      accessToken,
+		RefreshToken:     rawRefresh,
+		ExpiresIn:        int64(config.AppConfig.JWT.Expiry.Seconds()),
+		RefreshExpiresAt: refreshToken.ExpiresAt,
+	}, nil
+}
+
+// generateRandomToken returns a URL-safe random string built from n random bytes
+func generateRandomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hex SHA-256 digest used to store opaque tokens
+func hashToken(raw string) string {
+	sum := sha256.Sum256([]byte(raw))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,78 @@
+// Package spreadsheet reads and writes the rows of CSV and XLSX files, for users moving
+// data between the CRM and their spreadsheets.
+package spreadsheet
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"io"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+)
+
+// Supported file formats
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+var ErrUnsupportedFormat = errors.New("unsupported file type, upload a .csv or .xlsx file")
+
+// Reader returns the rows of a sheet one at a time. Read returns io.EOF after the last
+// row. Rows may have different lengths.
+type Reader interface {
+	Read() ([]string, error)
+}
+
+// FormatOf returns the format of a file from its extension
+func FormatOf(filename string) (string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv", ".txt":
+		return FormatCSV, nil
+	case ".xlsx":
+		return FormatXLSX, nil
+	}
+	return "", ErrUnsupportedFormat
+}
+
+// ParseDelimiter validates a CSV field delimiter, defaulting to a comma. "\t" and "tab"
+// select tabs.
+func ParseDelimiter(value string) (rune, error) {
+	switch value {
+	case "":
+		return ',', nil
+	case `\t`, "tab":
+		return '\t', nil
+	}
+
+	r, size := utf8.DecodeRuneInString(value)
+	if size != len(value) || r == '"' || r == '\r' || r == '\n' || r == utf8.RuneError {
+		return 0, errors.New("delimiter must be a single character other than a quote or line break")
+	}
+	return r, nil
+}
+
+type csvReader struct {
+	reader *csv.Reader
+}
+
+// NewCSVReader reads CSV rows separated by delimiter. A leading UTF-8 byte order mark,
+// as written by Excel, is dropped before parsing, so that a quoted first cell is unquoted.
+func NewCSVReader(r io.Reader, delimiter rune) Reader {
+	buffered := bufio.NewReader(r)
+	if bom, err := buffered.Peek(3); err == nil && string(bom) == "\uFEFF" {
+		buffered.Discard(3)
+	}
+
+	reader := csv.NewReader(buffered)
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	return &csvReader{reader: reader}
+}
+
+func (r *csvReader) Read() ([]string, error) {
+	return r.reader.Read()
+}
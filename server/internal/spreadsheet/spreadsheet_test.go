@@ -0,0 +1,28 @@
+package spreadsheet
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCSVReaderDropsByteOrderMark(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{"\uFEFFName,Phone\n", []string{"Name", "Phone"}},
+		{"\uFEFF\"Doe, Jane\",1\n", []string{"Doe, Jane", "1"}},
+		{"Name,Phone\n", []string{"Name", "Phone"}},
+	}
+
+	for _, tt := range tests {
+		got, err := NewCSVReader(strings.NewReader(tt.input), ',').Read()
+		if err != nil {
+			t.Fatalf("Read(%q): %v", tt.input, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Read(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,209 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// maxXLSXCellLength is the longest text an Excel cell holds
+const maxXLSXCellLength = 32767
+
+// Writer writes the rows of a sheet one at a time. Close must be called to complete the
+// file; it does not close the underlying writer.
+type Writer interface {
+	Write(row []string) error
+	Close() error
+}
+
+// ContentType returns the MIME type of a format
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv; charset=utf-8"
+}
+
+// NewWriter returns a writer for a supported format
+func NewWriter(w io.Writer, format string) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVWriter(w)
+	case FormatXLSX:
+		return NewXLSXWriter(w)
+	}
+	return nil, ErrUnsupportedFormat
+}
+
+type csvWriter struct {
+	writer *csv.Writer
+}
+
+// NewCSVWriter writes comma-separated rows preceded by a UTF-8 byte order mark, which
+// Excel needs to detect the encoding
+func NewCSVWriter(w io.Writer) (Writer, error) {
+	if _, err := io.WriteString(w, "\uFEFF"); err != nil {
+		return nil, err
+	}
+	return &csvWriter{writer: csv.NewWriter(w)}, nil
+}
+
+// Write escapes cells that spreadsheet applications would run as formulas
+func (w *csvWriter) Write(row []string) error {
+	escaped := make([]string, len(row))
+	for i, value := range row {
+		escaped[i] = EscapeFormula(value)
+	}
+	return w.writer.Write(escaped)
+}
+
+// formulaPrefixes are the first characters that make spreadsheet applications evaluate a cell
+const formulaPrefixes = "=+-@\t\r"
+
+// EscapeFormula prefixes a CSV cell starting like a formula (=, +, -, @, tab or carriage
+// return) with a single quote, so that Excel shows it as text instead of evaluating it.
+// Numbers and phone numbers such as -5 or +62 812 3456 7890 cannot call anything and are
+// left as they are.
+func EscapeFormula(value string) string {
+	if value != "" && strings.ContainsRune(formulaPrefixes, rune(value[0])) && !isNumeric(value) {
+		return "'" + value
+	}
+	return value
+}
+
+// UnescapeFormula removes the quote EscapeFormula puts in front of a cell, so that
+// exported files can be imported again
+func UnescapeFormula(value string) string {
+	if len(value) > 1 && value[0] == '\'' && strings.ContainsRune(formulaPrefixes, rune(value[1])) {
+		return value[1:]
+	}
+	return value
+}
+
+// isNumeric reports whether value is a signed number or a phone number: digits with
+// spaces, dots, dashes and parentheses after a leading sign
+func isNumeric(value string) bool {
+	digits := false
+	for i, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			digits = true
+		case i == 0 && (r == '+' || r == '-'):
+		case i > 0 && strings.ContainsRune(" .-()", r):
+		default:
+			return false
+		}
+	}
+	return digits
+}
+
+func (w *csvWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+type xlsxWriter struct {
+	archive *zip.Writer
+	sheet   io.Writer
+	rows    int
+}
+
+// xlsxParts are the fixed parts of a single-sheet workbook
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+// NewXLSXWriter streams a single-sheet workbook. Every cell is written as text, so that
+// values such as phone numbers keep their leading zeros and formulas are never evaluated.
+func NewXLSXWriter(w io.Writer) (Writer, error) {
+	archive := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		f, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(f, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.WriteString(sheet, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`)
+	if err != nil {
+		return nil, err
+	}
+
+	return &xlsxWriter{archive: archive, sheet: sheet}, nil
+}
+
+func (w *xlsxWriter) Write(row []string) error {
+	if w.rows == maxXLSXRows {
+		return errors.New("the sheet is full")
+	}
+	w.rows++
+	number := strconv.Itoa(w.rows)
+
+	var b strings.Builder
+	b.WriteString(`<row r="` + number + `">`)
+	for i, value := range row {
+		if i == maxXLSXColumns {
+			break
+		}
+		if value == "" {
+			continue
+		}
+		if utf8.RuneCountInString(value) > maxXLSXCellLength {
+			value = string([]rune(value)[:maxXLSXCellLength])
+		}
+		b.WriteString(`<c r="` + columnName(i) + number + `" t="inlineStr"><is><t xml:space="preserve">`)
+		xml.EscapeText(&b, []byte(value))
+		b.WriteString(`</t></is></c>`)
+	}
+	b.WriteString(`</row>`)
+
+	_, err := io.WriteString(w.sheet, b.String())
+	return err
+}
+
+func (w *xlsxWriter) Close() error {
+	if _, err := io.WriteString(w.sheet, `</sheetData></worksheet>`); err != nil {
+		return err
+	}
+	return w.archive.Close()
+}
+
+// columnName returns the letters of a 0-based column, e.g. 27 is "AB"
+func columnName(index int) string {
+	var name []byte
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = append([]byte{byte('A' + (index-1)%26)}, name...)
+	}
+	return string(name)
+}
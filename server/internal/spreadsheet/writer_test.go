@@ -0,0 +1,95 @@
+package spreadsheet
+
+import (
+	"bytes"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestCSVWriterEscapesFormulas(t *testing.T) {
+	tests := []struct {
+		value string
+		want  string
+	}{
+		{"=HYPERLINK(\"http://evil.example\",\"click\")", `"'=HYPERLINK(""http://evil.example"",""click"")"`},
+		{"+62 812 3456 7890", "+62 812 3456 7890"},
+		{"+1 (555) 123-4567", "+1 (555) 123-4567"},
+		{"-5", "-5"},
+		{"-1.5", "-1.5"},
+		{"-2+3", "'-2+3"},
+		{"+", "'+"},
+		{"-cmd|' /C calc'!A0", "'-cmd|' /C calc'!A0"},
+		{"@SUM(A1:A2)", "'@SUM(A1:A2)"},
+		{"\t=1+1", "'\t=1+1"},
+		{"\r=1+1", "\"'\r=1+1\""},
+		{"Jane", "Jane"},
+		{"a=b", "a=b"},
+		{"", ""},
+	}
+
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		w, err := NewCSVWriter(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Write([]string{tt.value}); err != nil {
+			t.Fatal(err)
+		}
+		if err := w.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		got := bytes.TrimSuffix(bytes.TrimPrefix(buf.Bytes(), []byte("\uFEFF")), []byte("\n"))
+		if string(got) != tt.want {
+			t.Errorf("Write(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestCSVWriterKeepsRow(t *testing.T) {
+	row := []string{"=1+1", "ok"}
+	w, err := NewCSVWriter(&bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(row); err != nil {
+		t.Fatal(err)
+	}
+	if row[0] != "=1+1" {
+		t.Errorf("Write changed the caller's row to %q", row)
+	}
+}
+
+func TestCSVExportRoundTrip(t *testing.T) {
+	row := []string{"=HYPERLINK(\"http://evil.example\")", "+62 812 3456 7890", "-5", "-2+3", "@home", "'quoted", "Jane"}
+
+	var buf bytes.Buffer
+	w, err := NewCSVWriter(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Write(row); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewCSVReader(&buf, ',')
+	cells, err := r.Read()
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make([]string, len(cells))
+	for i, cell := range cells {
+		got[i] = UnescapeFormula(cell)
+	}
+	if !reflect.DeepEqual(got, row) {
+		t.Errorf("round trip = %q, want %q", got, row)
+	}
+	if _, err := r.Read(); err != io.EOF {
+		t.Errorf("Read after the last row = %v, want io.EOF", err)
+	}
+}
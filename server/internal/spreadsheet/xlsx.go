@@ -0,0 +1,379 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Sheet size limits of Excel, which bound the cell references accepted in a sheet and
+// the length of its text
+const (
+	maxXLSXRows         = 1048576
+	maxXLSXColumns      = 16384
+	maxXLSXStringLength = 32767
+)
+
+// Uncompressed size limits, so that a small zip cannot inflate without bound. The sheet
+// is streamed; the shared strings and other parts are held in memory.
+const (
+	maxXLSXSheetSize     = 512 << 20
+	maxXLSXPartSize      = 128 << 20
+	maxXLSXSharedStrings = 2000000
+)
+
+var (
+	errNotXLSX      = errors.New("the file is not a valid .xlsx workbook")
+	errXLSXTooLarge = errors.New("the workbook is too large, split it into several files")
+)
+
+type xlsxReader struct {
+	sheet      io.ReadCloser
+	decoder    *xml.Decoder
+	strings    []string
+	nextRow    int // 1-based number of the row Read returns next
+	pending    []string
+	pendingRow int
+	done       bool
+}
+
+// NewXLSXReader reads the first worksheet of an Office Open XML workbook. Cells are
+// returned as displayed for text and as stored for numbers (no date or number formats);
+// formulas return their cached value. Empty rows are returned as empty slices so that
+// row numbers match the sheet.
+func NewXLSXReader(r io.ReaderAt, size int64) (Reader, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, errNotXLSX
+	}
+
+	sheetPath, err := firstSheetPath(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	shared, err := readSharedStrings(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	sheet, err := openPart(archive, sheetPath, maxXLSXSheetSize)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, errNotXLSX
+		}
+		return nil, err
+	}
+
+	return &xlsxReader{
+		sheet:   sheet,
+		decoder: xml.NewDecoder(sheet),
+		strings: shared,
+		nextRow: 1,
+	}, nil
+}
+
+func (r *xlsxReader) Read() ([]string, error) {
+	if r.pending == nil && !r.done {
+		row, number, err := r.readRow()
+		if err == io.EOF {
+			r.done = true
+			r.sheet.Close()
+		} else if err != nil {
+			return nil, err
+		} else {
+			r.pending, r.pendingRow = row, number
+		}
+	}
+	if r.pending == nil {
+		return nil, io.EOF
+	}
+
+	// Rows absent from the sheet are empty
+	if r.pendingRow > r.nextRow {
+		r.nextRow++
+		return []string{}, nil
+	}
+
+	row := r.pending
+	r.pending = nil
+	r.nextRow++
+	return row, nil
+}
+
+// readRow decodes the next <row> element and its 1-based number
+func (r *xlsxReader) readRow() ([]string, int, error) {
+	for {
+		token, err := r.decoder.Token()
+		if err != nil {
+			if err == io.EOF {
+				return nil, 0, io.EOF
+			}
+			return nil, 0, errNotXLSX
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+
+		var row xlsxRow
+		if err := r.decoder.DecodeElement(&row, &start); err != nil {
+			return nil, 0, errNotXLSX
+		}
+		if len(row.Cells) > maxXLSXColumns {
+			return nil, 0, errNotXLSX
+		}
+
+		number := row.Number
+		if number > maxXLSXRows {
+			return nil, 0, errNotXLSX
+		}
+		if number < r.nextRow {
+			number = r.nextRow
+		}
+		cells, err := r.cells(row)
+		if err != nil {
+			return nil, 0, err
+		}
+		return cells, number, nil
+	}
+}
+
+// cells places a row's cells at their columns
+func (r *xlsxReader) cells(row xlsxRow) ([]string, error) {
+	values := []string{}
+	for _, cell := range row.Cells {
+		column := len(values)
+		if cell.Ref != "" {
+			parsed, err := columnIndex(cell.Ref)
+			if err != nil {
+				return nil, err
+			}
+			column = parsed
+		}
+		for len(values) <= column {
+			values = append(values, "")
+		}
+
+		value, err := r.value(cell)
+		if err != nil {
+			return nil, err
+		}
+		values[column] = value
+	}
+	return values, nil
+}
+
+func (r *xlsxReader) value(cell xlsxCell) (string, error) {
+	switch cell.Type {
+	case "s":
+		index, err := strconv.Atoi(strings.TrimSpace(cell.Value))
+		if err != nil || index < 0 || index >= len(r.strings) {
+			return "", errNotXLSX
+		}
+		return r.strings[index], nil
+	case "inlineStr":
+		text := cell.Inline.text()
+		if utf8.RuneCountInString(text) > maxXLSXStringLength {
+			return "", errNotXLSX
+		}
+		return text, nil
+	case "b":
+		if cell.Value == "1" {
+			return "TRUE", nil
+		}
+		return "FALSE", nil
+	case "n", "":
+		return formatNumber(cell.Value), nil
+	}
+	return cell.Value, nil // str (formula result), e (error such as #N/A), d (ISO date)
+}
+
+// formatNumber writes numbers without exponent, e.g. phone numbers stored as numbers
+func formatNumber(value string) string {
+	if !strings.ContainsAny(value, "eE") {
+		return value
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return value
+	}
+	return strconv.FormatFloat(f, 'f', -1, 64)
+}
+
+// columnIndex returns the 0-based column of a cell reference such as "AB12"
+func columnIndex(ref string) (int, error) {
+	letters := strings.ToUpper(strings.TrimRight(ref, "0123456789"))
+	column := 0
+	for _, c := range letters {
+		if c < 'A' || c > 'Z' || column > maxXLSXColumns {
+			return 0, fmt.Errorf("invalid cell reference %q", ref)
+		}
+		column = column*26 + int(c-'A'+1)
+	}
+	if column == 0 || column > maxXLSXColumns {
+		return 0, fmt.Errorf("invalid cell reference %q", ref)
+	}
+	return column - 1, nil
+}
+
+type xlsxRow struct {
+	Number int        `xml:"r,attr"`
+	Cells  []xlsxCell `xml:"c"`
+}
+
+type xlsxCell struct {
+	Ref    string     `xml:"r,attr"`
+	Type   string     `xml:"t,attr"`
+	Value  string     `xml:"v"`
+	Inline xlsxString `xml:"is"`
+}
+
+// xlsxString is a shared or inline string: plain text or rich text runs. Phonetic
+// guides (rPh) are not part of the text.
+type xlsxString struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (s xlsxString) text() string {
+	if len(s.Runs) == 0 {
+		return s.Text
+	}
+	var b strings.Builder
+	b.WriteString(s.Text)
+	for _, run := range s.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+// readSharedStrings loads the workbook's string table. Workbooks without text have none.
+func readSharedStrings(archive *zip.Reader) ([]string, error) {
+	f, err := openPart(archive, "xl/sharedStrings.xml", maxXLSXPartSize)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	var shared []string
+	decoder := xml.NewDecoder(f)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return shared, nil
+		}
+		if err != nil {
+			return nil, errNotXLSX
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok || start.Name.Local != "si" {
+			continue
+		}
+		if len(shared) >= maxXLSXSharedStrings {
+			return nil, errXLSXTooLarge
+		}
+		var s xlsxString
+		if err := decoder.DecodeElement(&s, &start); err != nil {
+			return nil, errNotXLSX
+		}
+		text := s.text()
+		if utf8.RuneCountInString(text) > maxXLSXStringLength {
+			return nil, errNotXLSX
+		}
+		shared = append(shared, text)
+	}
+}
+
+// firstSheetPath resolves the part name of the workbook's first worksheet
+func firstSheetPath(archive *zip.Reader) (string, error) {
+	var workbook struct {
+		Sheets []struct {
+			Attrs []xml.Attr `xml:",any,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	if err := decodePart(archive, "xl/workbook.xml", &workbook); err != nil || len(workbook.Sheets) == 0 {
+		return "", errNotXLSX
+	}
+
+	// The relationship ID is the namespaced id attribute (r:id)
+	var relID string
+	for _, attr := range workbook.Sheets[0].Attrs {
+		if attr.Name.Local == "id" && attr.Name.Space != "" {
+			relID = attr.Value
+		}
+	}
+
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := decodePart(archive, "xl/_rels/workbook.xml.rels", &rels); err == nil {
+		for _, rel := range rels.Relationships {
+			if rel.ID != relID {
+				continue
+			}
+			if strings.HasPrefix(rel.Target, "/") {
+				return strings.TrimPrefix(rel.Target, "/"), nil
+			}
+			return path.Join("xl", rel.Target), nil
+		}
+	}
+
+	return "xl/worksheets/sheet1.xml", nil
+}
+
+func decodePart(archive *zip.Reader, name string, v interface{}) error {
+	f, err := openPart(archive, name, maxXLSXPartSize)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return xml.NewDecoder(f).Decode(v)
+}
+
+// openPart opens a part of the workbook of at most limit bytes uncompressed. Larger
+// declared sizes are refused up front and the content is cut off at limit, which the
+// XML decoder then rejects as truncated.
+func openPart(archive *zip.Reader, name string, limit int64) (io.ReadCloser, error) {
+	f, err := archive.Open(name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+		return nil, errNotXLSX
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, errNotXLSX
+	}
+	if info.Size() < 0 || info.Size() > limit {
+		f.Close()
+		return nil, errXLSXTooLarge
+	}
+
+	return &limitedPart{Reader: io.LimitReader(f, limit), Closer: f}, nil
+}
+
+type limitedPart struct {
+	io.Reader
+	io.Closer
+}
@@ -0,0 +1,180 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestXLSXReader(t *testing.T) {
+	missingRow := make([]string, 28)
+	missingRow[0], missingRow[27] = "last", "1"
+
+	tests := []struct {
+		file string
+		want [][]string
+	}{
+		{"shared_strings.xlsx", [][]string{
+			{"Name", "Phone", "Customer", "Active"},
+			{"Jane Doe", "6281234567890", "TRUE", "yes"},
+			{"Budi Santoso", "6281234567890", "FALSE", "#N/A"},
+			{"Yamada", "42.5", " padded "},
+		}},
+		{"inline_strings.xlsx", [][]string{
+			{"Name", "Email"},
+			{"Siti Rahma", "siti@example.com"},
+			{"Rich text", "=1+1"},
+		}},
+		{"missing_rows.xlsx", [][]string{
+			{"A", "", "C"},
+			{},
+			{"", "7"},
+			{},
+			{},
+			missingRow,
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.file, func(t *testing.T) {
+			data, err := os.ReadFile(filepath.Join("testdata", tt.file))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := readAll(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rows = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestXLSXReaderLimits(t *testing.T) {
+	workbook := `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheets><sheet name="Sheet1" sheetId="1"/></sheets></workbook>`
+	sheet := `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData><row r="1"><c r="A1" t="s"><v>0</v></c></row></sheetData></worksheet>`
+	sharedStrings := func(text string) string {
+		return `<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><si><t>` + text + `</t></si></sst>`
+	}
+
+	tests := []struct {
+		name  string
+		parts map[string]string
+		sizes map[string]uint64 // Declared uncompressed sizes
+		want  error
+	}{
+		{
+			name: "valid",
+			parts: map[string]string{
+				"xl/workbook.xml":          workbook,
+				"xl/worksheets/sheet1.xml": sheet,
+				"xl/sharedStrings.xml":     sharedStrings("ok"),
+			},
+		},
+		{
+			name: "sheet declared too large",
+			parts: map[string]string{
+				"xl/workbook.xml":          workbook,
+				"xl/worksheets/sheet1.xml": sheet,
+			},
+			sizes: map[string]uint64{"xl/worksheets/sheet1.xml": maxXLSXSheetSize + 1},
+			want:  errXLSXTooLarge,
+		},
+		{
+			name: "shared strings declared too large",
+			parts: map[string]string{
+				"xl/workbook.xml":          workbook,
+				"xl/worksheets/sheet1.xml": sheet,
+				"xl/sharedStrings.xml":     sharedStrings("ok"),
+			},
+			sizes: map[string]uint64{"xl/sharedStrings.xml": 1 << 40},
+			want:  errXLSXTooLarge,
+		},
+		{
+			name: "shared string too long",
+			parts: map[string]string{
+				"xl/workbook.xml":          workbook,
+				"xl/worksheets/sheet1.xml": sheet,
+				"xl/sharedStrings.xml":     sharedStrings(strings.Repeat("a", maxXLSXStringLength+1)),
+			},
+			want: errNotXLSX,
+		},
+		{
+			name: "shared string index out of range",
+			parts: map[string]string{
+				"xl/workbook.xml":          workbook,
+				"xl/worksheets/sheet1.xml": sheet,
+			},
+			want: errNotXLSX,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := readAll(buildWorkbook(t, tt.parts, tt.sizes))
+			if !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// readAll returns every row of the first sheet of a workbook
+func readAll(data []byte) ([][]string, error) {
+	reader, err := NewXLSXReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	var rows [][]string
+	for {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+}
+
+// buildWorkbook zips the given parts, stored uncompressed. A part with a size in sizes
+// declares that uncompressed size in its header instead of its real one.
+func buildWorkbook(t *testing.T, parts map[string]string, sizes map[string]uint64) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range parts {
+		var err error
+		var f io.Writer
+		if size, ok := sizes[name]; ok {
+			f, err = w.CreateRaw(&zip.FileHeader{
+				Name:               name,
+				Method:             zip.Store,
+				CompressedSize64:   uint64(len(content)),
+				UncompressedSize64: size,
+			})
+		} else {
+			f, err = w.Create(name)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := io.WriteString(f, content); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
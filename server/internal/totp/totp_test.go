@@ -0,0 +1,105 @@
+package totp
+
+import (
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA-1 key of RFC 6238 Appendix B, "12345678901234567890", base32 encoded
+var rfcSecret = encoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestValidateRFC6238Vectors(t *testing.T) {
+	// The 8 digit codes of Appendix B, truncated to the 6 digits used here (RFC 4226 5.3)
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, tt := range tests {
+		at := time.Unix(tt.unix, 0)
+		step, ok := Validate(rfcSecret, tt.code, at)
+		if !ok {
+			t.Errorf("Validate(%q) at %d = false, want true", tt.code, tt.unix)
+			continue
+		}
+		if want := tt.unix / period; step != want {
+			t.Errorf("Validate(%q) at %d step = %d, want %d", tt.code, tt.unix, step, want)
+		}
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	key, _ := encoding.DecodeString(rfcSecret)
+	now := time.Unix(1234567890, 0)
+	current := now.Unix() / period
+
+	tests := []struct {
+		name   string
+		offset int64
+		want   bool
+	}{
+		{"two steps before", -2, false},
+		{"one step before", -1, true},
+		{"current step", 0, true},
+		{"one step after", 1, true},
+		{"two steps after", 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := Validate(rfcSecret, generate(key, current+tt.offset), now)
+			if ok != tt.want {
+				t.Fatalf("Validate = %v, want %v", ok, tt.want)
+			}
+			if ok && step != current+tt.offset {
+				t.Errorf("step = %d, want %d", step, current+tt.offset)
+			}
+		})
+	}
+}
+
+func TestValidateReplay(t *testing.T) {
+	key, _ := encoding.DecodeString(rfcSecret)
+	first := time.Unix(1234567890, 0)
+	code := generate(key, first.Unix()/period)
+
+	step, ok := Validate(rfcSecret, code, first)
+	if !ok {
+		t.Fatal("Validate = false, want true")
+	}
+
+	// A code replayed within the skew matches the step it was used for, which callers
+	// reject as not after the last used step
+	replayed, ok := Validate(rfcSecret, code, first.Add(period*time.Second))
+	if !ok || replayed != step {
+		t.Errorf("replayed Validate = %d, %v; want %d, true", replayed, ok, step)
+	}
+
+	// The next code has a later step
+	next, ok := Validate(rfcSecret, generate(key, step+1), first.Add(period*time.Second))
+	if !ok || next <= step {
+		t.Errorf("next Validate = %d, %v; want a step after %d", next, ok, step)
+	}
+}
+
+func TestValidateRejectsMalformedCodes(t *testing.T) {
+	now := time.Unix(59, 0)
+	for _, code := range []string{"", "28708", "2870820", "abcdef", "94287082"} {
+		if _, ok := Validate(rfcSecret, code, now); ok {
+			t.Errorf("Validate(%q) = true, want false", code)
+		}
+	}
+	if _, ok := Validate("not base32!", "287082", now); ok {
+		t.Error("Validate with an invalid secret = true, want false")
+	}
+	if _, ok := Validate(rfcSecret, " 287082 ", now); !ok {
+		t.Error("Validate with a padded code = false, want true")
+	}
+}